@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/chuckie/commit-coach/internal/ports"
 )
@@ -11,25 +12,58 @@ import (
 // InMemory is a simple in-memory cache protected by a mutex.
 type InMemory struct {
 	mu    sync.RWMutex
-	cache map[string][]ports.CommitSuggestion
+	cache map[string]entry
+	// ttl bounds an entry's age; zero keeps entries forever.
+	ttl   time.Duration
+	clock ports.Clock
+}
+
+type entry struct {
+	suggestions []ports.CommitSuggestion
+	stored      time.Time
 }
 
 // NewInMemory creates a new in-memory cache.
 func NewInMemory() *InMemory {
 	return &InMemory{
-		cache: make(map[string][]ports.CommitSuggestion),
+		cache: make(map[string]entry),
 	}
 }
 
-// Get retrieves cached suggestions by key.
+// NewInMemoryWithTTL creates an in-memory cache whose entries miss once they
+// are older than ttl, as measured by clock.
+func NewInMemoryWithTTL(ttl time.Duration, clock ports.Clock) *InMemory {
+	c := NewInMemory()
+	c.ttl = ttl
+	c.clock = clock
+	return c
+}
+
+func (c *InMemory) now() time.Time {
+	if c.clock == nil {
+		return time.Now()
+	}
+	return c.clock.Now()
+}
+
+func (c *InMemory) expired(e entry) bool {
+	return c.ttl > 0 && c.now().Sub(e.stored) >= c.ttl
+}
+
+// Get retrieves cached suggestions by key. Expired entries are dropped and
+// reported as a miss.
 func (c *InMemory) Get(ctx context.Context, key string) ([]ports.CommitSuggestion, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	if val, ok := c.cache[key]; ok {
+	if e, ok := c.cache[key]; ok {
+		if c.expired(e) {
+			delete(c.cache, key)
+			return nil, fmt.Errorf("cache miss")
+		}
 		// Return a copy to prevent external mutation
-		result := make([]ports.CommitSuggestion, len(val))
-		copy(result, val)
+		result := make([]ports.CommitSuggestion, len(e.suggestions))
+		copy(result, e.suggestions)
 		return result, nil
 	}
 
@@ -44,8 +78,16 @@ func (c *InMemory) Set(ctx context.Context, key string, suggestions []ports.Comm
 	// Store a copy to prevent external mutation
 	cached := make([]ports.CommitSuggestion, len(suggestions))
 	copy(cached, suggestions)
-	c.cache[key] = cached
+	c.cache[key] = entry{suggestions: cached, stored: c.now()}
+
+	return nil
+}
 
+// Delete removes the entry for key, if any.
+func (c *InMemory) Delete(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.cache, key)
 	return nil
 }
 
@@ -53,10 +95,18 @@ func (c *InMemory) Set(ctx context.Context, key string, suggestions []ports.Comm
 func (c *InMemory) Clear() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	c.cache = make(map[string][]ports.CommitSuggestion)
+	c.cache = make(map[string]entry)
+}
+
+// Close implements io.Closer by releasing every entry. The cache stays
+// usable, starting empty.
+func (c *InMemory) Close() error {
+	c.Clear()
+	return nil
 }
 
-// Size returns the number of cached entries.
+// Size returns the number of cached entries, including expired ones not yet
+// looked up.
 func (c *InMemory) Size() int {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
@@ -0,0 +1,40 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/chuckie/commit-coach/internal/ports"
+	"github.com/chuckie/commit-coach/internal/testutil"
+)
+
+func TestInMemoryTTLExpiry(t *testing.T) {
+	ctx := context.Background()
+	clock := &testutil.FakeClock{T: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	c := NewInMemoryWithTTL(10*time.Minute, clock)
+	want := []ports.CommitSuggestion{{Type: "feat", Subject: "add parser"}}
+	_ = c.Set(ctx, "k", want)
+
+	clock.Advance(10*time.Minute - time.Second)
+	if got, err := c.Get(ctx, "k"); err != nil || len(got) != 1 {
+		t.Fatalf("Get() before TTL = %v, %v; want a hit", got, err)
+	}
+
+	clock.Advance(time.Second)
+	if _, err := c.Get(ctx, "k"); err == nil {
+		t.Error("Get() at TTL should miss")
+	}
+	if c.Size() != 0 {
+		t.Errorf("Size() = %d, want the expired entry dropped", c.Size())
+	}
+}
+
+func TestInMemoryWithoutTTLKeepsEntries(t *testing.T) {
+	ctx := context.Background()
+	c := NewInMemory()
+	_ = c.Set(ctx, "k", []ports.CommitSuggestion{{Type: "fix", Subject: "handle nil"}})
+	if _, err := c.Get(ctx, "k"); err != nil {
+		t.Errorf("Get() error = %v, want a hit", err)
+	}
+}
@@ -0,0 +1,12 @@
+// Package clock provides the real ports.Clock.
+package clock
+
+import "time"
+
+// System is the wall clock.
+type System struct{}
+
+// Now returns time.Now().
+func (System) Now() time.Time {
+	return time.Now()
+}
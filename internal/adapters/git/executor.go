@@ -1,29 +1,103 @@
 package git
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/chuckie/commit-coach/internal/ports"
 )
 
+// commitHooks are the hooks that can make git commit fail.
+var commitHooks = []string{"pre-commit", "prepare-commit-msg", "commit-msg"}
+
 // Executor implements ports.Git using os/exec.
 type Executor struct {
-	timeout time.Duration
+	timeout          time.Duration
+	commitTimeout    time.Duration
+	useMessageFlag   bool
+	submoduleContext bool
+	intentToAdd      bool
+	sign             bool
+	signingKey       string
 }
 
-// NewExecutor creates a new git executor.
-func NewExecutor() *Executor {
+// DefaultTimeout bounds each git command when NewExecutor is given none.
+const DefaultTimeout = ports.DefaultGitTimeout
+
+// waitDelay is how long a killed git command may keep its output pipes open,
+// e.g. through a hook's child process, before Wait gives up on them.
+const waitDelay = time.Second
+
+// NewExecutor creates a new git executor whose commands each run for at most
+// timeout (DefaultTimeout when timeout <= 0), or until the caller's context
+// ends if that is sooner.
+func NewExecutor(timeout time.Duration) *Executor {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
 	return &Executor{
-		timeout: 10 * time.Second,
+		timeout: timeout,
 	}
 }
 
+// SetCommitTimeout bounds git commit, which runs the commit hooks and so can
+// take far longer than other commands. 0, the default, leaves it to the
+// caller's context.
+func (e *Executor) SetCommitTimeout(d time.Duration) {
+	e.commitTimeout = d
+}
+
+// withTimeout limits ctx to the executor's timeout; an earlier deadline on
+// ctx still wins.
+func (e *Executor) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, e.timeout)
+}
+
+// gitCommand returns a git command that is killed when ctx ends.
+func gitCommand(ctx context.Context, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.WaitDelay = waitDelay
+	return cmd
+}
+
+// SetUseMessageFlag makes Commit pass single-line messages with -m instead of
+// a temp file (-F), so they read naturally in shell history and reflog tools.
+func (e *Executor) SetUseMessageFlag(v bool) {
+	e.useMessageFlag = v
+}
+
+// SetSubmoduleContext makes StagedDiff summarize submodule pointer changes as
+// commit logs (--submodule=log) instead of bare hash lines.
+func (e *Executor) SetSubmoduleContext(v bool) {
+	e.submoduleContext = v
+}
+
+// SetSigning makes Commit sign commits (-S), with keyID when it is set
+// (--gpg-sign=<keyID>). The format (gpg.format) is left to git config.
+func (e *Executor) SetSigning(enabled bool, keyID string) {
+	e.sign = enabled
+	e.signingKey = keyID
+}
+
+// SetIncludeIntentToAdd makes StagedDiff append the contents of files added
+// with "git add -N", which git diff --cached leaves out.
+func (e *Executor) SetIncludeIntentToAdd(v bool) {
+	e.intentToAdd = v
+}
+
 // IsInRepository checks if we are in a valid git repository.
 func (e *Executor) IsInRepository(ctx context.Context) (bool, error) {
-	cmd := exec.CommandContext(ctx, "git", "rev-parse", "--is-inside-work-tree")
+	ctx, cancel := e.withTimeout(ctx)
+	defer cancel()
+	cmd := gitCommand(ctx, "rev-parse", "--is-inside-work-tree")
 	output, err := cmd.Output()
 	if err != nil {
 		return false, nil // Not in repo
@@ -31,18 +105,321 @@ func (e *Executor) IsInRepository(ctx context.Context) (bool, error) {
 	return strings.TrimSpace(string(output)) == "true", nil
 }
 
-// StagedDiff returns the staged diff (git diff --cached --no-color).
+// StagedDiff returns the staged diff (git diff --cached --no-color), plus
+// submodule logs and intent-to-add files when enabled.
 func (e *Executor) StagedDiff(ctx context.Context) (string, error) {
-	cmd := exec.CommandContext(ctx, "git", "diff", "--cached", "--no-color")
+	return e.stagedDiff(ctx, nil)
+}
+
+// StagedDiffForPaths returns the staged diff limited to paths
+// (git diff --cached --no-color -- <paths>).
+func (e *Executor) StagedDiffForPaths(ctx context.Context, paths []string) (string, error) {
+	return e.stagedDiff(ctx, paths)
+}
+
+func (e *Executor) stagedDiff(ctx context.Context, paths []string) (string, error) {
+	ctx, cancel := e.withTimeout(ctx)
+	defer cancel()
+	cmd := gitCommand(ctx, withPaths(e.stagedDiffArgs(), paths)...)
 	output, err := cmd.Output()
 	if err != nil {
 		return "", fmt.Errorf("git diff failed: %w", err)
 	}
+	diff := string(output)
+
+	if e.intentToAdd {
+		// Intent-to-add entries are the only additions between the index and
+		// the working tree, so --diff-filter=A selects exactly those files.
+		cmd := gitCommand(ctx, withPaths([]string{"diff", "--no-color", "--diff-filter=A"}, paths)...)
+		ita, err := cmd.Output()
+		if err != nil {
+			return "", fmt.Errorf("git diff (intent-to-add) failed: %w", err)
+		}
+		diff += string(ita)
+	}
+	return diff, nil
+}
+
+// withPaths appends a pathspec list to git args; no paths means no limit.
+func withPaths(args, paths []string) []string {
+	if len(paths) == 0 {
+		return args
+	}
+	return append(append(args, "--"), paths...)
+}
+
+// WorkingTreeDiff returns the unstaged changes (git diff --no-color).
+func (e *Executor) WorkingTreeDiff(ctx context.Context) (string, error) {
+	ctx, cancel := e.withTimeout(ctx)
+	defer cancel()
+	args := []string{"diff", "--no-color"}
+	if e.submoduleContext {
+		args = append(args, "--submodule=log")
+	}
+	output, err := gitCommand(ctx, args...).Output()
+	if err != nil {
+		return "", fmt.Errorf("git diff failed: %w", err)
+	}
+	return string(output), nil
+}
+
+// stagedDiffArgs returns the arguments for the staged diff command.
+func (e *Executor) stagedDiffArgs() []string {
+	args := []string{"diff", "--cached", "--no-color"}
+	if e.submoduleContext {
+		args = append(args, "--submodule=log")
+	}
+	return args
+}
+
+// ConfigValue returns the value of a git config key (git config --get).
+// Unset keys return an empty string without error.
+func (e *Executor) ConfigValue(ctx context.Context, key string) (string, error) {
+	ctx, cancel := e.withTimeout(ctx)
+	defer cancel()
+	cmd := gitCommand(ctx, "config", "--get", key)
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			stderr := strings.TrimSpace(string(exitErr.Stderr))
+			// Exit code 1 with no diagnostics means the key is simply not set.
+			if exitErr.ExitCode() == 1 && stderr == "" {
+				return "", nil
+			}
+			return "", fmt.Errorf("git config --get %s failed: %s", key, stderr)
+		}
+		return "", fmt.Errorf("git config --get %s failed: %w", key, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// CurrentBranch returns the checked-out branch (git rev-parse --abbrev-ref HEAD).
+// A detached HEAD is reported as "HEAD".
+func (e *Executor) CurrentBranch(ctx context.Context) (string, error) {
+	ctx, cancel := e.withTimeout(ctx)
+	defer cancel()
+	cmd := gitCommand(ctx, "rev-parse", "--abbrev-ref", "HEAD")
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("git rev-parse failed: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("git rev-parse failed: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// RootDir returns the working tree's top level (git rev-parse --show-toplevel).
+func (e *Executor) RootDir(ctx context.Context) (string, error) {
+	ctx, cancel := e.withTimeout(ctx)
+	defer cancel()
+	cmd := gitCommand(ctx, "rev-parse", "--show-toplevel")
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("git rev-parse failed: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("git rev-parse failed: %w", err)
+	}
+	return filepath.FromSlash(strings.TrimSpace(string(output))), nil
+}
+
+// HeadHash returns the abbreviated hash of HEAD (git rev-parse --short HEAD).
+func (e *Executor) HeadHash(ctx context.Context) (string, error) {
+	ctx, cancel := e.withTimeout(ctx)
+	defer cancel()
+	cmd := gitCommand(ctx, "rev-parse", "--short", "HEAD")
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("git rev-parse failed: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("git rev-parse failed: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// CommitSubject returns the subject of the commit ref names (git log -1 --format=%s).
+func (e *Executor) CommitSubject(ctx context.Context, ref string) (string, error) {
+	ctx, cancel := e.withTimeout(ctx)
+	defer cancel()
+	if ref == "" || strings.HasPrefix(ref, "-") {
+		return "", fmt.Errorf("invalid commit ref %q", ref)
+	}
+	cmd := gitCommand(ctx, "log", "-1", "--format=%s", ref, "--")
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("git log %s failed: %s", ref, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("git log %s failed: %w", ref, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// RecentSubjects returns the subjects of the last n commits on HEAD
+// (git log -n <n> --format=%s). A repository without commits has none.
+func (e *Executor) RecentSubjects(ctx context.Context, n int) ([]string, error) {
+	ctx, cancel := e.withTimeout(ctx)
+	defer cancel()
+	if _, err := gitCommand(ctx, "rev-parse", "--verify", "-q", "HEAD").Output(); err != nil {
+		return nil, nil
+	}
+	cmd := gitCommand(ctx, "log", "-n", strconv.Itoa(n), "--format=%s", "HEAD", "--")
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("git log failed: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("git log failed: %w", err)
+	}
+	var subjects []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			subjects = append(subjects, line)
+		}
+	}
+	return subjects, nil
+}
+
+// UndoLastCommit drops the HEAD commit and keeps its changes staged
+// (git reset --soft HEAD~1).
+func (e *Executor) UndoLastCommit(ctx context.Context) error {
+	ctx, cancel := e.withTimeout(ctx)
+	defer cancel()
+	cmd := gitCommand(ctx, "reset", "--soft", "HEAD~1")
+	if _, err := cmd.Output(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return fmt.Errorf("git reset failed: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return fmt.Errorf("git reset failed: %w", err)
+	}
+	return nil
+}
+
+// StageAll stages every change in the working tree (git add -A).
+func (e *Executor) StageAll(ctx context.Context) error {
+	ctx, cancel := e.withTimeout(ctx)
+	defer cancel()
+	cmd := gitCommand(ctx, "add", "-A")
+	if _, err := cmd.Output(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return fmt.Errorf("git add failed: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return fmt.Errorf("git add failed: %w", err)
+	}
+	return nil
+}
+
+// RepoState reports a merge or rebase in progress by looking for MERGE_HEAD,
+// rebase-merge and rebase-apply in the git directory.
+func (e *Executor) RepoState(ctx context.Context) (ports.RepoState, error) {
+	ctx, cancel := e.withTimeout(ctx)
+	defer cancel()
+	root, err := e.RootDir(ctx)
+	if err != nil {
+		return ports.RepoState{}, err
+	}
+	names := []string{"MERGE_HEAD", "MERGE_MSG", "rebase-merge", "rebase-apply"}
+	var args []string
+	for _, name := range names {
+		args = append(args, "--git-path", name)
+	}
+	cmd := gitCommand(ctx, append([]string{"rev-parse"}, args...)...)
+	cmd.Dir = root
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return ports.RepoState{}, fmt.Errorf("git rev-parse failed: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return ports.RepoState{}, fmt.Errorf("git rev-parse failed: %w", err)
+	}
+	paths := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if len(paths) != len(names) {
+		return ports.RepoState{}, fmt.Errorf("git rev-parse returned %d paths, want %d", len(paths), len(names))
+	}
+	resolve := func(i int) string {
+		path := filepath.FromSlash(strings.TrimSpace(paths[i]))
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(root, path)
+		}
+		return path
+	}
+	exists := func(i int) bool {
+		_, err := os.Stat(resolve(i))
+		return err == nil
+	}
+
+	var state ports.RepoState
+	state.Merging = exists(0)
+	state.Rebasing = exists(2) || exists(3)
+	if state.Merging {
+		if b, err := os.ReadFile(resolve(1)); err == nil {
+			state.MergeMessage = stripCommentLines(string(b))
+		}
+	}
+	return state, nil
+}
+
+// stripCommentLines drops git's "#" comment lines from a prepared message.
+func stripCommentLines(message string) string {
+	var kept []string
+	for _, line := range strings.Split(message, "\n") {
+		if !strings.HasPrefix(line, "#") {
+			kept = append(kept, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(kept, "\n"))
+}
+
+// HooksDir returns the repository's hooks directory (git rev-parse --git-path hooks),
+// honouring core.hooksPath. The path is resolved from RootDir, so it is
+// absolute and the same from any subdirectory.
+func (e *Executor) HooksDir(ctx context.Context) (string, error) {
+	ctx, cancel := e.withTimeout(ctx)
+	defer cancel()
+	root, err := e.RootDir(ctx)
+	if err != nil {
+		return "", err
+	}
+	cmd := gitCommand(ctx, "rev-parse", "--git-path", "hooks")
+	cmd.Dir = root
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("git rev-parse failed: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("git rev-parse failed: %w", err)
+	}
+	dir := filepath.FromSlash(strings.TrimSpace(string(output)))
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(root, dir)
+	}
+	return dir, nil
+}
+
+// CommitMessage returns the full message of the given commit (git log -1 --format=%B).
+func (e *Executor) CommitMessage(ctx context.Context, ref string) (string, error) {
+	ctx, cancel := e.withTimeout(ctx)
+	defer cancel()
+	cmd := gitCommand(ctx, "log", "-1", "--format=%B", ref, "--")
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("git log failed: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("git log failed: %w", err)
+	}
 	return string(output), nil
 }
 
 // Commit runs git commit with a temp file message.
-func (e *Executor) Commit(ctx context.Context, message string, dryRun bool) (string, error) {
+func (e *Executor) Commit(ctx context.Context, message string, opts ports.CommitOptions) (string, error) {
+	if e.commitTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.commitTimeout)
+		defer cancel()
+	}
 	// Create temp file for message
 	tmpFile, err := os.CreateTemp("", "commit-coach-*.txt")
 	if err != nil {
@@ -61,24 +438,42 @@ func (e *Executor) Commit(ctx context.Context, message string, dryRun bool) (str
 	tmpFile.Close()
 
 	// Dry run: just show what would be committed
-	if dryRun {
+	if opts.DryRun {
 		return "[DRY RUN] Would commit:\n" + message, nil
 	}
 
+	if len(opts.Paths) > 0 {
+		if err := e.checkPathsStaged(ctx, opts.Paths); err != nil {
+			return "", err
+		}
+	}
+
 	// Execute git commit
-	cmd := exec.CommandContext(ctx, "git", "commit", "-F", tmpFile.Name())
-	output, err := cmd.Output()
-	if err != nil {
-		// Get stderr for better error messages
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			stderr := string(exitErr.Stderr)
-			return "", fmt.Errorf("git commit failed: %s", stderr)
+	cmd := gitCommand(ctx, e.commitArgs(message, tmpFile.Name(), opts)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("git commit did not finish: %w", ctx.Err())
+		}
+		if _, ok := err.(*exec.ExitError); ok {
+			// Hooks may print to either stream; git's own refusals are
+			// "fatal:" lines on stderr.
+			errOut := strings.TrimSpace(stderr.String())
+			if e.sign && (strings.Contains(errOut, "sign") || strings.Contains(errOut, "failed to write commit object")) {
+				return "", fmt.Errorf("git commit signing failed (check user.signingkey and gpg.format): %s", errOut)
+			}
+			if !opts.NoVerify && !strings.HasPrefix(errOut, "fatal:") && e.hasCommitHook(ctx) {
+				return "", &ports.HookRejectedError{Output: strings.TrimSpace(stdout.String() + "\n" + errOut)}
+			}
+			return "", fmt.Errorf("git commit failed: %s", errOut)
 		}
 		return "", fmt.Errorf("git commit failed: %w", err)
 	}
 
 	// Extract commit hash from output
-	outputStr := string(output)
+	outputStr := stdout.String()
 	hash := extractCommitHash(outputStr)
 	if hash == "" {
 		hash = "[commit created]" // Fallback
@@ -87,8 +482,68 @@ func (e *Executor) Commit(ctx context.Context, message string, dryRun bool) (str
 	return hash, nil
 }
 
+// checkPathsStaged refuses paths with unstaged changes: git commit --
+// <paths> commits the working-tree version of each path, so those changes
+// would go in without having been part of the staged diff.
+func (e *Executor) checkPathsStaged(ctx context.Context, paths []string) error {
+	cmd := gitCommand(ctx, append([]string{"diff", "--name-only", "--"}, paths...)...)
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return fmt.Errorf("git diff failed: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return fmt.Errorf("git diff failed: %w", err)
+	}
+	if dirty := strings.Fields(string(output)); len(dirty) > 0 {
+		return fmt.Errorf("unstaged changes in %s would be committed too; stage or stash them first", strings.Join(dirty, ", "))
+	}
+	return nil
+}
+
+// hasCommitHook reports whether an executable commit hook is installed.
+func (e *Executor) hasCommitHook(ctx context.Context) bool {
+	dir, err := e.HooksDir(ctx)
+	if err != nil {
+		return false
+	}
+	for _, name := range commitHooks {
+		// Windows has no exec bit; git runs any hook file there.
+		if info, err := os.Stat(filepath.Join(dir, name)); err == nil && !info.IsDir() && (runtime.GOOS == "windows" || info.Mode()&0o111 != 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// commitArgs returns the git commit arguments: --no-verify and the signing
+// flag when enabled, then -m for single-line messages when enabled,
+// otherwise -F with the message file, then "--" and opts.Paths if any.
+func (e *Executor) commitArgs(message, file string, opts ports.CommitOptions) []string {
+	args := []string{"commit"}
+	if opts.NoVerify {
+		args = append(args, "--no-verify")
+	}
+	switch {
+	case e.sign && e.signingKey != "":
+		args = append(args, "--gpg-sign="+e.signingKey)
+	case e.sign:
+		args = append(args, "-S")
+	}
+	if e.useMessageFlag && !strings.Contains(strings.TrimRight(message, "\n"), "\n") {
+		args = append(args, "-m", strings.TrimRight(message, "\n"))
+	} else {
+		args = append(args, "-F", file)
+	}
+	if len(opts.Paths) > 0 {
+		args = append(append(args, "--"), opts.Paths...)
+	}
+	return args
+}
+
 // extractCommitHash attempts to extract the commit hash from git output.
-// Git output typically looks like: "[branch_name hash_part] message"
+// Git output typically looks like: "[branch_name hash_part] message", but
+// root commits, detached HEADs and translated output vary, so callers prefer
+// HeadHash and use this only as a fallback.
 func extractCommitHash(output string) string {
 	// Look for pattern like "[main abc123d]"
 	lines := strings.Split(output, "\n")
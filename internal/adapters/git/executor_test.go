@@ -0,0 +1,523 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chuckie/commit-coach/internal/ports"
+)
+
+// initTestRepo creates a throwaway repository, isolates it from the user's
+// global/system git config and makes it the working directory for the test.
+func initTestRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	dir := t.TempDir()
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(dir, ".gitconfig-global"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	prev, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(prev) })
+
+	runGit(t, "init", "-q")
+	runGit(t, "config", "user.name", "Test User")
+	runGit(t, "config", "user.email", "test@example.com")
+	return dir
+}
+
+func runGit(t *testing.T, args ...string) string {
+	t.Helper()
+	out, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return string(out)
+}
+
+func TestConfigValueSet(t *testing.T) {
+	initTestRepo(t)
+	runGit(t, "config", "commit.template", "/tmp/template.txt")
+
+	v, err := NewExecutor(DefaultTimeout).ConfigValue(context.Background(), "commit.template")
+	if err != nil {
+		t.Fatalf("ConfigValue() error = %v", err)
+	}
+	if v != "/tmp/template.txt" {
+		t.Errorf("ConfigValue() = %q, want /tmp/template.txt", v)
+	}
+}
+
+func TestConfigValueUnset(t *testing.T) {
+	initTestRepo(t)
+
+	v, err := NewExecutor(DefaultTimeout).ConfigValue(context.Background(), "core.hooksPath")
+	if err != nil {
+		t.Fatalf("ConfigValue() error = %v, want nil for unset key", err)
+	}
+	if v != "" {
+		t.Errorf("ConfigValue() = %q, want empty", v)
+	}
+}
+
+func TestConfigValueInvalidKey(t *testing.T) {
+	initTestRepo(t)
+
+	if _, err := NewExecutor(DefaultTimeout).ConfigValue(context.Background(), "nosection"); err == nil {
+		t.Error("ConfigValue() should fail for an invalid key")
+	}
+}
+
+func TestCurrentBranch(t *testing.T) {
+	initTestRepo(t)
+	runGit(t, "checkout", "-q", "-b", "feature/summary")
+	runGit(t, "commit", "-q", "--allow-empty", "-m", "chore: init")
+
+	branch, err := NewExecutor(DefaultTimeout).CurrentBranch(context.Background())
+	if err != nil {
+		t.Fatalf("CurrentBranch() error = %v", err)
+	}
+	if branch != "feature/summary" {
+		t.Errorf("CurrentBranch() = %q, want feature/summary", branch)
+	}
+
+	runGit(t, "checkout", "-q", "--detach")
+	if branch, _ := NewExecutor(DefaultTimeout).CurrentBranch(context.Background()); branch != "HEAD" {
+		t.Errorf("CurrentBranch() detached = %q, want HEAD", branch)
+	}
+}
+
+func TestRootDirAndHooksDirFromSubdirectory(t *testing.T) {
+	dir := initTestRepo(t)
+	want, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		t.Fatalf("EvalSymlinks: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "pkg", "sub"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.Chdir(filepath.Join(dir, "pkg", "sub")); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	e := NewExecutor(DefaultTimeout)
+	root, err := e.RootDir(context.Background())
+	if err != nil {
+		t.Fatalf("RootDir() error = %v", err)
+	}
+	if root != want {
+		t.Errorf("RootDir() = %q, want %q", root, want)
+	}
+
+	hooks, err := e.HooksDir(context.Background())
+	if err != nil {
+		t.Fatalf("HooksDir() error = %v", err)
+	}
+	if wantHooks := filepath.Join(want, ".git", "hooks"); hooks != wantHooks {
+		t.Errorf("HooksDir() = %q, want %q", hooks, wantHooks)
+	}
+}
+
+func TestCommitArgs(t *testing.T) {
+	e := NewExecutor(DefaultTimeout)
+	e.SetUseMessageFlag(true)
+
+	single := e.commitArgs("feat: add parser\n", "/tmp/msg.txt", ports.CommitOptions{})
+	if want := []string{"commit", "-m", "feat: add parser"}; !slices.Equal(single, want) {
+		t.Errorf("single-line args = %v, want %v", single, want)
+	}
+
+	multi := e.commitArgs("feat: add parser\n\nHandles nested input.", "/tmp/msg.txt", ports.CommitOptions{})
+	if want := []string{"commit", "-F", "/tmp/msg.txt"}; !slices.Equal(multi, want) {
+		t.Errorf("multiline args = %v, want %v", multi, want)
+	}
+
+	e.SetUseMessageFlag(false)
+	if got := e.commitArgs("feat: add parser", "/tmp/msg.txt", ports.CommitOptions{}); got[1] != "-F" {
+		t.Errorf("args with -m disabled = %v, want -F", got)
+	}
+
+	e.SetSigning(true, "")
+	if got, want := e.commitArgs("feat: add parser", "/tmp/msg.txt", ports.CommitOptions{}), []string{"commit", "-S", "-F", "/tmp/msg.txt"}; !slices.Equal(got, want) {
+		t.Errorf("signed args = %v, want %v", got, want)
+	}
+	e.SetSigning(true, "ABCD1234")
+	if got, want := e.commitArgs("feat: add parser", "/tmp/msg.txt", ports.CommitOptions{}), []string{"commit", "--gpg-sign=ABCD1234", "-F", "/tmp/msg.txt"}; !slices.Equal(got, want) {
+		t.Errorf("args with a signing key = %v, want %v", got, want)
+	}
+
+	e.SetSigning(false, "")
+	if got, want := e.commitArgs("feat: add parser", "/tmp/msg.txt", ports.CommitOptions{NoVerify: true}), []string{"commit", "--no-verify", "-F", "/tmp/msg.txt"}; !slices.Equal(got, want) {
+		t.Errorf("no-verify args = %v, want %v", got, want)
+	}
+	if got, want := e.commitArgs("feat: add parser", "/tmp/msg.txt", ports.CommitOptions{Paths: []string{"a.go", "b.go"}}), []string{"commit", "-F", "/tmp/msg.txt", "--", "a.go", "b.go"}; !slices.Equal(got, want) {
+		t.Errorf("args with paths = %v, want %v", got, want)
+	}
+}
+
+func TestCommitSigningFailureIsReported(t *testing.T) {
+	initTestRepo(t)
+	// A signing program that always fails, like gpg without a secret key.
+	runGit(t, "config", "gpg.program", "false")
+	if err := os.WriteFile("a.txt", []byte("a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, "add", "a.txt")
+
+	e := NewExecutor(DefaultTimeout)
+	e.SetSigning(true, "")
+	_, err := e.Commit(context.Background(), "feat: add a", ports.CommitOptions{})
+	if err == nil || !strings.Contains(err.Error(), "signing failed") {
+		t.Errorf("Commit() error = %v, want a signing failure", err)
+	}
+}
+
+func TestCommitSingleLineWithMessageFlag(t *testing.T) {
+	initTestRepo(t)
+	if err := os.WriteFile("a.txt", []byte("a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, "add", "a.txt")
+
+	e := NewExecutor(DefaultTimeout)
+	e.SetUseMessageFlag(true)
+	if _, err := e.Commit(context.Background(), "feat: add a", ports.CommitOptions{}); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	if msg, _ := e.CommitMessage(context.Background(), "HEAD"); strings.TrimSpace(msg) != "feat: add a" {
+		t.Errorf("committed message = %q, want feat: add a", msg)
+	}
+}
+
+func TestHeadHashAfterRootAndDetachedCommits(t *testing.T) {
+	initTestRepo(t)
+	if err := os.WriteFile("a.txt", []byte("a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, "add", "a.txt")
+
+	e := NewExecutor(DefaultTimeout)
+	ctx := context.Background()
+	if _, err := e.Commit(ctx, "feat: root commit", ports.CommitOptions{}); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	want := strings.TrimSpace(runGit(t, "rev-parse", "--short", "HEAD"))
+	if got, err := e.HeadHash(ctx); err != nil || got != want {
+		t.Errorf("HeadHash() after root commit = %q, %v; want %q", got, err, want)
+	}
+
+	runGit(t, "checkout", "-q", "--detach")
+	if err := os.WriteFile("a.txt", []byte("b\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, "add", "a.txt")
+	if _, err := e.Commit(ctx, "fix: detached commit", ports.CommitOptions{}); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	want = strings.TrimSpace(runGit(t, "rev-parse", "--short", "HEAD"))
+	if got, err := e.HeadHash(ctx); err != nil || got != want {
+		t.Errorf("HeadHash() on detached HEAD = %q, %v; want %q", got, err, want)
+	}
+}
+
+func TestCommitRejectedByHook(t *testing.T) {
+	dir := initTestRepo(t)
+	if runtime.GOOS == "windows" {
+		t.Skip("shell hooks need a POSIX shell")
+	}
+	hook := "#!/bin/sh\necho 'lint: trailing whitespace in a.txt' >&2\nexit 1\n"
+	if err := os.WriteFile(filepath.Join(dir, ".git", "hooks", "pre-commit"), []byte(hook), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("a.txt", []byte("a \n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, "add", "a.txt")
+
+	_, err := NewExecutor(DefaultTimeout).Commit(context.Background(), "feat: add a", ports.CommitOptions{})
+	var hookErr *ports.HookRejectedError
+	if !errors.As(err, &hookErr) {
+		t.Fatalf("Commit() error = %v, want a HookRejectedError", err)
+	}
+	if hookErr.Output != "lint: trailing whitespace in a.txt" {
+		t.Errorf("Output = %q, want the hook's message", hookErr.Output)
+	}
+
+	// Without hooks, git's own failures stay plain errors.
+	if err := os.Remove(filepath.Join(dir, ".git", "hooks", "pre-commit")); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, "reset", "-q")
+	_, err = NewExecutor(DefaultTimeout).Commit(context.Background(), "feat: nothing", ports.CommitOptions{})
+	if err == nil || errors.As(err, &hookErr) {
+		t.Errorf("Commit() with nothing staged error = %v, want a plain error", err)
+	}
+}
+
+func TestCommitKilledAtDeadline(t *testing.T) {
+	dir := initTestRepo(t)
+	if runtime.GOOS == "windows" {
+		t.Skip("shell hooks need a POSIX shell")
+	}
+	hook := "#!/bin/sh\nsleep 5\n"
+	if err := os.WriteFile(filepath.Join(dir, ".git", "hooks", "pre-commit"), []byte(hook), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("a.txt", []byte("a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, "add", "a.txt")
+
+	// The caller's deadline wins over the longer commit timeout, and the
+	// commit timeout applies to callers without one.
+	short, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	for name, run := range map[string]func() error{
+		"caller deadline": func() error {
+			g := NewExecutor(DefaultTimeout)
+			g.SetCommitTimeout(time.Minute)
+			_, err := g.Commit(short, "feat: add a", ports.CommitOptions{})
+			return err
+		},
+		"commit timeout": func() error {
+			g := NewExecutor(DefaultTimeout)
+			g.SetCommitTimeout(200 * time.Millisecond)
+			_, err := g.Commit(context.Background(), "feat: add a", ports.CommitOptions{})
+			return err
+		},
+	} {
+		start := time.Now()
+		err := run()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("%s: Commit() error = %v, want the deadline", name, err)
+		}
+		if elapsed := time.Since(start); elapsed > 4*time.Second {
+			t.Errorf("%s: Commit() took %v, want git killed at the deadline", name, elapsed)
+		}
+	}
+	if _, err := NewExecutor(DefaultTimeout).HeadHash(context.Background()); err == nil {
+		t.Error("a commit was created despite the deadline")
+	}
+}
+
+func TestCommitOutlivesCommandTimeout(t *testing.T) {
+	dir := initTestRepo(t)
+	if runtime.GOOS == "windows" {
+		t.Skip("shell hooks need a POSIX shell")
+	}
+	hook := "#!/bin/sh\nsleep 1\n"
+	if err := os.WriteFile(filepath.Join(dir, ".git", "hooks", "pre-commit"), []byte(hook), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("a.txt", []byte("a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, "add", "a.txt")
+
+	// A slow hook is not cut off by the per-command timeout.
+	if _, err := NewExecutor(200*time.Millisecond).Commit(context.Background(), "feat: add a", ports.CommitOptions{}); err != nil {
+		t.Fatalf("Commit() error = %v, want the commit to wait for the hook", err)
+	}
+}
+
+func TestStagedDiffArgs(t *testing.T) {
+	e := NewExecutor(DefaultTimeout)
+	if got := e.stagedDiffArgs(); slices.Contains(got, "--submodule=log") {
+		t.Errorf("default args = %v, want no submodule log", got)
+	}
+	e.SetSubmoduleContext(true)
+	if got := e.stagedDiffArgs(); !slices.Contains(got, "--submodule=log") {
+		t.Errorf("args = %v, want --submodule=log", got)
+	}
+}
+
+func TestStagedDiffIncludesIntentToAdd(t *testing.T) {
+	initTestRepo(t)
+	if err := os.WriteFile("staged.txt", []byte("staged\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("planned.txt", []byte("planned content\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, "add", "staged.txt")
+	runGit(t, "add", "-N", "planned.txt")
+
+	e := NewExecutor(DefaultTimeout)
+	diff, err := e.StagedDiff(context.Background())
+	if err != nil {
+		t.Fatalf("StagedDiff() error = %v", err)
+	}
+	if strings.Contains(diff, "planned content") {
+		t.Error("intent-to-add content included without opting in")
+	}
+
+	e.SetIncludeIntentToAdd(true)
+	diff, err = e.StagedDiff(context.Background())
+	if err != nil {
+		t.Fatalf("StagedDiff() error = %v", err)
+	}
+	if !strings.Contains(diff, "+staged") || !strings.Contains(diff, "+planned content") {
+		t.Errorf("diff = %q, want staged and intent-to-add contents", diff)
+	}
+}
+
+func TestWorkingTreeDiff(t *testing.T) {
+	initTestRepo(t)
+	if err := os.WriteFile("tracked.txt", []byte("one\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, "add", "tracked.txt")
+	runGit(t, "commit", "-q", "-m", "init")
+	if err := os.WriteFile("tracked.txt", []byte("one\ntwo\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	e := NewExecutor(DefaultTimeout)
+	staged, err := e.StagedDiff(context.Background())
+	if err != nil {
+		t.Fatalf("StagedDiff() error = %v", err)
+	}
+	if staged != "" {
+		t.Errorf("StagedDiff() = %q, want empty", staged)
+	}
+	diff, err := e.WorkingTreeDiff(context.Background())
+	if err != nil {
+		t.Fatalf("WorkingTreeDiff() error = %v", err)
+	}
+	if !strings.Contains(diff, "+two") {
+		t.Errorf("WorkingTreeDiff() = %q, want the unstaged line", diff)
+	}
+}
+
+func TestCommitSubject(t *testing.T) {
+	initTestRepo(t)
+	runGit(t, "commit", "-q", "--allow-empty", "-m", "feat: first\n\nbody text")
+
+	e := NewExecutor(DefaultTimeout)
+	subject, err := e.CommitSubject(context.Background(), "HEAD")
+	if err != nil {
+		t.Fatalf("CommitSubject() error = %v", err)
+	}
+	if subject != "feat: first" {
+		t.Errorf("CommitSubject() = %q, want the subject line only", subject)
+	}
+	if _, err := e.CommitSubject(context.Background(), "--all"); err == nil {
+		t.Error("CommitSubject() accepted an option as a ref")
+	}
+}
+
+func TestRecentSubjects(t *testing.T) {
+	initTestRepo(t)
+	e := NewExecutor(DefaultTimeout)
+	subjects, err := e.RecentSubjects(context.Background(), 5)
+	if err != nil || subjects != nil {
+		t.Fatalf("RecentSubjects() before the first commit = %q, %v; want nil, nil", subjects, err)
+	}
+
+	for _, msg := range []string{"feat: first", "fix: second\n\nbody", "docs: third"} {
+		runGit(t, "commit", "-q", "--allow-empty", "-m", msg)
+	}
+	subjects, err = e.RecentSubjects(context.Background(), 2)
+	if err != nil {
+		t.Fatalf("RecentSubjects() error = %v", err)
+	}
+	if want := []string{"docs: third", "fix: second"}; strings.Join(subjects, "|") != strings.Join(want, "|") {
+		t.Errorf("RecentSubjects() = %q, want %q", subjects, want)
+	}
+}
+
+func TestCommitOnlyPaths(t *testing.T) {
+	initTestRepo(t)
+	for name, content := range map[string]string{"a.txt": "alpha\n", "b.txt": "bravo\n"} {
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runGit(t, "add", "a.txt", "b.txt")
+
+	e := NewExecutor(DefaultTimeout)
+	if _, err := e.Commit(context.Background(), "feat: add b", ports.CommitOptions{Paths: []string{"b.txt"}}); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	if got := strings.TrimSpace(runGit(t, "show", "--name-only", "--format=", "HEAD")); got != "b.txt" {
+		t.Errorf("committed files = %q, want b.txt", got)
+	}
+	if got := strings.TrimSpace(runGit(t, "diff", "--cached", "--name-only")); got != "a.txt" {
+		t.Errorf("still staged = %q, want a.txt", got)
+	}
+
+	// An unstaged edit to a listed path would slip into the commit.
+	if err := os.WriteFile("a.txt", []byte("alpha\nmore\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := e.Commit(context.Background(), "feat: add a", ports.CommitOptions{Paths: []string{"a.txt"}})
+	if err == nil || !strings.Contains(err.Error(), "unstaged changes in a.txt") {
+		t.Errorf("Commit() with an unstaged edit = %v, want a refusal", err)
+	}
+}
+
+func TestRepoState(t *testing.T) {
+	dir := initTestRepo(t)
+	e := NewExecutor(DefaultTimeout)
+	state, err := e.RepoState(context.Background())
+	if err != nil || state != (ports.RepoState{}) {
+		t.Fatalf("RepoState() = %+v, %v; want a clean state", state, err)
+	}
+
+	gitDir := filepath.Join(dir, ".git")
+	if err := os.WriteFile(filepath.Join(gitDir, "MERGE_HEAD"), []byte("0123456789abcdef0123456789abcdef01234567\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(gitDir, "MERGE_MSG"), []byte("Merge branch 'feature'\n\n# Conflicts:\n#\tmain.go\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(gitDir, "rebase-merge"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir("sub", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir("sub"); err != nil {
+		t.Fatal(err)
+	}
+
+	state, err = e.RepoState(context.Background())
+	want := ports.RepoState{Merging: true, MergeMessage: "Merge branch 'feature'", Rebasing: true}
+	if err != nil || state != want {
+		t.Errorf("RepoState() from a subdirectory = %+v, %v; want %+v", state, err, want)
+	}
+}
+
+func TestStagedDiffForPaths(t *testing.T) {
+	initTestRepo(t)
+	for name, content := range map[string]string{"a.txt": "alpha\n", "b.txt": "bravo\n"} {
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runGit(t, "add", "a.txt", "b.txt")
+
+	diff, err := NewExecutor(DefaultTimeout).StagedDiffForPaths(context.Background(), []string{"b.txt"})
+	if err != nil {
+		t.Fatalf("StagedDiffForPaths() error = %v", err)
+	}
+	if !strings.Contains(diff, "+bravo") || strings.Contains(diff, "alpha") {
+		t.Errorf("diff = %q, want only b.txt", diff)
+	}
+}
@@ -10,6 +10,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/chuckie/commit-coach/internal/adapters/llm/llmerr"
+	"github.com/chuckie/commit-coach/internal/adapters/llm/prompts"
 	"github.com/chuckie/commit-coach/internal/observability"
 	"github.com/chuckie/commit-coach/internal/ports"
 )
@@ -38,25 +40,58 @@ func NewClient(apiKey string) (*Client, error) {
 		apiKey:  apiKey,
 		baseURL: "https://api.anthropic.com/v1",
 		http: &http.Client{
-			Timeout: 90 * time.Second,
+			Timeout: ports.DefaultRequestTimeout,
 		},
 	}, nil
 }
 
+// SetTimeout bounds each request; d <= 0 keeps ports.DefaultRequestTimeout.
+func (c *Client) SetTimeout(d time.Duration) {
+	if d > 0 {
+		c.http.Timeout = d
+	}
+}
+
+// SetTransport sends requests through rt (see llmhttp.NewTransport); nil
+// means http.DefaultTransport.
+func (c *Client) SetTransport(rt http.RoundTripper) {
+	c.http.Transport = rt
+}
+
+// Close implements io.Closer by dropping idle keep-alive connections. The
+// client still works afterwards, dialing again as needed.
+func (c *Client) Close() error {
+	c.http.CloseIdleConnections()
+	return nil
+}
+
+// Capabilities implements ports.CapableLLM. The Messages API has no JSON
+// mode; the prompt alone asks for JSON.
+func (c *Client) Capabilities(model string) ports.Capabilities {
+	return ports.Capabilities{RespectsTemperature: true}
+}
+
 // SuggestCommits generates commit suggestions using Anthropic.
 func (c *Client) SuggestCommits(ctx context.Context, input ports.SuggestInput) ([]ports.CommitSuggestion, error) {
+	suggestions, _, err := c.SuggestCommitsWithUsage(ctx, input)
+	return suggestions, err
+}
+
+// SuggestCommitsWithUsage is SuggestCommits plus the token usage Anthropic reports.
+func (c *Client) SuggestCommitsWithUsage(ctx context.Context, input ports.SuggestInput) ([]ports.CommitSuggestion, *ports.Usage, error) {
 	model := strings.TrimSpace(input.Model)
 	if model == "" {
-		return nil, fmt.Errorf("anthropic model is required")
+		return nil, nil, fmt.Errorf("anthropic model is required")
 	}
 
-	prompt := buildCommitPrompt(input.StagedDiff)
+	prompt := buildCommitPrompt(input)
+	observability.DebugPrompt("anthropic", prompt)
 
 	reqBody := map[string]interface{}{
 		"model":       model,
 		"max_tokens":  1400,
 		"temperature": float64(input.Temperature),
-		"system":      "You are an expert git commit message writer. Return ONLY valid JSON matching the requested schema. No markdown, no extra text.",
+		"system":      prompts.System(input),
 		"messages": []map[string]string{
 			{
 				"role":    "user",
@@ -67,39 +102,43 @@ func (c *Client) SuggestCommits(ctx context.Context, input ports.SuggestInput) (
 
 	b, err := json.Marshal(reqBody)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+		return nil, nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/messages", bytes.NewReader(b))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("x-api-key", c.apiKey)
 	req.Header.Set("anthropic-version", "2023-06-01")
 
+	stop := observability.TimeLLM("anthropic", model)
 	resp, err := c.http.Do(req)
+	stop()
 	if err != nil {
-		return nil, fmt.Errorf("failed to call Anthropic API: %w", err)
+		return nil, nil, fmt.Errorf("failed to call Anthropic API: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, readErr := io.ReadAll(resp.Body)
 	if readErr != nil {
-		return nil, fmt.Errorf("failed to read response: %w", readErr)
+		return nil, nil, fmt.Errorf("failed to read response: %w", readErr)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		observability.Logger().Printf(
-			"anthropic: non-200 status=%d model=%q temp=%.2f body_len=%d body_snip=%q",
-			resp.StatusCode,
-			model,
-			input.Temperature,
-			len(body),
-			observability.Snip(observability.RedactForLog(string(body)), 1200),
-		)
-		return nil, fmt.Errorf("anthropic returned status %d: %s", resp.StatusCode, string(body))
+		observability.Event(map[string]any{
+			"level":     observability.StatusLevel(resp.StatusCode),
+			"provider":  "anthropic",
+			"status":    resp.StatusCode,
+			"message":   "non-200 response",
+			"model":     model,
+			"temp":      input.Temperature,
+			"body_len":  len(body),
+			"body_snip": observability.Snip(observability.RedactForLog(string(body)), 1200),
+		})
+		return nil, nil, llmerr.FromResponse("anthropic", resp.StatusCode, body)
 	}
 
 	var respData struct {
@@ -107,16 +146,28 @@ func (c *Client) SuggestCommits(ctx context.Context, input ports.SuggestInput) (
 			Type string `json:"type"`
 			Text string `json:"text"`
 		} `json:"content"`
+		Usage struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
 	}
 
 	if err := json.Unmarshal(body, &respData); err != nil {
-		observability.Logger().Printf(
-			"anthropic: failed to unmarshal response JSON: %v; body_len=%d body_snip=%q",
-			err,
-			len(body),
-			observability.Snip(observability.RedactForLog(string(body)), 1200),
-		)
-		return nil, fmt.Errorf("failed to parse response: %w", err)
+		observability.Event(map[string]any{
+			"level":     "error",
+			"provider":  "anthropic",
+			"message":   "failed to unmarshal response JSON",
+			"error":     err,
+			"body_len":  len(body),
+			"body_snip": observability.Snip(observability.RedactForLog(string(body)), 1200),
+		})
+		return nil, nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	usage := &ports.Usage{
+		PromptTokens:     respData.Usage.InputTokens,
+		CompletionTokens: respData.Usage.OutputTokens,
+		TotalTokens:      respData.Usage.InputTokens + respData.Usage.OutputTokens,
 	}
 
 	content := ""
@@ -128,35 +179,23 @@ func (c *Client) SuggestCommits(ctx context.Context, input ports.SuggestInput) (
 			}
 		}
 	}
+	observability.DebugResponse("anthropic", content)
 	if content == "" {
-		return nil, fmt.Errorf("anthropic returned empty text content")
+		return nil, usage, fmt.Errorf("anthropic returned %w", ports.ErrEmptyOutput)
 	}
 
 	suggestions, err := parseSuggestionsJSON(content)
 	if err != nil {
-		return nil, err
+		return nil, usage, err
 	}
-	if len(suggestions) != 3 {
-		return nil, fmt.Errorf("expected 3 suggestions, got %d", len(suggestions))
+	if n := input.WantCount(); len(suggestions) < n {
+		return nil, usage, fmt.Errorf("expected %d suggestions, got %d", n, len(suggestions))
 	}
-	return suggestions, nil
+	return suggestions[:input.WantCount()], usage, nil
 }
 
-func buildCommitPrompt(diff string) string {
-	return fmt.Sprintf(`Generate exactly 3 Conventional Commit suggestions for this staged diff.
-
-<diff>
-%s
-</diff>
-
-Return ONLY a single JSON object with this exact shape:
-{"suggestions":[{"type":"feat|fix|docs|style|refactor|perf|test|chore|build|ci|revert","subject":"...","body":"...","footer":"..."}]}
-
-Rules:
-- Exactly 3 suggestions
-- subject: max 72 characters, no newlines
-- body/footer may be empty strings
-`, diff)
+func buildCommitPrompt(input ports.SuggestInput) string {
+	return prompts.Commit(input)
 }
 
 func parseSuggestionsJSON(content string) ([]ports.CommitSuggestion, error) {
@@ -167,15 +206,17 @@ func parseSuggestionsJSON(content string) ([]ports.CommitSuggestion, error) {
 	jsonContent := extractJSON(content)
 
 	if err := json.Unmarshal([]byte(jsonContent), &resp); err != nil {
-		observability.Logger().Printf(
-			"anthropic: invalid JSON: %v; raw_len=%d raw_snip=%q; json_len=%d json_snip=%q",
-			err,
-			len(content),
-			observability.Snip(observability.RedactForLog(content), 600),
-			len(jsonContent),
-			observability.Snip(observability.RedactForLog(jsonContent), 600),
-		)
-		return nil, fmt.Errorf("invalid JSON format: %w", err)
+		observability.Event(map[string]any{
+			"level":     "error",
+			"provider":  "anthropic",
+			"message":   "invalid JSON",
+			"error":     err,
+			"raw_len":   len(content),
+			"raw_snip":  observability.Snip(observability.RedactForLog(content), 600),
+			"json_len":  len(jsonContent),
+			"json_snip": observability.Snip(observability.RedactForLog(jsonContent), 600),
+		})
+		return nil, fmt.Errorf("%w: %w", ports.ErrInvalidJSON, err)
 	}
 
 	return resp.Suggestions, nil
@@ -0,0 +1,181 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/chuckie/commit-coach/internal/ports"
+)
+
+// Member is one provider behind a CompositeLLM. Model replaces
+// SuggestInput.Model for its calls, since each provider needs its own.
+type Member struct {
+	Name  string
+	Model string
+	LLM   ports.LLM
+}
+
+// CompositeLLM asks several providers at once and merges their answers:
+// suggestions are interleaved in member order (each provider's first pick,
+// then each one's second, ...), duplicates by normalized subject are
+// dropped, and the first SuggestInput.WantCount are returned. A member that
+// fails is skipped; the call only fails when every member does.
+type CompositeLLM struct {
+	members []Member
+}
+
+// NewComposite returns a CompositeLLM over members.
+func NewComposite(members ...Member) *CompositeLLM {
+	return &CompositeLLM{members: members}
+}
+
+// Capabilities implements ports.CapableLLM: a capability holds only when
+// every member has it for its own model. model is ignored.
+func (c *CompositeLLM) Capabilities(model string) ports.Capabilities {
+	caps := ports.Capabilities{Streaming: true, JSONMode: true, RespectsTemperature: true}
+	for _, m := range c.members {
+		capable, ok := m.LLM.(ports.CapableLLM)
+		if !ok {
+			return ports.Capabilities{}
+		}
+		mc := capable.Capabilities(m.Model)
+		caps.Streaming = caps.Streaming && mc.Streaming
+		caps.JSONMode = caps.JSONMode && mc.JSONMode
+		caps.RespectsTemperature = caps.RespectsTemperature && mc.RespectsTemperature
+	}
+	return caps
+}
+
+// Close implements io.Closer, closing every member that is one.
+func (c *CompositeLLM) Close() error {
+	var errs []error
+	for _, m := range c.members {
+		if closer, ok := m.LLM.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", m.Name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SuggestCommits implements ports.LLM.
+func (c *CompositeLLM) SuggestCommits(ctx context.Context, input ports.SuggestInput) ([]ports.CommitSuggestion, error) {
+	suggestions, _, err := c.SuggestCommitsWithUsage(ctx, input)
+	return suggestions, err
+}
+
+// SuggestCommitsWithUsage implements ports.UsageLLM. Usage is the sum over
+// the members that report it, or nil when none do.
+func (c *CompositeLLM) SuggestCommitsWithUsage(ctx context.Context, input ports.SuggestInput) ([]ports.CommitSuggestion, *ports.Usage, error) {
+	if len(c.members) == 0 {
+		return nil, nil, errors.New("multi provider has no members")
+	}
+
+	type result struct {
+		suggestions []ports.CommitSuggestion
+		usage       *ports.Usage
+		err         error
+	}
+	results := make([]result, len(c.members))
+	var wg sync.WaitGroup
+	for i, m := range c.members {
+		wg.Add(1)
+		go func(i int, m Member) {
+			defer wg.Done()
+			in := input
+			if m.Model != "" {
+				in.Model = m.Model
+			}
+			var r result
+			if u, ok := m.LLM.(ports.UsageLLM); ok {
+				r.suggestions, r.usage, r.err = u.SuggestCommitsWithUsage(ctx, in)
+			} else {
+				r.suggestions, r.err = m.LLM.SuggestCommits(ctx, in)
+			}
+			results[i] = r
+		}(i, m)
+	}
+	wg.Wait()
+
+	var lists [][]ports.CommitSuggestion
+	var errs []error
+	var usage *ports.Usage
+	for i, r := range results {
+		if r.err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.members[i].Name, r.err))
+			continue
+		}
+		lists = append(lists, r.suggestions)
+		if r.usage != nil {
+			if usage == nil {
+				usage = &ports.Usage{}
+			}
+			usage.PromptTokens += r.usage.PromptTokens
+			usage.CompletionTokens += r.usage.CompletionTokens
+			usage.TotalTokens += r.usage.TotalTokens
+		}
+	}
+	if len(lists) == 0 {
+		return nil, nil, errors.Join(errs...)
+	}
+	return mergeSuggestions(lists, input.WantCount()), usage, nil
+}
+
+// mergeSuggestions interleaves lists, drops repeated subjects and keeps at
+// most n.
+func mergeSuggestions(lists [][]ports.CommitSuggestion, n int) []ports.CommitSuggestion {
+	seen := map[string]bool{}
+	var merged []ports.CommitSuggestion
+	for rank := 0; len(merged) < n; rank++ {
+		more := false
+		for _, list := range lists {
+			if rank >= len(list) {
+				continue
+			}
+			more = true
+			key := normalizeSubject(list[rank].Subject)
+			if seen[key] || len(merged) == n {
+				continue
+			}
+			seen[key] = true
+			merged = append(merged, list[rank])
+		}
+		if !more {
+			break
+		}
+	}
+	return merged
+}
+
+// normalizeSubject folds case, whitespace and a trailing period so near-
+// identical subjects from different providers count as one.
+func normalizeSubject(subject string) string {
+	subject = strings.TrimSuffix(strings.TrimSpace(subject), ".")
+	return strings.ToLower(strings.Join(strings.Fields(subject), " "))
+}
+
+// NewMulti builds a CompositeLLM from "provider:model" specs, e.g.
+// "groq:llama-3.1-8b-instant". keyFor supplies each provider's API key.
+func NewMulti(specs []string, keyFor func(provider string) string, baseURL, ollamaURL string, build func(provider, apiKey, baseURL, ollamaURL, model string) (ports.LLM, error)) (*CompositeLLM, error) {
+	if len(specs) == 0 {
+		return nil, errors.New("multi provider needs at least one provider:model entry")
+	}
+	members := make([]Member, 0, len(specs))
+	for _, spec := range specs {
+		provider, model, ok := strings.Cut(strings.TrimSpace(spec), ":")
+		if !ok || provider == "" || model == "" || provider == "multi" {
+			return nil, fmt.Errorf("invalid multi provider entry %q (want provider:model)", spec)
+		}
+		l, err := build(provider, keyFor(provider), baseURL, ollamaURL, model)
+		if err != nil {
+			return nil, fmt.Errorf("multi provider %s: %w", provider, err)
+		}
+		members = append(members, Member{Name: provider, Model: model, LLM: l})
+	}
+	return NewComposite(members...), nil
+}
@@ -0,0 +1,89 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/chuckie/commit-coach/internal/ports"
+	"github.com/chuckie/commit-coach/internal/testutil"
+)
+
+func TestCompositeMergesAndDedupes(t *testing.T) {
+	openai := &testutil.FakeLLM{
+		Suggestions: []ports.CommitSuggestion{
+			{Type: "feat", Subject: "add config loader"},
+			{Type: "feat", Subject: "load config from disk"},
+		},
+		Usage: &ports.Usage{TotalTokens: 100},
+	}
+	groq := &testutil.FakeLLM{
+		Suggestions: []ports.CommitSuggestion{
+			{Type: "feat", Subject: "Add config  loader."},
+			{Type: "refactor", Subject: "split config parsing"},
+		},
+		Usage: &ports.Usage{TotalTokens: 50},
+	}
+	c := NewComposite(
+		Member{Name: "openai", Model: "gpt-4o-mini", LLM: openai},
+		Member{Name: "groq", Model: "llama-3.1-8b-instant", LLM: groq},
+	)
+
+	got, usage, err := c.SuggestCommitsWithUsage(context.Background(), ports.SuggestInput{Model: "ignored", Count: 3})
+	if err != nil {
+		t.Fatalf("SuggestCommitsWithUsage() error = %v", err)
+	}
+	var subjects []string
+	for _, s := range got {
+		subjects = append(subjects, s.Subject)
+	}
+	want := "add config loader|load config from disk|split config parsing"
+	if strings.Join(subjects, "|") != want {
+		t.Errorf("subjects = %q, want %q (interleaved, duplicate dropped)", subjects, want)
+	}
+	if usage == nil || usage.TotalTokens != 150 {
+		t.Errorf("usage = %+v, want the members' sum", usage)
+	}
+	if openai.LastInput.Model != "gpt-4o-mini" || groq.LastInput.Model != "llama-3.1-8b-instant" {
+		t.Errorf("models sent = %q, %q; want each member's own", openai.LastInput.Model, groq.LastInput.Model)
+	}
+}
+
+func TestCompositePartialFailure(t *testing.T) {
+	ok := &testutil.FakeLLM{Suggestions: []ports.CommitSuggestion{{Type: "fix", Subject: "handle nil config"}}}
+	broken := &testutil.FakeLLM{Err: errors.New("status 503")}
+	c := NewComposite(Member{Name: "groq", LLM: broken}, Member{Name: "openai", LLM: ok})
+
+	got, err := c.SuggestCommits(context.Background(), ports.SuggestInput{})
+	if err != nil || len(got) != 1 || got[0].Subject != "handle nil config" {
+		t.Errorf("SuggestCommits() = %v, %v; want the healthy member's result", got, err)
+	}
+
+	ok.Err = errors.New("status 401")
+	_, err = c.SuggestCommits(context.Background(), ports.SuggestInput{})
+	if err == nil || !strings.Contains(err.Error(), "groq: status 503") || !strings.Contains(err.Error(), "openai: status 401") {
+		t.Errorf("error = %v, want both members' failures", err)
+	}
+}
+
+func TestNewMultiParsesSpecs(t *testing.T) {
+	var built []string
+	build := func(provider, apiKey, baseURL, ollamaURL, model string) (ports.LLM, error) {
+		built = append(built, provider+"/"+model+"/"+apiKey)
+		return &testutil.FakeLLM{}, nil
+	}
+	keyFor := func(p string) string { return p + "-key" }
+
+	if _, err := NewMulti([]string{"openai:gpt-4o-mini", " groq:openai/gpt-oss-20b"}, keyFor, "", "", build); err != nil {
+		t.Fatalf("NewMulti() error = %v", err)
+	}
+	if want := "openai/gpt-4o-mini/openai-key|groq/openai/gpt-oss-20b/groq-key"; strings.Join(built, "|") != want {
+		t.Errorf("built = %q, want %q", built, want)
+	}
+	for _, bad := range [][]string{nil, {"openai"}, {"multi:x"}} {
+		if _, err := NewMulti(bad, keyFor, "", "", build); err == nil {
+			t.Errorf("NewMulti(%q) should fail", bad)
+		}
+	}
+}
@@ -2,15 +2,57 @@ package llm
 
 import (
 	"fmt"
+	"net/http"
+	"time"
 
 	"github.com/chuckie/commit-coach/internal/adapters/llm/anthropic"
 	"github.com/chuckie/commit-coach/internal/adapters/llm/groq"
+	"github.com/chuckie/commit-coach/internal/adapters/llm/llmhttp"
 	"github.com/chuckie/commit-coach/internal/adapters/llm/mock"
 	"github.com/chuckie/commit-coach/internal/adapters/llm/ollama"
 	"github.com/chuckie/commit-coach/internal/adapters/llm/openai"
 	"github.com/chuckie/commit-coach/internal/ports"
 )
 
+// BuildFunc constructs a provider client; see NewFactory.
+type BuildFunc func(provider, apiKey, baseURL, ollamaURL, model string) (ports.LLM, error)
+
+// NewFactory returns a BuildFunc that covers every provider, including
+// "multi", whose members are multiSpecs ("provider:model") with API keys from
+// keyFor. groqAllowHighTemp is passed on to Groq clients, requestTimeout
+// (when positive) bounds every client's requests, and every client sends
+// its requests through the transport built from httpOpts.
+func NewFactory(multiSpecs []string, keyFor func(provider string) string, groqAllowHighTemp bool, requestTimeout time.Duration, httpOpts llmhttp.Options) BuildFunc {
+	transport, transportErr := llmhttp.NewTransport(httpOpts)
+	var build BuildFunc
+	build = func(provider, apiKey, baseURL, ollamaURL, model string) (ports.LLM, error) {
+		if transportErr != nil {
+			return nil, transportErr
+		}
+		if provider == "multi" {
+			return NewMulti(multiSpecs, keyFor, baseURL, ollamaURL, build)
+		}
+		l, err := NewFromConfig(provider, apiKey, baseURL, ollamaURL, model)
+		if err != nil {
+			return nil, err
+		}
+		if g, ok := l.(interface{ SetAllowHighTemp(bool) }); ok {
+			g.SetAllowHighTemp(groqAllowHighTemp)
+		}
+		if t, ok := l.(interface{ SetTimeout(time.Duration) }); ok && requestTimeout > 0 {
+			t.SetTimeout(requestTimeout)
+		}
+		if t, ok := l.(interface{ SetTransport(http.RoundTripper) }); ok && transport != nil {
+			t.SetTransport(transport)
+		}
+		return l, nil
+	}
+	return build
+}
+
+// mistralBaseURL is Mistral's OpenAI-compatible API.
+const mistralBaseURL = "https://api.mistral.ai/v1"
+
 // NewFromConfig creates a new LLM provider from configuration.
 func NewFromConfig(provider, apiKey, baseURL, ollamaURL, model string) (ports.LLM, error) {
 	switch provider {
@@ -20,6 +62,10 @@ func NewFromConfig(provider, apiKey, baseURL, ollamaURL, model string) (ports.LL
 		return anthropic.NewClient(apiKey)
 	case "groq":
 		return groq.NewClient(apiKey, model), nil
+	case "mistral":
+		return groq.NewCompatibleClient("mistral", mistralBaseURL, apiKey, model), nil
+	case "openai-compatible":
+		return openai.NewCompatibleClient(apiKey, baseURL)
 	case "ollama":
 		return ollama.NewClient(ollamaURL, model), nil
 	case "mock":
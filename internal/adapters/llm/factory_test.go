@@ -0,0 +1,110 @@
+package llm
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/chuckie/commit-coach/internal/adapters/llm/llmhttp"
+	"github.com/chuckie/commit-coach/internal/ports"
+)
+
+func TestClientCapabilities(t *testing.T) {
+	tests := []struct {
+		provider, model string
+		want            ports.Capabilities
+	}{
+		{"openai", "gpt-4o-mini", ports.Capabilities{JSONMode: true, RespectsTemperature: true}},
+		{"openai", "o3-mini", ports.Capabilities{JSONMode: true}},
+		{"groq", "llama-3.1-8b-instant", ports.Capabilities{JSONMode: true, RespectsTemperature: true}},
+		{"mistral", "codestral-latest", ports.Capabilities{JSONMode: true, RespectsTemperature: true}},
+		{"anthropic", "claude-3-5-haiku-latest", ports.Capabilities{RespectsTemperature: true}},
+		{"ollama", "llama3", ports.Capabilities{JSONMode: true, RespectsTemperature: true}},
+		{"mock", "mock", ports.Capabilities{}},
+	}
+	for _, tt := range tests {
+		l, err := NewFromConfig(tt.provider, "key", "", "http://localhost:11434", tt.model)
+		if err != nil {
+			t.Fatalf("NewFromConfig(%s) error = %v", tt.provider, err)
+		}
+		capable, ok := l.(ports.CapableLLM)
+		if !ok {
+			t.Errorf("%s client does not report capabilities", tt.provider)
+			continue
+		}
+		if got := capable.Capabilities(tt.model); got != tt.want {
+			t.Errorf("%s/%s capabilities = %+v, want %+v", tt.provider, tt.model, got, tt.want)
+		}
+	}
+}
+
+func TestCompositeCapabilitiesIntersect(t *testing.T) {
+	build := NewFactory([]string{"groq:llama-3.1-8b-instant", "openai:o3-mini"}, func(string) string { return "key" }, false, 0, llmhttp.Options{})
+	l, err := build("multi", "", "", "", "")
+	if err != nil {
+		t.Fatalf("build(multi) error = %v", err)
+	}
+	want := ports.Capabilities{JSONMode: true}
+	if got := l.(ports.CapableLLM).Capabilities(""); got != want {
+		t.Errorf("multi capabilities = %+v, want %+v (temperature lost to the reasoning member)", got, want)
+	}
+}
+
+// recordingTransport answers every request with a chat completion holding
+// one suggestion, recording the URL and Authorization header.
+type recordingTransport struct {
+	url, auth string
+}
+
+func (rt *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.url = req.URL.String()
+	rt.auth = req.Header.Get("Authorization")
+	body := `{"choices":[{"message":{"role":"assistant","content":"{\"suggestions\":[{\"type\":\"fix\",\"subject\":\"handle nil diff\"}]}"}}]}`
+	return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(body)), Request: req}, nil
+}
+
+func TestMistralTargetsMistralAPI(t *testing.T) {
+	rt := &recordingTransport{}
+	prev := http.DefaultTransport
+	http.DefaultTransport = rt
+	t.Cleanup(func() { http.DefaultTransport = prev })
+
+	l, err := NewFromConfig("mistral", "mistral-key", "", "", "codestral-latest")
+	if err != nil {
+		t.Fatalf("NewFromConfig(mistral) error = %v", err)
+	}
+	got, err := l.SuggestCommits(context.Background(), ports.SuggestInput{StagedDiff: "diff", Count: 1})
+	if err != nil {
+		t.Fatalf("SuggestCommits() error = %v", err)
+	}
+	if rt.url != "https://api.mistral.ai/v1/chat/completions" || rt.auth != "Bearer mistral-key" {
+		t.Errorf("request to %s with %q, want Mistral's chat completions with the key", rt.url, rt.auth)
+	}
+	if len(got) != 1 || got[0].Subject != "handle nil diff" {
+		t.Errorf("suggestions = %+v, want the one in choices[0]", got)
+	}
+}
+
+func TestFactoryUsesCustomTransport(t *testing.T) {
+	rt := &recordingTransport{}
+	build := NewFactory(nil, func(string) string { return "" }, false, 0, llmhttp.Options{Transport: rt})
+	for _, tt := range []struct{ provider, baseURL, wantURL string }{
+		{"groq", "", "https://api.groq.com/openai/v1/chat/completions"},
+		{"anthropic", "", "https://api.anthropic.com/v1/messages"},
+		{"ollama", "", "http://gpu-box:11434/api/"},
+		{"openai-compatible", "http://localhost:8080/v1", "http://localhost:8080/v1/chat/completions"},
+	} {
+		rt.url = ""
+		l, err := build(tt.provider, "key", tt.baseURL, "http://gpu-box:11434", "m")
+		if err != nil {
+			t.Fatalf("build(%s) error = %v", tt.provider, err)
+		}
+		// Only the destination matters; the canned reply need not parse.
+		_, _ = l.SuggestCommits(context.Background(), ports.SuggestInput{StagedDiff: "diff", Model: "m", Count: 1})
+		if !strings.HasPrefix(rt.url, tt.wantURL) {
+			t.Errorf("%s: transport saw %q, want a request to %s", tt.provider, rt.url, tt.wantURL)
+		}
+	}
+}
@@ -8,57 +8,131 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/chuckie/commit-coach/internal/adapters/llm/llmerr"
+	"github.com/chuckie/commit-coach/internal/adapters/llm/prompts"
 	"github.com/chuckie/commit-coach/internal/observability"
 	"github.com/chuckie/commit-coach/internal/ports"
 )
 
-// Client implements ports.LLM for Groq API (OpenAI-compatible).
+// Client implements ports.LLM for Groq API (OpenAI-compatible), and for
+// other OpenAI-compatible APIs through NewCompatibleClient.
 type Client struct {
+	// name is the provider name used in errors, logs and metrics.
+	name    string
 	apiKey  string
 	baseURL string
 	model   string
 	http    *http.Client
+	// allowHighTemp skips JSON mode so the requested temperature is honored.
+	allowHighTemp bool
+	// clampTemp caps the temperature in JSON mode; only Groq needs it.
+	clampTemp bool
 }
 
+// jsonModeMaxTemperature is the highest temperature sent while JSON mode is
+// on; higher values make Groq models fail json_validate far more often.
+const jsonModeMaxTemperature = 0.2
+
 // NewClient creates a new Groq client.
 func NewClient(apiKey, model string) *Client {
 	if model == "" {
 		model = "mixtral-8x7b-32768"
 	}
 
+	c := NewCompatibleClient("groq", "https://api.groq.com/openai/v1", apiKey, model)
+	c.clampTemp = true
+	return c
+}
+
+// NewCompatibleClient creates a client for another OpenAI-compatible chat
+// completions API at baseURL, e.g. Mistral's. name labels its errors and
+// logs.
+func NewCompatibleClient(name, baseURL, apiKey, model string) *Client {
 	return &Client{
+		name:    name,
 		apiKey:  apiKey,
-		baseURL: "https://api.groq.com/openai/v1",
+		baseURL: strings.TrimRight(baseURL, "/"),
 		model:   model,
 		http: &http.Client{
-			Timeout: 90 * time.Second,
+			Timeout: ports.DefaultRequestTimeout,
 		},
 	}
 }
 
+// SetTimeout bounds each request; d <= 0 keeps ports.DefaultRequestTimeout.
+func (c *Client) SetTimeout(d time.Duration) {
+	if d > 0 {
+		c.http.Timeout = d
+	}
+}
+
+// SetTransport sends requests through rt (see llmhttp.NewTransport); nil
+// means http.DefaultTransport.
+func (c *Client) SetTransport(rt http.RoundTripper) {
+	c.http.Transport = rt
+}
+
+// Close implements io.Closer by dropping idle keep-alive connections. The
+// client still works afterwards, dialing again as needed.
+func (c *Client) Close() error {
+	c.http.CloseIdleConnections()
+	return nil
+}
+
+// SetAllowHighTemp sends the requested temperature unclamped, without JSON
+// mode. Output is more varied but less reliably parseable.
+func (c *Client) SetAllowHighTemp(v bool) {
+	c.allowHighTemp = v
+}
+
+// EffectiveTemperature implements ports.TemperatureLimiter.
+func (c *Client) EffectiveTemperature(requested float32) float32 {
+	if c.clampTemp && !c.allowHighTemp && requested > jsonModeMaxTemperature {
+		return jsonModeMaxTemperature
+	}
+	return requested
+}
+
+// Capabilities implements ports.CapableLLM. Requests use JSON mode, which
+// a plain retry drops when the model rejects it.
+func (c *Client) Capabilities(model string) ports.Capabilities {
+	return ports.Capabilities{JSONMode: true, RespectsTemperature: true}
+}
+
 // SuggestCommits generates commit suggestions using Groq API.
 // Groq API is OpenAI-compatible.
 func (c *Client) SuggestCommits(ctx context.Context, input ports.SuggestInput) ([]ports.CommitSuggestion, error) {
-	prompt := buildCommitPrompt(input.StagedDiff)
+	suggestions, _, err := c.SuggestCommitsWithUsage(ctx, input)
+	return suggestions, err
+}
+
+// SuggestCommitsWithUsage is SuggestCommits plus the token usage Groq reports.
+func (c *Client) SuggestCommitsWithUsage(ctx context.Context, input ports.SuggestInput) ([]ports.CommitSuggestion, *ports.Usage, error) {
+	prompt := buildCommitPrompt(input)
+	observability.DebugPrompt(c.name, prompt)
 
 	// JSON-enforced mode works best with low temperature.
-	temp := input.Temperature
-	if temp > 0.2 {
-		temp = 0.2
+	temp := c.EffectiveTemperature(input.Temperature)
+	if temp < input.Temperature {
+		observability.Event(map[string]any{
+			"level":    "info",
+			"provider": c.name,
+			"message":  "temperature clamped for JSON mode (set GroqAllowHighTemp to honor it)",
+			"temp":     input.Temperature,
+			"clamped":  temp,
+		})
 	}
 
 	reqBody := map[string]interface{}{
 		"model": c.model,
-		// Ask the OpenAI-compatible API to return a JSON object. Some models may
-		// otherwise emit reasoning-only output with empty message.content.
-		"response_format": map[string]string{"type": "json_object"},
 		"messages": []map[string]string{
 			{
 				"role":    "system",
-				"content": "You are an expert git commit message writer. Return ONLY valid JSON matching the requested schema. No markdown, no extra text.",
+				"content": prompts.System(input),
 			},
 			{
 				"role":    "user",
@@ -68,41 +142,50 @@ func (c *Client) SuggestCommits(ctx context.Context, input ports.SuggestInput) (
 		"temperature": temp,
 		"max_tokens":  1400,
 	}
+	if !c.allowHighTemp {
+		// Ask the OpenAI-compatible API to return a JSON object. Some models may
+		// otherwise emit reasoning-only output with empty message.content.
+		reqBody["response_format"] = map[string]string{"type": "json_object"}
+	}
 
 	jsonBody, err := json.Marshal(reqBody)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+		return nil, nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/chat/completions", bytes.NewReader(jsonBody))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.apiKey))
 
+	stop := observability.TimeLLM(c.name, c.model)
 	resp, err := c.http.Do(req)
+	stop()
 	if err != nil {
-		return nil, fmt.Errorf("failed to call Groq API: %w", err)
+		return nil, nil, fmt.Errorf("failed to call %s API: %w", c.name, err)
 	}
 	defer resp.Body.Close()
 
 	body, readErr := io.ReadAll(resp.Body)
 	if readErr != nil {
-		return nil, fmt.Errorf("failed to read response: %w", readErr)
+		return nil, nil, fmt.Errorf("failed to read response: %w", readErr)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		observability.Logger().Printf(
-			"groq: non-200 status=%d model=%q temp=%.2f max_tokens=%v body_len=%d body_snip=%q",
-			resp.StatusCode,
-			c.model,
-			temp,
-			reqBody["max_tokens"],
-			len(body),
-			observability.Snip(observability.RedactForLog(string(body)), 1200),
-		)
+		observability.Event(map[string]any{
+			"level":      observability.StatusLevel(resp.StatusCode),
+			"provider":   c.name,
+			"status":     resp.StatusCode,
+			"message":    "non-200 response",
+			"model":      c.model,
+			"temp":       temp,
+			"max_tokens": reqBody["max_tokens"],
+			"body_len":   len(body),
+			"body_snip":  observability.Snip(observability.RedactForLog(string(body)), 1200),
+		})
 
 		// Some models/endpoints reject strict JSON mode with "json_validate_failed".
 		// Retry once without response_format (but still with a strict prompt) so we
@@ -111,12 +194,18 @@ func (c *Client) SuggestCommits(ctx context.Context, input ports.SuggestInput) (
 			return c.retryWithoutJSONMode(ctx, input, prompt)
 		}
 
-		return nil, fmt.Errorf("groq returned status %d: %s", resp.StatusCode, string(body))
+		return nil, nil, llmerr.FromResponse(c.name, resp.StatusCode, body)
 	}
 
 	if len(body) == 0 {
-		observability.Logger().Printf("groq: empty HTTP body (status=200) model=%q", c.model)
-		return nil, fmt.Errorf("groq returned empty response body")
+		observability.Event(map[string]any{
+			"level":    "error",
+			"provider": c.name,
+			"status":   resp.StatusCode,
+			"message":  "empty HTTP body",
+			"model":    c.model,
+		})
+		return nil, nil, fmt.Errorf("%s returned empty response body", c.name)
 	}
 
 	var respData struct {
@@ -127,27 +216,33 @@ func (c *Client) SuggestCommits(ctx context.Context, input ports.SuggestInput) (
 				Reasoning *string `json:"reasoning"`
 			} `json:"message"`
 		} `json:"choices"`
+		Usage *ports.Usage `json:"usage"`
 	}
 
 	if err := json.Unmarshal(body, &respData); err != nil {
-		observability.Logger().Printf(
-			"groq: failed to unmarshal response JSON: %v; body_len=%d body_snip=%q",
-			err,
-			len(body),
-			observability.Snip(observability.RedactForLog(string(body)), 1200),
-		)
-		return nil, fmt.Errorf("failed to parse response: %w", err)
+		observability.Event(map[string]any{
+			"level":     "error",
+			"provider":  c.name,
+			"message":   "failed to unmarshal response JSON",
+			"error":     err,
+			"body_len":  len(body),
+			"body_snip": observability.Snip(observability.RedactForLog(string(body)), 1200),
+		})
+		return nil, nil, fmt.Errorf("failed to parse response: %w", err)
 	}
 
 	if len(respData.Choices) == 0 {
-		observability.Logger().Printf(
-			"groq: no choices in response; body_len=%d body_snip=%q",
-			len(body),
-			observability.Snip(observability.RedactForLog(string(body)), 1200),
-		)
-		return nil, fmt.Errorf("no choices in response")
+		observability.Event(map[string]any{
+			"level":     "error",
+			"provider":  c.name,
+			"message":   "no choices in response",
+			"body_len":  len(body),
+			"body_snip": observability.Snip(observability.RedactForLog(string(body)), 1200),
+		})
+		return nil, nil, fmt.Errorf("no choices in response")
 	}
 
+	usage := respData.Usage
 	msg := respData.Choices[0].Message
 	content := ""
 	if msg.Content != nil {
@@ -158,39 +253,39 @@ func (c *Client) SuggestCommits(ctx context.Context, input ports.SuggestInput) (
 		// We'll attempt to parse JSON from reasoning as a fallback.
 		content = strings.TrimSpace(*msg.Reasoning)
 	}
+	observability.DebugResponse(c.name, content)
 	if content == "" {
-		observability.Logger().Printf(
-			"groq: empty assistant output; role=%q body_len=%d body_snip=%q",
-			msg.Role,
-			len(body),
-			observability.Snip(observability.RedactForLog(string(body)), 1200),
-		)
-		return nil, fmt.Errorf("groq returned empty assistant output")
-	}
-
-	suggestions, err := parseSuggestionsJSON(content)
+		observability.Event(map[string]any{
+			"level":     "error",
+			"provider":  c.name,
+			"message":   "empty assistant output",
+			"role":      msg.Role,
+			"body_len":  len(body),
+			"body_snip": observability.Snip(observability.RedactForLog(string(body)), 1200),
+		})
+		return nil, usage, fmt.Errorf("%s returned %w", c.name, ports.ErrEmptyOutput)
+	}
+
+	suggestions, err := parseSuggestionsJSON(c.name, content)
 	if err != nil {
-		return nil, err
+		return nil, usage, err
 	}
-	if len(suggestions) < 3 {
-		return nil, fmt.Errorf("expected 3 suggestions, got %d", len(suggestions))
+	if n := input.WantCount(); len(suggestions) < n {
+		return nil, usage, fmt.Errorf("expected %d suggestions, got %d", n, len(suggestions))
 	}
-	return suggestions[:3], nil
+	return suggestions[:input.WantCount()], usage, nil
 }
 
-func (c *Client) retryWithoutJSONMode(ctx context.Context, input ports.SuggestInput, prompt string) ([]ports.CommitSuggestion, error) {
+func (c *Client) retryWithoutJSONMode(ctx context.Context, input ports.SuggestInput, prompt string) ([]ports.CommitSuggestion, *ports.Usage, error) {
 	// Keep it deterministic.
-	temp := input.Temperature
-	if temp > 0.2 {
-		temp = 0.2
-	}
+	temp := c.EffectiveTemperature(input.Temperature)
 
 	reqBody := map[string]interface{}{
 		"model": c.model,
 		"messages": []map[string]string{
 			{
 				"role": "system",
-				"content": "Return ONLY valid JSON for the requested schema. Output must start with '{' and end with '}'. No markdown, no extra text.",
+				"content": prompts.System(input),
 			},
 			{
 				"role": "user",
@@ -203,34 +298,38 @@ func (c *Client) retryWithoutJSONMode(ctx context.Context, input ports.SuggestIn
 
 	jsonBody, err := json.Marshal(reqBody)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal retry request: %w", err)
+		return nil, nil, fmt.Errorf("failed to marshal retry request: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/chat/completions", bytes.NewReader(jsonBody))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create retry request: %w", err)
+		return nil, nil, fmt.Errorf("failed to create retry request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.apiKey))
 
+	stop := observability.TimeLLM(c.name, c.model)
 	resp, err := c.http.Do(req)
+	stop()
 	if err != nil {
-		return nil, fmt.Errorf("failed to call Groq API (retry): %w", err)
+		return nil, nil, fmt.Errorf("failed to call %s API (retry): %w", c.name, err)
 	}
 	defer resp.Body.Close()
 
 	body, _ := io.ReadAll(resp.Body)
 	if resp.StatusCode != http.StatusOK {
-		observability.Logger().Printf(
-			"groq: retry non-200 status=%d model=%q temp=%.2f max_tokens=%v body_len=%d body_snip=%q",
-			resp.StatusCode,
-			c.model,
-			temp,
-			reqBody["max_tokens"],
-			len(body),
-			observability.Snip(observability.RedactForLog(string(body)), 1200),
-		)
-		return nil, fmt.Errorf("groq returned status %d: %s", resp.StatusCode, string(body))
+		observability.Event(map[string]any{
+			"level":      observability.StatusLevel(resp.StatusCode),
+			"provider":   c.name,
+			"status":     resp.StatusCode,
+			"message":    "retry non-200 response",
+			"model":      c.model,
+			"temp":       temp,
+			"max_tokens": reqBody["max_tokens"],
+			"body_len":   len(body),
+			"body_snip":  observability.Snip(observability.RedactForLog(string(body)), 1200),
+		})
+		return nil, nil, llmerr.FromResponse(c.name, resp.StatusCode, body)
 	}
 
 	var respData struct {
@@ -241,20 +340,24 @@ func (c *Client) retryWithoutJSONMode(ctx context.Context, input ports.SuggestIn
 				Reasoning *string `json:"reasoning"`
 			} `json:"message"`
 		} `json:"choices"`
+		Usage *ports.Usage `json:"usage"`
 	}
 	if err := json.Unmarshal(body, &respData); err != nil {
-		observability.Logger().Printf(
-			"groq: retry failed to unmarshal response JSON: %v; body_len=%d body_snip=%q",
-			err,
-			len(body),
-			observability.Snip(observability.RedactForLog(string(body)), 1200),
-		)
-		return nil, fmt.Errorf("failed to parse response (retry): %w", err)
+		observability.Event(map[string]any{
+			"level":     "error",
+			"provider":  c.name,
+			"message":   "retry failed to unmarshal response JSON",
+			"error":     err,
+			"body_len":  len(body),
+			"body_snip": observability.Snip(observability.RedactForLog(string(body)), 1200),
+		})
+		return nil, nil, fmt.Errorf("failed to parse response (retry): %w", err)
 	}
 	if len(respData.Choices) == 0 {
-		return nil, fmt.Errorf("no choices in response (retry)")
+		return nil, nil, fmt.Errorf("no choices in response (retry)")
 	}
 
+	usage := respData.Usage
 	msg := respData.Choices[0].Message
 	content := ""
 	if msg.Content != nil {
@@ -263,54 +366,79 @@ func (c *Client) retryWithoutJSONMode(ctx context.Context, input ports.SuggestIn
 	if content == "" && msg.Reasoning != nil {
 		content = strings.TrimSpace(*msg.Reasoning)
 	}
+	observability.DebugResponse(c.name, content)
 	if content == "" {
-		return nil, fmt.Errorf("groq returned empty assistant output (retry)")
+		return nil, usage, fmt.Errorf("%s returned %w (retry)", c.name, ports.ErrEmptyOutput)
 	}
 
-	suggestions, err := parseSuggestionsJSON(content)
+	suggestions, err := parseSuggestionsJSON(c.name, content)
 	if err != nil {
-		return nil, err
+		return nil, usage, err
 	}
-	if len(suggestions) < 3 {
-		return nil, fmt.Errorf("expected 3 suggestions, got %d", len(suggestions))
+	if n := input.WantCount(); len(suggestions) < n {
+		return nil, usage, fmt.Errorf("expected %d suggestions, got %d", n, len(suggestions))
 	}
-	return suggestions[:3], nil
+	return suggestions[:input.WantCount()], usage, nil
 }
 
-// buildCommitPrompt creates a prompt for commit message generation.
-func buildCommitPrompt(diff string) string {
-	return fmt.Sprintf(`Generate exactly 3 Conventional Commit suggestions for this staged diff.
+// ListModels implements ports.ModelLister (GET /models).
+func (c *Client) ListModels(ctx context.Context) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/models", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.apiKey))
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call %s API: %w", c.name, err)
+	}
+	defer resp.Body.Close()
 
-<diff>
-%s
-</diff>
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, llmerr.FromResponse(c.name, resp.StatusCode, body)
+	}
 
-Return ONLY a single JSON object with this exact shape:
-{"suggestions":[{"type":"feat|fix|docs|style|refactor|perf|test|chore|build|ci|revert","subject":"...","body":"...","footer":"..."}]}
+	var list struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("failed to parse model list: %w", err)
+	}
+	names := make([]string, 0, len(list.Data))
+	for _, m := range list.Data {
+		names = append(names, m.ID)
+	}
+	sort.Strings(names)
+	return names, nil
+}
 
-Rules:
-- Exactly 3 suggestions
-- subject: max 72 characters, no newlines
-- body/footer may be empty strings
-`, diff)
+// buildCommitPrompt creates a prompt for commit message generation.
+func buildCommitPrompt(input ports.SuggestInput) string {
+	return prompts.Commit(input)
 }
 
-func parseSuggestionsJSON(content string) ([]ports.CommitSuggestion, error) {
+func parseSuggestionsJSON(provider, content string) ([]ports.CommitSuggestion, error) {
 	var resp struct {
 		Suggestions []ports.CommitSuggestion `json:"suggestions"`
 	}
 
 	jsonContent := extractJSON(content)
 	if err := json.Unmarshal([]byte(jsonContent), &resp); err != nil {
-		observability.Logger().Printf(
-			"groq: invalid JSON: %v; raw_len=%d raw_snip=%q; json_len=%d json_snip=%q",
-			err,
-			len(content),
-			observability.Snip(observability.RedactForLog(content), 600),
-			len(jsonContent),
-			observability.Snip(observability.RedactForLog(jsonContent), 600),
-		)
-		return nil, fmt.Errorf("invalid JSON: %w", err)
+		observability.Event(map[string]any{
+			"level":     "error",
+			"provider":  provider,
+			"message":   "invalid JSON",
+			"error":     err,
+			"raw_len":   len(content),
+			"raw_snip":  observability.Snip(observability.RedactForLog(content), 600),
+			"json_len":  len(jsonContent),
+			"json_snip": observability.Snip(observability.RedactForLog(jsonContent), 600),
+		})
+		return nil, fmt.Errorf("%w: %w", ports.ErrInvalidJSON, err)
 	}
 	if len(resp.Suggestions) == 0 {
 		return nil, errors.New("no suggestions in response")
@@ -0,0 +1,182 @@
+package groq
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chuckie/commit-coach/internal/ports"
+)
+
+// stubServer answers /chat/completions with one suggestion and records
+// request bodies.
+func stubServer(t *testing.T) (*httptest.Server, *[]map[string]interface{}) {
+	t.Helper()
+	var requests []map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		requests = append(requests, body)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{
+				{"message": map[string]string{"role": "assistant", "content": `{"suggestions":[{"type":"feat","subject":"add x"}]}`}},
+			},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func TestTemperatureClampedInJSONMode(t *testing.T) {
+	srv, requests := stubServer(t)
+	c := NewClient("gsk-test", "llama")
+	c.baseURL = srv.URL
+
+	if got := c.EffectiveTemperature(0.8); got != jsonModeMaxTemperature {
+		t.Errorf("EffectiveTemperature(0.8) = %v, want %v", got, jsonModeMaxTemperature)
+	}
+	if _, err := c.SuggestCommits(context.Background(), ports.SuggestInput{StagedDiff: "diff", Temperature: 0.8, Count: 1}); err != nil {
+		t.Fatalf("SuggestCommits() error = %v", err)
+	}
+	req := (*requests)[0]
+	if temp, _ := req["temperature"].(float64); temp > 0.2001 {
+		t.Errorf("temperature sent = %v, want clamped to 0.2", temp)
+	}
+	if _, ok := req["response_format"]; !ok {
+		t.Error("response_format missing, want JSON mode")
+	}
+}
+
+func TestCompatibleClient(t *testing.T) {
+	srv, requests := stubServer(t)
+	c := NewCompatibleClient("mistral", srv.URL+"/", "key", "codestral-latest")
+
+	if got := c.EffectiveTemperature(0.8); got != 0.8 {
+		t.Errorf("EffectiveTemperature(0.8) = %v, want no Groq clamp", got)
+	}
+	if _, err := c.SuggestCommits(context.Background(), ports.SuggestInput{StagedDiff: "diff", Temperature: 0.8, Count: 1}); err != nil {
+		t.Fatalf("SuggestCommits() error = %v", err)
+	}
+	if model := (*requests)[0]["model"]; model != "codestral-latest" {
+		t.Errorf("model sent = %v, want codestral-latest", model)
+	}
+
+	srv.Close()
+	_, err := c.SuggestCommits(context.Background(), ports.SuggestInput{StagedDiff: "diff", Count: 1})
+	if err == nil || !strings.Contains(err.Error(), "mistral API") {
+		t.Errorf("error = %v, want it labeled with the provider name", err)
+	}
+}
+
+func TestAllowHighTempSkipsClamp(t *testing.T) {
+	srv, requests := stubServer(t)
+	c := NewClient("gsk-test", "llama")
+	c.baseURL = srv.URL
+	c.SetAllowHighTemp(true)
+
+	if got := c.EffectiveTemperature(0.8); got != 0.8 {
+		t.Errorf("EffectiveTemperature(0.8) = %v, want 0.8", got)
+	}
+	if _, err := c.SuggestCommits(context.Background(), ports.SuggestInput{StagedDiff: "diff", Temperature: 0.8, Count: 1}); err != nil {
+		t.Fatalf("SuggestCommits() error = %v", err)
+	}
+	req := (*requests)[0]
+	if temp, _ := req["temperature"].(float64); temp < 0.79 {
+		t.Errorf("temperature sent = %v, want 0.8", temp)
+	}
+	if _, ok := req["response_format"]; ok {
+		t.Error("response_format sent, want JSON mode off")
+	}
+}
+
+func TestBuildCommitPromptBranch(t *testing.T) {
+	prompt := buildCommitPrompt(ports.SuggestInput{StagedDiff: "+x", Branch: "refactor-auth-middleware"})
+	if !strings.Contains(prompt, "The work is on branch refactor-auth-middleware.") {
+		t.Errorf("prompt missing the feature branch:\n%s", prompt)
+	}
+	prompt = buildCommitPrompt(ports.SuggestInput{StagedDiff: "+x", Branch: "main"})
+	if strings.Contains(prompt, "branch") {
+		t.Errorf("prompt mentions main:\n%s", prompt)
+	}
+}
+
+func TestCustomSystemPromptKeepsJSONInstruction(t *testing.T) {
+	srv, requests := stubServer(t)
+	c := NewClient("gsk-test", "llama")
+	c.baseURL = srv.URL
+
+	if _, err := c.SuggestCommits(context.Background(), ports.SuggestInput{StagedDiff: "+x", Count: 1, SystemPrompt: "You write terse commit messages."}); err != nil {
+		t.Fatalf("SuggestCommits() error = %v", err)
+	}
+	messages := (*requests)[0]["messages"].([]interface{})
+	system := messages[0].(map[string]interface{})["content"].(string)
+	if !strings.HasPrefix(system, "You write terse commit messages.") || strings.Contains(system, "expert git commit message writer") {
+		t.Errorf("system message = %q, want the custom prompt in place of the default", system)
+	}
+	if !strings.Contains(system, "Return ONLY valid JSON") {
+		t.Errorf("system message = %q, want the JSON instruction kept", system)
+	}
+}
+
+func TestJSONModeRetryKeepsCustomSystemPrompt(t *testing.T) {
+	var requests []map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		requests = append(requests, body)
+		if _, jsonMode := body["response_format"]; jsonMode {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"message":"Failed to validate JSON","code":"json_validate_failed"}}`))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{
+				{"message": map[string]string{"role": "assistant", "content": `{"suggestions":[{"type":"feat","subject":"add x"}]}`}},
+			},
+		})
+	}))
+	defer srv.Close()
+	c := NewCompatibleClient("mistral", srv.URL, "key", "codestral-latest")
+
+	if _, err := c.SuggestCommits(context.Background(), ports.SuggestInput{StagedDiff: "+x", Count: 1, SystemPrompt: "You write terse commit messages."}); err != nil {
+		t.Fatalf("SuggestCommits() error = %v", err)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("sent %d requests, want the JSON-mode one and a retry", len(requests))
+	}
+	messages := requests[1]["messages"].([]interface{})
+	system := messages[0].(map[string]interface{})["content"].(string)
+	if !strings.HasPrefix(system, "You write terse commit messages.") || !strings.Contains(system, "Return ONLY valid JSON") {
+		t.Errorf("retry system message = %q, want the custom prompt plus the JSON instruction", system)
+	}
+}
+
+func TestListModels(t *testing.T) {
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models" {
+			http.NotFound(w, r)
+			return
+		}
+		auth = r.Header.Get("Authorization")
+		w.Write([]byte(`{"object":"list","data":[{"id":"llama-3.3-70b-versatile"},{"id":"gemma2-9b-it"}]}`))
+	}))
+	defer srv.Close()
+	c := NewClient("gsk-test", "llama")
+	c.baseURL = srv.URL
+
+	models, err := c.ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("ListModels() error = %v", err)
+	}
+	if strings.Join(models, ",") != "gemma2-9b-it,llama-3.3-70b-versatile" {
+		t.Errorf("ListModels() = %q, want both ids sorted", models)
+	}
+	if auth != "Bearer gsk-test" {
+		t.Errorf("Authorization = %q, want the API key", auth)
+	}
+}
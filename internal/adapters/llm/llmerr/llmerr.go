@@ -0,0 +1,55 @@
+// Package llmerr builds ports.ProviderError values from provider responses.
+// It lives outside package llm so the provider clients can import it.
+package llmerr
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/chuckie/commit-coach/internal/observability"
+	"github.com/chuckie/commit-coach/internal/ports"
+)
+
+// maxMessageRunes bounds messages taken from unrecognised response bodies.
+const maxMessageRunes = 300
+
+// New returns a ProviderError with message redacted. An empty message falls
+// back to the HTTP status text.
+func New(provider string, status int, message string) *ports.ProviderError {
+	message = strings.TrimSpace(message)
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	return &ports.ProviderError{
+		Provider:   provider,
+		StatusCode: status,
+		Message:    observability.RedactForLog(message),
+	}
+}
+
+// FromResponse parses the error envelope in body. It understands
+// {"error":{"message":"..."}} (OpenAI, Anthropic, Groq) and {"error":"..."}
+// (Ollama); anything else is used as-is, shortened.
+func FromResponse(provider string, status int, body []byte) *ports.ProviderError {
+	return New(provider, status, envelopeMessage(body))
+}
+
+func envelopeMessage(body []byte) string {
+	var envelope struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if err := json.Unmarshal(body, &envelope); err == nil && len(envelope.Error) > 0 {
+		var nested struct {
+			Message string `json:"message"`
+		}
+		if err := json.Unmarshal(envelope.Error, &nested); err == nil && nested.Message != "" {
+			return nested.Message
+		}
+		var plain string
+		if err := json.Unmarshal(envelope.Error, &plain); err == nil && plain != "" {
+			return plain
+		}
+	}
+	return observability.Snip(strings.TrimSpace(string(body)), maxMessageRunes)
+}
@@ -0,0 +1,46 @@
+package llmerr
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFromResponse(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"openai", `{"error":{"message":"Incorrect API key provided","type":"invalid_request_error"}}`, "Incorrect API key provided"},
+		{"anthropic", `{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`, "invalid x-api-key"},
+		{"ollama", `{"error":"model is loading"}`, "model is loading"},
+		{"plain text", "upstream timeout\n", "upstream timeout"},
+		{"empty", "", "Bad Gateway"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := 401
+			if tt.name == "empty" {
+				status = 502
+			}
+			pe := FromResponse(tt.name, status, []byte(tt.body))
+			if pe.Message != tt.want {
+				t.Errorf("Message = %q, want %q", pe.Message, tt.want)
+			}
+			if pe.StatusCode != status || pe.Provider != tt.name {
+				t.Errorf("got provider %q status %d", pe.Provider, pe.StatusCode)
+			}
+		})
+	}
+}
+
+func TestFromResponseRedactsKeys(t *testing.T) {
+	body := `{"error":{"message":"Incorrect API key provided: sk-abcdefghijklmnopqrstuvwxyz123456"}}`
+	pe := FromResponse("openai", 401, []byte(body))
+	if strings.Contains(pe.Message, "abcdefghijklmnop") {
+		t.Errorf("key leaked into message: %q", pe.Message)
+	}
+	if !strings.Contains(pe.Error(), "openai returned status 401") {
+		t.Errorf("Error() = %q", pe.Error())
+	}
+}
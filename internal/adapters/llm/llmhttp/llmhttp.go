@@ -0,0 +1,74 @@
+// Package llmhttp builds the HTTP transport shared by the provider clients.
+// It lives outside package llm so the provider clients can import it.
+package llmhttp
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// Options configures provider HTTP traffic. The zero value keeps Go's
+// default transport, which already honors HTTP_PROXY, HTTPS_PROXY and
+// NO_PROXY.
+type Options struct {
+	// CABundle is a PEM file of extra trusted roots, added to the system
+	// pool, for endpoints behind a TLS-intercepting proxy or with an
+	// internal CA.
+	CABundle string
+	// InsecureSkipVerify turns off certificate verification. Only meant for
+	// internal endpoints that can't be given a proper certificate.
+	InsecureSkipVerify bool
+	// Transport, when set, is used as is and the other options are ignored.
+	Transport http.RoundTripper
+}
+
+// NewTransport returns the transport described by opts, or nil when opts
+// asks for nothing beyond http.DefaultTransport.
+func NewTransport(opts Options) (http.RoundTripper, error) {
+	if opts.Transport != nil {
+		return opts.Transport, nil
+	}
+	if opts.CABundle == "" && !opts.InsecureSkipVerify {
+		return nil, nil
+	}
+
+	base, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return nil, errors.New("default HTTP transport was replaced; cannot apply TLS options")
+	}
+	// The clone keeps the default's proxy-from-environment behaviour.
+	t := base.Clone()
+	t.TLSClientConfig = &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: opts.InsecureSkipVerify,
+	}
+	if opts.CABundle != "" {
+		pool, err := caPool(opts.CABundle)
+		if err != nil {
+			return nil, err
+		}
+		t.TLSClientConfig.RootCAs = pool
+	}
+	return t, nil
+}
+
+// caPool returns the system roots plus the certificates in the PEM file at
+// path.
+func caPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read CA bundle: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("CA bundle %s holds no PEM certificates", path)
+	}
+	return pool, nil
+}
@@ -0,0 +1,38 @@
+package llmhttp
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewTransport(t *testing.T) {
+	if rt, err := NewTransport(Options{}); rt != nil || err != nil {
+		t.Errorf("NewTransport(zero) = %v, %v; want nil so the default transport is used", rt, err)
+	}
+
+	custom := http.NewFileTransport(http.Dir("."))
+	if rt, _ := NewTransport(Options{Transport: custom, InsecureSkipVerify: true}); rt != custom {
+		t.Errorf("NewTransport() = %v, want the custom transport as is", rt)
+	}
+
+	rt, err := NewTransport(Options{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatalf("NewTransport(insecure) error = %v", err)
+	}
+	tr := rt.(*http.Transport)
+	if !tr.TLSClientConfig.InsecureSkipVerify || tr.Proxy == nil {
+		t.Errorf("transport = %+v, want verification off and proxies from the environment", tr)
+	}
+
+	bad := filepath.Join(t.TempDir(), "ca.pem")
+	os.WriteFile(bad, []byte("not a certificate"), 0o600)
+	if _, err := NewTransport(Options{CABundle: bad}); err == nil || !strings.Contains(err.Error(), "no PEM certificates") {
+		t.Errorf("NewTransport(bad bundle) error = %v", err)
+	}
+	if _, err := NewTransport(Options{CABundle: bad + ".missing"}); err == nil {
+		t.Error("NewTransport(missing bundle) should fail")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"hash/fnv"
 
+	"github.com/chuckie/commit-coach/internal/adapters/llm/prompts"
 	"github.com/chuckie/commit-coach/internal/ports"
 )
 
@@ -15,6 +16,12 @@ func NewClient() *Client {
 	return &Client{}
 }
 
+// Capabilities implements ports.CapableLLM. Mock output ignores the
+// request apart from the diff and count.
+func (c *Client) Capabilities(model string) ports.Capabilities {
+	return ports.Capabilities{}
+}
+
 // SuggestCommits returns deterministic mock commit suggestions based on the input.
 func (c *Client) SuggestCommits(ctx context.Context, input ports.SuggestInput) ([]ports.CommitSuggestion, error) {
 	// Deterministic based on diff content hash
@@ -32,13 +39,14 @@ func (c *Client) SuggestCommits(ctx context.Context, input ports.SuggestInput) (
 		{"chore", "update dependencies and maintenance tasks", "Performs routine maintenance and dependency updates to keep the project healthy."},
 	}
 
-	result := make([]ports.CommitSuggestion, 0, 3)
-	for i := 0; i < 3; i++ {
+	count := input.WantCount()
+	result := make([]ports.CommitSuggestion, 0, count)
+	for i := 0; i < count; i++ {
 		idx := int((hash + uint64(i)) % uint64(len(patterns)))
 		p := patterns[idx]
 		subject := p.subject
-		if len(subject) > 72 {
-			subject = subject[:72]
+		if max := prompts.SubjectMaxLen(input); len(subject) > max {
+			subject = subject[:max]
 		}
 		result = append(result, ports.CommitSuggestion{
 			Type:    p.commitType,
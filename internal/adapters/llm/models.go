@@ -0,0 +1,90 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/chuckie/commit-coach/internal/ports"
+)
+
+// ModelListTTL is how long a fetched model list is reused.
+const ModelListTTL = 10 * time.Minute
+
+// ModelCache keeps live model lists in a small JSON file keyed by provider,
+// so repeated lookups skip the network. Read and write errors are treated
+// as a miss; the cache is only an optimization.
+type ModelCache struct {
+	path  string
+	ttl   time.Duration
+	clock ports.Clock
+}
+
+type cachedModels struct {
+	Models  []string  `json:"models"`
+	Fetched time.Time `json:"fetched"`
+}
+
+// NewModelCache returns a cache backed by the file at path whose entries
+// expire after ttl.
+func NewModelCache(path string, ttl time.Duration, clock ports.Clock) *ModelCache {
+	return &ModelCache{path: path, ttl: ttl, clock: clock}
+}
+
+// Get returns provider's list if it was stored less than ttl ago.
+func (c *ModelCache) Get(provider string) ([]string, bool) {
+	entry, ok := c.load()[provider]
+	if !ok || c.clock.Now().Sub(entry.Fetched) >= c.ttl {
+		return nil, false
+	}
+	return entry.Models, true
+}
+
+// Set stores provider's list.
+func (c *ModelCache) Set(provider string, models []string) error {
+	all := c.load()
+	all[provider] = cachedModels{Models: models, Fetched: c.clock.Now()}
+	b, err := json.MarshalIndent(all, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, b, 0o600)
+}
+
+func (c *ModelCache) load() map[string]cachedModels {
+	all := map[string]cachedModels{}
+	if b, err := os.ReadFile(c.path); err == nil {
+		_ = json.Unmarshal(b, &all)
+	}
+	return all
+}
+
+// ListModels returns provider's models and whether they are live: from
+// cache when fresh, else fetched from l when it is a ports.ModelLister.
+// When no live list is available it returns fallback and false, with the
+// fetch error (nil if l cannot list models) so callers can say why. cache
+// may be nil.
+func ListModels(ctx context.Context, provider string, l ports.LLM, cache *ModelCache, fallback []string) ([]string, bool, error) {
+	if cache != nil {
+		if models, ok := cache.Get(provider); ok {
+			return models, true, nil
+		}
+	}
+	lister, ok := l.(ports.ModelLister)
+	if !ok {
+		return fallback, false, nil
+	}
+	models, err := lister.ListModels(ctx)
+	if err != nil || len(models) == 0 {
+		return fallback, false, err
+	}
+	if cache != nil {
+		_ = cache.Set(provider, models)
+	}
+	return models, true, nil
+}
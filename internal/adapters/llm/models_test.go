@@ -0,0 +1,54 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chuckie/commit-coach/internal/testutil"
+)
+
+type stubLister struct {
+	testutil.FakeLLM
+	models []string
+	err    error
+	calls  int
+}
+
+func (s *stubLister) ListModels(ctx context.Context) ([]string, error) {
+	s.calls++
+	return s.models, s.err
+}
+
+func TestListModelsCachesAndFallsBack(t *testing.T) {
+	clock := &testutil.FakeClock{T: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	cache := NewModelCache(filepath.Join(t.TempDir(), "models.json"), ModelListTTL, clock)
+	fallback := []string{"static"}
+	ctx := context.Background()
+
+	lister := &stubLister{models: []string{"live-a", "live-b"}}
+	models, live, err := ListModels(ctx, "groq", lister, cache, fallback)
+	if err != nil || !live || strings.Join(models, ",") != "live-a,live-b" {
+		t.Fatalf("ListModels() = %q, %v, %v; want the live list", models, live, err)
+	}
+
+	// Within the TTL the cached list is used even when the network fails.
+	lister.err = errors.New("dial tcp: connection refused")
+	if models, live, _ := ListModels(ctx, "groq", lister, cache, fallback); !live || len(models) != 2 || lister.calls != 1 {
+		t.Errorf("cached lookup = %q, live %v after %d calls; want the cached list without a call", models, live, lister.calls)
+	}
+
+	clock.Advance(ModelListTTL)
+	models, live, err = ListModels(ctx, "groq", lister, cache, fallback)
+	if err == nil || live || strings.Join(models, ",") != "static" {
+		t.Errorf("expired lookup = %q, %v, %v; want the fallback and the fetch error", models, live, err)
+	}
+
+	// Providers that cannot list models get the fallback without an error.
+	if models, live, err := ListModels(ctx, "anthropic", &testutil.FakeLLM{}, nil, fallback); err != nil || live || models[0] != "static" {
+		t.Errorf("non-lister = %q, %v, %v; want the fallback", models, live, err)
+	}
+}
@@ -9,7 +9,10 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/chuckie/commit-coach/internal/adapters/llm/llmerr"
+	"github.com/chuckie/commit-coach/internal/adapters/llm/prompts"
 	"github.com/chuckie/commit-coach/internal/observability"
 	"github.com/chuckie/commit-coach/internal/ports"
 )
@@ -19,6 +22,34 @@ type Client struct {
 	baseURL string
 	model   string
 	http    *http.Client
+	// pulled is set once the model has been confirmed present locally.
+	pulled bool
+	// useSchema sends a JSON schema as "format" (Ollama 0.5+) instead of "json".
+	useSchema bool
+	// timeout bounds each request through its context, since http has no
+	// timeout of its own; 0 leaves the caller's deadline alone.
+	timeout time.Duration
+}
+
+// suggestionsSchema is the structured-output schema for the suggestions shape.
+var suggestionsSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"suggestions": map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"type":    map[string]interface{}{"type": "string"},
+					"subject": map[string]interface{}{"type": "string"},
+					"body":    map[string]interface{}{"type": "string"},
+					"footer":  map[string]interface{}{"type": "string"},
+				},
+				"required": []string{"type", "subject"},
+			},
+		},
+	},
+	"required": []string{"suggestions"},
 }
 
 // NewClient creates a new Ollama client.
@@ -37,85 +68,207 @@ func NewClient(baseURL, model string) *Client {
 	}
 }
 
+// SetTimeout bounds each request, including the model check, via the
+// context deadline; d <= 0 relies on the caller's deadline only.
+func (c *Client) SetTimeout(d time.Duration) {
+	c.timeout = max(d, 0)
+}
+
+// SetTransport sends requests through rt (see llmhttp.NewTransport); nil
+// means http.DefaultTransport.
+func (c *Client) SetTransport(rt http.RoundTripper) {
+	c.http.Transport = rt
+}
+
+// Close implements io.Closer by dropping idle keep-alive connections. The
+// client still works afterwards, dialing again as needed.
+func (c *Client) Close() error {
+	c.http.CloseIdleConnections()
+	return nil
+}
+
+// SetStructuredOutput makes requests pass a JSON schema as "format" rather
+// than plain "json". Requires an Ollama server with structured outputs.
+func (c *Client) SetStructuredOutput(v bool) {
+	c.useSchema = v
+}
+
+// Capabilities implements ports.CapableLLM. "format" constrains every
+// request to JSON.
+func (c *Client) Capabilities(model string) ports.Capabilities {
+	return ports.Capabilities{JSONMode: true, RespectsTemperature: true}
+}
+
 // SuggestCommits generates commit suggestions using Ollama.
 func (c *Client) SuggestCommits(ctx context.Context, input ports.SuggestInput) ([]ports.CommitSuggestion, error) {
+	suggestions, _, err := c.SuggestCommitsWithUsage(ctx, input)
+	return suggestions, err
+}
+
+// SuggestCommitsWithUsage is SuggestCommits plus the token counts Ollama reports.
+func (c *Client) SuggestCommitsWithUsage(ctx context.Context, input ports.SuggestInput) ([]ports.CommitSuggestion, *ports.Usage, error) {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+	if err := c.ensurePulled(ctx); err != nil {
+		return nil, nil, err
+	}
+
 	// Build prompt
-	prompt := buildCommitPrompt(input.StagedDiff)
+	prompt := buildCommitPrompt(input)
+	observability.DebugPrompt("ollama", prompt)
 
 	// Call Ollama API
+	// "format" constrains decoding to JSON; extractJSON below still handles
+	// older servers that ignore it and wrap the object in prose.
+	var format interface{} = "json"
+	if c.useSchema {
+		format = suggestionsSchema
+	}
 	reqBody := map[string]interface{}{
 		"model":  c.model,
 		"prompt": prompt,
 		"stream": false,
+		"format": format,
 		"options": map[string]interface{}{
 			"temperature": input.Temperature,
 		},
 	}
+	// Without a configured system prompt the model's own template applies.
+	if input.SystemPrompt != "" {
+		reqBody["system"] = prompts.System(input)
+	}
 
 	jsonBody, err := json.Marshal(reqBody)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+		return nil, nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/api/generate", bytes.NewReader(jsonBody))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 
+	stop := observability.TimeLLM("ollama", c.model)
 	resp, err := c.http.Do(req)
+	stop()
 	if err != nil {
-		return nil, fmt.Errorf("failed to call Ollama: %w", err)
+		return nil, nil, fmt.Errorf("failed to call Ollama: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("ollama returned status %d: %s", resp.StatusCode, string(body))
+		if resp.StatusCode == http.StatusNotFound && strings.Contains(string(body), "not found") {
+			return nil, nil, c.notPulledError()
+		}
+		return nil, nil, llmerr.FromResponse("ollama", resp.StatusCode, body)
 	}
 
 	// Parse response
 	var respData struct {
-		Response string `json:"response"`
+		Response        string `json:"response"`
+		PromptEvalCount int    `json:"prompt_eval_count"`
+		EvalCount       int    `json:"eval_count"`
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&respData); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
+		return nil, nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	usage := &ports.Usage{
+		PromptTokens:     respData.PromptEvalCount,
+		CompletionTokens: respData.EvalCount,
+		TotalTokens:      respData.PromptEvalCount + respData.EvalCount,
+	}
+
+	observability.DebugResponse("ollama", respData.Response)
+	if strings.TrimSpace(respData.Response) == "" {
+		return nil, usage, fmt.Errorf("ollama returned %w", ports.ErrEmptyOutput)
 	}
 
 	suggestions, err := parseSuggestionsJSON(respData.Response)
 	if err != nil {
-		return nil, err
+		return nil, usage, err
 	}
-	if len(suggestions) < 3 {
-		return nil, fmt.Errorf("expected 3 suggestions, got %d", len(suggestions))
+	if n := input.WantCount(); len(suggestions) < n {
+		return nil, usage, fmt.Errorf("expected %d suggestions, got %d", n, len(suggestions))
 	}
-	return suggestions[:3], nil
+	return suggestions[:input.WantCount()], usage, nil
 }
 
-// buildCommitPrompt creates a prompt for commit message generation.
-func buildCommitPrompt(diff string) string {
-	return fmt.Sprintf(`You are an expert at writing Conventional Commits.
+// ListModels returns the names of locally pulled models (GET /api/tags).
+func (c *Client) ListModels(ctx context.Context) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/api/tags", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call Ollama: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, llmerr.FromResponse("ollama", resp.StatusCode, body)
+	}
 
-Generate exactly 3 commit message suggestions for the following staged diff.
+	var tags struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return nil, fmt.Errorf("failed to parse model list: %w", err)
+	}
 
-<diff>
-%s
-</diff>
+	names := make([]string, 0, len(tags.Models))
+	for _, m := range tags.Models {
+		names = append(names, m.Name)
+	}
+	return names, nil
+}
 
-Return ONLY valid JSON (no markdown code blocks) with this shape:
-{
-  "suggestions": [
-    {"type": "feat|fix|docs|style|refactor|perf|test|chore|build|ci|revert", "subject": "...", "body": "...", "footer": "..."}
-  ]
+// ensurePulled checks once per client that the model is available locally.
+// If the model list cannot be fetched the check is skipped and the generate
+// call reports any problem itself.
+func (c *Client) ensurePulled(ctx context.Context) error {
+	if c.pulled {
+		return nil
+	}
+	names, err := c.ListModels(ctx)
+	if err != nil {
+		observability.Event(map[string]any{
+			"level":    "warn",
+			"provider": "ollama",
+			"message":  "model list unavailable, skipping pull check",
+			"error":    err,
+		})
+		return nil
+	}
+	for _, name := range names {
+		// "llama3" is shorthand for "llama3:latest".
+		if name == c.model || name == c.model+":latest" {
+			c.pulled = true
+			return nil
+		}
+	}
+	return c.notPulledError()
+}
+
+func (c *Client) notPulledError() error {
+	return fmt.Errorf("model %q not pulled; run: ollama pull %s", c.model, c.model)
 }
 
-Rules:
-- Exactly 3 suggestions
-- subject: max 72 characters, no newlines
-- body/footer optional
-`, diff)
+// buildCommitPrompt creates a prompt for commit message generation.
+func buildCommitPrompt(input ports.SuggestInput) string {
+	return prompts.Commit(input)
 }
 
 func parseSuggestionsJSON(content string) ([]ports.CommitSuggestion, error) {
@@ -125,15 +278,17 @@ func parseSuggestionsJSON(content string) ([]ports.CommitSuggestion, error) {
 
 	jsonContent := extractJSON(content)
 	if err := json.Unmarshal([]byte(jsonContent), &resp); err != nil {
-		observability.Logger().Printf(
-			"ollama: invalid JSON: %v; raw_len=%d raw_snip=%q; json_len=%d json_snip=%q",
-			err,
-			len(content),
-			observability.Snip(observability.RedactForLog(content), 600),
-			len(jsonContent),
-			observability.Snip(observability.RedactForLog(jsonContent), 600),
-		)
-		return nil, fmt.Errorf("invalid JSON: %w", err)
+		observability.Event(map[string]any{
+			"level":     "error",
+			"provider":  "ollama",
+			"message":   "invalid JSON",
+			"error":     err,
+			"raw_len":   len(content),
+			"raw_snip":  observability.Snip(observability.RedactForLog(content), 600),
+			"json_len":  len(jsonContent),
+			"json_snip": observability.Snip(observability.RedactForLog(jsonContent), 600),
+		})
+		return nil, fmt.Errorf("%w: %w", ports.ErrInvalidJSON, err)
 	}
 	if len(resp.Suggestions) == 0 {
 		return nil, errors.New("no suggestions in response")
@@ -146,6 +301,17 @@ func extractJSON(content string) string {
 	trimmed = strings.TrimPrefix(trimmed, "```json")
 	trimmed = strings.TrimPrefix(trimmed, "```")
 	trimmed = strings.TrimSuffix(trimmed, "```")
-	return strings.TrimSpace(trimmed)
+	trimmed = strings.TrimSpace(trimmed)
+
+	// Older servers ignore "format" and may wrap the object in prose;
+	// fall back to the outermost {...} span.
+	if !strings.HasPrefix(trimmed, "{") {
+		start := strings.Index(trimmed, "{")
+		end := strings.LastIndex(trimmed, "}")
+		if start >= 0 && end > start {
+			return trimmed[start : end+1]
+		}
+	}
+	return trimmed
 }
 
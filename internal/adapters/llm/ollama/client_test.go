@@ -0,0 +1,161 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chuckie/commit-coach/internal/ports"
+)
+
+const wellFormed = `{"suggestions":[{"type":"feat","subject":"add x"}]}`
+
+// fakeOllama serves /api/tags with the given models and answers /api/generate
+// with response, recording each generate request body.
+func fakeOllama(t *testing.T, response string, models ...string) (*httptest.Server, *[]map[string]interface{}) {
+	t.Helper()
+	var requests []map[string]interface{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/tags", func(w http.ResponseWriter, r *http.Request) {
+		var entries []string
+		for _, m := range models {
+			entries = append(entries, fmt.Sprintf(`{"name":%q}`, m))
+		}
+		fmt.Fprintf(w, `{"models":[%s]}`, strings.Join(entries, ","))
+	})
+	mux.HandleFunc("/api/generate", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		requests = append(requests, body)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"response":          response,
+			"prompt_eval_count": 10,
+			"eval_count":        5,
+		})
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func TestListModels(t *testing.T) {
+	srv, _ := fakeOllama(t, wellFormed, "llama3:latest", "qwen2.5-coder:7b")
+
+	names, err := NewClient(srv.URL, "llama3").ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("ListModels() error = %v", err)
+	}
+	if len(names) != 2 || names[0] != "llama3:latest" || names[1] != "qwen2.5-coder:7b" {
+		t.Errorf("ListModels() = %v", names)
+	}
+}
+
+func TestSuggestCommitsModelNotPulled(t *testing.T) {
+	srv, requests := fakeOllama(t, wellFormed, "llama3:latest")
+
+	_, err := NewClient(srv.URL, "qwen3-coder").SuggestCommits(context.Background(), ports.SuggestInput{StagedDiff: "diff", Count: 1})
+	if err == nil || !strings.Contains(err.Error(), `model "qwen3-coder" not pulled; run: ollama pull qwen3-coder`) {
+		t.Errorf("error = %v, want not-pulled hint", err)
+	}
+	if len(*requests) != 0 {
+		t.Errorf("generate called %d times, want 0", len(*requests))
+	}
+}
+
+func TestSuggestCommitsPulledModelWithImplicitTag(t *testing.T) {
+	srv, _ := fakeOllama(t, wellFormed, "llama3:latest")
+
+	suggestions, err := NewClient(srv.URL, "llama3").SuggestCommits(context.Background(), ports.SuggestInput{StagedDiff: "diff", Count: 1})
+	if err != nil {
+		t.Fatalf("SuggestCommits() error = %v", err)
+	}
+	if len(suggestions) != 1 || suggestions[0].Subject != "add x" {
+		t.Errorf("SuggestCommits() = %+v", suggestions)
+	}
+}
+
+func TestSuggestCommitsRequestsJSONFormat(t *testing.T) {
+	for name, response := range map[string]string{
+		"well-formed": wellFormed,
+		"chatty":      "Sure! Here are your suggestions:\n```json\n" + wellFormed + "\n```\nLet me know if you need more.",
+	} {
+		t.Run(name, func(t *testing.T) {
+			srv, requests := fakeOllama(t, response, "llama3:latest")
+
+			suggestions, err := NewClient(srv.URL, "llama3").SuggestCommits(context.Background(), ports.SuggestInput{StagedDiff: "diff", Count: 1})
+			if err != nil {
+				t.Fatalf("SuggestCommits() error = %v", err)
+			}
+			if len(suggestions) != 1 || suggestions[0].Subject != "add x" {
+				t.Errorf("SuggestCommits() = %+v", suggestions)
+			}
+			if got := (*requests)[0]["format"]; got != "json" {
+				t.Errorf("format = %v, want json", got)
+			}
+		})
+	}
+}
+
+func TestSuggestCommitsStructuredOutputSchema(t *testing.T) {
+	srv, requests := fakeOllama(t, wellFormed, "llama3:latest")
+	c := NewClient(srv.URL, "llama3")
+	c.SetStructuredOutput(true)
+
+	if _, err := c.SuggestCommits(context.Background(), ports.SuggestInput{StagedDiff: "diff", Count: 1}); err != nil {
+		t.Fatalf("SuggestCommits() error = %v", err)
+	}
+	schema, ok := (*requests)[0]["format"].(map[string]interface{})
+	if !ok || schema["type"] != "object" {
+		t.Errorf("format = %v, want JSON schema object", (*requests)[0]["format"])
+	}
+}
+
+func TestBuildCommitPromptIncludesExamples(t *testing.T) {
+	prompt := buildCommitPrompt(ports.SuggestInput{
+		StagedDiff: "diff --git a/x b/x",
+		Examples:   []string{"feat(parser): support nested lists"},
+	})
+	if !strings.Contains(prompt, "<example>\nfeat(parser): support nested lists\n</example>") {
+		t.Errorf("prompt missing example block:\n%s", prompt)
+	}
+	if strings.Index(prompt, "<example>") > strings.Index(prompt, "<diff>") {
+		t.Error("examples should come before the diff")
+	}
+
+	if prompt := buildCommitPrompt(ports.SuggestInput{StagedDiff: "diff"}); strings.Contains(prompt, "<example>") {
+		t.Errorf("prompt without examples has an example block:\n%s", prompt)
+	}
+}
+
+func TestSetTimeoutAppliesThroughContext(t *testing.T) {
+	release := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/tags", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"models":[{"name":"llama3"}]}`)
+	})
+	mux.HandleFunc("/api/generate", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	defer close(release)
+
+	c := NewClient(srv.URL, "llama3")
+	c.SetTimeout(50 * time.Millisecond)
+	start := time.Now()
+	_, err := c.SuggestCommits(context.Background(), ports.SuggestInput{StagedDiff: "diff", Count: 1})
+	if err == nil {
+		t.Fatal("SuggestCommits() succeeded, want a timeout")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("SuggestCommits() took %v, want it cut off by the timeout", elapsed)
+	}
+}
@@ -3,20 +3,34 @@ package openai
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"sort"
+	"strings"
 	"time"
 
 	openai "github.com/sashabaranov/go-openai"
 
+	"github.com/chuckie/commit-coach/internal/adapters/llm/llmerr"
+	"github.com/chuckie/commit-coach/internal/adapters/llm/prompts"
 	"github.com/chuckie/commit-coach/internal/observability"
 	"github.com/chuckie/commit-coach/internal/ports"
 )
 
+// DebugHTTPEnv logs the raw, redacted HTTP exchange with OpenAI to the error
+// log, which the go-openai SDK otherwise hides.
+const DebugHTTPEnv = "COMMIT_COACH_OPENAI_DEBUG"
+
 // Client implements ports.LLM for OpenAI API.
 type Client struct {
-	apiKey  string
-	baseURL string
+	name    string
 	timeout time.Duration
+	// http carries every request. sdk wraps it and is built once, so
+	// connections are pooled across calls; the timeout is applied through
+	// each call's context.
+	http *http.Client
+	sdk  *openai.Client
 }
 
 // NewClient creates a new OpenAI client.
@@ -27,25 +41,81 @@ func NewClient(apiKey, baseURL string) (*Client, error) {
 	if baseURL == "" {
 		baseURL = "https://api.openai.com/v1"
 	}
-	return &Client{
-		apiKey:  apiKey,
-		baseURL: baseURL,
-		timeout: 90 * time.Second,
-	}, nil
+	return newClient("openai", apiKey, baseURL), nil
+}
+
+// NewCompatibleClient creates a client for a self-hosted OpenAI-compatible
+// server such as llama.cpp's server or LM Studio. baseURL is required and
+// apiKey may be empty, since those servers usually run without auth.
+func NewCompatibleClient(apiKey, baseURL string) (*Client, error) {
+	baseURL = strings.TrimRight(strings.TrimSpace(baseURL), "/")
+	if baseURL == "" {
+		return nil, fmt.Errorf("openai-compatible provider needs a base URL")
+	}
+	return newClient("openai-compatible", apiKey, baseURL), nil
+}
+
+// newClient builds the client and its go-openai client for the key and
+// base URL; name labels errors, logs and metrics.
+func newClient(name, apiKey, baseURL string) *Client {
+	c := &Client{
+		name:    name,
+		timeout: ports.DefaultRequestTimeout,
+		http:    &http.Client{},
+	}
+	if observability.EnvEnabled(DebugHTTPEnv) {
+		c.http.Transport = &observability.LoggingTransport{Provider: name}
+	}
+	config := openai.DefaultConfig(apiKey)
+	config.BaseURL = baseURL
+	config.HTTPClient = c.http
+	c.sdk = openai.NewClientWithConfig(config)
+	return c
+}
+
+// SetTimeout bounds each request; d <= 0 keeps ports.DefaultRequestTimeout.
+func (c *Client) SetTimeout(d time.Duration) {
+	if d > 0 {
+		c.timeout = d
+	}
+}
+
+// SetTransport sends requests through rt (see llmhttp.NewTransport); nil
+// means http.DefaultTransport.
+func (c *Client) SetTransport(rt http.RoundTripper) {
+	if logging, ok := c.http.Transport.(*observability.LoggingTransport); ok {
+		logging.Next = rt
+		return
+	}
+	c.http.Transport = rt
+}
+
+// Close implements io.Closer by dropping idle keep-alive connections. The
+// client still works afterwards, dialing again as needed.
+func (c *Client) Close() error {
+	c.http.CloseIdleConnections()
+	return nil
+}
+
+// Capabilities implements ports.CapableLLM. Reasoning models only accept
+// the default temperature.
+func (c *Client) Capabilities(model string) ports.Capabilities {
+	return ports.Capabilities{JSONMode: true, RespectsTemperature: !isReasoningModel(model)}
 }
 
 // SuggestCommits generates 3 commit suggestions using OpenAI.
 func (c *Client) SuggestCommits(ctx context.Context, input ports.SuggestInput) ([]ports.CommitSuggestion, error) {
-	// Create OpenAI client configuration
-	config := openai.DefaultConfig(c.apiKey)
-	if c.baseURL != "" {
-		config.BaseURL = c.baseURL
-	}
+	suggestions, _, err := c.SuggestCommitsWithUsage(ctx, input)
+	return suggestions, err
+}
 
-	client := openai.NewClientWithConfig(config)
+// SuggestCommitsWithUsage is SuggestCommits plus the token usage OpenAI reports.
+func (c *Client) SuggestCommitsWithUsage(ctx context.Context, input ports.SuggestInput) ([]ports.CommitSuggestion, *ports.Usage, error) {
+	client := c.sdk
 
 	// Build the prompt
 	prompt := c.buildPrompt(input)
+	observability.DebugPrompt(c.name, prompt)
 
 	// Create completion request
 	req := openai.ChatCompletionRequest{
@@ -57,56 +127,158 @@ func (c *Client) SuggestCommits(ctx context.Context, input ports.SuggestInput) (
 				Content: prompt,
 			},
 		},
+		// JSON mode avoids markdown-wrapped replies; the prompt mentions JSON
+		// as the API requires.
+		ResponseFormat: &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatTypeJSONObject,
+		},
+	}
+	// The user prompt already carries the JSON rules, so a system message is
+	// only sent when one is configured.
+	if input.SystemPrompt != "" {
+		req.Messages = append([]openai.ChatCompletionMessage{{
+			Role:    openai.ChatMessageRoleSystem,
+			Content: prompts.System(input),
+		}}, req.Messages...)
+	}
+	// Reasoning models only accept the default temperature. A zero value is
+	// dropped from the request by the field's omitempty tag. We never send
+	// max_tokens, so the max_completion_tokens rename does not affect us.
+	if isReasoningModel(input.Model) {
+		req.Temperature = 0
 	}
 
 	// Make request with timeout
 	ctx, cancel := context.WithTimeout(ctx, c.timeout)
 	defer cancel()
 
-	resp, err := client.CreateChatCompletion(ctx, req)
+	resp, err := c.createTimed(ctx, client, req)
+	if err != nil && rejectsParam(err, "response_format") {
+		// Some proxies/compatible base URLs do not support JSON mode; the
+		// prompt still asks for JSON and extractJSON copes with wrapping.
+		observability.Event(map[string]any{
+			"level":    "info",
+			"provider": c.name,
+			"message":  "endpoint rejected response_format; retrying without it",
+		})
+		req.ResponseFormat = nil
+		resp, err = c.createTimed(ctx, client, req)
+	}
+	if err != nil && req.Temperature != 0 && rejectsParam(err, "temperature") {
+		// Unknown reasoning-style model: retry once with the default temperature.
+		observability.Event(map[string]any{
+			"level":    "info",
+			"provider": c.name,
+			"message":  "model rejected temperature; retrying without it",
+			"model":    input.Model,
+		})
+		req.Temperature = 0
+		resp, err = c.createTimed(ctx, client, req)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("OpenAI API error: %w", err)
+		return nil, nil, c.providerError(err)
+	}
+
+	usage := &ports.Usage{
+		PromptTokens:     resp.Usage.PromptTokens,
+		CompletionTokens: resp.Usage.CompletionTokens,
+		TotalTokens:      resp.Usage.TotalTokens,
 	}
 
 	if len(resp.Choices) == 0 {
-		return nil, fmt.Errorf("no choices returned from OpenAI")
+		return nil, usage, fmt.Errorf("no choices returned from OpenAI")
 	}
 
 	// Parse response
 	content := resp.Choices[0].Message.Content
-	suggestions, err := c.parseResponse(content)
+	observability.DebugResponse(c.name, content)
+	if strings.TrimSpace(content) == "" {
+		return nil, usage, fmt.Errorf("OpenAI returned %w", ports.ErrEmptyOutput)
+	}
+	suggestions, err := c.parseResponse(content, input.WantCount())
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse OpenAI response: %w", err)
+		return nil, usage, fmt.Errorf("failed to parse OpenAI response: %w", err)
 	}
 
-	return suggestions, nil
+	return suggestions, usage, nil
 }
 
-// buildPrompt constructs the prompt for OpenAI.
-func (c *Client) buildPrompt(input ports.SuggestInput) string {
-	return `You are an expert at writing Conventional Commits. Generate exactly 3 commit message suggestions for the following staged changes.
+// isReasoningModel reports whether model is an OpenAI reasoning model
+// (o1/o3/o4 families, gpt-5*) that rejects non-default sampling params.
+func isReasoningModel(model string) bool {
+	m := strings.ToLower(model)
+	if strings.HasPrefix(m, "gpt-5") {
+		return true
+	}
+	for _, family := range []string{"o1", "o3", "o4"} {
+		if m == family || strings.HasPrefix(m, family+"-") {
+			return true
+		}
+	}
+	return false
+}
 
-Staged diff:
-` + input.StagedDiff + `
+// ListModels implements ports.ModelLister (GET /models).
+func (c *Client) ListModels(ctx context.Context) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
 
-Return ONLY a valid JSON array with exactly 3 objects, each with these fields (no extra fields):
-{
-  "suggestions": [
-    {"type": "feat|fix|docs|style|refactor|perf|test|chore", "subject": "...", "body": "...", "footer": "..."}
-  ]
+	list, err := c.sdk.ListModels(ctx)
+	if err != nil {
+		return nil, c.providerError(err)
+	}
+	names := make([]string, 0, len(list.Models))
+	for _, m := range list.Models {
+		names = append(names, m.ID)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// createTimed is CreateChatCompletion with latency logging.
+func (c *Client) createTimed(ctx context.Context, client *openai.Client, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	stop := observability.TimeLLM(c.name, req.Model)
+	defer stop()
+	return client.CreateChatCompletion(ctx, req)
+}
+
+// providerError converts go-openai HTTP errors to *ports.ProviderError;
+// transport errors are wrapped as before.
+func (c *Client) providerError(err error) error {
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) && apiErr.HTTPStatusCode != 0 {
+		return llmerr.New(c.name, apiErr.HTTPStatusCode, apiErr.Message)
+	}
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) && reqErr.HTTPStatusCode != 0 {
+		msg := ""
+		if reqErr.Err != nil {
+			msg = reqErr.Err.Error()
+		}
+		return llmerr.New(c.name, reqErr.HTTPStatusCode, msg)
+	}
+	return fmt.Errorf("OpenAI API error: %w", err)
 }
 
-Rules:
-- Subject: max 72 characters, no newlines
-- Body: optional multiline explanation
-- Footer: optional, use "BREAKING CHANGE: ..." or "Closes #123"
-- Keep messages clear and specific to the staged changes
+// rejectsParam reports whether err is a 400 complaining about request param.
+func rejectsParam(err error, param string) bool {
+	var apiErr *openai.APIError
+	if !errors.As(err, &apiErr) || apiErr.HTTPStatusCode != http.StatusBadRequest {
+		return false
+	}
+	if apiErr.Param != nil && *apiErr.Param == param {
+		return true
+	}
+	return strings.Contains(strings.ToLower(apiErr.Message), param)
+}
 
-Return ONLY JSON, no markdown code blocks.`
+// buildPrompt constructs the prompt for OpenAI.
+func (c *Client) buildPrompt(input ports.SuggestInput) string {
+	return prompts.Commit(input)
 }
 
 // parseResponse extracts suggestions from the JSON response.
-func (c *Client) parseResponse(content string) ([]ports.CommitSuggestion, error) {
+func (c *Client) parseResponse(content string, count int) ([]ports.CommitSuggestion, error) {
 	// Try to parse as direct JSON first
 	var resp struct {
 		Suggestions []ports.CommitSuggestion `json:"suggestions"`
@@ -116,22 +288,24 @@ func (c *Client) parseResponse(content string) ([]ports.CommitSuggestion, error)
 	jsonContent := c.extractJSON(content)
 
 	if err := json.Unmarshal([]byte(jsonContent), &resp); err != nil {
-		observability.Logger().Printf(
-			"openai: invalid JSON: %v; raw_len=%d raw_snip=%q; json_len=%d json_snip=%q",
-			err,
-			len(content),
-			observability.Snip(observability.RedactForLog(content), 600),
-			len(jsonContent),
-			observability.Snip(observability.RedactForLog(jsonContent), 600),
-		)
-		return nil, fmt.Errorf("invalid JSON format: %w", err)
+		observability.Event(map[string]any{
+			"level":     "error",
+			"provider":  c.name,
+			"message":   "invalid JSON",
+			"error":     err,
+			"raw_len":   len(content),
+			"raw_snip":  observability.Snip(observability.RedactForLog(content), 600),
+			"json_len":  len(jsonContent),
+			"json_snip": observability.Snip(observability.RedactForLog(jsonContent), 600),
+		})
+		return nil, fmt.Errorf("%w: %w", ports.ErrInvalidJSON, err)
 	}
 
-	if len(resp.Suggestions) != 3 {
-		return nil, fmt.Errorf("expected 3 suggestions, got %d", len(resp.Suggestions))
+	if len(resp.Suggestions) < count {
+		return nil, fmt.Errorf("expected %d suggestions, got %d", count, len(resp.Suggestions))
 	}
 
-	return resp.Suggestions, nil
+	return resp.Suggestions[:count], nil
 }
 
 // extractJSON extracts JSON from response (handles markdown code blocks).
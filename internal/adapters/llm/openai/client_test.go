@@ -0,0 +1,289 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/chuckie/commit-coach/internal/observability"
+	"github.com/chuckie/commit-coach/internal/ports"
+)
+
+const suggestionsJSON = `{"suggestions":[{"type":"feat","subject":"add x"}]}`
+
+// stubServer answers /chat/completions with content and records request bodies.
+func stubServer(t *testing.T, content string) (*httptest.Server, *[]map[string]interface{}) {
+	t.Helper()
+	var requests []map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		requests = append(requests, body)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{
+				{"message": map[string]string{"role": "assistant", "content": content}},
+			},
+			"usage": map[string]int{"prompt_tokens": 10, "completion_tokens": 5, "total_tokens": 15},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func TestTemperatureOmittedForReasoningModels(t *testing.T) {
+	for model, wantTemp := range map[string]bool{
+		"gpt-4o-mini": true,
+		"o1":          false,
+		"o3-mini":     false,
+		"gpt-5-nano":  false,
+	} {
+		t.Run(model, func(t *testing.T) {
+			srv, requests := stubServer(t, suggestionsJSON)
+			c, _ := NewClient("sk-test", srv.URL)
+
+			_, err := c.SuggestCommits(context.Background(), ports.SuggestInput{StagedDiff: "diff", Model: model, Temperature: 0.7, Count: 1})
+			if err != nil {
+				t.Fatalf("SuggestCommits() error = %v", err)
+			}
+			_, hasTemp := (*requests)[0]["temperature"]
+			if hasTemp != wantTemp {
+				t.Errorf("temperature sent = %v, want %v (body %v)", hasTemp, wantTemp, (*requests)[0])
+			}
+		})
+	}
+}
+
+func TestRetryWithoutTemperatureWhenRejected(t *testing.T) {
+	var temps []interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		temps = append(temps, body["temperature"])
+		w.Header().Set("Content-Type", "application/json")
+		if _, ok := body["temperature"]; ok {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":{"message":"Unsupported value: 'temperature' does not support 0.7 with this model.","type":"invalid_request_error","param":"temperature"}}`)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{
+				{"message": map[string]string{"role": "assistant", "content": suggestionsJSON}},
+			},
+		})
+	}))
+	defer srv.Close()
+
+	c, _ := NewClient("sk-test", srv.URL)
+	if _, err := c.SuggestCommits(context.Background(), ports.SuggestInput{StagedDiff: "diff", Model: "future-reasoner", Temperature: 0.7, Count: 1}); err != nil {
+		t.Fatalf("SuggestCommits() error = %v", err)
+	}
+	if len(temps) != 2 || temps[1] != nil {
+		t.Errorf("temperatures sent = %v, want a retry without temperature", temps)
+	}
+}
+
+func TestRequestsJSONObjectResponseFormat(t *testing.T) {
+	srv, requests := stubServer(t, suggestionsJSON)
+	c, _ := NewClient("sk-test", srv.URL)
+
+	if _, err := c.SuggestCommits(context.Background(), ports.SuggestInput{StagedDiff: "diff", Model: "gpt-4o-mini", Count: 1}); err != nil {
+		t.Fatalf("SuggestCommits() error = %v", err)
+	}
+	format, _ := (*requests)[0]["response_format"].(map[string]interface{})
+	if format["type"] != "json_object" {
+		t.Errorf("response_format = %v, want json_object", (*requests)[0]["response_format"])
+	}
+}
+
+func TestRetryWithoutResponseFormatWhenRejected(t *testing.T) {
+	var formats []interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		formats = append(formats, body["response_format"])
+		w.Header().Set("Content-Type", "application/json")
+		if _, ok := body["response_format"]; ok {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":{"message":"Unrecognized request argument supplied: response_format","type":"invalid_request_error"}}`)
+			return
+		}
+		// Without JSON mode the proxy's model wraps the object in markdown.
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{
+				{"message": map[string]string{"role": "assistant", "content": "```json\n" + suggestionsJSON + "\n```"}},
+			},
+		})
+	}))
+	defer srv.Close()
+
+	c, _ := NewClient("sk-test", srv.URL)
+	suggestions, err := c.SuggestCommits(context.Background(), ports.SuggestInput{StagedDiff: "diff", Model: "gpt-4o-mini", Count: 1})
+	if err != nil {
+		t.Fatalf("SuggestCommits() error = %v", err)
+	}
+	if len(suggestions) != 1 || suggestions[0].Subject != "add x" {
+		t.Errorf("SuggestCommits() = %+v", suggestions)
+	}
+	if len(formats) != 2 || formats[1] != nil {
+		t.Errorf("response_format sent = %v, want a retry without it", formats)
+	}
+}
+
+func TestAPIErrorBecomesProviderError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"error":{"message":"Incorrect API key provided: sk-abcdefghijklmnopqrstuvwx","type":"invalid_request_error","code":"invalid_api_key"}}`)
+	}))
+	defer srv.Close()
+
+	c, _ := NewClient("sk-test", srv.URL)
+	_, err := c.SuggestCommits(context.Background(), ports.SuggestInput{StagedDiff: "diff", Model: "gpt-4o-mini", Count: 1})
+	var pe *ports.ProviderError
+	if !errors.As(err, &pe) {
+		t.Fatalf("error = %v, want *ports.ProviderError", err)
+	}
+	if pe.Provider != "openai" || pe.StatusCode != http.StatusUnauthorized {
+		t.Errorf("got provider %q status %d", pe.Provider, pe.StatusCode)
+	}
+	if !strings.HasPrefix(pe.Message, "Incorrect API key provided") || strings.Contains(pe.Message, "abcdefghijklmnop") {
+		t.Errorf("Message = %q, want the redacted envelope message", pe.Message)
+	}
+}
+
+func TestVerboseLogsRedactedPromptAndResponse(t *testing.T) {
+	var buf bytes.Buffer
+	observability.SetDebugOutput(&buf)
+	defer observability.SetDebugOutput(nil)
+
+	srv, _ := stubServer(t, suggestionsJSON)
+	c, _ := NewClient("sk-test", srv.URL)
+	diff := "+token = \"sk-abcdefghijklmnopqrstuvwxyz123456\"\n"
+	if _, err := c.SuggestCommits(context.Background(), ports.SuggestInput{StagedDiff: diff, Model: "gpt-4o-mini", Count: 1}); err != nil {
+		t.Fatalf("SuggestCommits() error = %v", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{"[debug] openai prompt:", "[REDACTED]", "[debug] openai response: " + suggestionsJSON} {
+		if !strings.Contains(out, want) {
+			t.Errorf("debug output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "abcdefghijklmnop") {
+		t.Errorf("debug output leaked a key:\n%s", out)
+	}
+}
+
+func TestDebugHTTPEnvLogsExchange(t *testing.T) {
+	t.Setenv(DebugHTTPEnv, "1")
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	srv, _ := stubServer(t, suggestionsJSON)
+	c, _ := NewClient("sk-test", srv.URL)
+	diff := "+token = \"sk-abcdefghijklmnopqrstuvwxyz123456\"\n"
+	if _, err := c.SuggestCommits(context.Background(), ports.SuggestInput{StagedDiff: diff, Model: "gpt-4o-mini", Count: 1}); err != nil {
+		t.Fatalf("SuggestCommits() error = %v", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{"openai http request: POST " + srv.URL + "/chat/completions", "openai http response: status=200", "add x"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "abcdefghijklmnop") {
+		t.Errorf("log leaked a key:\n%s", out)
+	}
+}
+
+func TestListModels(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"object":"list","data":[{"id":"gpt-4o-mini","object":"model"},{"id":"gpt-4.1","object":"model"}]}`))
+	}))
+	defer srv.Close()
+	c, _ := NewClient("sk-test", srv.URL)
+
+	models, err := c.ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("ListModels() error = %v", err)
+	}
+	if strings.Join(models, ",") != "gpt-4.1,gpt-4o-mini" {
+		t.Errorf("ListModels() = %q, want both ids sorted", models)
+	}
+}
+
+func TestCompatibleClientWithoutKey(t *testing.T) {
+	var path, auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, auth = r.URL.Path, r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{
+				{"message": map[string]string{"role": "assistant", "content": suggestionsJSON}},
+			},
+		})
+	}))
+	defer srv.Close()
+
+	if _, err := NewCompatibleClient("", ""); err == nil {
+		t.Error("NewCompatibleClient() without a base URL should fail")
+	}
+	c, err := NewCompatibleClient("", srv.URL+"/v1/")
+	if err != nil {
+		t.Fatalf("NewCompatibleClient() error = %v", err)
+	}
+	got, err := c.SuggestCommits(context.Background(), ports.SuggestInput{StagedDiff: "+x\n", Model: "local-model", Count: 1})
+	if err != nil || len(got) != 1 || got[0].Subject != "add x" {
+		t.Fatalf("SuggestCommits() = %v, %v", got, err)
+	}
+	if path != "/v1/chat/completions" {
+		t.Errorf("request path = %q, want /v1/chat/completions", path)
+	}
+	if strings.TrimSpace(strings.TrimPrefix(auth, "Bearer")) != "" {
+		t.Errorf("Authorization = %q, want no key", auth)
+	}
+}
+
+func TestRepeatedCallsReuseConnections(t *testing.T) {
+	srv, _ := stubServer(t, suggestionsJSON)
+	var dials atomic.Int32
+	dialer := &net.Dialer{}
+	transport := &http.Transport{DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dials.Add(1)
+		return dialer.DialContext(ctx, network, addr)
+	}}
+	t.Cleanup(transport.CloseIdleConnections)
+
+	c, _ := NewClient("sk-test", srv.URL)
+	c.SetTransport(transport)
+	sdk := c.sdk
+	for i := 0; i < 3; i++ {
+		if _, err := c.SuggestCommits(context.Background(), ports.SuggestInput{StagedDiff: "+x\n", Model: "gpt-4o-mini", Count: 1}); err != nil {
+			t.Fatalf("call %d: SuggestCommits() error = %v", i+1, err)
+		}
+	}
+	if c.sdk != sdk {
+		t.Error("the go-openai client was rebuilt between calls")
+	}
+	if n := dials.Load(); n != 1 {
+		t.Errorf("dialed %d times for repeated calls, want 1 pooled connection", n)
+	}
+}
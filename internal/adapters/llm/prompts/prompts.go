@@ -0,0 +1,113 @@
+// Package prompts holds prompt fragments shared by the LLM providers.
+package prompts
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/chuckie/commit-coach/internal/domain"
+	"github.com/chuckie/commit-coach/internal/ports"
+)
+
+// FewShotBlock renders example commit messages as an in-context block to
+// place before the diff. It returns "" when there are no examples.
+func FewShotBlock(examples []string) string {
+	var b strings.Builder
+	for _, ex := range examples {
+		ex = strings.TrimSpace(ex)
+		if ex == "" {
+			continue
+		}
+		b.WriteString("<example>\n" + ex + "\n</example>\n")
+	}
+	if b.Len() == 0 {
+		return ""
+	}
+	return "Match the style of these example commit messages from this project:\n\n" + b.String() + "\n"
+}
+
+// DefaultSystemPrompt is the system message used unless
+// SuggestInput.SystemPrompt is set.
+const DefaultSystemPrompt = "You are an expert git commit message writer."
+
+// jsonOnlyInstruction follows every system prompt, custom ones included, so
+// the output contract holds whatever the persona says.
+const jsonOnlyInstruction = "Return ONLY valid JSON matching the requested schema. No markdown, no extra text."
+
+// System is the system message for input: SuggestInput.SystemPrompt (or
+// DefaultSystemPrompt) followed by the JSON-only instruction.
+func System(input ports.SuggestInput) string {
+	persona := strings.TrimSpace(input.SystemPrompt)
+	if persona == "" {
+		persona = DefaultSystemPrompt
+	}
+	return persona + " " + jsonOnlyInstruction
+}
+
+// SubjectMaxLen is the subject limit to put in the prompt for input.
+func SubjectMaxLen(input ports.SuggestInput) int {
+	if input.SubjectMaxLen > 0 {
+		return input.SubjectMaxLen
+	}
+	return domain.DefaultSubjectMaxLen
+}
+
+// BranchBlock mentions the branch the work is on, as context to place
+// before the diff. Detached HEAD and long-lived branches (main, master,
+// develop) say nothing about the change, so they render as "".
+func BranchBlock(branch string) string {
+	switch branch {
+	case "", "HEAD", "main", "master", "develop":
+		return ""
+	}
+	return "The work is on branch " + branch + ".\n\n"
+}
+
+// TypeBlock asks for commitType on every suggestion, as context to place
+// before the diff. It returns "" when the model may choose.
+func TypeBlock(commitType string) string {
+	if commitType == "" {
+		return ""
+	}
+	return "Every suggestion must use the commit type \"" + commitType + "\"; write the subject and body for that type.\n\n"
+}
+
+// NotesBlock renders SuggestInput.Notes as a list to place before the diff.
+// It returns "" when there are none.
+func NotesBlock(notes []string) string {
+	if len(notes) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("Notes about this change:\n")
+	for _, n := range notes {
+		b.WriteString("- " + n + "\n")
+	}
+	return b.String() + "\n"
+}
+
+// BodyOnly is the prompt for rewriting just the body and footer of the
+// header in input.LockedType and input.LockedSubject. The reply uses the
+// same {"suggestions":[...]} shape as a normal request, with one entry.
+func BodyOnly(input ports.SuggestInput) string {
+	shape, _ := json.Marshal(map[string][]map[string]string{
+		"suggestions": {{"type": input.LockedType, "subject": input.LockedSubject, "body": "...", "footer": "..."}},
+	})
+	return fmt.Sprintf(`Write the body and footer for this Conventional Commit header, based on the staged diff.
+
+Header: %s: %s
+
+%s<diff>
+%s
+</diff>
+
+Return ONLY a single JSON object with this exact shape:
+%s
+
+Rules:
+- Exactly 1 suggestion; keep type and subject exactly as given
+- body: explain what changed and why, wrapped at 72 characters
+- footer: "BREAKING CHANGE: ..." or issue references, or an empty string
+`, input.LockedType, input.LockedSubject, FewShotBlock(input.Examples)+BranchBlock(input.Branch)+NotesBlock(input.Notes), input.StagedDiff, shape)
+}
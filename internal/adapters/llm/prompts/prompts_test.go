@@ -0,0 +1,65 @@
+package prompts
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/chuckie/commit-coach/internal/ports"
+)
+
+func TestBodyOnly(t *testing.T) {
+	prompt := BodyOnly(ports.SuggestInput{
+		StagedDiff:    "+func Parse() {}",
+		LockedType:    "feat",
+		LockedSubject: `add "strict" parser mode`,
+	})
+
+	for _, want := range []string{
+		`Header: feat: add "strict" parser mode`,
+		"<diff>\n+func Parse() {}\n</diff>",
+		`"subject":"add \"strict\" parser mode"`,
+	} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q:\n%s", want, prompt)
+		}
+	}
+}
+
+func TestSubjectMaxLen(t *testing.T) {
+	if got := SubjectMaxLen(ports.SuggestInput{}); got != 72 {
+		t.Errorf("SubjectMaxLen(zero) = %d, want 72", got)
+	}
+	if got := SubjectMaxLen(ports.SuggestInput{SubjectMaxLen: 50}); got != 50 {
+		t.Errorf("SubjectMaxLen(50) = %d, want 50", got)
+	}
+}
+
+func TestSystem(t *testing.T) {
+	if got, want := System(ports.SuggestInput{}), "You are an expert git commit message writer. Return ONLY valid JSON matching the requested schema. No markdown, no extra text."; got != want {
+		t.Errorf("System() = %q, want %q", got, want)
+	}
+	got := System(ports.SuggestInput{SystemPrompt: "  Be terse.\n"})
+	if !strings.HasPrefix(got, "Be terse. ") || !strings.HasSuffix(got, jsonOnlyInstruction) {
+		t.Errorf("System(custom) = %q, want the custom prompt then the JSON instruction", got)
+	}
+}
+
+func TestBranchBlock(t *testing.T) {
+	for _, branch := range []string{"", "HEAD", "main", "master", "develop"} {
+		if got := BranchBlock(branch); got != "" {
+			t.Errorf("BranchBlock(%q) = %q, want empty", branch, got)
+		}
+	}
+	if got, want := BranchBlock("feature/JIRA-1234-thing"), "The work is on branch feature/JIRA-1234-thing.\n\n"; got != want {
+		t.Errorf("BranchBlock() = %q, want %q", got, want)
+	}
+}
+
+func TestNotesBlock(t *testing.T) {
+	if got := NotesBlock(nil); got != "" {
+		t.Errorf("NotesBlock(nil) = %q, want empty", got)
+	}
+	if got, want := NotesBlock([]string{"only modes changed"}), "Notes about this change:\n- only modes changed\n\n"; got != want {
+		t.Errorf("NotesBlock() = %q, want %q", got, want)
+	}
+}
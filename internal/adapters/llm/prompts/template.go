@@ -0,0 +1,92 @@
+package prompts
+
+import (
+	"strings"
+	"text/template"
+
+	"github.com/chuckie/commit-coach/internal/domain"
+	"github.com/chuckie/commit-coach/internal/observability"
+	"github.com/chuckie/commit-coach/internal/ports"
+)
+
+// DefaultTemplate is the commit prompt used unless
+// SuggestInput.PromptTemplate is set. Templates are rendered with
+// TemplateData and may call join (strings.Join).
+const DefaultTemplate = `Generate exactly {{.Count}} Conventional Commit suggestions for this staged diff.
+
+{{.Context}}<diff>
+{{.Diff}}
+</diff>
+
+Return ONLY a single JSON object with this exact shape:
+{"suggestions":[{"type":"{{join .Types "|"}}","subject":"...","body":"...","footer":"..."}]}
+
+Rules:
+- Exactly {{.Count}} suggestions
+- subject: max {{.SubjectMaxLen}} characters, no newlines
+- body/footer may be empty strings
+`
+
+// TemplateData is what a prompt template is rendered with.
+type TemplateData = ports.PromptTemplateData
+
+var defaultTemplate = template.Must(ports.ParsePromptTemplate(DefaultTemplate))
+
+// NewTemplateData collects the template fields for input.
+func NewTemplateData(input ports.SuggestInput) TemplateData {
+	var subjects []string
+	for _, ex := range input.Examples {
+		if subject, _, _ := strings.Cut(strings.TrimSpace(ex), "\n"); subject != "" {
+			subjects = append(subjects, subject)
+		}
+	}
+	types := domain.ValidCommitTypes
+	if input.Type != "" {
+		types = []string{input.Type}
+	}
+	return TemplateData{
+		Diff:            input.StagedDiff,
+		FileList:        input.FileList,
+		RecentSubjects:  input.RecentSubjects,
+		ExampleSubjects: subjects,
+		Branch:          input.Branch,
+		Count:           input.WantCount(),
+		Types:           types,
+		SubjectMaxLen:   SubjectMaxLen(input),
+		Context:         FewShotBlock(input.Examples) + BranchBlock(input.Branch) + TypeBlock(input.Type) + NotesBlock(input.Notes),
+	}
+}
+
+// Render parses text as a prompt template and executes it with data.
+func Render(text string, data TemplateData) (string, error) {
+	tmpl, err := ports.ParsePromptTemplate(text)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// Commit builds the suggestion prompt every provider sends: BodyOnly when
+// a header is locked, otherwise SuggestInput.PromptTemplate (or
+// DefaultTemplate). A custom template that fails to render is logged and
+// the default used instead.
+func Commit(input ports.SuggestInput) string {
+	if input.LockedSubject != "" {
+		return BodyOnly(input)
+	}
+	data := NewTemplateData(input)
+	if input.PromptTemplate != "" {
+		out, err := Render(input.PromptTemplate, data)
+		if err == nil {
+			return out
+		}
+		observability.Logf(observability.LevelWarn, "prompt template: %v; using the default", err)
+	}
+	var b strings.Builder
+	_ = defaultTemplate.Execute(&b, data)
+	return b.String()
+}
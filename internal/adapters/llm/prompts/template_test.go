@@ -0,0 +1,79 @@
+package prompts
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/chuckie/commit-coach/internal/ports"
+)
+
+func TestCommitDefaultTemplate(t *testing.T) {
+	prompt := Commit(ports.SuggestInput{
+		StagedDiff:    "+func Parse() {}",
+		Count:         2,
+		SubjectMaxLen: 50,
+		Branch:        "feature/parser",
+		Notes:         []string{"only modes changed"},
+	})
+	for _, want := range []string{
+		"Generate exactly 2 Conventional Commit suggestions",
+		"The work is on branch feature/parser.\n\nNotes about this change:\n- only modes changed\n\n<diff>\n+func Parse() {}\n</diff>",
+		`"type":"feat|fix|docs|style|refactor|perf|test|chore|build|ci|revert"`,
+		"- Exactly 2 suggestions",
+		"- subject: max 50 characters",
+	} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q:\n%s", want, prompt)
+		}
+	}
+
+	locked := ports.SuggestInput{StagedDiff: "+x", LockedType: "feat", LockedSubject: "add x"}
+	if got := Commit(locked); got != BodyOnly(locked) {
+		t.Errorf("Commit() with a locked header = %q, want BodyOnly", got)
+	}
+}
+
+func TestCommitRequiredType(t *testing.T) {
+	got := Commit(ports.SuggestInput{StagedDiff: "+x", Count: 1, Type: "fix"})
+	for _, want := range []string{`"type":"fix"`, `must use the commit type "fix"`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Commit() missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "feat|") {
+		t.Errorf("Commit() still offers every type:\n%s", got)
+	}
+}
+
+func TestCommitCustomTemplate(t *testing.T) {
+	input := ports.SuggestInput{
+		StagedDiff:     "+x",
+		FileList:       []string{"a.go", "b.go"},
+		Examples:       []string{"feat(parser): support nested lists\n\nBody.", "fix: handle nil"},
+		RecentSubjects: []string{"chore: bump deps", "docs: fix typo"},
+		Branch:         "feature/x",
+		Count:          1,
+		PromptTemplate: `{{.Count}} JSON for {{join .FileList ","}} on {{.Branch}}; like {{join .ExampleSubjects " / "}}; after {{join .RecentSubjects " / "}}; types {{len .Types}}: {{.Diff}}`,
+	}
+	want := "1 JSON for a.go,b.go on feature/x; like feat(parser): support nested lists / fix: handle nil; after chore: bump deps / docs: fix typo; types 11: +x"
+	if got := Commit(input); got != want {
+		t.Errorf("Commit() = %q, want %q", got, want)
+	}
+
+	// A template that cannot render falls back to the default.
+	input.PromptTemplate = "{{.Missing}}"
+	if got := Commit(input); !strings.Contains(got, "Generate exactly 1 Conventional Commit suggestions") {
+		t.Errorf("Commit() with a broken template = %q, want the default prompt", got)
+	}
+}
+
+func TestValidateTemplate(t *testing.T) {
+	if err := ports.ValidatePromptTemplate(DefaultTemplate); err != nil {
+		t.Errorf("ValidatePromptTemplate(DefaultTemplate) = %v", err)
+	}
+	for _, bad := range []string{"{{.Diff", "{{.Missing}}", "{{nope .Diff}}"} {
+		if err := ports.ValidatePromptTemplate(bad); err == nil {
+			t.Errorf("ValidatePromptTemplate(%q) should fail", bad)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package notes
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Failures implements ports.FailureNotes with a small JSON file keyed by
+// provider and model. Read errors are treated as "no note"; the notes are
+// only hints, so a missing or corrupt file must never block a run.
+type Failures struct {
+	mu   sync.Mutex
+	path string
+}
+
+type failureNote struct {
+	Kind string    `json:"kind"`
+	At   time.Time `json:"at"`
+}
+
+// NewFailures returns a store backed by the file at path. The file is created
+// on the first recorded failure.
+func NewFailures(path string) *Failures {
+	return &Failures{path: path}
+}
+
+// LastFailure returns the kind recorded for provider/model, or "".
+func (f *Failures) LastFailure(provider, model string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.load()[noteKey(provider, model)].Kind
+}
+
+// RecordFailure stores kind for provider/model; an empty kind removes it.
+func (f *Failures) RecordFailure(provider, model, kind string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	notes := f.load()
+	key := noteKey(provider, model)
+	if kind == "" {
+		if _, ok := notes[key]; !ok {
+			return nil
+		}
+		delete(notes, key)
+	} else {
+		notes[key] = failureNote{Kind: kind, At: time.Now().UTC()}
+	}
+
+	b, err := json.MarshalIndent(notes, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode failure notes: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+		return fmt.Errorf("create state dir: %w", err)
+	}
+	if err := os.WriteFile(f.path, append(b, '\n'), 0o600); err != nil {
+		return fmt.Errorf("write failure notes: %w", err)
+	}
+	return nil
+}
+
+func (f *Failures) load() map[string]failureNote {
+	notes := make(map[string]failureNote)
+	b, err := os.ReadFile(f.path)
+	if err != nil {
+		return notes
+	}
+	if err := json.Unmarshal(b, &notes); err != nil {
+		return make(map[string]failureNote)
+	}
+	return notes
+}
+
+func noteKey(provider, model string) string {
+	return provider + "/" + model
+}
@@ -0,0 +1,52 @@
+package notes
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFailuresRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "failures.json")
+	f := NewFailures(path)
+
+	if got := f.LastFailure("groq", "m1"); got != "" {
+		t.Fatalf("LastFailure() on empty store = %q, want empty", got)
+	}
+	if err := f.RecordFailure("groq", "m1", "invalid_json"); err != nil {
+		t.Fatalf("RecordFailure() error = %v", err)
+	}
+
+	// A fresh store reads what the previous run wrote.
+	again := NewFailures(path)
+	if got := again.LastFailure("groq", "m1"); got != "invalid_json" {
+		t.Errorf("LastFailure() = %q, want invalid_json", got)
+	}
+	if got := again.LastFailure("groq", "m2"); got != "" {
+		t.Errorf("LastFailure() for another model = %q, want empty", got)
+	}
+
+	if err := again.RecordFailure("groq", "m1", ""); err != nil {
+		t.Fatalf("RecordFailure(clear) error = %v", err)
+	}
+	if got := NewFailures(path).LastFailure("groq", "m1"); got != "" {
+		t.Errorf("LastFailure() after clear = %q, want empty", got)
+	}
+}
+
+func TestFailuresIgnoresCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "failures.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	f := NewFailures(path)
+	if got := f.LastFailure("openai", "gpt"); got != "" {
+		t.Errorf("LastFailure() = %q, want empty", got)
+	}
+	if err := f.RecordFailure("openai", "gpt", "empty_output"); err != nil {
+		t.Fatalf("RecordFailure() error = %v", err)
+	}
+	if got := f.LastFailure("openai", "gpt"); got != "empty_output" {
+		t.Errorf("LastFailure() = %q, want empty_output", got)
+	}
+}
@@ -3,26 +3,97 @@ package app
 import (
 	"context"
 	"crypto/sha256"
+	"errors"
 	"fmt"
 	"io"
+	"regexp"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/chuckie/commit-coach/internal/domain"
+	"github.com/chuckie/commit-coach/internal/observability"
 	"github.com/chuckie/commit-coach/internal/ports"
 	"github.com/chuckie/commit-coach/internal/security"
 )
 
+// ErrNoStagedChanges is returned when there is nothing staged to describe.
+var ErrNoStagedChanges = errors.New("no staged changes")
+
+// ErrNoMeaningfulChanges is returned when the staged diff is blank or only
+// file headers, with nothing a commit message could describe.
+var ErrNoMeaningfulChanges = errors.New("no meaningful staged changes: the staged diff has no content, mode, rename or binary changes")
+
+// ErrAllExcluded is returned when every staged file matches an exclude
+// pattern (SetExclude).
+var ErrAllExcluded = errors.New("every staged file is excluded by .commit-coachignore")
+
 // SuggestService generates commit suggestions.
 type SuggestService struct {
-	llm       ports.LLM
-	git       ports.Git
-	redactor  ports.Redactor
-	cache     ports.Cache
-	diffCap   int
-	timeout   time.Duration
-	useCache  bool
+	llm      ports.LLM
+	git      ports.Git
+	redactor ports.Redactor
+	cache    ports.Cache
+	diffCap  int
+	timeout  time.Duration
+	useCache bool
+	count    int
+	// retryEmpty regenerates once when the provider returns empty output.
+	retryEmpty bool
+	examples   []string
+	// maxFiles switches to a summarized diff above this many files; 0 disables.
+	maxFiles int
+	// failureNotes remembers the last failure kind per provider/model.
+	failureNotes ports.FailureNotes
+	// includeUnstaged falls back to the working-tree diff when nothing is staged.
+	includeUnstaged bool
+	// paths limits the staged diff to these pathspecs; empty means everything.
+	paths []string
+	// noRedact sends the diff without redaction (explicit opt-out).
+	noRedact bool
+	// warnedNoRedact records that RedactionDisabledWarning was returned once.
+	warnedNoRedact bool
+	// summarizeHunks replaces binary files and very long hunks with short
+	// placeholders before capping.
+	summarizeHunks bool
+	// subjectLimits caps subject length, optionally per commit type.
+	subjectLimits domain.SubjectLimits
+	// subjectStyle controls subject case and verb normalization.
+	subjectStyle   domain.SubjectStyle
+	systemPrompt   string
+	promptTemplate string
+	// commitType is the type every suggestion must use; "" lets the model
+	// choose.
+	commitType string
+	// exclude drops matching files from the diff; excludePatterns is the
+	// source, kept for the cache key.
+	exclude         []excludeRule
+	excludePatterns []string
+	// clock times provider calls; nil means the wall clock.
+	clock ports.Clock
 }
 
+// Hunks longer than largeHunkLines keep only their first largeHunkKeepLines
+// lines when SetSummarizeLargeHunks is on.
+const (
+	largeHunkLines     = 150
+	largeHunkKeepLines = 20
+)
+
+// summarizedTopFiles is how many of the largest files keep their full diff
+// when a change is summarized for touching more than maxFiles files.
+const summarizedTopFiles = 10
+
+// exampleBudgetDivisor limits few-shot examples to 1/N of the diff cap so
+// they never crowd out the diff itself.
+const exampleBudgetDivisor = 4
+
+// Suggestion count bounds; requested counts are clamped into this range.
+const (
+	MinSuggestionCount = 1
+	MaxSuggestionCount = 10
+)
+
 // NewSuggestService creates a new suggestion service.
 func NewSuggestService(llm ports.LLM, git ports.Git, redactor ports.Redactor, cache ports.Cache, diffCap int, useCache bool) *SuggestService {
 	return &SuggestService{
@@ -31,77 +102,494 @@ func NewSuggestService(llm ports.LLM, git ports.Git, redactor ports.Redactor, ca
 		redactor: redactor,
 		cache:    cache,
 		diffCap:  diffCap,
-		timeout:  90 * time.Second,
+		timeout:  ports.DefaultRequestTimeout,
 		useCache: useCache,
+		count:    ports.DefaultSuggestionCount,
+	}
+}
+
+// SetCount sets how many suggestions to request, clamped to
+// [MinSuggestionCount, MaxSuggestionCount]. Zero or negative selects the default.
+func (s *SuggestService) SetCount(n int) {
+	if n <= 0 {
+		n = ports.DefaultSuggestionCount
+	}
+	s.count = ClampSuggestionCount(n)
+}
+
+// SetRetryEmpty enables one automatic regeneration, with a slightly higher
+// temperature, when the provider returns ports.ErrEmptyOutput.
+func (s *SuggestService) SetRetryEmpty(v bool) {
+	s.retryEmpty = v
+}
+
+// SetExamples sets few-shot example commit messages to include in the prompt.
+func (s *SuggestService) SetExamples(examples []string) {
+	s.examples = examples
+}
+
+// SetMaxFiles sets the changed-file count above which the diff is replaced by
+// a stat summary plus the largest files. Zero or negative disables it.
+func (s *SuggestService) SetMaxFiles(n int) {
+	s.maxFiles = n
+}
+
+// SetFailureNotes enables hints on repeat failures of the same kind for a
+// provider and model. nil disables them.
+func (s *SuggestService) SetFailureNotes(n ports.FailureNotes) {
+	s.failureNotes = n
+}
+
+// SetIncludeUnstaged makes suggestions fall back to the unstaged working-tree
+// diff when nothing is staged. Results say when that happened (Unstaged).
+func (s *SuggestService) SetIncludeUnstaged(v bool) {
+	s.includeUnstaged = v
+}
+
+// SetSummarizeLargeHunks replaces binary files with "[binary: path]" and
+// trims hunks over largeHunkLines lines, so generated files and blobs don't
+// use up the diff cap. Off by default, which drops binary files entirely.
+func (s *SuggestService) SetSummarizeLargeHunks(v bool) {
+	s.summarizeHunks = v
+}
+
+// SetSubjectLimits sets the subject length limits suggestions are
+// normalized and validated against.
+func (s *SuggestService) SetSubjectLimits(limits domain.SubjectLimits) {
+	s.subjectLimits = limits
+}
+
+// SetRedact turns secret redaction of the diff on (the default) or off.
+// Turning it off is for debugging false positives on non-sensitive repos;
+// the first result afterwards carries RedactionDisabledWarning.
+func (s *SuggestService) SetRedact(enabled bool) {
+	s.noRedact = !enabled
+}
+
+// RedactionDisabledWarning is returned once per service when the diff is
+// sent without redaction.
+const RedactionDisabledWarning = "REDACTION IS OFF: the raw diff, including any secrets in it, is sent to the provider"
+
+// SetTimeout bounds each suggestion request; d <= 0 keeps
+// ports.DefaultRequestTimeout.
+func (s *SuggestService) SetTimeout(d time.Duration) {
+	if d > 0 {
+		s.timeout = d
+	}
+}
+
+// SetSubjectStyle sets how suggestion subjects are normalized.
+func (s *SuggestService) SetSubjectStyle(style domain.SubjectStyle) {
+	s.subjectStyle = style
+}
+
+// SetSystemPrompt replaces the providers' default system message; "" keeps
+// the default.
+func (s *SuggestService) SetSystemPrompt(prompt string) {
+	s.systemPrompt = prompt
+}
+
+// SetPromptTemplate replaces the default suggestion prompt with a
+// text/template; "" keeps the default.
+func (s *SuggestService) SetPromptTemplate(text string) {
+	s.promptTemplate = text
+}
+
+// SetCommitType makes every suggestion use commitType, which the prompt
+// asks for so the subject and body are written for it. "" lets the model
+// choose.
+func (s *SuggestService) SetCommitType(commitType string) {
+	s.commitType = commitType
+}
+
+// SubjectLimits returns the limits set by SetSubjectLimits.
+func (s *SuggestService) SubjectLimits() domain.SubjectLimits {
+	return s.subjectLimits
+}
+
+// SetPaths limits suggestions to the staged changes under paths (git
+// pathspecs). Each path must have staged changes. nil means the whole index.
+func (s *SuggestService) SetPaths(paths []string) {
+	s.paths = paths
+}
+
+// SetClock replaces the wall clock used to time provider calls, for tests.
+func (s *SuggestService) SetClock(clock ports.Clock) {
+	s.clock = clock
+}
+
+func (s *SuggestService) now() time.Time {
+	if s.clock == nil {
+		return time.Now()
+	}
+	return s.clock.Now()
+}
+
+// IncludeUnstaged reports whether the working-tree fallback is enabled.
+func (s *SuggestService) IncludeUnstaged() bool {
+	return s.includeUnstaged
+}
+
+// ClampSuggestionCount bounds n to [MinSuggestionCount, MaxSuggestionCount].
+func ClampSuggestionCount(n int) int {
+	if n < MinSuggestionCount {
+		return MinSuggestionCount
+	}
+	if n > MaxSuggestionCount {
+		return MaxSuggestionCount
+	}
+	return n
+}
+
+// SuggestResult is the outcome of a suggestion run plus metadata about it.
+type SuggestResult struct {
+	Suggestions []domain.Suggestion `json:"suggestions"`
+	// Usage is the provider-reported token usage; nil for cache hits or
+	// providers that don't report it.
+	Usage *ports.Usage `json:"usage,omitempty"`
+	// Warnings are non-fatal notes for the user, e.g. a clamped temperature.
+	Warnings []string `json:"warnings,omitempty"`
+	// Redacted is true when secrets were found in the staged diff and
+	// redacted before it was sent (never when redaction is off).
+	Redacted bool `json:"redacted"`
+	// Unstaged is true when nothing was staged and the suggestions describe
+	// the working-tree diff instead (see SetIncludeUnstaged).
+	Unstaged bool `json:"unstaged"`
+	// Truncated is true when the diff was cut to fit the byte budget.
+	// OriginalBytes is the size of the diff read from git and SentBytes the
+	// size of what the provider saw.
+	Truncated     bool `json:"truncated"`
+	OriginalBytes int  `json:"original_bytes"`
+	SentBytes     int  `json:"sent_bytes"`
+	// DurationMS is how long the provider took, retries included; 0 for
+	// cache hits.
+	DurationMS int64 `json:"duration_ms"`
+	// Cached is true when the suggestions came from the cache instead of
+	// the provider; never for RegenerateCommitsDetailed.
+	Cached bool `json:"cached"`
+	// Merging and Rebasing report a merge or rebase in progress, which the
+	// commit would conclude or join; see RepoStateNote.
+	Merging  bool `json:"merging"`
+	Rebasing bool `json:"rebasing"`
+}
+
+// TruncationNote describes how much of the diff was sent, e.g. "diff
+// truncated: sent 8 KB of 40 KB", or is empty when nothing was cut.
+func (r *SuggestResult) TruncationNote() string {
+	if !r.Truncated {
+		return ""
+	}
+	return fmt.Sprintf("diff truncated: sent %s of %s", formatSize(r.SentBytes), formatSize(r.OriginalBytes))
+}
+
+// RepoStateNote warns about a merge or rebase in progress, or is empty when
+// there is none.
+func (r *SuggestResult) RepoStateNote() string {
+	switch {
+	case r.Rebasing:
+		return "a rebase is in progress; the commit joins the rebase (continue it with git rebase --continue)"
+	case r.Merging:
+		return "a merge is in progress; committing concludes it"
+	}
+	return ""
+}
+
+// formatSize renders n bytes as whole kilobytes, or bytes below 1 KB.
+func formatSize(n int) string {
+	if n < 1024 {
+		return fmt.Sprintf("%d B", n)
+	}
+	return fmt.Sprintf("%d KB", (n+512)/1024)
+}
+
+// newSuggestResult assembles a result from the prepared diff it describes.
+func (s *SuggestService) newSuggestResult(prepared *PreparedDiff, suggestions []domain.Suggestion, usage *ports.Usage, warnings []string) *SuggestResult {
+	return &SuggestResult{
+		Suggestions:   suggestions,
+		Usage:         usage,
+		Warnings:      warnings,
+		Redacted:      prepared.SecretsFound && !s.noRedact,
+		Unstaged:      prepared.Unstaged,
+		Truncated:     prepared.Truncated,
+		OriginalBytes: prepared.StagedBytes,
+		SentBytes:     prepared.Bytes,
+		Merging:       prepared.Merging,
+		Rebasing:      prepared.Rebasing,
 	}
 }
 
-// SuggestCommits generates 3 commit suggestions based on staged diff.
+// UnstagedWarning explains that suggestions came from unstaged changes, which
+// a plain commit would not include.
+const UnstagedWarning = "nothing is staged; suggestions describe unstaged working-tree changes (stage them with git add before committing)"
+
+// SuggestCommits generates commit suggestions (3 by default; see SetCount)
+// based on the staged diff.
 func (s *SuggestService) SuggestCommits(ctx context.Context, provider, model string, temperature float32) ([]domain.Suggestion, error) {
+	result, err := s.SuggestCommitsDetailed(ctx, provider, model, temperature)
+	if err != nil {
+		return nil, err
+	}
+	return result.Suggestions, nil
+}
+
+// SuggestCommitsDetailed is SuggestCommits plus metadata about the run.
+func (s *SuggestService) SuggestCommitsDetailed(ctx context.Context, provider, model string, temperature float32) (*SuggestResult, error) {
+	return s.suggestDetailed(ctx, provider, model, temperature, false)
+}
+
+// RegenerateCommitsDetailed is SuggestCommitsDetailed without the cache
+// read: it drops any cached entry for this request, asks the provider again
+// and caches the fresh result.
+func (s *SuggestService) RegenerateCommitsDetailed(ctx context.Context, provider, model string, temperature float32) (*SuggestResult, error) {
+	return s.suggestDetailed(ctx, provider, model, temperature, true)
+}
+
+func (s *SuggestService) suggestDetailed(ctx context.Context, provider, model string, temperature float32, fresh bool) (*SuggestResult, error) {
 	// Add timeout to context
 	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
 
-	// Step 1: Check if in repository
-	inRepo, err := s.git.IsInRepository(ctx)
+	// Steps 1-5: Repo check, staged diff, binary stripping, cap and redaction
+	prepared, err := s.prepare(ctx, provider, model, temperature)
 	if err != nil {
-		return nil, fmt.Errorf("failed to check repository status: %w", err)
+		return nil, err
 	}
-	if !inRepo {
-		return nil, fmt.Errorf("not in a git repository")
+	diffHash := prepared.CacheKey
+	var warnings []string
+	if prepared.Summarized {
+		warnings = append(warnings, fmt.Sprintf("%d files changed (more than %d); sent a stat summary plus the %d largest files", prepared.TotalFiles, s.maxFiles, min(summarizedTopFiles, prepared.TotalFiles)))
 	}
-
-	// Step 2: Get staged diff
-	diff, err := s.git.StagedDiff(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read staged diff: %w", err)
+	if s.noRedact && !s.warnedNoRedact {
+		s.warnedNoRedact = true
+		warnings = append(warnings, RedactionDisabledWarning)
 	}
-	if diff == "" {
-		return nil, fmt.Errorf("no staged changes")
+	if prepared.SecretsFound && !s.noRedact {
+		observability.Logf(observability.LevelInfo, "suggest: secrets detected in staged diff; %d redacted before sending", prepared.Redactions)
 	}
 
-	// Step 3: Check cache
-	diffHash := s.hashDiff(diff, provider, model)
-	if s.useCache && s.cache != nil {
+	// Step 6: Check cache
+	if s.useCache && s.cache != nil && fresh {
+		_ = s.cache.Delete(ctx, diffHash) // ignore cache errors
+	} else if s.useCache && s.cache != nil {
 		if cached, err := s.cache.Get(ctx, diffHash); err == nil {
-			return s.validateAndNormalize(cached)
+			observability.RecordCacheHit()
+			suggestions, err := s.validateAndNormalize(cached, s.count)
+			if err != nil {
+				return nil, err
+			}
+			result := s.newSuggestResult(prepared, suggestions, nil, warnings)
+			result.Cached = true
+			return result, nil
 		}
 	}
 
-	// Step 4: Cap and redact diff
-	cappedDiff := s.capDiff(diff, s.diffCap)
-	redactedDiff := s.redactor.Redact(cappedDiff)
-
-	// Step 5: Build file list
-	fileList := []string{} // TODO: extract from diff
+	// Step 7: Call LLM
+	input := s.suggestInput(prepared, model, temperature)
 
-	// Step 6: Call LLM
-	input := ports.SuggestInput{
-		StagedDiff:  redactedDiff,
-		FileList:    fileList,
-		Model:       model,
-		Temperature: temperature,
+	if limiter, ok := s.llm.(ports.TemperatureLimiter); ok {
+		if effective := limiter.EffectiveTemperature(temperature); effective < temperature {
+			warnings = append(warnings, fmt.Sprintf("%s lowers temperature %.2f to %.2f for reliable JSON output", provider, temperature, effective))
+		}
 	}
 
-	llmSuggestions, err := s.llm.SuggestCommits(ctx, input)
+	start := s.now()
+	llmSuggestions, usage, err := s.callLLM(ctx, input)
+	if err != nil && s.retryEmpty && errors.Is(err, ports.ErrEmptyOutput) {
+		input.Temperature = nudgeTemperature(input.Temperature)
+		llmSuggestions, usage, err = s.callLLM(ctx, input)
+	}
+	elapsed := s.now().Sub(start)
 	if err != nil {
-		return nil, fmt.Errorf("LLM error: %w", err)
+		return nil, s.noteFailure(provider, model, failureKind(err), fmt.Errorf("LLM error: %w", err))
 	}
 
-	// Step 7: Validate suggestions
-	result, err := s.validateAndNormalize(llmSuggestions)
+	// Step 8: Validate suggestions
+	suggestions, err := s.validateAndNormalize(llmSuggestions, s.count)
 	if err != nil {
-		return nil, fmt.Errorf("invalid suggestions from LLM: %w", err)
+		return nil, s.noteFailure(provider, model, failureInvalidSuggestions, fmt.Errorf("invalid suggestions from LLM: %w", err))
 	}
+	_ = s.noteFailure(provider, model, "", nil)
 
-	// Step 8: Cache result
+	// Step 9: Cache result
 	if s.useCache && s.cache != nil {
 		_ = s.cache.Set(ctx, diffHash, llmSuggestions) // ignore cache errors
 	}
 
+	result := s.newSuggestResult(prepared, suggestions, usage, warnings)
+	result.DurationMS = elapsed.Milliseconds()
 	return result, nil
 }
 
+// suggestInput is the provider request for prepared, with every prompt
+// setting of s applied.
+func (s *SuggestService) suggestInput(prepared *PreparedDiff, model string, temperature float32) ports.SuggestInput {
+	return ports.SuggestInput{
+		StagedDiff:     prepared.Diff,
+		FileList:       prepared.Files,
+		Model:          model,
+		Temperature:    temperature,
+		Count:          s.count,
+		Examples:       fitExamples(s.examples, s.diffCap/exampleBudgetDivisor),
+		Notes:          prepared.notes(),
+		Branch:         prepared.Branch,
+		RecentSubjects: prepared.RecentSubjects,
+		Type:           s.commitType,
+		SystemPrompt:   s.systemPrompt,
+		PromptTemplate: s.promptTemplate,
+		// The limit for types without an override.
+		SubjectMaxLen: s.subjectLimits.For(""),
+	}
+}
+
+// recentSubjectCount is how many commit subjects custom templates get.
+const recentSubjectCount = 10
+
+// recentSubjects returns the latest commit subjects for
+// SuggestInput.RecentSubjects. Only custom templates use them, so the
+// default prompt skips the git call; errors mean none.
+func (s *SuggestService) recentSubjects(ctx context.Context) []string {
+	if s.promptTemplate == "" {
+		return nil
+	}
+	subjects, err := s.git.RecentSubjects(ctx, recentSubjectCount)
+	if err != nil {
+		return nil
+	}
+	return subjects
+}
+
+// currentBranch returns the branch name for SuggestInput.Branch, or "" when
+// git cannot tell.
+func (s *SuggestService) currentBranch(ctx context.Context) string {
+	branch, err := s.git.CurrentBranch(ctx)
+	if err != nil {
+		return ""
+	}
+	return branch
+}
+
+// RegenerateBody asks the provider for a new body and footer for chosen,
+// keeping its type and subject exactly. The result is not cached.
+func (s *SuggestService) RegenerateBody(ctx context.Context, provider, model string, temperature float32, chosen domain.Suggestion) (domain.Suggestion, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	prepared, err := s.prepare(ctx, provider, model, temperature)
+	if err != nil {
+		return domain.Suggestion{}, err
+	}
+
+	input := s.suggestInput(prepared, model, temperature)
+	input.Count = 1
+	input.LockedType = chosen.Type
+	input.LockedSubject = chosen.Subject
+	out, _, err := s.callLLM(ctx, input)
+	if err != nil {
+		return domain.Suggestion{}, fmt.Errorf("LLM error: %w", err)
+	}
+	if len(out) == 0 {
+		return domain.Suggestion{}, fmt.Errorf("invalid suggestions from LLM: expected 1 suggestion, got 0")
+	}
+
+	// The model only writes the body and footer; the header is the user's.
+	ds := domain.Suggestion{
+		Type:    chosen.Type,
+		Subject: chosen.Subject,
+		Body:    out[0].Body,
+		Footer:  out[0].Footer,
+	}
+	ds.NormalizeStyled(s.subjectLimits, s.subjectStyle)
+	if err := ds.ValidateWith(s.subjectLimits); err != nil {
+		return domain.Suggestion{}, fmt.Errorf("invalid suggestion from LLM: %w", err)
+	}
+	return ds, nil
+}
+
+// callLLM asks the provider for suggestions, collecting usage when supported.
+func (s *SuggestService) callLLM(ctx context.Context, input ports.SuggestInput) ([]ports.CommitSuggestion, *ports.Usage, error) {
+	var (
+		suggestions []ports.CommitSuggestion
+		usage       *ports.Usage
+		err         error
+	)
+	if withUsage, ok := s.llm.(ports.UsageLLM); ok {
+		suggestions, usage, err = withUsage.SuggestCommitsWithUsage(ctx, input)
+	} else {
+		suggestions, err = s.llm.SuggestCommits(ctx, input)
+	}
+	if err != nil {
+		observability.RecordLLMFailure()
+	}
+	return suggestions, usage, err
+}
+
+// Failure kinds recorded in the failure notes.
+const (
+	failureInvalidJSON        = "invalid_json"
+	failureEmptyOutput        = "empty_output"
+	failureInvalidSuggestions = "invalid_suggestions"
+)
+
+// failureHints are shown when a provider/model fails the same way twice.
+var failureHints = map[string]string{
+	failureInvalidJSON:        "this model has returned non-JSON before; try a lower temperature or another model",
+	failureEmptyOutput:        "this model has returned empty output before; try --retry-empty or another model",
+	failureInvalidSuggestions: "this model has returned malformed suggestions before; try another model",
+}
+
+// failureKind classifies model-output failures worth remembering. Transport
+// and HTTP errors are usually transient and return "".
+func failureKind(err error) string {
+	switch {
+	case errors.Is(err, ports.ErrInvalidJSON):
+		return failureInvalidJSON
+	case errors.Is(err, ports.ErrEmptyOutput):
+		return failureEmptyOutput
+	}
+	return ""
+}
+
+// noteFailure records kind for provider/model and returns err, prefixed with
+// a hint when the previous run failed the same way. A nil err with an empty
+// kind clears the note after a success.
+func (s *SuggestService) noteFailure(provider, model, kind string, err error) error {
+	if s.failureNotes == nil || (kind == "" && err != nil) {
+		return err
+	}
+	previous := s.failureNotes.LastFailure(provider, model)
+	if recErr := s.failureNotes.RecordFailure(provider, model, kind); recErr != nil {
+		observability.Logf(observability.LevelWarn, "suggest: failed to record failure note: %v", recErr)
+	}
+	if err != nil && kind == previous {
+		return fmt.Errorf("hint: %s\n%w", failureHints[kind], err)
+	}
+	return err
+}
+
+// fitExamples keeps whole examples, in order, while their combined size stays
+// within budget bytes. Later examples are dropped first.
+func fitExamples(examples []string, budget int) []string {
+	var kept []string
+	used := 0
+	for _, ex := range examples {
+		if used+len(ex) > budget {
+			break
+		}
+		used += len(ex)
+		kept = append(kept, ex)
+	}
+	return kept
+}
+
+// nudgeTemperature raises t slightly for a retry, staying within the 0–1
+// range every provider accepts unless t was already above it.
+func nudgeTemperature(t float32) float32 {
+	if t+0.1 <= 1 {
+		return t + 0.1
+	}
+	return t
+}
+
 // SetLLM swaps the LLM implementation used by this service.
 // Safe to call from the Bubble Tea Update loop (single-owner).
 func (s *SuggestService) SetLLM(llm ports.LLM) {
@@ -111,33 +599,59 @@ func (s *SuggestService) SetLLM(llm ports.LLM) {
 	s.llm = llm
 }
 
-// hashDiff computes a SHA256 hash of the diff plus a cache namespace.
-func (s *SuggestService) hashDiff(diff, provider, model string) string {
+// hashDiff computes a SHA256 hash of the diff plus a cache namespace: every
+// setting that changes the request, so materially different requests never
+// share an entry. root keeps identical diffs in different repositories or
+// worktrees apart, and branch is part of the prompt.
+func (s *SuggestService) hashDiff(diff, root, branch, provider, model string, temperature float32, count int, examples, recent []string) string {
 	h := sha256.New()
 	io.WriteString(h, diff)
+	io.WriteString(h, "\nroot=")
+	io.WriteString(h, root)
+	io.WriteString(h, "\nbranch=")
+	io.WriteString(h, branch)
 	io.WriteString(h, "\nprovider=")
 	io.WriteString(h, provider)
 	io.WriteString(h, "\nmodel=")
 	io.WriteString(h, model)
+	fmt.Fprintf(h, "\ntemperature=%.2f", temperature)
+	fmt.Fprintf(h, "\ncount=%d", count)
+	fmt.Fprintf(h, "\nmax_files=%d", s.maxFiles)
+	fmt.Fprintf(h, "\nredact=%t", !s.noRedact)
+	fmt.Fprintf(h, "\nsummarize_hunks=%t", s.summarizeHunks)
+	fmt.Fprintf(h, "\nsystem_prompt=%q", s.systemPrompt)
+	fmt.Fprintf(h, "\nprompt_template=%q", s.promptTemplate)
+	fmt.Fprintf(h, "\ncommit_type=%q", s.commitType)
+	for _, ex := range examples {
+		fmt.Fprintf(h, "\nexample=%q", ex)
+	}
+	for _, subject := range recent {
+		fmt.Fprintf(h, "\nrecent=%q", subject)
+	}
+	for _, path := range s.paths {
+		fmt.Fprintf(h, "\npath=%q", path)
+	}
+	for _, pattern := range s.excludePatterns {
+		fmt.Fprintf(h, "\nexclude=%q", pattern)
+	}
 	return fmt.Sprintf("%x", h.Sum(nil))
 }
 
-// capDiff truncates diff to max size.
+// capDiff fits diff into maxBytes, keeping every file's header (see
+// capDiffSmart).
 func (s *SuggestService) capDiff(diff string, maxBytes int) string {
-	if len(diff) <= maxBytes {
-		return diff
-	}
-	return diff[:maxBytes]
+	return capDiffSmart(diff, maxBytes)
 }
 
-// validateAndNormalize converts port suggestions to domain suggestions with validation.
-func (s *SuggestService) validateAndNormalize(portSuggestions []ports.CommitSuggestion) ([]domain.Suggestion, error) {
-	if len(portSuggestions) < 3 {
-		return nil, fmt.Errorf("expected 3 suggestions, got %d", len(portSuggestions))
+// validateAndNormalize converts the first count port suggestions to domain
+// suggestions with validation.
+func (s *SuggestService) validateAndNormalize(portSuggestions []ports.CommitSuggestion, count int) ([]domain.Suggestion, error) {
+	if len(portSuggestions) < count {
+		return nil, fmt.Errorf("expected %d suggestions, got %d", count, len(portSuggestions))
 	}
 
-	result := make([]domain.Suggestion, 3)
-	for i := 0; i < 3; i++ {
+	result := make([]domain.Suggestion, count)
+	for i := 0; i < count; i++ {
 		ps := portSuggestions[i]
 		ds := domain.Suggestion{
 			Type:    ps.Type,
@@ -145,8 +659,8 @@ func (s *SuggestService) validateAndNormalize(portSuggestions []ports.CommitSugg
 			Body:    ps.Body,
 			Footer:  ps.Footer,
 		}
-		ds.Normalize()
-		if err := ds.Validate(); err != nil {
+		ds.NormalizeStyled(s.subjectLimits, s.subjectStyle)
+		if err := ds.ValidateWith(s.subjectLimits); err != nil {
 			return nil, fmt.Errorf("suggestion %d validation failed: %w", i, err)
 		}
 		result[i] = ds
@@ -157,8 +671,10 @@ func (s *SuggestService) validateAndNormalize(portSuggestions []ports.CommitSugg
 
 // CommitService handles commit execution.
 type CommitService struct {
-	git     ports.Git
-	timeout time.Duration
+	git           ports.Git
+	timeout       time.Duration
+	commitTimeout time.Duration
+	ticketPattern *regexp.Regexp
 }
 
 // NewCommitService creates a new commit service.
@@ -169,38 +685,169 @@ func NewCommitService(git ports.Git) *CommitService {
 	}
 }
 
-// Commit executes a git commit with the given message (atomically).
-func (c *CommitService) Commit(ctx context.Context, message string, dryRun bool) (hash string, err error) {
+// SetTimeout bounds each git step other than the commit itself (default
+// 10s).
+func (c *CommitService) SetTimeout(d time.Duration) {
+	if d > 0 {
+		c.timeout = d
+	}
+}
+
+// SetCommitTimeout bounds Commit, which runs the commit hooks. 0, the
+// default, leaves it to the caller's context.
+func (c *CommitService) SetCommitTimeout(d time.Duration) {
+	c.commitTimeout = d
+}
+
+// DetachedHeadWarning explains why committing on a detached HEAD needs confirmation.
+const DetachedHeadWarning = "You're in detached HEAD; this commit won't be on a branch."
+
+// DetachedHead reports whether HEAD is detached, so a new commit would not
+// be on any branch. Errors (e.g. an unborn HEAD) count as not detached.
+func (c *CommitService) DetachedHead(ctx context.Context) bool {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	branch, err := c.git.CurrentBranch(ctx)
+	return err == nil && branch == "HEAD"
+}
+
+// FixupMessage returns "fixup! <subject>" for the commit ref names, the
+// message git rebase --autosquash folds into that commit. Existing fixup! or
+// squash! prefixes on the target are dropped so chains still match the
+// original commit.
+func (c *CommitService) FixupMessage(ctx context.Context, ref string) (string, error) {
 	ctx, cancel := context.WithTimeout(ctx, c.timeout)
 	defer cancel()
 
+	subject, err := c.git.CommitSubject(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to read fixup target: %w", err)
+	}
+	for {
+		trimmed := strings.TrimPrefix(strings.TrimPrefix(subject, "fixup! "), "squash! ")
+		if trimmed == subject {
+			break
+		}
+		subject = trimmed
+	}
+	if subject == "" {
+		return "", fmt.Errorf("fixup target %s has an empty subject", ref)
+	}
+	return "fixup! " + subject, nil
+}
+
+// Commit executes a git commit with the given message (atomically).
+func (c *CommitService) Commit(ctx context.Context, message string, opts ports.CommitOptions) (hash string, err error) {
+	if c.commitTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.commitTimeout)
+		defer cancel()
+	}
+
 	// Validate message before attempting commit
 	if message == "" {
 		return "", fmt.Errorf("commit message cannot be empty")
 	}
+	message = c.addTicketRef(ctx, message)
 
 	// Attempt commit
-	hash, err = c.git.Commit(ctx, message, dryRun)
+	hash, err = c.git.Commit(ctx, message, opts)
 	if err != nil {
 		return "", fmt.Errorf("git commit failed: %w", err)
 	}
+	if opts.DryRun {
+		return hash, nil
+	}
 
+	// Ask git for the new HEAD rather than trusting the parsed commit
+	// output; that stays the fallback.
+	if head, err := c.git.HeadHash(ctx); err == nil && head != "" {
+		return head, nil
+	}
 	return hash, nil
 }
 
+// StageAllIfNothingStaged runs git add -A when the index is empty, for the
+// explicit stage-everything choices (suggest --add-all, a in the TUI). It
+// reports whether it staged; a non-empty index is left alone so selective
+// staging is never undone.
+func (c *CommitService) StageAllIfNothingStaged(ctx context.Context) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	diff, err := c.git.StagedDiff(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to read staged diff: %w", err)
+	}
+	if diff != "" {
+		return false, nil
+	}
+	if err := c.git.StageAll(ctx); err != nil {
+		return false, fmt.Errorf("failed to stage changes: %w", err)
+	}
+	return true, nil
+}
+
+// Undo drops the commit hash names, which must still be HEAD, and keeps its
+// changes staged. hash is what Commit returned; checking it first means a
+// commit made elsewhere in the meantime is never the one undone.
+func (c *CommitService) Undo(ctx context.Context, hash string) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	head, err := c.git.HeadHash(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read HEAD: %w", err)
+	}
+	if hash == "" || head != hash {
+		return fmt.Errorf("HEAD is no longer commit %s; not undoing", hash)
+	}
+	if err := c.git.UndoLastCommit(ctx); err != nil {
+		return fmt.Errorf("undo failed: %w", err)
+	}
+	return nil
+}
+
 // App is the application container with all services.
 type App struct {
-	Suggest *SuggestService
-	Commit  *CommitService
+	Suggest  *SuggestService
+	Commit   *CommitService
 	Redactor ports.Redactor
+
+	closeOnce sync.Once
 }
 
-// NewApp creates a new application with all dependencies wired.
+// Close releases what the provider and cache hold, such as idle HTTP
+// connections and cached entries, for those that implement io.Closer. Only
+// the first call does anything; later ones, concurrent or not, return nil.
+func (a *App) Close() error {
+	var err error
+	a.closeOnce.Do(func() {
+		var errs []error
+		for _, r := range []any{a.Suggest.llm, a.Suggest.cache} {
+			if closer, ok := r.(io.Closer); ok {
+				if err := closer.Close(); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		}
+		err = errors.Join(errs...)
+	})
+	return err
+}
+
+// NewApp creates a new application with all dependencies wired, using the
+// built-in redaction patterns.
 func NewApp(llm ports.LLM, git ports.Git, cache ports.Cache, diffCap int, useCache bool) *App {
-	redactor := security.NewRedactor()
+	return NewAppWithRedactor(llm, git, cache, diffCap, useCache, security.NewRedactor())
+}
+
+// NewAppWithRedactor is NewApp with a caller-supplied redactor, e.g. one
+// built from Config.RedactPatterns.
+func NewAppWithRedactor(llm ports.LLM, git ports.Git, cache ports.Cache, diffCap int, useCache bool, redactor ports.Redactor) *App {
 	return &App{
-		Suggest: NewSuggestService(llm, git, redactor, cache, diffCap, useCache),
-		Commit:  NewCommitService(git),
+		Suggest:  NewSuggestService(llm, git, redactor, cache, diffCap, useCache),
+		Commit:   NewCommitService(git),
 		Redactor: redactor,
 	}
 }
@@ -0,0 +1,335 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// splitDiffFiles splits a unified git diff into per-file sections, each
+// starting at its "diff --git" header. Any preamble before the first header
+// is returned as its own section.
+func splitDiffFiles(diff string) []string {
+	var sections []string
+	start := 0
+	for i := 0; i < len(diff); {
+		end := strings.IndexByte(diff[i:], '\n')
+		lineEnd := len(diff)
+		if end >= 0 {
+			lineEnd = i + end + 1
+		}
+		if strings.HasPrefix(diff[i:], "diff --git ") && i > start {
+			sections = append(sections, diff[start:i])
+			start = i
+		}
+		i = lineEnd
+	}
+	if start < len(diff) {
+		sections = append(sections, diff[start:])
+	}
+	return sections
+}
+
+// isBinarySection reports whether a per-file diff section describes a binary
+// file, based on the markers git emits in place of text hunks.
+func isBinarySection(section string) bool {
+	for _, line := range strings.Split(section, "\n") {
+		if strings.HasPrefix(line, "@@") {
+			return false
+		}
+		if strings.HasPrefix(line, "GIT binary patch") {
+			return true
+		}
+		if strings.HasPrefix(line, "Binary files ") && strings.HasSuffix(strings.TrimRight(line, "\r"), " differ") {
+			return true
+		}
+	}
+	return false
+}
+
+// stripBinaryFiles removes binary file sections from diff and appends a note
+// saying how many were omitted. It returns the new diff and that count.
+func stripBinaryFiles(diff string) (string, int) {
+	var b strings.Builder
+	omitted := 0
+	for _, section := range splitDiffFiles(diff) {
+		if isBinarySection(section) {
+			omitted++
+			continue
+		}
+		b.WriteString(section)
+	}
+	if omitted == 0 {
+		return diff, 0
+	}
+
+	out := b.String()
+	if out != "" && !strings.HasSuffix(out, "\n") {
+		out += "\n"
+	}
+	noun := "files"
+	if omitted == 1 {
+		noun = "file"
+	}
+	return out + fmt.Sprintf("(%d binary %s omitted)\n", omitted, noun), omitted
+}
+
+// FileStat is the per-file line count of a diff, like one row of git diff --stat.
+type FileStat struct {
+	Path    string
+	Added   int
+	Deleted int
+	Binary  bool
+}
+
+// DiffStat summarizes a unified diff per file.
+func DiffStat(diff string) []FileStat {
+	var stats []FileStat
+	for _, section := range splitDiffFiles(diff) {
+		if !strings.HasPrefix(section, "diff --git ") {
+			continue
+		}
+		stat := FileStat{Path: sectionPath(section), Binary: isBinarySection(section)}
+		inHunk := false
+		for _, line := range strings.Split(section, "\n") {
+			switch {
+			case strings.HasPrefix(line, "@@"):
+				inHunk = true
+			case !inHunk:
+			case strings.HasPrefix(line, "+"):
+				stat.Added++
+			case strings.HasPrefix(line, "-"):
+				stat.Deleted++
+			}
+		}
+		stats = append(stats, stat)
+	}
+	return stats
+}
+
+// sectionPath extracts the new-side path from a "diff --git a/x b/y" header.
+func sectionPath(section string) string {
+	header := section
+	if i := strings.IndexByte(header, '\n'); i >= 0 {
+		header = header[:i]
+	}
+	header = strings.TrimRight(header, "\r")
+	if i := strings.LastIndex(header, " b/"); i >= 0 {
+		return header[i+len(" b/"):]
+	}
+	return strings.TrimPrefix(header, "diff --git ")
+}
+
+// summarizeLargeDiff replaces a diff that touches too many files with a stat
+// line per file followed by the full sections of the top files by changed
+// lines, so the model sees the shape of the change plus its core.
+func summarizeLargeDiff(diff string, top int) string {
+	stats := DiffStat(diff)
+	ranked := make([]FileStat, len(stats))
+	copy(ranked, stats)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Added+ranked[i].Deleted > ranked[j].Added+ranked[j].Deleted
+	})
+	if top > len(ranked) {
+		top = len(ranked)
+	}
+	keep := make(map[string]bool, top)
+	for _, stat := range ranked[:top] {
+		keep[stat.Path] = true
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "(%d files changed; showing a stat summary and the %d largest files)\n", len(stats), top)
+	for _, stat := range stats {
+		fmt.Fprintf(&b, " %s | +%d -%d\n", stat.Path, stat.Added, stat.Deleted)
+	}
+	b.WriteString("\n")
+	for _, section := range splitDiffFiles(diff) {
+		if strings.HasPrefix(section, "diff --git ") && keep[sectionPath(section)] {
+			b.WriteString(section)
+		}
+	}
+	return b.String()
+}
+
+// summarizeLargeHunks is the structure-aware alternative to dropping binary
+// files and relying on the byte cap alone: binary sections keep their header
+// and get a "[binary: path]" placeholder, and hunks longer than maxLines keep
+// their first keepLines lines plus a note saying how many were left out. It
+// returns the new diff and the number of binary files replaced.
+func summarizeLargeHunks(diff string, maxLines, keepLines int) (string, int) {
+	var b strings.Builder
+	binaries := 0
+	for _, section := range splitDiffFiles(diff) {
+		if !strings.HasPrefix(section, "diff --git ") {
+			b.WriteString(section)
+			continue
+		}
+		if isBinarySection(section) {
+			binaries++
+			header := section
+			if i := strings.IndexByte(header, '\n'); i >= 0 {
+				header = header[:i+1]
+			}
+			fmt.Fprintf(&b, "%s[binary: %s]\n", header, sectionPath(section))
+			continue
+		}
+		b.WriteString(shortenHunks(section, maxLines, keepLines))
+	}
+	return b.String(), binaries
+}
+
+// shortenHunks trims every hunk in a file section longer than maxLines.
+func shortenHunks(section string, maxLines, keepLines int) string {
+	lines := strings.SplitAfter(section, "\n")
+	var b strings.Builder
+	var hunk []string
+	flush := func() {
+		if len(hunk) <= maxLines {
+			for _, line := range hunk {
+				b.WriteString(line)
+			}
+		} else {
+			for _, line := range hunk[:keepLines] {
+				b.WriteString(line)
+			}
+			added, deleted := 0, 0
+			for _, line := range hunk[keepLines:] {
+				switch {
+				case strings.HasPrefix(line, "+"):
+					added++
+				case strings.HasPrefix(line, "-"):
+					deleted++
+				}
+			}
+			fmt.Fprintf(&b, "[%d more lines omitted: +%d -%d]\n", len(hunk)-keepLines, added, deleted)
+		}
+		hunk = hunk[:0]
+	}
+
+	inHunk := false
+	for _, line := range lines {
+		if strings.HasPrefix(line, "@@") {
+			flush()
+			inHunk = true
+			b.WriteString(line)
+			continue
+		}
+		if !inHunk {
+			b.WriteString(line)
+			continue
+		}
+		if line != "" {
+			hunk = append(hunk, line)
+		}
+	}
+	flush()
+	return b.String()
+}
+
+// capDiffSmart fits diff into roughly maxBytes without losing track of any
+// file: every file keeps its "diff --git" line, and the remaining budget is
+// shared across the files' bodies (small files get all they need, large
+// ones an equal share of the rest), each cut at a line boundary. A note
+// counts the files that were cut. Headers are kept even when they alone
+// exceed maxBytes.
+func capDiffSmart(diff string, maxBytes int) string {
+	if len(diff) <= maxBytes {
+		return diff
+	}
+
+	sections := splitDiffFiles(diff)
+	headers := make([]string, len(sections))
+	bodies := make([]string, len(sections))
+	budget := maxBytes
+	for i, section := range sections {
+		if strings.HasPrefix(section, "diff --git ") {
+			end := strings.IndexByte(section, '\n') + 1
+			if end == 0 {
+				end = len(section)
+			}
+			headers[i], bodies[i] = section[:end], section[end:]
+		} else {
+			bodies[i] = section
+		}
+		budget -= len(headers[i])
+	}
+	const noteReserve = 40
+	budget -= noteReserve
+
+	// Water-fill: visit bodies from smallest to largest, giving each at most
+	// an equal share of what is left.
+	order := make([]int, len(sections))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return len(bodies[order[a]]) < len(bodies[order[b]]) })
+	kept := make([]string, len(sections))
+	cut := 0
+	for n, i := range order {
+		share := 0
+		if budget > 0 {
+			share = budget / (len(order) - n)
+		}
+		body := bodies[i]
+		if len(body) > share {
+			body = body[:share]
+			if j := strings.LastIndexByte(body, '\n'); j >= 0 {
+				body = body[:j+1]
+			} else {
+				body = ""
+			}
+			cut++
+		}
+		kept[i] = body
+		budget -= len(body)
+	}
+
+	var b strings.Builder
+	for i := range sections {
+		b.WriteString(headers[i])
+		b.WriteString(kept[i])
+		if headers[i] != "" && !strings.HasSuffix(headers[i]+kept[i], "\n") {
+			b.WriteString("\n")
+		}
+	}
+	if cut > 0 {
+		noun := "files"
+		if cut == 1 {
+			noun = "file"
+		}
+		fmt.Fprintf(&b, "[%d more %s truncated]\n", cut, noun)
+	}
+	return b.String()
+}
+
+// diffShape reports whether diff changes any file content (hunk lines,
+// renames, copies, binary files, added or deleted files, submodules) and,
+// when it doesn't, whether it still changes a file mode. A diff that is
+// neither is blank or headers only. Text without "diff --git" headers is
+// taken as content when it isn't blank.
+func diffShape(diff string) (hasContent, modeOnly bool) {
+	if !strings.Contains(diff, "diff --git ") {
+		return strings.TrimSpace(diff) != "", false
+	}
+	inHunk := false
+	for _, line := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			inHunk = false
+		case strings.HasPrefix(line, "@@"):
+			inHunk = true
+		case inHunk && (strings.HasPrefix(line, "+") || strings.HasPrefix(line, "-")):
+			return true, false
+		case inHunk:
+		case strings.HasPrefix(line, "old mode ") || strings.HasPrefix(line, "new mode "):
+			modeOnly = true
+		case strings.HasPrefix(line, "rename from "), strings.HasPrefix(line, "copy from "),
+			strings.HasPrefix(line, "new file mode "), strings.HasPrefix(line, "deleted file mode "),
+			strings.HasPrefix(line, "Binary files "), line == "GIT binary patch",
+			strings.HasPrefix(line, "Submodule "):
+			return true, false
+		}
+	}
+	return false, modeOnly
+}
@@ -0,0 +1,127 @@
+package app
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// excludeRule is one compiled gitignore-style pattern.
+type excludeRule struct {
+	re     *regexp.Regexp
+	negate bool
+}
+
+// SetExclude drops files whose paths match the gitignore-style patterns
+// (e.g. from .commit-coachignore) before the diff is capped, redacted or
+// sent. A pattern without a slash matches a file or directory name at any
+// depth, a trailing slash matches directories only, "**" spans directories
+// and a leading "!" re-includes. The last matching pattern wins.
+func (s *SuggestService) SetExclude(patterns []string) error {
+	rules := make([]excludeRule, 0, len(patterns))
+	for _, p := range patterns {
+		rule, err := compileExclude(p)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, rule)
+	}
+	s.exclude = rules
+	s.excludePatterns = patterns
+	return nil
+}
+
+// compileExclude turns a gitignore-style glob into an anchored regexp that
+// matches the path itself or anything beneath it.
+func compileExclude(pattern string) (excludeRule, error) {
+	rule := excludeRule{}
+	p := pattern
+	if strings.HasPrefix(p, "!") {
+		rule.negate = true
+		p = p[1:]
+	}
+	dirOnly := strings.HasSuffix(p, "/")
+	p = strings.TrimSuffix(p, "/")
+	anchored := strings.Contains(p, "/")
+	p = strings.TrimPrefix(p, "/")
+	if p == "" {
+		return rule, fmt.Errorf("invalid exclude pattern %q", pattern)
+	}
+
+	var b strings.Builder
+	b.WriteString("^")
+	if !anchored {
+		b.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(p); i++ {
+		switch c := p[i]; {
+		case strings.HasPrefix(p[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(p[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(p[i+1:], ']')
+			if end < 0 {
+				return rule, fmt.Errorf("invalid exclude pattern %q: unterminated [", pattern)
+			}
+			class := p[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	if dirOnly {
+		b.WriteString("/.*$")
+	} else {
+		b.WriteString("(?:/.*)?$")
+	}
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return rule, fmt.Errorf("invalid exclude pattern %q: %w", pattern, err)
+	}
+	rule.re = re
+	return rule, nil
+}
+
+// excluded reports whether path is dropped by rules.
+func excluded(rules []excludeRule, path string) bool {
+	out := false
+	for _, r := range rules {
+		if r.re.MatchString(path) {
+			out = !r.negate
+		}
+	}
+	return out
+}
+
+// excludeFiles removes the per-file sections of diff whose paths are
+// excluded by rules, returning the rest and the removed paths.
+func excludeFiles(diff string, rules []excludeRule) (string, []string) {
+	if len(rules) == 0 {
+		return diff, nil
+	}
+	var (
+		b       strings.Builder
+		removed []string
+	)
+	for _, section := range splitDiffFiles(diff) {
+		if strings.HasPrefix(section, "diff --git ") {
+			if path := sectionPath(section); excluded(rules, path) {
+				removed = append(removed, path)
+				continue
+			}
+		}
+		b.WriteString(section)
+	}
+	return b.String(), removed
+}
@@ -0,0 +1,255 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// PreparedDiff is the staged diff exactly as it would be sent to the
+// provider, plus what was done to it on the way.
+type PreparedDiff struct {
+	Diff          string   `json:"-"`
+	Files         []string `json:"files"`
+	Bytes         int      `json:"bytes"`
+	StagedBytes   int      `json:"staged_bytes"`
+	Truncated     bool     `json:"truncated"`
+	TotalFiles    int      `json:"total_files"`
+	Summarized    bool     `json:"summarized"`
+	BinaryOmitted int      `json:"binary_omitted"`
+	Redactions    int      `json:"redactions"`
+	SecretsFound  bool     `json:"secrets_found"`
+	Unstaged      bool     `json:"unstaged"`
+	// ModeOnly is true when the only change is to file modes.
+	ModeOnly bool `json:"mode_only"`
+	// Excluded lists the files dropped by SetExclude.
+	Excluded []string `json:"excluded,omitempty"`
+	// RedactionOff is true when the diff is sent as-is (SetRedact(false)).
+	RedactionOff bool `json:"redaction_off"`
+	// Merging and Rebasing report an operation the commit would be part
+	// of; MergeMessage is git's prepared message for the merge.
+	Merging      bool   `json:"merging"`
+	Rebasing     bool   `json:"rebasing"`
+	MergeMessage string `json:"-"`
+	// Branch is the current branch, sent to the provider; "" when git
+	// cannot tell.
+	Branch string `json:"-"`
+	// RecentSubjects are the latest commit subjects, read only for custom
+	// prompt templates.
+	RecentSubjects []string `json:"-"`
+	CacheKey       string   `json:"cache_key"`
+}
+
+// PrepareDiff runs the suggestion pipeline up to, but not including, the
+// provider call: repo check, staged (or, when enabled, working-tree) diff,
+// binary stripping, cap and redaction. Nothing is sent and the cache is not consulted.
+// provider, model and temperature only go into the cache key.
+func (s *SuggestService) PrepareDiff(ctx context.Context, provider, model string, temperature float32) (*PreparedDiff, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.prepare(ctx, provider, model, temperature)
+}
+
+func (s *SuggestService) prepare(ctx context.Context, provider, model string, temperature float32) (*PreparedDiff, error) {
+	inRepo, err := s.git.IsInRepository(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check repository status: %w", err)
+	}
+	if !inRepo {
+		return nil, fmt.Errorf("not in a git repository")
+	}
+
+	diff, unstaged, err := s.readDiff(ctx)
+	if err != nil {
+		return nil, err
+	}
+	diff, excludedFiles := excludeFiles(diff, s.exclude)
+	if diff == "" {
+		return nil, ErrAllExcluded
+	}
+	hasContent, modeOnly := diffShape(diff)
+	if !hasContent && !modeOnly {
+		return nil, ErrNoMeaningfulChanges
+	}
+
+	// The root only namespaces the cache; a failure to resolve it must not
+	// block suggestions. Likewise the repo state only adds context.
+	root, _ := s.git.RootDir(ctx)
+	state, _ := s.git.RepoState(ctx)
+	branch := s.currentBranch(ctx)
+	recent := s.recentSubjects(ctx)
+
+	var (
+		textDiff string
+		omitted  int
+	)
+	if s.summarizeHunks {
+		textDiff, omitted = summarizeLargeHunks(diff, largeHunkLines, largeHunkKeepLines)
+	} else {
+		textDiff, omitted = stripBinaryFiles(diff)
+	}
+	textStats := DiffStat(textDiff)
+	summarized := s.maxFiles > 0 && len(textStats) > s.maxFiles
+	if summarized {
+		textDiff = summarizeLargeDiff(textDiff, summarizedTopFiles)
+	}
+	capped := s.capDiff(textDiff, s.diffCap)
+	redacted := capped
+	if !s.noRedact {
+		redacted = s.redactor.Redact(capped)
+	}
+
+	// A summarized diff names every file in its stat lines, even those
+	// whose hunks were left out.
+	listed := textStats
+	if !summarized {
+		listed = DiffStat(capped)
+	}
+	var files []string
+	for _, stat := range listed {
+		files = append(files, stat.Path)
+	}
+
+	return &PreparedDiff{
+		Diff:           redacted,
+		Files:          files,
+		Bytes:          len(redacted),
+		StagedBytes:    len(diff),
+		Truncated:      capped != textDiff,
+		TotalFiles:     len(textStats),
+		Summarized:     summarized,
+		BinaryOmitted:  omitted,
+		Redactions:     countRedactions(capped, redacted),
+		SecretsFound:   s.redactor.Contains(capped),
+		Unstaged:       unstaged,
+		ModeOnly:       modeOnly,
+		Excluded:       excludedFiles,
+		RedactionOff:   s.noRedact,
+		Merging:        state.Merging,
+		Rebasing:       state.Rebasing,
+		MergeMessage:   state.MergeMessage,
+		Branch:         branch,
+		RecentSubjects: recent,
+		CacheKey:       s.hashDiff(diff, root, branch, provider, model, temperature, s.count, s.examples, recent),
+	}, nil
+}
+
+// modeOnlyNote tells the model why a diff has no hunks.
+const modeOnlyNote = "Only file modes changed (e.g. a script was made executable); no file contents changed."
+
+// mergeNote introduces git's prepared merge message to the model.
+const mergeNote = "This commit concludes a merge. Git prepared this merge message:\n"
+
+// notes returns the SuggestInput.Notes for prepared.
+func (p *PreparedDiff) notes() []string {
+	var notes []string
+	if p.ModeOnly {
+		notes = append(notes, modeOnlyNote)
+	}
+	if p.Merging && p.MergeMessage != "" {
+		notes = append(notes, mergeNote+"  "+strings.ReplaceAll(p.MergeMessage, "\n", "\n  "))
+	}
+	return notes
+}
+
+// readDiff returns the diff to describe: the staged diff (limited by
+// SetPaths), or the working-tree diff when nothing is staged and
+// SetIncludeUnstaged is on, in which case unstaged is true.
+func (s *SuggestService) readDiff(ctx context.Context) (diff string, unstaged bool, err error) {
+	if len(s.paths) > 0 {
+		if diff, err = s.stagedDiffForPaths(ctx); err != nil {
+			return "", false, err
+		}
+	} else if diff, err = s.git.StagedDiff(ctx); err != nil {
+		return "", false, fmt.Errorf("failed to read staged diff: %w", err)
+	}
+	if diff == "" && s.includeUnstaged && len(s.paths) == 0 {
+		if diff, err = s.git.WorkingTreeDiff(ctx); err != nil {
+			return "", false, fmt.Errorf("failed to read working tree diff: %w", err)
+		}
+		unstaged = diff != ""
+	}
+	if diff == "" {
+		return "", false, ErrNoStagedChanges
+	}
+	return diff, unstaged, nil
+}
+
+// stagedDiffForPaths returns the staged diff limited to s.paths, failing
+// when any one of them has nothing staged so a typo isn't silently ignored.
+// The paths are checked against the files of the one combined diff.
+func (s *SuggestService) stagedDiffForPaths(ctx context.Context) (string, error) {
+	diff, err := s.git.StagedDiffForPaths(ctx, s.paths)
+	if err != nil {
+		return "", fmt.Errorf("failed to read staged diff: %w", err)
+	}
+	var files []string
+	for _, stat := range DiffStat(diff) {
+		files = append(files, stat.Path)
+	}
+	prefix, prefixRead := "", false
+	for _, spec := range s.paths {
+		if pathspecMatchesAny(spec, files) {
+			continue
+		}
+		// Pathspecs are relative to the working directory, diff paths to
+		// the repository root.
+		if !prefixRead {
+			prefix, prefixRead = s.workdirPrefix(ctx), true
+		}
+		if prefix == "" || !pathspecMatchesAny(path.Join(prefix, filepath.ToSlash(spec)), files) {
+			return "", fmt.Errorf("no staged changes in %s", spec)
+		}
+	}
+	return diff, nil
+}
+
+// pathspecMatchesAny reports whether spec names one of files or a directory
+// holding one. Globs and pathspec magic (":(...)") can't be checked here;
+// git already applied them, so they always match.
+func pathspecMatchesAny(spec string, files []string) bool {
+	if strings.HasPrefix(spec, ":") || strings.ContainsAny(spec, "*?[") {
+		return true
+	}
+	spec = path.Clean(filepath.ToSlash(spec))
+	for _, f := range files {
+		if spec == "." || f == spec || strings.HasPrefix(f, spec+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// workdirPrefix returns the working directory relative to the repository
+// root, "" at the root or when either can't be resolved.
+func (s *SuggestService) workdirPrefix(ctx context.Context) string {
+	root, err := s.git.RootDir(ctx)
+	if err != nil || root == "" {
+		return ""
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	// git reports the root with symlinks resolved.
+	if resolved, err := filepath.EvalSymlinks(cwd); err == nil {
+		cwd = resolved
+	}
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+	rel, err := filepath.Rel(root, cwd)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
+
+// countRedactions is the number of secrets the redactor replaced.
+func countRedactions(before, after string) int {
+	const marker = "[REDACTED]"
+	return strings.Count(after, marker) - strings.Count(before, marker)
+}
@@ -0,0 +1,65 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/chuckie/commit-coach/internal/domain"
+)
+
+// CommitSummary is the pre-flight view of what a commit will contain.
+type CommitSummary struct {
+	Message  string
+	Branch   string
+	Files    []FileStat
+	Warnings []string
+}
+
+// PreviewDiff returns the raw diff the suggestions are generated from, for
+// display: the staged diff (limited by SetPaths), or the working-tree diff
+// when nothing is staged and SetIncludeUnstaged is on. Nothing is capped,
+// excluded or redacted.
+func (s *SuggestService) PreviewDiff(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	diff, _, err := s.readDiff(ctx)
+	return diff, err
+}
+
+// Summarize gathers the branch, diffstat and warnings (lint violations,
+// redacted secrets) for committing suggestion. The diff is read the way
+// suggestions read it: limited by SetPaths, falling back to the working
+// tree, and without excluded files.
+func (s *SuggestService) Summarize(ctx context.Context, suggestion domain.Suggestion) (*CommitSummary, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	diff, _, err := s.readDiff(ctx)
+	if err != nil {
+		return nil, err
+	}
+	diff, _ = excludeFiles(diff, s.exclude)
+
+	summary := &CommitSummary{
+		Message: suggestion.Format(),
+		Files:   DiffStat(diff),
+	}
+
+	// Branch is informational; an unborn or unreadable HEAD should not block the summary.
+	if branch, err := s.git.CurrentBranch(ctx); err == nil {
+		summary.Branch = branch
+	}
+
+	for _, violation := range suggestion.LintWith(s.subjectLimits) {
+		summary.Warnings = append(summary.Warnings, "lint: "+violation.Error())
+	}
+
+	if n := countRedactions(diff, s.redactor.Redact(diff)); n > 0 && s.noRedact {
+		summary.Warnings = append(summary.Warnings, fmt.Sprintf("redaction is off: %d possible secret(s) were sent to the LLM", n))
+	} else if n > 0 {
+		summary.Warnings = append(summary.Warnings, fmt.Sprintf("redacted %d secret(s) from the diff sent to the LLM", n))
+	}
+
+	return summary, nil
+}
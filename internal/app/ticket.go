@@ -0,0 +1,53 @@
+package app
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/chuckie/commit-coach/internal/domain"
+)
+
+// TicketFromBranch returns the first match of pattern in branch, or "" when
+// there is none, HEAD is detached or pattern is nil.
+func TicketFromBranch(branch string, pattern *regexp.Regexp) string {
+	if pattern == nil || branch == "" || branch == "HEAD" {
+		return ""
+	}
+	return pattern.FindString(branch)
+}
+
+// SetTicketPattern makes Commit add a "Refs: <ticket>" footer when pattern
+// matches the current branch name. nil (the default) turns this off.
+func (c *CommitService) SetTicketPattern(pattern *regexp.Regexp) {
+	c.ticketPattern = pattern
+}
+
+// addTicketRef applies SetTicketPattern to message. Failing to read the
+// branch leaves the message alone, as do "fixup!" and "squash!" commits,
+// which rebase --autosquash folds into a commit that has its own footer.
+func (c *CommitService) addTicketRef(ctx context.Context, message string) string {
+	if c.ticketPattern == nil || strings.HasPrefix(message, "fixup! ") || strings.HasPrefix(message, "squash! ") {
+		return message
+	}
+	branch, err := c.git.CurrentBranch(ctx)
+	if err != nil {
+		return message
+	}
+	return withTicketRef(message, TicketFromBranch(branch, c.ticketPattern))
+}
+
+// withTicketRef appends "Refs: <ticket>" to message, joining an existing
+// footer paragraph if there is one. A message that already mentions the
+// ticket is returned unchanged.
+func withTicketRef(message, ticket string) string {
+	if ticket == "" || strings.Contains(message, ticket) {
+		return message
+	}
+	message = strings.TrimRight(message, "\n")
+	ref := "Refs: " + ticket
+	if domain.ParseMessage(message).Footer != "" {
+		return message + "\n" + ref
+	}
+	return message + "\n\n" + ref
+}
@@ -4,8 +4,16 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"regexp"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/chuckie/commit-coach/internal/domain"
+	"github.com/chuckie/commit-coach/internal/ports"
+	"github.com/chuckie/commit-coach/internal/security"
 )
 
 // Config holds all application configuration.
@@ -21,28 +29,162 @@ type Config struct {
 	DryRun      bool
 	Redact      bool
 	UseCache    bool
+	// OmitAPIKey makes SaveToFile leave APIKey out of the config file; the key
+	// must then come from the provider's env var at runtime.
+	OmitAPIKey bool
+	// SuggestCount is how many suggestions to request (clamped to 1–10).
+	SuggestCount int
+	// CommitUseMessageFlag commits single-line messages with -m instead of -F
+	// (off by default).
+	CommitUseMessageFlag bool
+	// RetryEmpty regenerates once when the model returns empty output.
+	RetryEmpty bool
+	// FewShotExamples are example commit messages shown to the model so it
+	// mimics the team's style. FewShotExamplesFile adds more from a file,
+	// one example per block separated by "---" lines.
+	FewShotExamples     []string
+	FewShotExamplesFile string
+	// SubmoduleContext shows submodule changes as commit logs in the diff.
+	SubmoduleContext bool
+	// IncludeIntentToAdd adds files staged with "git add -N" to the diff.
+	IncludeIntentToAdd bool
+	// GroqAllowHighTemp sends temperatures above 0.2 to Groq by turning off
+	// JSON mode, at some cost in parse reliability.
+	GroqAllowHighTemp bool
+	// RedactPatterns are extra regular expressions scrubbed from diffs in
+	// addition to the built-in secret patterns.
+	RedactPatterns []string
+	// MaxFiles switches to a stat summary plus the largest files when more
+	// files than this are staged; 0, the default, disables it.
+	MaxFiles int
+	// SubjectMaxLen is the subject length limit (default 72); it is also the
+	// limit the model is asked to respect.
+	SubjectMaxLen int
+	// TypeSubjectLimits overrides the subject length limit for specific
+	// commit types, e.g. {"revert": 100}; other types keep the global limit.
+	TypeSubjectLimits map[string]int
+	// SummarizeLargeHunks replaces binary files and very long hunks with short
+	// placeholders instead of dropping binaries and relying on DiffCap alone.
+	// Off by default.
+	SummarizeLargeHunks bool
+	// DefaultSelection picks the suggestion highlighted first in the TUI:
+	// "first" (or empty) for the first one, "best" for the highest-scoring.
+	DefaultSelection string
+	// MultiProviders lists the "provider:model" members asked at once when
+	// Provider is "multi", e.g. ["openai:gpt-4o-mini", "groq:llama-3.1-8b-instant"].
+	// Each member's API key comes from its env var (OPENAI_API_KEY, ...).
+	MultiProviders []string
+	// SignCommits passes -S to git commit. SigningKey, when set, picks the
+	// key (--gpg-sign=<key>); otherwise git's user.signingkey is used. The
+	// signing format (gpg.format, e.g. ssh) always comes from git config.
+	SignCommits bool
+	SigningKey  string
+	// SubjectKeepCase stops suggestions' subjects from having their first
+	// letter lowercased. StrictSubjectStyle also rewrites non-imperative
+	// first verbs ("added" becomes "add").
+	SubjectKeepCase    bool
+	StrictSubjectStyle bool
+	// SystemPrompt replaces the default system message ("You are an expert
+	// git commit message writer."); the JSON-only instruction is always
+	// appended. SystemPrompts overrides it per provider, e.g.
+	// {"ollama": "..."}.
+	SystemPrompt  string
+	SystemPrompts map[string]string
+	// RequestTimeout bounds each suggestion request, in seconds (default 90).
+	RequestTimeout int
+	// GitTimeout bounds each git command, in seconds (default 10).
+	// GitCommitTimeout bounds git commit, which runs the commit hooks; 0
+	// (the default) lets it run until the hooks finish.
+	GitTimeout       int
+	GitCommitTimeout int
+	// CABundle is a PEM file of extra CA certificates trusted for provider
+	// requests, e.g. a corporate proxy's. InsecureSkipVerify turns off
+	// certificate checks entirely, for internal endpoints only. Proxies come
+	// from HTTP_PROXY/HTTPS_PROXY as usual.
+	CABundle           string
+	InsecureSkipVerify bool
+	// PromptTemplate replaces the suggestion prompt: a text/template file
+	// path, or the template itself. It is rendered with .Diff, .FileList,
+	// .RecentSubjects (latest commit subjects), .ExampleSubjects, .Branch,
+	// .Count, .Types, .SubjectMaxLen and .Context (the examples, branch and
+	// notes blocks of the default prompt).
+	PromptTemplate string
+	// TicketFromBranch adds a "Refs: <ticket>" footer on commit when
+	// TicketPattern (default `[A-Z]+-\d+`) matches the branch name, e.g.
+	// JIRA-1234 on feature/JIRA-1234-thing.
+	TicketFromBranch bool
+	TicketPattern    string
+	// Keybindings remaps TUI list actions to comma-separated keys, e.g.
+	// {"commit": "c", "first": "gg"}; see ui.KeyMap for the action names.
+	Keybindings map[string]string
+}
+
+// DefaultTicketPattern matches issue keys such as JIRA-1234.
+const DefaultTicketPattern = `[A-Z]+-\d+`
+
+// Default selection strategies.
+const (
+	SelectionFirst = "first"
+	SelectionBest  = "best"
+)
+
+// Defaults returns a Config populated with built-in default values.
+func Defaults() *Config {
+	return &Config{
+		Provider:         "openai",
+		APIKey:           "",
+		Model:            "gpt-4o-mini",
+		Temperature:      0.7,
+		BaseURL:          "",
+		OllamaURL:        "http://localhost:11434",
+		DiffCap:          8192,
+		ConfirmSend:      true,
+		DryRun:           false,
+		Redact:           true,
+		UseCache:         true,
+		OmitAPIKey:       false,
+		SuggestCount:     3,
+		SubjectMaxLen:    domain.DefaultSubjectMaxLen,
+		TicketPattern:    DefaultTicketPattern,
+		RequestTimeout:   int(ports.DefaultRequestTimeout / time.Second),
+		GitTimeout:       int(ports.DefaultGitTimeout / time.Second),
+		DefaultSelection: SelectionFirst,
+	}
 }
 
 // Load loads configuration with precedence:
 // environment variables → config file → defaults.
 func Load() (*Config, error) {
-	// 1) Defaults
-	cfg := &Config{
-		Provider:    "openai",
-		APIKey:      "",
-		Model:       "gpt-4o-mini",
-		Temperature: 0.7,
-		BaseURL:     "",
-		OllamaURL:   "http://localhost:11434",
-		DiffCap:     8192,
-		ConfirmSend: true,
-		DryRun:      false,
-		Redact:      true,
-		UseCache:    true,
+	path, _ := DefaultConfigPath() // empty path skips the file layer
+	return LoadWithFile(path)
+}
+
+// LoadWithFile is like Load but reads the config file at path instead of the
+// default location.
+func LoadWithFile(path string) (*Config, error) {
+	cfg := Resolve(path)
+
+	for _, check := range Check(cfg) {
+		if check.Err == nil {
+			continue
+		}
+		if IsSetupRequired(check.Err) {
+			return cfg, check.Err
+		}
+		return nil, check.Err
 	}
 
+	return cfg, nil
+}
+
+// Resolve merges defaults, the config file at path (best-effort) and env
+// overrides without validating the result.
+func Resolve(path string) *Config {
+	// 1) Defaults
+	cfg := Defaults()
+
 	// 2) Config file (best-effort)
-	if path, err := DefaultConfigPath(); err == nil {
+	if path != "" {
 		if fileCfg, err := LoadFromFile(path); err == nil && fileCfg != nil {
 			applyPartialConfig(cfg, fileCfg)
 		}
@@ -79,6 +221,69 @@ func Load() (*Config, error) {
 	if _, ok := os.LookupEnv("ENABLE_CACHE"); ok {
 		cfg.UseCache = getEnvBool("ENABLE_CACHE", cfg.UseCache)
 	}
+	if _, ok := os.LookupEnv("SUGGEST_COUNT"); ok {
+		cfg.SuggestCount = getEnvInt("SUGGEST_COUNT", cfg.SuggestCount)
+	}
+	if _, ok := os.LookupEnv("MAX_FILES"); ok {
+		cfg.MaxFiles = getEnvInt("MAX_FILES", cfg.MaxFiles)
+	}
+	if _, ok := os.LookupEnv("SUMMARIZE_LARGE_HUNKS"); ok {
+		cfg.SummarizeLargeHunks = getEnvBool("SUMMARIZE_LARGE_HUNKS", cfg.SummarizeLargeHunks)
+	}
+	if v, ok := os.LookupEnv("DEFAULT_SELECTION"); ok && v != "" {
+		cfg.DefaultSelection = v
+	}
+	if v, ok := os.LookupEnv("MULTI_PROVIDERS"); ok && v != "" {
+		cfg.MultiProviders = strings.Split(v, ",")
+	}
+	if _, ok := os.LookupEnv("SIGN_COMMITS"); ok {
+		cfg.SignCommits = getEnvBool("SIGN_COMMITS", cfg.SignCommits)
+	}
+	if v, ok := os.LookupEnv("SIGNING_KEY"); ok {
+		cfg.SigningKey = v
+	}
+	if _, ok := os.LookupEnv("SUBJECT_MAX_LEN"); ok {
+		cfg.SubjectMaxLen = getEnvInt("SUBJECT_MAX_LEN", cfg.SubjectMaxLen)
+	}
+	if v, ok := os.LookupEnv("LLM_SYSTEM_PROMPT"); ok {
+		cfg.SystemPrompt = v
+	}
+	if _, ok := os.LookupEnv("LLM_TIMEOUT_SECONDS"); ok {
+		cfg.RequestTimeout = getEnvInt("LLM_TIMEOUT_SECONDS", cfg.RequestTimeout)
+	}
+	if _, ok := os.LookupEnv("GIT_TIMEOUT_SECONDS"); ok {
+		cfg.GitTimeout = getEnvInt("GIT_TIMEOUT_SECONDS", cfg.GitTimeout)
+	}
+	if _, ok := os.LookupEnv("GIT_COMMIT_TIMEOUT_SECONDS"); ok {
+		cfg.GitCommitTimeout = getEnvInt("GIT_COMMIT_TIMEOUT_SECONDS", cfg.GitCommitTimeout)
+	}
+	if v, ok := os.LookupEnv("LLM_CA_BUNDLE"); ok {
+		cfg.CABundle = v
+	}
+	if _, ok := os.LookupEnv("LLM_INSECURE_SKIP_VERIFY"); ok {
+		cfg.InsecureSkipVerify = getEnvBool("LLM_INSECURE_SKIP_VERIFY", cfg.InsecureSkipVerify)
+	}
+	if v, ok := os.LookupEnv("LLM_PROMPT_TEMPLATE"); ok {
+		cfg.PromptTemplate = v
+	}
+	if _, ok := os.LookupEnv("TICKET_FROM_BRANCH"); ok {
+		cfg.TicketFromBranch = getEnvBool("TICKET_FROM_BRANCH", cfg.TicketFromBranch)
+	}
+	if v, ok := os.LookupEnv("TICKET_PATTERN"); ok && v != "" {
+		cfg.TicketPattern = v
+	}
+	if _, ok := os.LookupEnv("SUBJECT_KEEP_CASE"); ok {
+		cfg.SubjectKeepCase = getEnvBool("SUBJECT_KEEP_CASE", cfg.SubjectKeepCase)
+	}
+	if _, ok := os.LookupEnv("STRICT_SUBJECT_STYLE"); ok {
+		cfg.StrictSubjectStyle = getEnvBool("STRICT_SUBJECT_STYLE", cfg.StrictSubjectStyle)
+	}
+	if _, ok := os.LookupEnv("GROQ_ALLOW_HIGH_TEMP"); ok {
+		cfg.GroqAllowHighTemp = getEnvBool("GROQ_ALLOW_HIGH_TEMP", cfg.GroqAllowHighTemp)
+	}
+	if _, ok := os.LookupEnv("STORE_API_KEY"); ok {
+		cfg.OmitAPIKey = !getEnvBool("STORE_API_KEY", !cfg.OmitAPIKey)
+	}
 
 	// Provider-specific API keys:
 	// - If env var exists (even empty), it wins.
@@ -96,34 +301,279 @@ func Load() (*Config, error) {
 		if _, ok := os.LookupEnv("GROQ_API_KEY"); ok {
 			cfg.APIKey = getEnv("GROQ_API_KEY", "")
 		}
+	case "mistral":
+		if _, ok := os.LookupEnv("MISTRAL_API_KEY"); ok {
+			cfg.APIKey = getEnv("MISTRAL_API_KEY", "")
+		}
+	case "openai-compatible":
+		// Optional: local servers usually run without auth.
+		if _, ok := os.LookupEnv("OPENAI_COMPATIBLE_API_KEY"); ok {
+			cfg.APIKey = getEnv("OPENAI_COMPATIBLE_API_KEY", "")
+		}
 	case "mock":
 		cfg.APIKey = "mock"
 	case "ollama":
 		cfg.APIKey = "ollama"
 	}
 
-	// Validate
-	if cfg.Provider != "openai" && cfg.Provider != "anthropic" && cfg.Provider != "groq" && cfg.Provider != "mock" && cfg.Provider != "ollama" {
-		return nil, fmt.Errorf("invalid provider: %s (must be 'openai', 'anthropic', 'groq', 'mock', or 'ollama')", cfg.Provider)
+	return cfg
+}
+
+// SubjectLimits returns the subject length limits for validation.
+func (c *Config) SubjectLimits() domain.SubjectLimits {
+	return domain.SubjectLimits{Max: c.SubjectMaxLen, ByType: c.TypeSubjectLimits}
+}
+
+// SubjectStyle returns how suggestion subjects are normalized.
+func (c *Config) SubjectStyle() domain.SubjectStyle {
+	return domain.SubjectStyle{KeepCase: c.SubjectKeepCase, Strict: c.StrictSubjectStyle}
+}
+
+// SystemPromptFor returns the system prompt for provider: its SystemPrompts
+// entry if any, else SystemPrompt.
+func (c *Config) SystemPromptFor(provider string) string {
+	if p, ok := c.SystemPrompts[provider]; ok {
+		return p
+	}
+	return c.SystemPrompt
+}
+
+// RequestTimeoutDuration returns RequestTimeout as a time.Duration.
+func (c *Config) RequestTimeoutDuration() time.Duration {
+	return time.Duration(c.RequestTimeout) * time.Second
+}
+
+// GitTimeoutDuration returns GitTimeout as a time.Duration.
+func (c *Config) GitTimeoutDuration() time.Duration {
+	return time.Duration(c.GitTimeout) * time.Second
+}
+
+// GitCommitTimeoutDuration returns GitCommitTimeout as a time.Duration; 0
+// means no limit.
+func (c *Config) GitCommitTimeoutDuration() time.Duration {
+	return time.Duration(c.GitCommitTimeout) * time.Second
+}
+
+// PromptTemplateText returns the PromptTemplate contents: the file it
+// names if there is one, else PromptTemplate itself.
+func (c *Config) PromptTemplateText() (string, error) {
+	if c.PromptTemplate == "" {
+		return "", nil
+	}
+	if info, err := os.Stat(c.PromptTemplate); err == nil && !info.IsDir() {
+		b, err := os.ReadFile(c.PromptTemplate)
+		if err != nil {
+			return "", fmt.Errorf("failed to read prompt template: %w", err)
+		}
+		return string(b), nil
+	}
+	return c.PromptTemplate, nil
+}
+
+// TicketRegexp returns the compiled TicketPattern, or nil when
+// TicketFromBranch is off or the pattern does not compile (Check reports
+// that).
+func (c *Config) TicketRegexp() *regexp.Regexp {
+	if !c.TicketFromBranch {
+		return nil
+	}
+	re, err := regexp.Compile(c.TicketPattern)
+	if err != nil {
+		return nil
+	}
+	return re
+}
+
+// Examples returns FewShotExamples followed by the examples read from
+// FewShotExamplesFile, if set.
+func (c *Config) Examples() ([]string, error) {
+	examples := append([]string(nil), c.FewShotExamples...)
+	if c.FewShotExamplesFile == "" {
+		return examples, nil
+	}
+	fromFile, err := ReadExamplesFile(c.FewShotExamplesFile)
+	if err != nil {
+		return nil, err
+	}
+	return append(examples, fromFile...), nil
+}
+
+// ReadExamplesFile reads example commit messages separated by "---" lines.
+func ReadExamplesFile(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read examples file: %w", err)
+	}
+	var examples []string
+	var current []string
+	flush := func() {
+		if ex := strings.TrimSpace(strings.Join(current, "\n")); ex != "" {
+			examples = append(examples, ex)
+		}
+		current = nil
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		if strings.TrimSpace(line) == "---" {
+			flush()
+			continue
+		}
+		current = append(current, line)
 	}
+	flush()
+	return examples, nil
+}
+
+// FallbackMockEnv opts into using the mock provider when no API key is
+// configured, instead of failing with ErrSetupRequired.
+const FallbackMockEnv = "COMMIT_COACH_FALLBACK_MOCK"
+
+// FallbackToMockEnabled reports whether FallbackMockEnv is set to a truthy value.
+func FallbackToMockEnabled() bool {
+	return getEnvBool(FallbackMockEnv, false)
+}
+
+// UseMock switches cfg to the mock provider in place.
+func UseMock(cfg *Config) {
+	cfg.Provider = "mock"
+	cfg.Model = "mock"
+	cfg.APIKey = "mock"
+}
 
-	if (cfg.Provider == "openai" || cfg.Provider == "groq" || cfg.Provider == "anthropic") && cfg.APIKey == "" {
+// FieldCheck is the validation outcome for a single config field.
+type FieldCheck struct {
+	Field string
+	Err   error
+}
+
+// Check validates cfg field by field, in the order Load reports errors.
+// Every check runs so callers can report all problems at once.
+func Check(cfg *Config) []FieldCheck {
+	checks := []FieldCheck{
+		{Field: "provider"},
+		{Field: "api-key"},
+		{Field: "temperature"},
+		{Field: "diff-cap"},
+		{Field: "redact-patterns"},
+		{Field: "default-selection"},
+		{Field: "type-subject-limits"},
+		{Field: "multi-providers"},
+		{Field: "subject-max-len"},
+		{Field: "ticket-pattern"},
+		{Field: "prompt-template"},
+		{Field: "request-timeout"},
+		{Field: "git-timeout"},
+	}
+
+	if cfg.Provider != "openai" && cfg.Provider != "anthropic" && cfg.Provider != "groq" && cfg.Provider != "mistral" && cfg.Provider != "openai-compatible" && cfg.Provider != "mock" && cfg.Provider != "ollama" && cfg.Provider != "multi" {
+		checks[0].Err = fmt.Errorf("invalid provider: %s (must be 'openai', 'anthropic', 'groq', 'mistral', 'openai-compatible', 'mock', 'ollama', or 'multi')", cfg.Provider)
+	}
+
+	if cfg.Provider == "openai-compatible" && strings.TrimSpace(cfg.BaseURL) == "" {
+		checks[0].Err = fmt.Errorf("%w: provider openai-compatible needs the server's base URL; set OPENAI_BASE_URL (e.g. http://localhost:8080/v1)", ErrSetupRequired)
+	}
+
+	if (cfg.Provider == "openai" || cfg.Provider == "groq" || cfg.Provider == "mistral" || cfg.Provider == "anthropic") && cfg.APIKey == "" {
 		// Anthropic uses ANTHROPIC_API_KEY (not PROVIDER_API_KEY like openai/groq), so keep the hint explicit.
 		if cfg.Provider == "anthropic" {
-			return cfg, fmt.Errorf("%w: API key not found for provider anthropic; set ANTHROPIC_API_KEY env var", ErrSetupRequired)
+			checks[1].Err = fmt.Errorf("%w: API key not found for provider anthropic; set ANTHROPIC_API_KEY env var", ErrSetupRequired)
+		} else {
+			checks[1].Err = fmt.Errorf("%w: API key not found for provider %s; set %s_API_KEY env var", ErrSetupRequired, cfg.Provider, strings.ToUpper(cfg.Provider))
 		}
-		return cfg, fmt.Errorf("%w: API key not found for provider %s; set %s_API_KEY env var", ErrSetupRequired, cfg.Provider, strings.ToUpper(cfg.Provider))
 	}
 
 	if cfg.Temperature < 0 || cfg.Temperature > 2 {
-		return nil, fmt.Errorf("temperature must be between 0 and 2, got %.2f", cfg.Temperature)
+		checks[2].Err = fmt.Errorf("temperature must be between 0 and 2, got %.2f", cfg.Temperature)
 	}
 
 	if cfg.DiffCap <= 0 {
-		return nil, fmt.Errorf("diff cap must be positive, got %d", cfg.DiffCap)
+		checks[3].Err = fmt.Errorf("diff cap must be positive, got %d", cfg.DiffCap)
 	}
 
-	return cfg, nil
+	if _, err := security.CompilePatterns(cfg.RedactPatterns); err != nil {
+		checks[4].Err = err
+	}
+
+	switch cfg.DefaultSelection {
+	case "", SelectionFirst, SelectionBest:
+	default:
+		checks[5].Err = fmt.Errorf("default selection must be %q or %q, got %q", SelectionFirst, SelectionBest, cfg.DefaultSelection)
+	}
+
+	for _, t := range sortedKeys(cfg.TypeSubjectLimits) {
+		if n := cfg.TypeSubjectLimits[t]; n <= 0 {
+			checks[6].Err = fmt.Errorf("subject limit for type %q must be positive, got %d", t, n)
+			break
+		}
+		if !slices.Contains(domain.ValidCommitTypes, t) {
+			checks[6].Err = fmt.Errorf("subject limit set for unknown commit type %q", t)
+			break
+		}
+	}
+
+	if cfg.Provider == "multi" {
+		checks[7].Err = checkMultiProviders(cfg.MultiProviders)
+	}
+
+	if cfg.SubjectMaxLen <= 0 {
+		checks[8].Err = fmt.Errorf("subject max length must be positive, got %d", cfg.SubjectMaxLen)
+	}
+
+	if cfg.TicketFromBranch {
+		if _, err := regexp.Compile(cfg.TicketPattern); err != nil {
+			checks[9].Err = fmt.Errorf("invalid ticket pattern: %w", err)
+		}
+	}
+
+	if text, err := cfg.PromptTemplateText(); err != nil {
+		checks[10].Err = err
+	} else if text != "" {
+		if err := ports.ValidatePromptTemplate(text); err != nil {
+			checks[10].Err = fmt.Errorf("invalid prompt template: %w", err)
+		}
+	}
+
+	if cfg.RequestTimeout <= 0 {
+		checks[11].Err = fmt.Errorf("request timeout must be a positive number of seconds, got %d", cfg.RequestTimeout)
+	}
+
+	if cfg.GitTimeout <= 0 {
+		checks[12].Err = fmt.Errorf("git timeout must be a positive number of seconds, got %d", cfg.GitTimeout)
+	} else if cfg.GitCommitTimeout < 0 {
+		checks[12].Err = fmt.Errorf("git commit timeout must be 0 (no limit) or a positive number of seconds, got %d", cfg.GitCommitTimeout)
+	}
+
+	return checks
+}
+
+// checkMultiProviders validates the "provider:model" members of the multi
+// provider, including that each member's API key env var is set.
+func checkMultiProviders(specs []string) error {
+	if len(specs) == 0 {
+		return fmt.Errorf("provider multi needs MultiProviders, e.g. [\"openai:gpt-4o-mini\", \"groq:llama-3.1-8b-instant\"]")
+	}
+	for _, spec := range specs {
+		provider, model, ok := strings.Cut(strings.TrimSpace(spec), ":")
+		switch {
+		case !ok || model == "":
+			return fmt.Errorf("invalid multi provider entry %q (want provider:model)", spec)
+		case provider != "openai" && provider != "anthropic" && provider != "groq" && provider != "mistral" && provider != "mock" && provider != "ollama":
+			return fmt.Errorf("invalid provider %q in multi provider entry %q", provider, spec)
+		}
+		if env := APIKeyEnvVar(provider); env != "" && os.Getenv(env) == "" {
+			return fmt.Errorf("multi provider member %s needs %s to be set", provider, env)
+		}
+	}
+	return nil
+}
+
+// sortedKeys returns m's keys in order, so validation errors are stable.
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 func applyPartialConfig(dst *Config, src *PartialConfig) {
@@ -163,6 +613,110 @@ func applyPartialConfig(dst *Config, src *PartialConfig) {
 	if src.UseCache != nil {
 		dst.UseCache = *src.UseCache
 	}
+	if src.OmitAPIKey != nil {
+		dst.OmitAPIKey = *src.OmitAPIKey
+	}
+	if src.SuggestCount != nil {
+		dst.SuggestCount = *src.SuggestCount
+	}
+	if src.CommitUseMessageFlag != nil {
+		dst.CommitUseMessageFlag = *src.CommitUseMessageFlag
+	}
+	if src.RetryEmpty != nil {
+		dst.RetryEmpty = *src.RetryEmpty
+	}
+	if src.FewShotExamples != nil {
+		dst.FewShotExamples = src.FewShotExamples
+	}
+	if src.FewShotExamplesFile != nil {
+		dst.FewShotExamplesFile = *src.FewShotExamplesFile
+	}
+	if src.SubmoduleContext != nil {
+		dst.SubmoduleContext = *src.SubmoduleContext
+	}
+	if src.IncludeIntentToAdd != nil {
+		dst.IncludeIntentToAdd = *src.IncludeIntentToAdd
+	}
+	if src.GroqAllowHighTemp != nil {
+		dst.GroqAllowHighTemp = *src.GroqAllowHighTemp
+	}
+	if src.MultiProviders != nil {
+		dst.MultiProviders = src.MultiProviders
+	}
+	if src.SignCommits != nil {
+		dst.SignCommits = *src.SignCommits
+	}
+	if src.SigningKey != nil {
+		dst.SigningKey = *src.SigningKey
+	}
+	if src.RedactPatterns != nil {
+		dst.RedactPatterns = src.RedactPatterns
+	}
+	if src.MaxFiles != nil {
+		dst.MaxFiles = *src.MaxFiles
+	}
+	if src.TypeSubjectLimits != nil {
+		dst.TypeSubjectLimits = src.TypeSubjectLimits
+	}
+	if src.SummarizeLargeHunks != nil {
+		dst.SummarizeLargeHunks = *src.SummarizeLargeHunks
+	}
+	if src.DefaultSelection != nil {
+		dst.DefaultSelection = *src.DefaultSelection
+	}
+	if src.Keybindings != nil {
+		dst.Keybindings = src.Keybindings
+	}
+	if src.SubjectMaxLen != nil {
+		dst.SubjectMaxLen = *src.SubjectMaxLen
+	}
+	if src.SystemPrompt != nil {
+		dst.SystemPrompt = *src.SystemPrompt
+	}
+	if src.SystemPrompts != nil {
+		dst.SystemPrompts = src.SystemPrompts
+	}
+	if src.RequestTimeout != nil {
+		dst.RequestTimeout = *src.RequestTimeout
+	}
+	if src.GitTimeout != nil {
+		dst.GitTimeout = *src.GitTimeout
+	}
+	if src.GitCommitTimeout != nil {
+		dst.GitCommitTimeout = *src.GitCommitTimeout
+	}
+	if src.CABundle != nil {
+		dst.CABundle = *src.CABundle
+	}
+	if src.InsecureSkipVerify != nil {
+		dst.InsecureSkipVerify = *src.InsecureSkipVerify
+	}
+	if src.PromptTemplate != nil {
+		dst.PromptTemplate = *src.PromptTemplate
+	}
+	if src.TicketFromBranch != nil {
+		dst.TicketFromBranch = *src.TicketFromBranch
+	}
+	if src.TicketPattern != nil {
+		dst.TicketPattern = *src.TicketPattern
+	}
+	if src.SubjectKeepCase != nil {
+		dst.SubjectKeepCase = *src.SubjectKeepCase
+	}
+	if src.StrictSubjectStyle != nil {
+		dst.StrictSubjectStyle = *src.StrictSubjectStyle
+	}
+}
+
+// APIKeyEnvVar returns the env var that supplies the API key for provider,
+// or "" when the provider doesn't need one.
+func APIKeyEnvVar(provider string) string {
+	switch provider {
+	case "openai", "anthropic", "groq", "mistral":
+		return strings.ToUpper(provider) + "_API_KEY"
+	default:
+		return ""
+	}
 }
 
 // IsSetupRequired returns true when err indicates we should prompt for config.
@@ -2,7 +2,10 @@ package config
 
 import (
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func isolateUserConfigDir(t *testing.T) {
@@ -124,6 +127,39 @@ func TestConfigLoadAnthropic(t *testing.T) {
 	}
 }
 
+func TestConfigLoadMistral(t *testing.T) {
+	isolateUserConfigDir(t)
+	t.Setenv("LLM_PROVIDER", "mistral")
+	t.Setenv("LLM_MODEL", "codestral-latest")
+	t.Setenv("MISTRAL_API_KEY", "")
+
+	if _, err := Load(); !IsSetupRequired(err) {
+		t.Errorf("Load() without MISTRAL_API_KEY error = %v, want setup required", err)
+	}
+	t.Setenv("MISTRAL_API_KEY", "mistral-test")
+	cfg, err := Load()
+	if err != nil || cfg.APIKey != "mistral-test" {
+		t.Fatalf("Load() = %+v, %v; want the key from MISTRAL_API_KEY", cfg, err)
+	}
+}
+
+func TestConfigLoadOpenAICompatible(t *testing.T) {
+	isolateUserConfigDir(t)
+	t.Setenv("LLM_PROVIDER", "openai-compatible")
+	t.Setenv("LLM_MODEL", "local-model")
+	t.Setenv("OPENAI_BASE_URL", "")
+	t.Setenv("OPENAI_COMPATIBLE_API_KEY", "")
+
+	if _, err := Load(); !IsSetupRequired(err) {
+		t.Errorf("Load() without OPENAI_BASE_URL error = %v, want setup required", err)
+	}
+	t.Setenv("OPENAI_BASE_URL", "http://localhost:8080/v1")
+	cfg, err := Load()
+	if err != nil || cfg.APIKey != "" || cfg.BaseURL != "http://localhost:8080/v1" {
+		t.Fatalf("Load() = %+v, %v; want no key required and the base URL kept", cfg, err)
+	}
+}
+
 func TestConfigDefaults(t *testing.T) {
 	isolateUserConfigDir(t)
 
@@ -162,3 +198,292 @@ func TestConfigDefaults(t *testing.T) {
 		t.Error("Default redact should be true")
 	}
 }
+
+func TestCheckReportsEveryField(t *testing.T) {
+	cfg := Defaults()
+	cfg.Provider = "groq"
+	cfg.APIKey = ""
+	cfg.Temperature = 3
+	cfg.DiffCap = 0
+	cfg.DefaultSelection = "random"
+
+	failed := map[string]bool{}
+	for _, c := range Check(cfg) {
+		if c.Err != nil {
+			failed[c.Field] = true
+		}
+	}
+
+	if failed["provider"] {
+		t.Error("provider groq should pass")
+	}
+	for _, field := range []string{"api-key", "temperature", "diff-cap", "default-selection"} {
+		if !failed[field] {
+			t.Errorf("expected %s to fail", field)
+		}
+	}
+}
+
+func TestLoadWithFile(t *testing.T) {
+	isolateUserConfigDir(t)
+	os.Unsetenv("LLM_PROVIDER")
+	os.Unsetenv("LLM_MODEL")
+	os.Unsetenv("LLM_TEMPERATURE")
+
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.json")
+	if err := os.WriteFile(good, []byte(`{"Provider":"mock","Model":"mock"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadWithFile(good)
+	if err != nil {
+		t.Fatalf("LoadWithFile(good) error = %v", err)
+	}
+	if cfg.Provider != "mock" {
+		t.Errorf("Provider = %s, want mock", cfg.Provider)
+	}
+
+	bad := filepath.Join(dir, "bad.json")
+	if err := os.WriteFile(bad, []byte(`{"Provider":"mock","Temperature":5}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadWithFile(bad); err == nil {
+		t.Error("LoadWithFile(bad) should reject out-of-range temperature")
+	}
+}
+
+func TestLoadRejectsInvalidRedactPattern(t *testing.T) {
+	isolateUserConfigDir(t)
+	os.Unsetenv("LLM_PROVIDER")
+
+	path, err := DefaultConfigPath()
+	if err != nil {
+		t.Fatalf("DefaultConfigPath() error = %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(`{"Provider":"mock","RedactPatterns":["acme_sk_[a-z0-9"]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Load()
+	if err == nil || !strings.Contains(err.Error(), "invalid redact pattern") {
+		t.Errorf("Load() error = %v, want invalid redact pattern", err)
+	}
+}
+
+func TestExamplesFromConfigAndFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "examples.txt")
+	content := "feat(parser): support nested lists\n\nRefs: PAR-12\n---\nfix(cli): exit 2 on unknown flags\n---\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &Config{FewShotExamples: []string{"docs: explain setup"}, FewShotExamplesFile: path}
+	examples, err := cfg.Examples()
+	if err != nil {
+		t.Fatalf("Examples() error = %v", err)
+	}
+	want := []string{
+		"docs: explain setup",
+		"feat(parser): support nested lists\n\nRefs: PAR-12",
+		"fix(cli): exit 2 on unknown flags",
+	}
+	if len(examples) != len(want) {
+		t.Fatalf("Examples() = %q, want %q", examples, want)
+	}
+	for i := range want {
+		if examples[i] != want[i] {
+			t.Errorf("Examples()[%d] = %q, want %q", i, examples[i], want[i])
+		}
+	}
+
+	cfg.FewShotExamplesFile = filepath.Join(t.TempDir(), "missing.txt")
+	if _, err := cfg.Examples(); err == nil {
+		t.Error("Examples() should fail for a missing file")
+	}
+}
+
+func TestCheckTypeSubjectLimits(t *testing.T) {
+	cfg := Defaults()
+	cfg.Provider = "mock"
+	cfg.TypeSubjectLimits = map[string]int{"revert": 100}
+	if err := checkErr(cfg, "type-subject-limits"); err != nil {
+		t.Errorf("valid limits rejected: %v", err)
+	}
+	if got := cfg.SubjectLimits().For("revert"); got != 100 {
+		t.Errorf("SubjectLimits().For(revert) = %d, want 100", got)
+	}
+
+	cfg.TypeSubjectLimits = map[string]int{"feature": 60}
+	if err := checkErr(cfg, "type-subject-limits"); err == nil {
+		t.Error("unknown commit type accepted")
+	}
+	cfg.TypeSubjectLimits = map[string]int{"revert": 0}
+	if err := checkErr(cfg, "type-subject-limits"); err == nil {
+		t.Error("non-positive limit accepted")
+	}
+}
+
+func TestSubjectMaxLen(t *testing.T) {
+	t.Setenv("SUBJECT_MAX_LEN", "50")
+	cfg := Resolve("")
+	if cfg.SubjectMaxLen != 50 {
+		t.Fatalf("SubjectMaxLen = %d, want 50 from SUBJECT_MAX_LEN", cfg.SubjectMaxLen)
+	}
+	if got := cfg.SubjectLimits().For("feat"); got != 50 {
+		t.Errorf("SubjectLimits().For(feat) = %d, want 50", got)
+	}
+	if err := checkErr(cfg, "subject-max-len"); err != nil {
+		t.Errorf("valid limit rejected: %v", err)
+	}
+	cfg.SubjectMaxLen = 0
+	if err := checkErr(cfg, "subject-max-len"); err == nil {
+		t.Error("zero limit accepted")
+	}
+}
+
+func TestSystemPromptFor(t *testing.T) {
+	cfg := Defaults()
+	cfg.SystemPrompt = "global"
+	cfg.SystemPrompts = map[string]string{"ollama": "local"}
+	if got := cfg.SystemPromptFor("ollama"); got != "local" {
+		t.Errorf("SystemPromptFor(ollama) = %q, want the override", got)
+	}
+	if got := cfg.SystemPromptFor("groq"); got != "global" {
+		t.Errorf("SystemPromptFor(groq) = %q, want SystemPrompt", got)
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	if got := Defaults().RequestTimeoutDuration(); got != 90*time.Second {
+		t.Errorf("default RequestTimeoutDuration() = %v, want 90s", got)
+	}
+	t.Setenv("LLM_TIMEOUT_SECONDS", "300")
+	cfg := Resolve("")
+	if got := cfg.RequestTimeoutDuration(); got != 300*time.Second {
+		t.Errorf("RequestTimeoutDuration() = %v, want 300s from LLM_TIMEOUT_SECONDS", got)
+	}
+	cfg.RequestTimeout = 0
+	if err := checkErr(cfg, "request-timeout"); err == nil {
+		t.Error("zero timeout accepted")
+	}
+}
+
+func TestDefaultsLeaveOptInsOff(t *testing.T) {
+	cfg := Defaults()
+	if cfg.SummarizeLargeHunks {
+		t.Error("SummarizeLargeHunks is on by default; binaries should still be dropped unless asked")
+	}
+	if cfg.MaxFiles != 0 {
+		t.Errorf("MaxFiles = %d by default, want 0 (off)", cfg.MaxFiles)
+	}
+	if cfg.CommitUseMessageFlag {
+		t.Error("CommitUseMessageFlag is on by default; commits should keep using -F unless asked")
+	}
+}
+
+func TestGitTimeout(t *testing.T) {
+	cfg := Defaults()
+	if got := cfg.GitTimeoutDuration(); got != 10*time.Second {
+		t.Errorf("default GitTimeoutDuration() = %v, want 10s", got)
+	}
+	if got := cfg.GitCommitTimeoutDuration(); got != 0 {
+		t.Errorf("default GitCommitTimeoutDuration() = %v, want 0 (no limit)", got)
+	}
+	t.Setenv("GIT_TIMEOUT_SECONDS", "30")
+	t.Setenv("GIT_COMMIT_TIMEOUT_SECONDS", "600")
+	cfg = Resolve("")
+	if got := cfg.GitTimeoutDuration(); got != 30*time.Second {
+		t.Errorf("GitTimeoutDuration() = %v, want 30s from GIT_TIMEOUT_SECONDS", got)
+	}
+	if got := cfg.GitCommitTimeoutDuration(); got != 10*time.Minute {
+		t.Errorf("GitCommitTimeoutDuration() = %v, want 10m from GIT_COMMIT_TIMEOUT_SECONDS", got)
+	}
+	cfg.GitCommitTimeout = -1
+	if err := checkErr(cfg, "git-timeout"); err == nil {
+		t.Error("negative commit timeout accepted")
+	}
+	cfg.GitCommitTimeout = 0
+	cfg.GitTimeout = 0
+	if err := checkErr(cfg, "git-timeout"); err == nil {
+		t.Error("zero git timeout accepted")
+	}
+}
+
+func TestPromptTemplate(t *testing.T) {
+	cfg := Defaults()
+	cfg.Provider = "mock"
+	if text, err := cfg.PromptTemplateText(); err != nil || text != "" {
+		t.Errorf("PromptTemplateText() = %q, %v; want empty by default", text, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "prompt.tmpl")
+	if err := os.WriteFile(path, []byte("Return JSON for {{.Diff}}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg.PromptTemplate = path
+	if text, err := cfg.PromptTemplateText(); err != nil || text != "Return JSON for {{.Diff}}" {
+		t.Errorf("PromptTemplateText() = %q, %v; want the file contents", text, err)
+	}
+	if err := checkErr(cfg, "prompt-template"); err != nil {
+		t.Errorf("valid template file rejected: %v", err)
+	}
+
+	cfg.PromptTemplate = "{{.Diff} inline"
+	if err := checkErr(cfg, "prompt-template"); err == nil {
+		t.Error("unparsable inline template accepted")
+	}
+}
+
+func TestTicketRegexp(t *testing.T) {
+	cfg := Defaults()
+	if cfg.TicketRegexp() != nil {
+		t.Error("TicketRegexp() should be nil while TicketFromBranch is off")
+	}
+	cfg.TicketFromBranch = true
+	if re := cfg.TicketRegexp(); re == nil || re.String() != DefaultTicketPattern {
+		t.Errorf("TicketRegexp() = %v, want the default pattern", re)
+	}
+	cfg.TicketPattern = "[A-Z+"
+	if err := checkErr(cfg, "ticket-pattern"); err == nil {
+		t.Error("invalid ticket pattern accepted")
+	}
+}
+
+func TestCheckMultiProviders(t *testing.T) {
+	t.Setenv("GROQ_API_KEY", "gsk_test")
+	t.Setenv("OPENAI_API_KEY", "")
+	cfg := Defaults()
+	cfg.Provider = "multi"
+	cfg.APIKey = ""
+
+	if err := checkErr(cfg, "multi-providers"); err == nil {
+		t.Error("multi without members accepted")
+	}
+	cfg.MultiProviders = []string{"groq:llama-3.1-8b-instant", "ollama:llama3"}
+	if err := checkErr(cfg, "provider"); err != nil {
+		t.Errorf("provider multi rejected: %v", err)
+	}
+	if err := checkErr(cfg, "multi-providers"); err != nil {
+		t.Errorf("valid members rejected: %v", err)
+	}
+	cfg.MultiProviders = append(cfg.MultiProviders, "openai:gpt-4o-mini")
+	if err := checkErr(cfg, "multi-providers"); err == nil || !strings.Contains(err.Error(), "OPENAI_API_KEY") {
+		t.Errorf("member without a key: err = %v, want a hint about OPENAI_API_KEY", err)
+	}
+	cfg.MultiProviders = []string{"groq"}
+	if err := checkErr(cfg, "multi-providers"); err == nil {
+		t.Error("entry without a model accepted")
+	}
+}
+
+func checkErr(cfg *Config, field string) error {
+	for _, c := range Check(cfg) {
+		if c.Field == field {
+			return c.Err
+		}
+	}
+	return nil
+}
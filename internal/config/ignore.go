@@ -0,0 +1,70 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/chuckie/commit-coach/internal/security"
+)
+
+// IgnoreFileName is the per-repository file, committed at the repo root,
+// that lists paths to keep out of the diff and extra patterns to redact.
+const IgnoreFileName = ".commit-coachignore"
+
+// IgnoreFile is a parsed .commit-coachignore. Lines are gitignore-style
+// exclude globs until a "redact:" line; after it each line is a regular
+// expression to redact. An "exclude:" line switches back. Blank lines and
+// lines starting with "#" are ignored.
+type IgnoreFile struct {
+	Exclude []string
+	Redact  []string
+}
+
+// LoadIgnoreFile reads IgnoreFileName from the repository root. A missing
+// file yields an empty IgnoreFile.
+func LoadIgnoreFile(root string) (*IgnoreFile, error) {
+	if root == "" {
+		return &IgnoreFile{}, nil
+	}
+	path := filepath.Join(root, IgnoreFileName)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &IgnoreFile{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	ignore, err := ParseIgnoreFile(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return ignore, nil
+}
+
+// ParseIgnoreFile parses the contents of a .commit-coachignore. Redact
+// patterns are compiled so a typo is reported with its line number.
+func ParseIgnoreFile(data string) (*IgnoreFile, error) {
+	ignore := &IgnoreFile{}
+	redact := false
+	for i, line := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case line == "redact:":
+			redact = true
+		case line == "exclude:":
+			redact = false
+		case redact:
+			if _, err := security.CompilePatterns([]string{line}); err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			ignore.Redact = append(ignore.Redact, line)
+		default:
+			ignore.Exclude = append(ignore.Exclude, line)
+		}
+	}
+	return ignore, nil
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadIgnoreFile(t *testing.T) {
+	root := t.TempDir()
+	ignore, err := LoadIgnoreFile(root)
+	if err != nil {
+		t.Fatalf("LoadIgnoreFile() without a file error = %v", err)
+	}
+	if len(ignore.Exclude) != 0 || len(ignore.Redact) != 0 {
+		t.Errorf("LoadIgnoreFile() without a file = %+v, want empty", ignore)
+	}
+
+	content := "# generated code\r\nvendor/\n*.lock\n\nredact:\ninternal\\.example\\.com\n# comment\nexclude:\n/testdata/secrets/**\n"
+	if err := os.WriteFile(filepath.Join(root, IgnoreFileName), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ignore, err = LoadIgnoreFile(root)
+	if err != nil {
+		t.Fatalf("LoadIgnoreFile() error = %v", err)
+	}
+	if want := []string{"vendor/", "*.lock", "/testdata/secrets/**"}; !reflect.DeepEqual(ignore.Exclude, want) {
+		t.Errorf("Exclude = %q, want %q", ignore.Exclude, want)
+	}
+	if want := []string{`internal\.example\.com`}; !reflect.DeepEqual(ignore.Redact, want) {
+		t.Errorf("Redact = %q, want %q", ignore.Redact, want)
+	}
+}
+
+func TestParseIgnoreFileRejectsBadRedactPattern(t *testing.T) {
+	_, err := ParseIgnoreFile("*.lock\nredact:\n(unclosed\n")
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("ParseIgnoreFile() error = %v, want one naming line 3", err)
+	}
+}
@@ -1,5 +1,10 @@
 package config
 
+import (
+	"fmt"
+	"slices"
+)
+
 // ProviderModels lists supported model options per provider.
 // Used by the interactive installer UI.
 var ProviderModels = map[string][]string{
@@ -19,6 +24,14 @@ var ProviderModels = map[string][]string{
 		"groq/compound",
 		"groq/compound-mini",
 	},
+	"mistral": {
+		"codestral-latest",
+		"mistral-large-latest",
+		"mistral-medium-latest",
+		"mistral-small-latest",
+		"ministral-8b-latest",
+		"open-mistral-nemo",
+	},
 	"openai": {
 		"gpt-5.2",
 		"gpt-5-mini",
@@ -39,5 +52,30 @@ var ProviderModels = map[string][]string{
 		"gemma2",
 		"mistral",
 	},
-	"mock": {"mock"},
+	// Local OpenAI-compatible servers (llama.cpp, LM Studio) serve whatever
+	// is loaded and mostly ignore the name; "local-model" is LM Studio's
+	// placeholder.
+	"openai-compatible": {"local-model"},
+	"mock":              {"mock"},
+}
+
+// ValidateModel returns a warning when model is not in
+// ProviderModels[provider]. It is advisory only: the lists go stale, so
+// callers should show the error rather than fail. Ollama runs arbitrary
+// local models and is never checked, nor is openai-compatible or a provider
+// without a list.
+func ValidateModel(provider, model string) error {
+	known, ok := ProviderModels[provider]
+	if !ok || provider == "ollama" || provider == "openai-compatible" || model == "" {
+		return nil
+	}
+	if slices.Contains(known, model) {
+		return nil
+	}
+	for other, models := range ProviderModels {
+		if other != provider && other != "ollama" && slices.Contains(models, model) {
+			return fmt.Errorf("model %q is a %s model, not a %s one", model, other, provider)
+		}
+	}
+	return fmt.Errorf("model %q is not a known %s model (it may still work if the list is out of date)", model, provider)
 }
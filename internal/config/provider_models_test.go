@@ -0,0 +1,24 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateModel(t *testing.T) {
+	if err := ValidateModel("openai", "gpt-4o-mini"); err != nil {
+		t.Errorf("known model: %v", err)
+	}
+	if err := ValidateModel("groq", "gpt-4o"); err == nil || !strings.Contains(err.Error(), "openai model") {
+		t.Errorf("openai model on groq: err = %v, want a hint naming openai", err)
+	}
+	if err := ValidateModel("anthropic", "claude-9"); err == nil || !strings.Contains(err.Error(), "out of date") {
+		t.Errorf("unknown model: err = %v, want a soft warning", err)
+	}
+	if err := ValidateModel("ollama", "my-finetune:7b"); err != nil {
+		t.Errorf("ollama model checked: %v", err)
+	}
+	if err := ValidateModel("multi", "anything"); err != nil {
+		t.Errorf("provider without a list checked: %v", err)
+	}
+}
@@ -10,17 +10,48 @@ import (
 // PartialConfig represents a config file with optional fields.
 // This prevents missing keys from clobbering defaults.
 type PartialConfig struct {
-	Provider    *string  `json:"Provider,omitempty"`
-	APIKey      *string  `json:"APIKey,omitempty"`
-	Model       *string  `json:"Model,omitempty"`
-	Temperature *float32 `json:"Temperature,omitempty"`
-	BaseURL     *string  `json:"BaseURL,omitempty"`
-	OllamaURL   *string  `json:"OllamaURL,omitempty"`
-	DiffCap     *int     `json:"DiffCap,omitempty"`
-	ConfirmSend *bool    `json:"ConfirmSend,omitempty"`
-	DryRun      *bool    `json:"DryRun,omitempty"`
-	Redact      *bool    `json:"Redact,omitempty"`
-	UseCache    *bool    `json:"UseCache,omitempty"`
+	Provider             *string           `json:"Provider,omitempty"`
+	APIKey               *string           `json:"APIKey,omitempty"`
+	Model                *string           `json:"Model,omitempty"`
+	Temperature          *float32          `json:"Temperature,omitempty"`
+	BaseURL              *string           `json:"BaseURL,omitempty"`
+	OllamaURL            *string           `json:"OllamaURL,omitempty"`
+	DiffCap              *int              `json:"DiffCap,omitempty"`
+	ConfirmSend          *bool             `json:"ConfirmSend,omitempty"`
+	DryRun               *bool             `json:"DryRun,omitempty"`
+	Redact               *bool             `json:"Redact,omitempty"`
+	UseCache             *bool             `json:"UseCache,omitempty"`
+	OmitAPIKey           *bool             `json:"OmitAPIKey,omitempty"`
+	SuggestCount         *int              `json:"SuggestCount,omitempty"`
+	CommitUseMessageFlag *bool             `json:"CommitUseMessageFlag,omitempty"`
+	RetryEmpty           *bool             `json:"RetryEmpty,omitempty"`
+	FewShotExamples      []string          `json:"FewShotExamples,omitempty"`
+	FewShotExamplesFile  *string           `json:"FewShotExamplesFile,omitempty"`
+	SubmoduleContext     *bool             `json:"SubmoduleContext,omitempty"`
+	IncludeIntentToAdd   *bool             `json:"IncludeIntentToAdd,omitempty"`
+	GroqAllowHighTemp    *bool             `json:"GroqAllowHighTemp,omitempty"`
+	RedactPatterns       []string          `json:"RedactPatterns,omitempty"`
+	MaxFiles             *int              `json:"MaxFiles,omitempty"`
+	DefaultSelection     *string           `json:"DefaultSelection,omitempty"`
+	SummarizeLargeHunks  *bool             `json:"SummarizeLargeHunks,omitempty"`
+	TypeSubjectLimits    map[string]int    `json:"TypeSubjectLimits,omitempty"`
+	Keybindings          map[string]string `json:"Keybindings,omitempty"`
+	MultiProviders       []string          `json:"MultiProviders,omitempty"`
+	SignCommits          *bool             `json:"SignCommits,omitempty"`
+	SigningKey           *string           `json:"SigningKey,omitempty"`
+	SubjectMaxLen        *int              `json:"SubjectMaxLen,omitempty"`
+	SystemPrompt         *string           `json:"SystemPrompt,omitempty"`
+	SystemPrompts        map[string]string `json:"SystemPrompts,omitempty"`
+	RequestTimeout       *int              `json:"RequestTimeout,omitempty"`
+	GitTimeout           *int              `json:"GitTimeout,omitempty"`
+	GitCommitTimeout     *int              `json:"GitCommitTimeout,omitempty"`
+	CABundle             *string           `json:"CABundle,omitempty"`
+	InsecureSkipVerify   *bool             `json:"InsecureSkipVerify,omitempty"`
+	PromptTemplate       *string           `json:"PromptTemplate,omitempty"`
+	TicketFromBranch     *bool             `json:"TicketFromBranch,omitempty"`
+	TicketPattern        *string           `json:"TicketPattern,omitempty"`
+	SubjectKeepCase      *bool             `json:"SubjectKeepCase,omitempty"`
+	StrictSubjectStyle   *bool             `json:"StrictSubjectStyle,omitempty"`
 }
 
 // DefaultConfigPath returns the default per-user config path.
@@ -37,6 +68,26 @@ func DefaultConfigPath() (string, error) {
 	return filepath.Join(dir, "commit-coach", "config.json"), nil
 }
 
+// DefaultFailureNotesPath returns where per-provider failure notes are kept,
+// next to the config file.
+func DefaultFailureNotesPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("get user config dir: %w", err)
+	}
+	return filepath.Join(dir, "commit-coach", "failures.json"), nil
+}
+
+// DefaultModelCachePath returns where fetched model lists are cached, next
+// to the config file.
+func DefaultModelCachePath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("get user config dir: %w", err)
+	}
+	return filepath.Join(dir, "commit-coach", "models.json"), nil
+}
+
 // LoadFromFile loads config from a JSON file. If the file doesn't exist, returns (nil, nil).
 func LoadFromFile(path string) (*PartialConfig, error) {
 	b, err := os.ReadFile(path)
@@ -54,9 +105,25 @@ func LoadFromFile(path string) (*PartialConfig, error) {
 	return &cfg, nil
 }
 
+// LoadStored returns the defaults overlaid with the config file at path,
+// without env overrides or validation: what SaveToFile should write back
+// when only a few fields change. A missing file yields the defaults.
+func LoadStored(path string) (*Config, error) {
+	cfg := Defaults()
+	fileCfg, err := LoadFromFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if fileCfg != nil {
+		applyPartialConfig(cfg, fileCfg)
+	}
+	return cfg, nil
+}
+
 // SaveToFile saves config to a JSON file (atomic write). Creates directories as needed.
 //
-// NOTE: This may include API keys. The file is written with 0600 permissions.
+// NOTE: This may include API keys unless cfg.OmitAPIKey is set, in which
+// case the APIKey field is left out. The file is written with 0600 permissions.
 func SaveToFile(path string, cfg *Config) error {
 	if cfg == nil {
 		return fmt.Errorf("config is nil")
@@ -66,7 +133,7 @@ func SaveToFile(path string, cfg *Config) error {
 		return fmt.Errorf("create config dir: %w", err)
 	}
 
-	b, err := json.MarshalIndent(cfg, "", "  ")
+	b, err := encodeConfig(cfg)
 	if err != nil {
 		return fmt.Errorf("encode config JSON: %w", err)
 	}
@@ -103,6 +170,24 @@ func SaveToFile(path string, cfg *Config) error {
 	return nil
 }
 
+// encodeConfig marshals cfg, dropping APIKey when the key shouldn't be stored.
+func encodeConfig(cfg *Config) ([]byte, error) {
+	if !cfg.OmitAPIKey {
+		return json.MarshalIndent(cfg, "", "  ")
+	}
+
+	raw, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	delete(fields, "APIKey")
+	return json.MarshalIndent(fields, "", "  ")
+}
+
 // DeleteConfig removes the config file at the given path.
 func DeleteConfig(path string) error {
 	if err := os.Remove(path); err != nil {
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -62,3 +63,41 @@ func TestLoadFromFileMissing(t *testing.T) {
 		t.Fatalf("LoadFromFile() = %#v, want nil", cfg)
 	}
 }
+
+func TestSaveToFileWithoutAPIKey(t *testing.T) {
+	isolateUserConfigDir(t)
+	path, err := DefaultConfigPath()
+	if err != nil {
+		t.Fatalf("DefaultConfigPath() error = %v", err)
+	}
+
+	in := Defaults()
+	in.Provider = "openai"
+	in.APIKey = "sk-should-not-be-written"
+	in.OmitAPIKey = true
+
+	if err := SaveToFile(path, in); err != nil {
+		t.Fatalf("SaveToFile() error = %v", err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+	if strings.Contains(string(b), `"APIKey"`) || strings.Contains(string(b), in.APIKey) {
+		t.Fatalf("config file contains the API key:\n%s", b)
+	}
+
+	t.Setenv("OPENAI_API_KEY", "sk-from-env")
+	t.Setenv("LLM_PROVIDER", "")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.APIKey != "sk-from-env" {
+		t.Errorf("APIKey = %q, want key from env", cfg.APIKey)
+	}
+	if !cfg.OmitAPIKey {
+		t.Error("OmitAPIKey should round-trip as true")
+	}
+}
@@ -0,0 +1,36 @@
+package domain
+
+import "strings"
+
+// preferredSubjectLen is the conventional short-subject length; subjects up
+// to this length score a bonus (72 is only the hard limit).
+const preferredSubjectLen = 50
+
+// ScoreSuggestion rates how well s follows commit conventions; higher is
+// better. Each lint violation costs heavily, then a short subject, no
+// trailing period and an explanatory body each add a point.
+func ScoreSuggestion(s Suggestion) int {
+	score := -10 * len(s.Lint())
+	if len(s.Subject) <= preferredSubjectLen {
+		score++
+	}
+	if !strings.HasSuffix(s.Subject, ".") {
+		score++
+	}
+	if strings.TrimSpace(s.Body) != "" {
+		score++
+	}
+	return score
+}
+
+// BestSuggestion returns the index of the highest-scoring suggestion, the
+// earliest on ties, or 0 for an empty list.
+func BestSuggestion(suggestions []Suggestion) int {
+	best := 0
+	for i := 1; i < len(suggestions); i++ {
+		if ScoreSuggestion(suggestions[i]) > ScoreSuggestion(suggestions[best]) {
+			best = i
+		}
+	}
+	return best
+}
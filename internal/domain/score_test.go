@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScoreSuggestion(t *testing.T) {
+	plain := Suggestion{Type: "feat", Subject: "add parser"}
+	withBody := Suggestion{Type: "feat", Subject: "add parser", Body: "Parses nested lists."}
+	period := Suggestion{Type: "feat", Subject: "add parser."}
+	long := Suggestion{Type: "feat", Subject: strings.Repeat("a", 60)}
+	invalid := Suggestion{Type: "feature", Subject: "add parser", Body: "Body."}
+
+	if ScoreSuggestion(withBody) <= ScoreSuggestion(plain) {
+		t.Error("a body should score higher than none")
+	}
+	if ScoreSuggestion(period) >= ScoreSuggestion(plain) {
+		t.Error("a trailing period should score lower")
+	}
+	if ScoreSuggestion(long) >= ScoreSuggestion(plain) {
+		t.Error("a subject over 50 characters should score lower")
+	}
+	if ScoreSuggestion(invalid) >= ScoreSuggestion(long) {
+		t.Error("lint violations should outweigh style points")
+	}
+}
+
+func TestBestSuggestion(t *testing.T) {
+	list := []Suggestion{
+		{Type: "feat", Subject: "add parser."},
+		{Type: "feat", Subject: "add parser", Body: "Parses nested lists."},
+		{Type: "feat", Subject: "add a parser", Body: "Same score, later."},
+	}
+	if got := BestSuggestion(list); got != 1 {
+		t.Errorf("BestSuggestion() = %d, want 1 (earliest of the top scores)", got)
+	}
+	if got := BestSuggestion(nil); got != 0 {
+		t.Errorf("BestSuggestion(nil) = %d, want 0", got)
+	}
+}
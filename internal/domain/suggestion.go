@@ -12,6 +12,29 @@ var ValidCommitTypes = []string{
 	"feat", "fix", "docs", "style", "refactor", "perf", "test", "chore", "build", "ci", "revert",
 }
 
+// DefaultSubjectMaxLen is the subject length limit when none is configured.
+const DefaultSubjectMaxLen = 72
+
+// SubjectLimits caps subject length, optionally per commit type (e.g. a
+// longer limit for revert, whose subject quotes the reverted one).
+type SubjectLimits struct {
+	// Max applies to types without an override; 0 means DefaultSubjectMaxLen.
+	Max int
+	// ByType overrides Max for the listed types.
+	ByType map[string]int
+}
+
+// For returns the subject limit for commitType.
+func (l SubjectLimits) For(commitType string) int {
+	if n, ok := l.ByType[commitType]; ok && n > 0 {
+		return n
+	}
+	if l.Max > 0 {
+		return l.Max
+	}
+	return DefaultSubjectMaxLen
+}
+
 // Suggestion represents a validated commit suggestion.
 type Suggestion struct {
 	Type    string
@@ -21,58 +44,184 @@ type Suggestion struct {
 }
 
 // Validate checks a suggestion against domain rules.
+// It returns the first violation found; see Lint for the full list.
 func (s Suggestion) Validate() error {
+	return s.ValidateWith(SubjectLimits{})
+}
+
+// ValidateWith is Validate with configured subject limits.
+func (s Suggestion) ValidateWith(limits SubjectLimits) error {
+	if violations := s.LintWith(limits); len(violations) > 0 {
+		return violations[0]
+	}
+	return nil
+}
+
+// Lint checks a suggestion against domain rules and returns every violation.
+func (s Suggestion) Lint() []error {
+	return s.LintWith(SubjectLimits{})
+}
+
+// LintWith is Lint with configured subject limits.
+func (s Suggestion) LintWith(limits SubjectLimits) []error {
+	var violations []error
+
 	// Type validation
 	if s.Type == "" {
-		return fmt.Errorf("type is required")
-	}
-	if !isValidType(s.Type) {
-		return fmt.Errorf("invalid type %q; must be one of: %v", s.Type, ValidCommitTypes)
+		violations = append(violations, fmt.Errorf("type is required"))
+	} else if !isValidType(s.Type) {
+		violations = append(violations, fmt.Errorf("invalid type %q; must be one of: %v", s.Type, ValidCommitTypes))
 	}
 
 	// Subject validation
 	if s.Subject == "" {
-		return fmt.Errorf("subject is required")
+		violations = append(violations, fmt.Errorf("subject is required"))
 	}
-	if len(s.Subject) > 72 {
-		return fmt.Errorf("subject exceeds 72 characters (%d)", len(s.Subject))
+	if max := limits.For(s.Type); len(s.Subject) > max {
+		violations = append(violations, fmt.Errorf("subject exceeds %d characters (%d)", max, len(s.Subject)))
 	}
 	if strings.Contains(s.Subject, "\n") {
-		return fmt.Errorf("subject must not contain newlines")
+		violations = append(violations, fmt.Errorf("subject must not contain newlines"))
 	}
 	if hasControlChars(s.Subject) {
-		return fmt.Errorf("subject contains control characters")
+		violations = append(violations, fmt.Errorf("subject contains control characters"))
 	}
 
 	// Body validation (optional)
 	if s.Body != "" && hasControlChars(s.Body) {
-		return fmt.Errorf("body contains control characters")
+		violations = append(violations, fmt.Errorf("body contains control characters"))
 	}
 
 	// Footer validation (optional)
 	if s.Footer != "" {
 		if !isValidFooter(s.Footer) {
-			return fmt.Errorf("invalid footer format; must match ^(BREAKING CHANGE|Closes|Refs): .*")
+			violations = append(violations, fmt.Errorf("invalid footer format; must match ^(BREAKING CHANGE|Closes|Refs): .*"))
 		}
 		if hasControlChars(s.Footer) {
-			return fmt.Errorf("footer contains control characters")
+			violations = append(violations, fmt.Errorf("footer contains control characters"))
 		}
 	}
 
-	return nil
+	return violations
+}
+
+// ParseMessage parses a commit message laid out like Format's output:
+// "type: subject", then optional blank-line separated body and footer.
+// The last paragraph is treated as the footer when it looks like one.
+// ParseMessage neither normalizes nor validates; call Lint on the result.
+func ParseMessage(text string) Suggestion {
+	text = strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n"))
+
+	header, rest, _ := strings.Cut(text, "\n")
+	var s Suggestion
+	if typ, subject, ok := strings.Cut(header, ": "); ok {
+		s.Type = strings.TrimSpace(typ)
+		s.Subject = strings.TrimSpace(subject)
+	} else {
+		s.Subject = strings.TrimSpace(header)
+	}
+
+	var paragraphs []string
+	for _, p := range strings.Split(strings.TrimSpace(rest), "\n\n") {
+		if p = strings.TrimSpace(p); p != "" {
+			paragraphs = append(paragraphs, p)
+		}
+	}
+	if n := len(paragraphs); n > 0 && isValidFooter(paragraphs[n-1]) {
+		s.Footer = paragraphs[n-1]
+		paragraphs = paragraphs[:n-1]
+	}
+	s.Body = strings.Join(paragraphs, "\n\n")
+
+	return s
 }
 
-// Normalize applies whitespace normalization to the suggestion.
+// SubjectStyle controls how Normalize rewrites subjects beyond trimming a
+// trailing period.
+type SubjectStyle struct {
+	// KeepCase leaves the subject's first letter as written instead of
+	// lowercasing it.
+	KeepCase bool
+	// Strict rewrites an obviously non-imperative first verb, e.g. "added"
+	// or "fixes", to the imperative ("add", "fix").
+	Strict bool
+}
+
+// imperativeVerbs maps common past-tense, third-person and -ing forms of
+// commit verbs to the imperative.
+var imperativeVerbs = map[string]string{
+	"added": "add", "adds": "add", "adding": "add",
+	"fixed": "fix", "fixes": "fix", "fixing": "fix",
+	"updated": "update", "updates": "update", "updating": "update",
+	"removed": "remove", "removes": "remove", "removing": "remove",
+	"changed": "change", "changes": "change", "changing": "change",
+	"implemented": "implement", "implements": "implement",
+	"refactored": "refactor", "refactors": "refactor",
+	"renamed": "rename", "renames": "rename",
+	"moved": "move", "moves": "move",
+	"improved": "improve", "improves": "improve",
+	"created": "create", "creates": "create",
+	"deleted": "delete", "deletes": "delete",
+	"replaced": "replace", "replaces": "replace",
+	"introduced": "introduce", "introduces": "introduce",
+	"bumped": "bump", "bumps": "bump",
+	"upgraded": "upgrade", "upgrades": "upgrade",
+	"documented": "document", "documents": "document",
+	"simplified": "simplify", "simplifies": "simplify",
+}
+
+// Normalize trims whitespace, lowercases the type and the subject's first
+// letter and drops a trailing period from the subject.
 func (s *Suggestion) Normalize() {
+	s.NormalizeWith(SubjectLimits{})
+}
+
+// NormalizeWith is Normalize with configured subject limits.
+func (s *Suggestion) NormalizeWith(limits SubjectLimits) {
+	s.NormalizeStyled(limits, SubjectStyle{})
+}
+
+// NormalizeStyled is Normalize with configured subject limits and style.
+func (s *Suggestion) NormalizeStyled(limits SubjectLimits, style SubjectStyle) {
 	s.Type = strings.TrimSpace(strings.ToLower(s.Type))
-	s.Subject = strings.TrimSpace(s.Subject)
+	s.Subject = styleSubject(strings.TrimSpace(s.Subject), style)
 	s.Body = strings.TrimSpace(s.Body)
 	s.Footer = strings.TrimSpace(s.Footer)
 
 	// Truncate subject if needed (though this should not happen after validation)
-	if len(s.Subject) > 72 {
-		s.Subject = s.Subject[:72]
+	if max := limits.For(s.Type); len(s.Subject) > max {
+		s.Subject = s.Subject[:max]
+	}
+}
+
+// styleSubject applies style to a trimmed subject. An ellipsis is kept, and
+// lowercasing skips words that look like acronyms or identifiers ("README",
+// "HTTPClient").
+func styleSubject(subject string, style SubjectStyle) string {
+	if strings.HasSuffix(subject, ".") && !strings.HasSuffix(subject, "..") {
+		subject = strings.TrimSpace(strings.TrimSuffix(subject, "."))
+	}
+	if subject == "" {
+		return subject
+	}
+	first, rest, spaced := strings.Cut(subject, " ")
+	if style.Strict {
+		if verb, ok := imperativeVerbs[strings.ToLower(first)]; ok {
+			if r := []rune(first); unicode.IsUpper(r[0]) {
+				verb = strings.ToUpper(verb[:1]) + verb[1:]
+			}
+			first = verb
+		}
+	}
+	if !style.KeepCase {
+		if r := []rune(first); len(r) == 1 || !unicode.IsUpper(r[1]) {
+			first = string(unicode.ToLower(r[0])) + string(r[1:])
+		}
+	}
+	if !spaced {
+		return first
 	}
+	return first + " " + rest
 }
 
 // Format returns the formatted commit message.
@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -106,6 +107,32 @@ func TestSuggestionNormalize(t *testing.T) {
 	}
 }
 
+func TestNormalizeSubjectStyle(t *testing.T) {
+	tests := []struct {
+		subject string
+		style   SubjectStyle
+		want    string
+	}{
+		{"Add parser.", SubjectStyle{}, "add parser"},
+		{"add parser...", SubjectStyle{}, "add parser..."},
+		{"README: fix typo", SubjectStyle{}, "README: fix typo"},
+		{"Add parser.", SubjectStyle{KeepCase: true}, "Add parser"},
+		{"Added feature.", SubjectStyle{}, "added feature"},
+		{"Added feature.", SubjectStyle{Strict: true}, "add feature"},
+		{"Fixes nil config", SubjectStyle{Strict: true, KeepCase: true}, "Fix nil config"},
+		{"updating deps", SubjectStyle{Strict: true}, "update deps"},
+		{"Fixed", SubjectStyle{Strict: true}, "fix"},
+		{"address review notes", SubjectStyle{Strict: true}, "address review notes"},
+	}
+	for _, tt := range tests {
+		s := Suggestion{Type: "feat", Subject: tt.subject}
+		s.NormalizeStyled(SubjectLimits{}, tt.style)
+		if s.Subject != tt.want {
+			t.Errorf("NormalizeStyled(%q, %+v) subject = %q, want %q", tt.subject, tt.style, s.Subject, tt.want)
+		}
+	}
+}
+
 func TestSuggestionFormat(t *testing.T) {
 	sugg := Suggestion{
 		Type:    "fix",
@@ -139,3 +166,120 @@ func TestControlCharsDetection(t *testing.T) {
 		}
 	}
 }
+
+func TestSuggestionLintCollectsAll(t *testing.T) {
+	sugg := Suggestion{
+		Type:    "feature",
+		Subject: strings.Repeat("x", 80),
+		Footer:  "see ticket",
+	}
+
+	violations := sugg.Lint()
+	if len(violations) != 3 {
+		t.Fatalf("Lint() = %v, want 3 violations (type, subject length, footer)", violations)
+	}
+	if err := sugg.Validate(); err == nil || err.Error() != violations[0].Error() {
+		t.Errorf("Validate() = %v, want first lint violation %v", err, violations[0])
+	}
+}
+
+func TestParseMessage(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want Suggestion
+	}{
+		{
+			name: "subject only",
+			text: "fix: handle nil pointer\n",
+			want: Suggestion{Type: "fix", Subject: "handle nil pointer"},
+		},
+		{
+			name: "body and footer",
+			text: "fix: handle nil pointer\n\nAdded null check\n\nRefs: #123",
+			want: Suggestion{Type: "fix", Subject: "handle nil pointer", Body: "Added null check", Footer: "Refs: #123"},
+		},
+		{
+			name: "multi-paragraph body",
+			text: "docs: expand readme\n\nFirst.\n\nSecond.",
+			want: Suggestion{Type: "docs", Subject: "expand readme", Body: "First.\n\nSecond."},
+		},
+		{
+			name: "missing type",
+			text: "Added stuff",
+			want: Suggestion{Subject: "Added stuff"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseMessage(tt.text); got != tt.want {
+				t.Errorf("ParseMessage() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMessageRoundTrip(t *testing.T) {
+	sugg := Suggestion{Type: "feat", Subject: "add parser", Body: "Line one\nLine two", Footer: "BREAKING CHANGE: new API"}
+	if got := ParseMessage(sugg.Format()); got != sugg {
+		t.Errorf("ParseMessage(Format()) = %#v, want %#v", got, sugg)
+	}
+}
+
+
+func TestSubjectLimitsPerType(t *testing.T) {
+	limits := SubjectLimits{Max: 50, ByType: map[string]int{"revert": 100}}
+	subject := strings.Repeat("a", 80)
+
+	revert := Suggestion{Type: "revert", Subject: subject}
+	if err := revert.ValidateWith(limits); err != nil {
+		t.Errorf("revert within its override: %v", err)
+	}
+	feat := Suggestion{Type: "feat", Subject: subject}
+	if err := feat.ValidateWith(limits); err == nil || !strings.Contains(err.Error(), "exceeds 50 characters") {
+		t.Errorf("feat over the global limit: err = %v, want exceeds 50", err)
+	}
+	if err := (Suggestion{Type: "revert", Subject: strings.Repeat("a", 101)}).ValidateWith(limits); err == nil {
+		t.Error("revert over its override should fail")
+	}
+
+	// Normalization truncates to the type's own limit.
+	feat.NormalizeWith(limits)
+	revert.NormalizeWith(limits)
+	if len(feat.Subject) != 50 || len(revert.Subject) != 80 {
+		t.Errorf("normalized lengths = %d (feat), %d (revert); want 50, 80", len(feat.Subject), len(revert.Subject))
+	}
+
+	// Without configuration the built-in limit applies to every type.
+	if got := (SubjectLimits{}).For("revert"); got != DefaultSubjectMaxLen {
+		t.Errorf("default limit = %d, want %d", got, DefaultSubjectMaxLen)
+	}
+}
+
+func TestSubjectMaxLenBoundary(t *testing.T) {
+	for _, max := range []int{50, DefaultSubjectMaxLen} {
+		limits := SubjectLimits{Max: max}
+
+		exact := Suggestion{Type: "feat", Subject: strings.Repeat("a", max)}
+		if err := exact.ValidateWith(limits); err != nil {
+			t.Errorf("max %d: subject of exactly %d rejected: %v", max, max, err)
+		}
+		exact.NormalizeWith(limits)
+		if len(exact.Subject) != max {
+			t.Errorf("max %d: Normalize changed an in-limit subject to %d characters", max, len(exact.Subject))
+		}
+
+		over := Suggestion{Type: "feat", Subject: strings.Repeat("a", max+1)}
+		if err := over.ValidateWith(limits); err == nil {
+			t.Errorf("max %d: subject of %d accepted", max, max+1)
+		}
+		over.NormalizeWith(limits)
+		if len(over.Subject) != max {
+			t.Errorf("max %d: Normalize truncated to %d characters, want %d", max, len(over.Subject), max)
+		}
+		if err := over.ValidateWith(limits); err != nil {
+			t.Errorf("max %d: normalized subject still fails validation: %v", max, err)
+		}
+	}
+}
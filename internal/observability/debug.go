@@ -0,0 +1,68 @@
+package observability
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// DebugEnv enables debug output like --verbose.
+const DebugEnv = "COMMIT_COACH_DEBUG"
+
+// debugSnipRunes bounds response snippets in debug output.
+const debugSnipRunes = 800
+
+var (
+	debugMu  sync.Mutex
+	debugOut io.Writer
+)
+
+// DebugEnvEnabled reports whether COMMIT_COACH_DEBUG is set to a truthy value.
+func DebugEnvEnabled() bool {
+	return EnvEnabled(DebugEnv)
+}
+
+// EnvEnabled reports whether the env var name is set to a truthy value
+// (1, true, yes or on).
+func EnvEnabled(name string) bool {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv(name))) {
+	case "1", "true", "yes", "on":
+		return true
+	}
+	return false
+}
+
+// SetDebugOutput sends debug output to w; nil turns it off.
+func SetDebugOutput(w io.Writer) {
+	debugMu.Lock()
+	defer debugMu.Unlock()
+	debugOut = w
+}
+
+// Debugf writes a redacted debug line when debug output is on.
+func Debugf(format string, args ...interface{}) {
+	debugMu.Lock()
+	defer debugMu.Unlock()
+	if debugOut == nil {
+		return
+	}
+	msg := RedactForLog(fmt.Sprintf(format, args...))
+	fmt.Fprintf(debugOut, "[debug] %s\n", strings.TrimRight(msg, "\n"))
+}
+
+// DebugPrompt logs the prompt about to be sent to provider, to the debug
+// output and to the error log at debug level.
+func DebugPrompt(provider, prompt string) {
+	Debugf("%s prompt:\n%s", provider, prompt)
+	Event(map[string]any{"level": "debug", "provider": provider, "message": "prompt", "prompt": prompt})
+}
+
+// DebugResponse logs a snippet of provider's raw response text, like
+// DebugPrompt.
+func DebugResponse(provider, content string) {
+	snip := Snip(content, debugSnipRunes)
+	Debugf("%s response: %s", provider, snip)
+	Event(map[string]any{"level": "debug", "provider": provider, "message": "response", "response": snip})
+}
@@ -0,0 +1,27 @@
+package observability
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDebugfRedactsAndIsOffByDefault(t *testing.T) {
+	var buf bytes.Buffer
+	Debugf("dropped")
+	SetDebugOutput(&buf)
+	t.Cleanup(func() { SetDebugOutput(nil) })
+
+	DebugPrompt("openai", "diff --git a/.env b/.env\n+OPENAI_API_KEY=sk-abcdefghijklmnopqrstuvwxyz123456\n")
+
+	out := buf.String()
+	if strings.Contains(out, "dropped") {
+		t.Errorf("Debugf wrote before output was set:\n%s", out)
+	}
+	if !strings.HasPrefix(out, "[debug] openai prompt:\n") {
+		t.Errorf("unexpected debug output:\n%s", out)
+	}
+	if strings.Contains(out, "abcdefghijklmnop") {
+		t.Errorf("debug output leaked a key:\n%s", out)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"unicode/utf8"
 
@@ -19,12 +21,22 @@ var (
 	initErr  error
 )
 
+// LogMaxMBEnv caps the error log size in megabytes; 0 disables rotation.
+const LogMaxMBEnv = "COMMIT_COACH_LOG_MAX_MB"
+
+// defaultLogMaxMB is the cap when LogMaxMBEnv is unset or invalid.
+const defaultLogMaxMB = 5
+
 // Init configures logging to a local error log file.
 //
 // Default path is ./commit-coach-error.log, override with COMMIT_COACH_LOG_PATH.
+// COMMIT_COACH_LOG_FORMAT=json writes one JSON object per entry instead.
+// Entries below COMMIT_COACH_LOG_LEVEL (default warn) are skipped.
+// A log over COMMIT_COACH_LOG_MAX_MB (default 5) is moved to <path>.1 first.
 // The log is redacted to avoid leaking secrets.
 func Init() (path string, cleanup func(), err error) {
 	initOnce.Do(func() {
+		levelFromEnv()
 		logPath = os.Getenv("COMMIT_COACH_LOG_PATH")
 		if logPath == "" {
 			logPath = "commit-coach-error.log"
@@ -35,11 +47,18 @@ func Init() (path string, cleanup func(), err error) {
 			_ = os.MkdirAll(dir, 0o755)
 		}
 
-		logFile, initErr = os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		logFile, initErr = openLogFile(logPath, logMaxBytes())
 		if initErr != nil {
 			return
 		}
 
+		if jsonFormatRequested() {
+			jsonOut = &jsonWriter{out: logFile}
+			logger = log.New(jsonOut, "", 0)
+			log.SetOutput(jsonOut)
+			log.SetFlags(0)
+			return
+		}
 		logger = log.New(logFile, "", log.LstdFlags|log.Lmicroseconds)
 		log.SetOutput(logFile)
 		log.SetFlags(log.LstdFlags | log.Lmicroseconds)
@@ -54,6 +73,30 @@ func Init() (path string, cleanup func(), err error) {
 	return logPath, cleanup, initErr
 }
 
+func logMaxBytes() int64 {
+	mb, err := strconv.Atoi(strings.TrimSpace(os.Getenv(LogMaxMBEnv)))
+	if err != nil || mb < 0 {
+		mb = defaultLogMaxMB
+	}
+	return int64(mb) << 20
+}
+
+// openLogFile opens path for appending, first rotating it to path+".1" when
+// it is larger than maxBytes (0 disables rotation). Only one old file is
+// kept. If the rename fails the log is truncated instead, so it still stays
+// bounded.
+func openLogFile(path string, maxBytes int64) (*os.File, error) {
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if info, err := os.Stat(path); err == nil && maxBytes > 0 && info.Size() > maxBytes {
+		backup := path + ".1"
+		_ = os.Remove(backup) // rename does not replace an existing file on Windows
+		if err := os.Rename(path, backup); err != nil {
+			flags |= os.O_TRUNC
+		}
+	}
+	return os.OpenFile(path, flags, 0o600)
+}
+
 // Logger returns the configured file logger if available.
 func Logger() *log.Logger {
 	if logger != nil {
@@ -73,10 +116,13 @@ func RedactForLog(s string) string {
 }
 
 // Snip returns a safe prefix of s, capped by rune count.
+// A trailing incomplete multibyte sequence (e.g. from a byte-capped diff) is
+// dropped so the snippet never contains a replacement character.
 func Snip(s string, maxRunes int) string {
 	if maxRunes <= 0 {
 		return ""
 	}
+	s = trimIncompleteRune(s)
 
 	n := 0
 	idx := 0
@@ -97,3 +143,16 @@ func Snip(s string, maxRunes int) string {
 	}
 	return s[:idx] + "…"
 }
+
+// trimIncompleteRune removes a partial UTF-8 sequence from the end of s.
+func trimIncompleteRune(s string) string {
+	for i := 1; i <= utf8.UTFMax && i <= len(s); i++ {
+		if utf8.RuneStart(s[len(s)-i]) {
+			if !utf8.FullRuneInString(s[len(s)-i:]) {
+				return s[:len(s)-i]
+			}
+			break
+		}
+	}
+	return s
+}
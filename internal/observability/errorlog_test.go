@@ -0,0 +1,81 @@
+package observability
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSnipTrimsIncompleteTrailingRune(t *testing.T) {
+	// "héllo wörld" capped mid-way through the two-byte "ö".
+	full := "héllo wörld"
+	partial := full[:strings.Index(full, "ö")+1]
+
+	for _, max := range []int{3, 100} {
+		got := Snip(partial, max)
+		if strings.ContainsRune(got, '�') {
+			t.Errorf("Snip(%q, %d) = %q, contains replacement character", partial, max, got)
+		}
+	}
+	if got := Snip(partial, 100); got != "héllo w" {
+		t.Errorf("Snip() = %q, want %q", got, "héllo w")
+	}
+	if got := Snip(full, 100); got != full {
+		t.Errorf("Snip() = %q, want unchanged %q", got, full)
+	}
+}
+
+func TestOpenLogFileRotatesPastCap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "commit-coach-error.log")
+	old := strings.Repeat("old entry\n", 20)
+	if err := os.WriteFile(path, []byte(old), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := openLogFile(path, 100)
+	if err != nil {
+		t.Fatalf("openLogFile() error = %v", err)
+	}
+	_, _ = f.WriteString("new entry\n")
+	_ = f.Close()
+
+	if b, _ := os.ReadFile(path + ".1"); string(b) != old {
+		t.Errorf("rotated file = %q, want the old log", b)
+	}
+	if b, _ := os.ReadFile(path); string(b) != "new entry\n" {
+		t.Errorf("log = %q, want only the new entry", b)
+	}
+
+	// Under the cap the log is appended to.
+	f, err = openLogFile(path, 100)
+	if err != nil {
+		t.Fatalf("openLogFile() error = %v", err)
+	}
+	_, _ = f.WriteString("second\n")
+	_ = f.Close()
+	if b, _ := os.ReadFile(path); string(b) != "new entry\nsecond\n" {
+		t.Errorf("log = %q, want appended entries", b)
+	}
+}
+
+func TestOpenLogFileTruncatesWhenRenameFails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "commit-coach-error.log")
+	if err := os.WriteFile(path, []byte(strings.Repeat("x", 200)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// A non-empty directory in the backup's place blocks the rename.
+	if err := os.MkdirAll(filepath.Join(path+".1", "keep"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := openLogFile(path, 100)
+	if err != nil {
+		t.Fatalf("openLogFile() error = %v", err)
+	}
+	_, _ = f.WriteString("fresh\n")
+	_ = f.Close()
+	if b, _ := os.ReadFile(path); string(b) != "fresh\n" {
+		t.Errorf("log = %q, want it truncated", b)
+	}
+}
@@ -0,0 +1,111 @@
+package observability
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogFormatEnv selects the error log format: "text" (the default) or "json"
+// for one JSON object per line.
+const LogFormatEnv = "COMMIT_COACH_LOG_FORMAT"
+
+// jsonOut is set by Init when LogFormatEnv is "json"; nil means text.
+var jsonOut *jsonWriter
+
+func jsonFormatRequested() bool {
+	return strings.EqualFold(strings.TrimSpace(os.Getenv(LogFormatEnv)), "json")
+}
+
+// Event logs one entry. The usual keys are "level", "provider", "status" and
+// "message"; any others are kept as extra fields. In text mode the entry
+// renders as "provider: message key=value ..."; in JSON mode it is written as
+// an object with a timestamp added. String and error values are redacted in
+// both. Entries below the log level (see SetLogLevel) are dropped; a missing
+// level counts as info.
+func Event(fields map[string]any) {
+	level := LevelInfo
+	if s, ok := fields["level"].(string); ok {
+		if l, err := ParseLevel(s); err == nil {
+			level = l
+		}
+	}
+	if !LevelEnabled(level) {
+		return
+	}
+	if jsonOut != nil {
+		jsonOut.writeEntry(fields)
+		return
+	}
+	Logger().Print(RedactForLog(formatText(fields)))
+}
+
+func formatText(fields map[string]any) string {
+	var b strings.Builder
+	if l, ok := fields["level"].(string); ok {
+		fmt.Fprintf(&b, "%s ", strings.ToUpper(l))
+	}
+	if p, ok := fields["provider"]; ok {
+		fmt.Fprintf(&b, "%v: ", p)
+	}
+	if m, ok := fields["message"]; ok {
+		fmt.Fprint(&b, m)
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		switch k {
+		case "provider", "message", "level":
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if s, ok := fields[k].(string); ok {
+			fmt.Fprintf(&b, " %s=%q", k, s)
+		} else {
+			fmt.Fprintf(&b, " %s=%v", k, fields[k])
+		}
+	}
+	return b.String()
+}
+
+// jsonWriter writes JSON log entries to out. As an io.Writer it wraps each
+// plain log line in an entry, so Logger().Printf output stays parseable.
+type jsonWriter struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+func (w *jsonWriter) Write(p []byte) (int, error) {
+	w.writeEntry(map[string]any{"message": strings.TrimRight(string(p), "\n")})
+	return len(p), nil
+}
+
+func (w *jsonWriter) writeEntry(fields map[string]any) {
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	entry := map[string]any{"time": now, "level": "info"}
+	for k, v := range fields {
+		switch v := v.(type) {
+		case string:
+			entry[k] = RedactForLog(v)
+		case error:
+			entry[k] = RedactForLog(v.Error())
+		default:
+			entry[k] = v
+		}
+	}
+	b, err := json.Marshal(entry)
+	if err != nil {
+		b, _ = json.Marshal(map[string]any{"time": now, "level": "error", "message": "unencodable log entry: " + err.Error()})
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, _ = w.out.Write(append(b, '\n'))
+}
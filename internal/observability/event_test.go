@@ -0,0 +1,104 @@
+package observability
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestEventTextFormat(t *testing.T) {
+	var buf strings.Builder
+	saved := logger
+	logger = log.New(&buf, "", 0)
+	defer func() { logger = saved }()
+
+	Event(map[string]any{
+		"level":     "error",
+		"provider":  "groq",
+		"status":    503,
+		"message":   "non-200 response",
+		"body_snip": "key sk-abcdefghijklmnopqrstuvwx",
+	})
+	out := buf.String()
+	if !strings.HasPrefix(out, `ERROR groq: non-200 response body_snip="key `) || !strings.Contains(out, "status=503") {
+		t.Errorf("text entry = %q", out)
+	}
+	if strings.Contains(out, "abcdefghijklmnop") {
+		t.Errorf("text entry leaked a key: %q", out)
+	}
+}
+
+func TestEventJSONFormat(t *testing.T) {
+	var buf strings.Builder
+	saved := jsonOut
+	jsonOut = &jsonWriter{out: &buf}
+	defer func() { jsonOut = saved }()
+
+	Event(map[string]any{
+		"level":    "error",
+		"provider": "openai",
+		"status":   401,
+		"message":  "request failed",
+		"error":    errors.New("bad key sk-abcdefghijklmnopqrstuvwx"),
+	})
+	log.New(jsonOut, "", 0).Printf("suggest: plain line")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2:\n%s", len(lines), buf.String())
+	}
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("entry is not JSON: %v\n%s", err, lines[0])
+	}
+	if entry["level"] != "error" || entry["provider"] != "openai" || entry["status"] != float64(401) || entry["message"] != "request failed" || entry["time"] == nil {
+		t.Errorf("entry = %v", entry)
+	}
+	if strings.Contains(lines[0], "abcdefghijklmnop") {
+		t.Errorf("entry leaked a key: %s", lines[0])
+	}
+	var plain map[string]any
+	if err := json.Unmarshal([]byte(lines[1]), &plain); err != nil || plain["message"] != "suggest: plain line" || plain["level"] != "info" {
+		t.Errorf("plain log line = %s (%v), want a wrapped info entry", lines[1], err)
+	}
+}
+
+func TestEventRespectsLogLevel(t *testing.T) {
+	var buf strings.Builder
+	savedLogger, savedLevel := logger, Level(logLevel.Load())
+	logger = log.New(&buf, "", 0)
+	defer func() { logger = savedLogger; SetLogLevel(savedLevel) }()
+
+	SetLogLevel(LevelWarn)
+	Event(map[string]any{"level": StatusLevel(503), "provider": "groq", "message": "retryable"})
+	Event(map[string]any{"level": "info", "provider": "groq", "message": "routine"})
+	DebugPrompt("groq", "the prompt")
+	Logf(LevelError, "failed: key sk-abcdefghijklmnopqrstuvwx")
+	out := buf.String()
+	if !strings.Contains(out, "WARN groq: retryable") || !strings.Contains(out, "ERROR failed") {
+		t.Errorf("log = %q, want the warn and error entries", out)
+	}
+	if strings.Contains(out, "routine") || strings.Contains(out, "the prompt") || strings.Contains(out, "abcdefghijklmnop") {
+		t.Errorf("log = %q, want info and debug dropped and keys redacted", out)
+	}
+
+	buf.Reset()
+	SetLogLevel(LevelDebug)
+	DebugPrompt("groq", "the prompt")
+	if !strings.Contains(buf.String(), `DEBUG groq: prompt prompt="the prompt"`) {
+		t.Errorf("log = %q, want the prompt at debug level", buf.String())
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for in, want := range map[string]Level{"error": LevelError, "WARN": LevelWarn, "warning": LevelWarn, " info ": LevelInfo, "debug": LevelDebug} {
+		if got, err := ParseLevel(in); err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Error("ParseLevel(loud) should fail")
+	}
+}
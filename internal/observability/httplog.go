@@ -0,0 +1,80 @@
+package observability
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+)
+
+// httpLogSnipRunes bounds each logged request or response body.
+const httpLogSnipRunes = 4000
+
+// LoggingTransport is an http.RoundTripper that records the method, URL,
+// status and redacted bodies of each exchange, for SDK-based clients whose
+// raw HTTP traffic is otherwise hidden. Headers are never logged, so
+// Authorization stays out of the log.
+type LoggingTransport struct {
+	Provider string
+	// Next performs the request; nil means http.DefaultTransport.
+	Next http.RoundTripper
+	// Logger receives the records; nil means Logger().
+	Logger *log.Logger
+}
+
+// RoundTrip implements http.RoundTripper. Bodies are read and replaced so
+// the request and response behave exactly as without logging.
+func (t *LoggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	logger := t.Logger
+	if logger == nil {
+		logger = Logger()
+	}
+
+	var reqBody []byte
+	if req.Body != nil {
+		b, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		reqBody = b
+		req.Body = io.NopCloser(bytes.NewReader(b))
+	}
+	logger.Printf("%s http request: %s %s body=%q", t.Provider, req.Method, req.URL.Redacted(), logBody(reqBody))
+
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		logger.Printf("%s http error: %v", t.Provider, RedactForLog(err.Error()))
+		return nil, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	if err != nil {
+		logger.Printf("%s http response: status=%d (body unreadable: %v)", t.Provider, resp.StatusCode, err)
+		return resp, nil
+	}
+	logger.Printf("%s http response: status=%d body=%q", t.Provider, resp.StatusCode, logBody(respBody))
+	return resp, nil
+}
+
+// CloseIdleConnections passes the call on to Next, so http.Client's method
+// of the same name still reaches the real transport.
+func (t *LoggingTransport) CloseIdleConnections() {
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if c, ok := next.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
+
+func logBody(b []byte) string {
+	return Snip(RedactForLog(string(b)), httpLogSnipRunes)
+}
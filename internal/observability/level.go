@@ -0,0 +1,85 @@
+package observability
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// LogLevelEnv sets the error log threshold: error, warn (the default), info
+// or debug.
+const LogLevelEnv = "COMMIT_COACH_LOG_LEVEL"
+
+// Level is the severity of a log entry; lower is more severe.
+type Level int32
+
+const (
+	LevelError Level = iota
+	LevelWarn
+	LevelInfo
+	LevelDebug
+)
+
+var levelNames = []string{"error", "warn", "info", "debug"}
+
+func (l Level) String() string {
+	if l < LevelError || l > LevelDebug {
+		return fmt.Sprintf("level(%d)", int32(l))
+	}
+	return levelNames[l]
+}
+
+// ParseLevel parses a level name (case-insensitive; "warning" is accepted).
+func ParseLevel(s string) (Level, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "warning" {
+		s = "warn"
+	}
+	for i, name := range levelNames {
+		if s == name {
+			return Level(i), nil
+		}
+	}
+	return LevelWarn, fmt.Errorf("invalid log level %q (want error, warn, info or debug)", s)
+}
+
+var logLevel atomic.Int32
+
+func init() {
+	logLevel.Store(int32(LevelWarn))
+}
+
+// SetLogLevel makes entries less severe than l skip the error log.
+func SetLogLevel(l Level) {
+	logLevel.Store(int32(l))
+}
+
+// LevelEnabled reports whether entries at l are written.
+func LevelEnabled(l Level) bool {
+	return l <= Level(logLevel.Load())
+}
+
+// levelFromEnv applies LogLevelEnv when it names a valid level.
+func levelFromEnv() {
+	if l, err := ParseLevel(os.Getenv(LogLevelEnv)); err == nil {
+		SetLogLevel(l)
+	}
+}
+
+// Logf writes a redacted entry at level l to the error log.
+func Logf(l Level, format string, args ...interface{}) {
+	if !LevelEnabled(l) {
+		return
+	}
+	Event(map[string]any{"level": l.String(), "message": fmt.Sprintf(format, args...)})
+}
+
+// StatusLevel classifies a failed HTTP status: rate limits and server errors
+// are usually retried, so they log as warnings; anything else is an error.
+func StatusLevel(status int) string {
+	if status == 429 || status >= 500 {
+		return LevelWarn.String()
+	}
+	return LevelError.String()
+}
@@ -0,0 +1,55 @@
+package observability
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// RunStats counts LLM activity in this process.
+type RunStats struct {
+	Calls     int64 `json:"calls"`
+	CacheHits int64 `json:"cache_hits"`
+	Failures  int64 `json:"failures"`
+}
+
+var calls, cacheHits, failures atomic.Int64
+
+// TimeLLM counts a provider round trip and returns a func that logs its
+// elapsed time when called. Without an initialized log file the returned
+// func does nothing and no clock is read.
+func TimeLLM(provider, model string) func() {
+	calls.Add(1)
+	if logger == nil || !LevelEnabled(LevelInfo) {
+		return func() {}
+	}
+	start := time.Now()
+	return func() {
+		Event(map[string]any{"level": "info", "provider": provider, "message": "llm call", "model": model, "elapsed_ms": time.Since(start).Milliseconds()})
+	}
+}
+
+// RecordCacheHit counts a suggestion served from the cache.
+func RecordCacheHit() {
+	cacheHits.Add(1)
+}
+
+// RecordLLMFailure counts a provider call that returned an error.
+func RecordLLMFailure() {
+	failures.Add(1)
+}
+
+// Stats returns a snapshot of the counters.
+func Stats() RunStats {
+	return RunStats{
+		Calls:     calls.Load(),
+		CacheHits: cacheHits.Load(),
+		Failures:  failures.Load(),
+	}
+}
+
+// ResetStats zeroes the counters.
+func ResetStats() {
+	calls.Store(0)
+	cacheHits.Store(0)
+	failures.Store(0)
+}
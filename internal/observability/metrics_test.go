@@ -0,0 +1,20 @@
+package observability
+
+import "testing"
+
+func TestStatsCounts(t *testing.T) {
+	ResetStats()
+	t.Cleanup(ResetStats)
+
+	stop := TimeLLM("groq", "llama")
+	stop()
+	TimeLLM("groq", "llama")()
+	RecordCacheHit()
+	RecordLLMFailure()
+
+	got := Stats()
+	want := RunStats{Calls: 2, CacheHits: 1, Failures: 1}
+	if got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+}
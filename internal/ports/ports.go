@@ -2,27 +2,152 @@ package ports
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 )
 
+// ErrEmptyOutput is wrapped by providers when the model answered successfully
+// but with no content to parse. It is distinct from transport/HTTP errors.
+var ErrEmptyOutput = errors.New("empty model output")
+
+// ErrInvalidJSON is wrapped by providers when the model answered but its
+// content could not be parsed as the expected JSON.
+var ErrInvalidJSON = errors.New("invalid JSON")
+
+// ProviderError is a non-success HTTP response from an LLM provider. Message
+// is the human-readable text from the provider's error envelope, already
+// redacted so it is safe to show in the UI.
+type ProviderError struct {
+	Provider   string
+	StatusCode int
+	Message    string
+}
+
+func (e *ProviderError) Error() string {
+	return fmt.Sprintf("%s returned status %d: %s", e.Provider, e.StatusCode, e.Message)
+}
+
+// HookRejectedError is returned by Git.Commit when a commit hook
+// (pre-commit, prepare-commit-msg or commit-msg) exits non-zero, so callers
+// can keep the message for a retry. Output is what the hook printed.
+type HookRejectedError struct {
+	Output string
+}
+
+func (e *HookRejectedError) Error() string {
+	return "commit rejected by a git hook: " + e.Output
+}
+
 // LLM is the interface for language model providers.
 type LLM interface {
 	SuggestCommits(ctx context.Context, input SuggestInput) ([]CommitSuggestion, error)
 }
 
+// UsageLLM is implemented by providers that report token usage.
+type UsageLLM interface {
+	LLM
+	SuggestCommitsWithUsage(ctx context.Context, input SuggestInput) ([]CommitSuggestion, *Usage, error)
+}
+
+// TemperatureLimiter is implemented by providers that may send a lower
+// temperature than requested (e.g. to keep JSON mode reliable).
+type TemperatureLimiter interface {
+	EffectiveTemperature(requested float32) float32
+}
+
+// Capabilities describes what a provider does with a request, so callers
+// can adapt, e.g. by not offering a temperature the model ignores.
+type Capabilities struct {
+	// Streaming means suggestions can be read as they are generated.
+	Streaming bool
+	// JSONMode means the API constrains replies to JSON rather than relying
+	// on the prompt alone.
+	JSONMode bool
+	// RespectsTemperature means SuggestInput.Temperature reaches the model
+	// (possibly clamped; see TemperatureLimiter).
+	RespectsTemperature bool
+}
+
+// CapableLLM is implemented by providers that report their Capabilities.
+// They can depend on the model, since the model is chosen per request.
+type CapableLLM interface {
+	LLM
+	Capabilities(model string) Capabilities
+}
+
+// ModelLister is implemented by providers that can list the models
+// available to the configured account or server.
+type ModelLister interface {
+	ListModels(ctx context.Context) ([]string, error)
+}
+
+// Usage is the token consumption reported by a provider for one call.
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// DefaultSuggestionCount is the number of suggestions requested when
+// SuggestInput.Count is unset.
+const DefaultSuggestionCount = 3
+
+// DefaultRequestTimeout bounds one suggestion request when no timeout is
+// configured.
+const DefaultRequestTimeout = 90 * time.Second
+
+// DefaultGitTimeout bounds one git command when no timeout is configured.
+const DefaultGitTimeout = 10 * time.Second
+
 // SuggestInput is the input to LLM.SuggestCommits.
 type SuggestInput struct {
-	StagedDiff string
-	FileList   []string
-	Model      string
+	StagedDiff  string
+	FileList    []string
+	Model       string
 	Temperature float32
-	Options    map[string]interface{} // provider-specific options
+	Count       int      // number of suggestions wanted; 0 means DefaultSuggestionCount
+	Examples    []string // few-shot example commit messages, already trimmed to budget
+	// LockedType and LockedSubject, when set, ask for a new body and footer
+	// for that exact header instead of fresh suggestions.
+	LockedType    string
+	LockedSubject string
+	// SystemPrompt replaces the default system message; providers still
+	// append their JSON-only instruction. "" keeps the default.
+	SystemPrompt string
+	// PromptTemplate is a text/template replacing the default suggestion
+	// prompt (see PromptTemplateData); "" keeps the default.
+	PromptTemplate string
+	// Branch is the checked-out branch name, a hint about the change's
+	// intent; "" when unknown.
+	Branch string
+	// RecentSubjects are the subject lines of the latest commits, newest
+	// first, for custom prompt templates; nil when none were read.
+	RecentSubjects []string
+	// Type, when set, is the commit type every suggestion must use (e.g.
+	// the hook's --type); "" lets the model choose.
+	Type string
+	// SubjectMaxLen is the subject length limit to ask for; 0 means the
+	// default of 72.
+	SubjectMaxLen int
+	// Notes are facts about the change the diff alone doesn't make obvious,
+	// shown to the model before the diff.
+	Notes   []string
+	Options map[string]interface{} // provider-specific options
+}
+
+// WantCount returns the number of suggestions requested.
+func (in SuggestInput) WantCount() int {
+	if in.Count <= 0 {
+		return DefaultSuggestionCount
+	}
+	return in.Count
 }
 
 // CommitSuggestion is a single commit suggestion from the LLM.
 type CommitSuggestion struct {
 	Type    string // "feat", "fix", "docs", etc.
-	Subject string // max 72 chars
+	Subject string // at most SuggestInput.SubjectMaxLen chars
 	Body    string // optional, multiline
 	Footer  string // optional, "BREAKING CHANGE: ..."
 }
@@ -30,14 +155,64 @@ type CommitSuggestion struct {
 // Git is the interface for git operations.
 type Git interface {
 	StagedDiff(ctx context.Context) (string, error)
-	Commit(ctx context.Context, message string, dryRun bool) (hash string, err error)
+	// StagedDiffForPaths is StagedDiff limited to paths (git pathspecs).
+	StagedDiffForPaths(ctx context.Context, paths []string) (string, error)
+	// WorkingTreeDiff returns unstaged changes (git diff --no-color).
+	WorkingTreeDiff(ctx context.Context) (string, error)
+	Commit(ctx context.Context, message string, opts CommitOptions) (hash string, err error)
 	IsInRepository(ctx context.Context) (bool, error)
+	// ConfigValue returns a git config value, or "" when the key is unset.
+	ConfigValue(ctx context.Context, key string) (string, error)
+	// CurrentBranch returns the checked-out branch name, or "HEAD" when detached.
+	CurrentBranch(ctx context.Context) (string, error)
+	// RootDir returns the absolute path of the working tree's top level.
+	RootDir(ctx context.Context) (string, error)
+	// CommitSubject returns the subject line of the commit ref points to.
+	CommitSubject(ctx context.Context, ref string) (string, error)
+	// RecentSubjects returns the subject lines of the last n commits on
+	// HEAD, newest first.
+	RecentSubjects(ctx context.Context, n int) ([]string, error)
+	// HeadHash returns the abbreviated hash of HEAD.
+	HeadHash(ctx context.Context) (string, error)
+	// UndoLastCommit moves HEAD back one commit and keeps its changes
+	// staged (git reset --soft HEAD~1).
+	UndoLastCommit(ctx context.Context) error
+	// RepoState reports a merge or rebase in progress.
+	RepoState(ctx context.Context) (RepoState, error)
+	// StageAll stages every working-tree change, untracked files
+	// included (git add -A).
+	StageAll(ctx context.Context) error
+}
+
+// RepoState describes an operation in progress in the repository, which
+// the next commit becomes part of.
+type RepoState struct {
+	// Merging is true while a merge waits for its commit (MERGE_HEAD).
+	Merging bool
+	// MergeMessage is git's prepared merge message (MERGE_MSG) without
+	// comment lines; empty unless Merging.
+	MergeMessage string
+	// Rebasing is true while a rebase is stopped (rebase-merge or
+	// rebase-apply).
+	Rebasing bool
+}
+
+// CommitOptions modify a Git.Commit call.
+type CommitOptions struct {
+	// DryRun reports what would be committed without committing.
+	DryRun bool
+	// NoVerify skips the pre-commit and commit-msg hooks (--no-verify).
+	NoVerify bool
+	// Paths commits only these pathspecs (git commit -- <paths>); other
+	// staged changes stay staged. Empty commits the whole index.
+	Paths []string
 }
 
 // Redactor redacts sensitive data from text.
 type Redactor interface {
 	Redact(text string) string
 	RedactLog(text string) string // for logging (more aggressive)
+	Contains(text string) bool    // whether Redact would change text
 }
 
 // Clock provides current time (mockable).
@@ -49,4 +224,15 @@ type Clock interface {
 type Cache interface {
 	Get(ctx context.Context, key string) ([]CommitSuggestion, error)
 	Set(ctx context.Context, key string, suggestions []CommitSuggestion) error
+	// Delete drops key's entry; deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// FailureNotes remembers the last failure kind per provider and model across
+// runs, so a repeat failure can come with a hint instead of a fresh diagnosis.
+type FailureNotes interface {
+	// LastFailure returns the recorded kind, or "" when there is none.
+	LastFailure(provider, model string) string
+	// RecordFailure stores kind; "" clears the note after a success.
+	RecordFailure(provider, model, kind string) error
 }
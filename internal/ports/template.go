@@ -0,0 +1,55 @@
+package ports
+
+import (
+	"io"
+	"strings"
+	"text/template"
+)
+
+// PromptTemplateData is what a suggestion prompt template
+// (SuggestInput.PromptTemplate) is rendered with.
+type PromptTemplateData struct {
+	Diff     string
+	FileList []string
+	// RecentSubjects are the subject lines of the latest commits, newest
+	// first (SuggestInput.RecentSubjects).
+	RecentSubjects []string
+	// ExampleSubjects are the subject lines of the few-shot examples
+	// (SuggestInput.Examples).
+	ExampleSubjects []string
+	// Branch is the checked-out branch, "" when unknown.
+	Branch string
+	Count  int
+	// Types are the allowed commit types: just SuggestInput.Type when set.
+	Types         []string
+	SubjectMaxLen int
+	// Context is the examples, branch, type and notes blocks the default
+	// prompt places before the diff; "" when there are none.
+	Context string
+}
+
+// ParsePromptTemplate parses text as a prompt template. Templates may call
+// join (strings.Join); a missing field is an error.
+func ParsePromptTemplate(text string) (*template.Template, error) {
+	return template.New("prompt").Funcs(template.FuncMap{"join": strings.Join}).Option("missingkey=error").Parse(text)
+}
+
+// ValidatePromptTemplate reports whether text parses and renders with
+// sample data, so a bad template is caught when the config is checked
+// rather than per request.
+func ValidatePromptTemplate(text string) error {
+	tmpl, err := ParsePromptTemplate(text)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(io.Discard, PromptTemplateData{
+		Diff:            "+x",
+		FileList:        []string{"x"},
+		RecentSubjects:  []string{"fix: handle y"},
+		ExampleSubjects: []string{"feat: add x"},
+		Branch:          "feature/x",
+		Count:           DefaultSuggestionCount,
+		Types:           []string{"feat", "fix"},
+		SubjectMaxLen:   72,
+	})
+}
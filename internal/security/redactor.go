@@ -1,7 +1,9 @@
 package security
 
 import (
+	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
@@ -28,12 +30,54 @@ func NewRedactor() *Redactor {
 		// GitHub tokens
 		regexp.MustCompile(`ghp_[a-zA-Z0-9]{36}`),
 		regexp.MustCompile(`ghu_[a-zA-Z0-9]{36}`),
-		// Private keys (PEM format start)
-		regexp.MustCompile(`-----BEGIN (?:RSA |DSA |EC )?PRIVATE KEY-----`),
+		// Slack tokens
+		regexp.MustCompile(`xox[baprs]-[0-9A-Za-z\-]{10,}`),
+		// Stripe keys; short sk_test_ mentions in docs and comments are left alone
+		regexp.MustCompile(`(?:sk|rk)_(?:live|test)_[0-9a-zA-Z]{24,}`),
+		regexp.MustCompile(`pk_live_[0-9a-zA-Z]{24,}`),
+		// JWTs (header.payload.signature)
+		regexp.MustCompile(`eyJ[a-zA-Z0-9_\-]{10,}\.eyJ[a-zA-Z0-9_\-]{10,}\.[a-zA-Z0-9_\-]{10,}`),
+		// GCP service-account and Azure credential JSON fields
+		regexp.MustCompile(`"(?:private_key|private_key_id|client_secret|clientSecret)":\s*"[^"]+"`),
+		// Azure storage connection strings
+		regexp.MustCompile(`AccountKey=[a-zA-Z0-9+/=]{20,}`),
+		// Private key blocks (PEM and OpenSSH), then a bare header if the block
+		// was cut off by the diff cap
+		regexp.MustCompile(`-----BEGIN (?:RSA |DSA |EC |OPENSSH |ENCRYPTED )?PRIVATE KEY-----[\s\S]*?-----END (?:RSA |DSA |EC |OPENSSH |ENCRYPTED )?PRIVATE KEY-----`),
+		regexp.MustCompile(`-----BEGIN (?:RSA |DSA |EC |OPENSSH |ENCRYPTED )?PRIVATE KEY-----`),
 	}
 	return &Redactor{patterns: patterns}
 }
 
+// NewRedactorWithPatterns creates a redactor with the default patterns plus
+// extra, which are Go regular expressions (e.g. team-specific token formats).
+func NewRedactorWithPatterns(extra []string) (*Redactor, error) {
+	compiled, err := CompilePatterns(extra)
+	if err != nil {
+		return nil, err
+	}
+	r := NewRedactor()
+	r.patterns = append(r.patterns, compiled...)
+	return r, nil
+}
+
+// CompilePatterns compiles user-supplied redaction patterns, rejecting empty
+// ones since they would match everywhere.
+func CompilePatterns(exprs []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(exprs))
+	for _, expr := range exprs {
+		if strings.TrimSpace(expr) == "" {
+			return nil, fmt.Errorf("redact pattern must not be empty")
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact pattern %q: %w", expr, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
 // Redact removes sensitive patterns from text.
 func (r *Redactor) Redact(text string) string {
 	result := text
@@ -65,11 +109,18 @@ func (r *Redactor) Contains(text string) bool {
 	return false
 }
 
-// SummarizeRedactions describes what was redacted.
+// redactionMarkers are the placeholders Redact and RedactLog substitute.
+var redactionMarkers = []string{"[REDACTED]", "[IP]", "[EMAIL]"}
+
+// SummarizeRedactions describes what was redacted. It works for both Redact
+// and RedactLog output; markers already present in original are not counted.
 func SummarizeRedactions(original, redacted string) string {
 	if original == redacted {
 		return "no redactions"
 	}
-	count := strings.Count(redacted, "[REDACTED]")
-	return "removed " + string(rune(count)) + " secret(s)"
+	count := 0
+	for _, marker := range redactionMarkers {
+		count += strings.Count(redacted, marker) - strings.Count(original, marker)
+	}
+	return "removed " + strconv.Itoa(count) + " secret(s)"
 }
@@ -5,6 +5,8 @@ import (
 	"crypto/sha256"
 	"fmt"
 	"io"
+	"slices"
+	"time"
 
 	"github.com/chuckie/commit-coach/internal/ports"
 )
@@ -12,25 +14,82 @@ import (
 // FakeLLM is a deterministic fake LLM for testing.
 type FakeLLM struct {
 	Suggestions []ports.CommitSuggestion
+	Usage       *ports.Usage
 	Err         error
+	Errs        []error // returned one per call, in order, before Err applies
 	CallCount   int
+	LastInput   ports.SuggestInput
+	// Delay makes each call wait this long, or until ctx is done.
+	Delay time.Duration
+	// CloseCalls counts Close calls; CloseErr is what Close returns.
+	CloseCalls int
+	CloseErr   error
+}
+
+func (f *FakeLLM) Close() error {
+	f.CloseCalls++
+	return f.CloseErr
 }
 
 func (f *FakeLLM) SuggestCommits(ctx context.Context, input ports.SuggestInput) ([]ports.CommitSuggestion, error) {
+	suggestions, _, err := f.SuggestCommitsWithUsage(ctx, input)
+	return suggestions, err
+}
+
+func (f *FakeLLM) SuggestCommitsWithUsage(ctx context.Context, input ports.SuggestInput) ([]ports.CommitSuggestion, *ports.Usage, error) {
 	f.CallCount++
+	f.LastInput = input
+	if f.Delay > 0 {
+		select {
+		case <-time.After(f.Delay):
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+	}
+	if len(f.Errs) > 0 {
+		err := f.Errs[0]
+		f.Errs = f.Errs[1:]
+		if err != nil {
+			return nil, nil, err
+		}
+	}
 	if f.Err != nil {
-		return nil, f.Err
+		return nil, nil, f.Err
 	}
-	return f.Suggestions, nil
+	return f.Suggestions, f.Usage, nil
 }
 
 // FakeGit is a fake git adapter for testing.
 type FakeGit struct {
-	StagedDiffContent string
-	StagedDiffErr     error
-	CommittedMessages []string
-	CommitErr         error
-	IsInRepoValue     bool
+	StagedDiffContent      string
+	StagedDiffErr          error
+	PathDiffs              map[string]string // StagedDiffForPaths content per path
+	LastPaths              []string
+	PathDiffCalls          int // StagedDiffForPaths calls
+	WorkingTreeDiffContent string
+	WorkingTreeDiffErr     error
+	CommittedMessages      []string
+	StagedFiles            []string // what Commit leaves staged; see Commit
+	LastCommitOptions      ports.CommitOptions
+	CommitErr              error
+	IsInRepoValue          bool
+	ConfigValues           map[string]string
+	ConfigErr              error
+	Branch                 string
+	BranchErr              error
+	RootDirValue           string
+	RootDirErr             error
+	Subjects               map[string]string // CommitSubject results by ref
+	RecentSubjectsValue    []string          // RecentSubjects results, newest first
+	HeadHashValue          string
+	HeadHashErr            error
+	HeadHashCalls          int
+	UndoCalls              int
+	UndoErr                error
+	RepoStateValue         ports.RepoState
+	RepoStateErr           error
+	StageAllCalls          int
+	StageAllErr            error
 }
 
 func (f *FakeGit) StagedDiff(ctx context.Context) (string, error) {
@@ -40,12 +99,42 @@ func (f *FakeGit) StagedDiff(ctx context.Context) (string, error) {
 	return f.StagedDiffContent, nil
 }
 
-func (f *FakeGit) Commit(ctx context.Context, message string, dryRun bool) (string, error) {
+func (f *FakeGit) StagedDiffForPaths(ctx context.Context, paths []string) (string, error) {
+	if f.StagedDiffErr != nil {
+		return "", f.StagedDiffErr
+	}
+	f.LastPaths = paths
+	f.PathDiffCalls++
+	var diff string
+	for _, path := range paths {
+		diff += f.PathDiffs[path]
+	}
+	return diff, nil
+}
+
+func (f *FakeGit) WorkingTreeDiff(ctx context.Context) (string, error) {
+	if f.WorkingTreeDiffErr != nil {
+		return "", f.WorkingTreeDiffErr
+	}
+	return f.WorkingTreeDiffContent, nil
+}
+
+// Commit records the message and removes the committed files from
+// StagedFiles: all of them, or only those in opts.Paths.
+func (f *FakeGit) Commit(ctx context.Context, message string, opts ports.CommitOptions) (string, error) {
+	f.LastCommitOptions = opts
 	if f.CommitErr != nil {
 		return "", f.CommitErr
 	}
-	if !dryRun {
+	if !opts.DryRun {
 		f.CommittedMessages = append(f.CommittedMessages, message)
+		var kept []string
+		for _, file := range f.StagedFiles {
+			if len(opts.Paths) > 0 && !slices.Contains(opts.Paths, file) {
+				kept = append(kept, file)
+			}
+		}
+		f.StagedFiles = kept
 	}
 	return "abc123def456", nil
 }
@@ -54,6 +143,85 @@ func (f *FakeGit) IsInRepository(ctx context.Context) (bool, error) {
 	return f.IsInRepoValue, nil
 }
 
+func (f *FakeGit) ConfigValue(ctx context.Context, key string) (string, error) {
+	if f.ConfigErr != nil {
+		return "", f.ConfigErr
+	}
+	return f.ConfigValues[key], nil
+}
+
+func (f *FakeGit) CurrentBranch(ctx context.Context) (string, error) {
+	if f.BranchErr != nil {
+		return "", f.BranchErr
+	}
+	return f.Branch, nil
+}
+
+func (f *FakeGit) RootDir(ctx context.Context) (string, error) {
+	if f.RootDirErr != nil {
+		return "", f.RootDirErr
+	}
+	return f.RootDirValue, nil
+}
+
+func (f *FakeGit) RecentSubjects(ctx context.Context, n int) ([]string, error) {
+	if len(f.RecentSubjectsValue) > n {
+		return f.RecentSubjectsValue[:n], nil
+	}
+	return f.RecentSubjectsValue, nil
+}
+
+func (f *FakeGit) CommitSubject(ctx context.Context, ref string) (string, error) {
+	subject, ok := f.Subjects[ref]
+	if !ok {
+		return "", fmt.Errorf("unknown revision %q", ref)
+	}
+	return subject, nil
+}
+
+// HeadHash returns HeadHashValue, or the hash Commit reports when it is
+// unset.
+func (f *FakeGit) HeadHash(ctx context.Context) (string, error) {
+	f.HeadHashCalls++
+	if f.HeadHashErr != nil {
+		return "", f.HeadHashErr
+	}
+	if f.HeadHashValue == "" {
+		return "abc123def456", nil
+	}
+	return f.HeadHashValue, nil
+}
+
+// UndoLastCommit counts calls and drops the last committed message.
+func (f *FakeGit) UndoLastCommit(ctx context.Context) error {
+	f.UndoCalls++
+	if f.UndoErr != nil {
+		return f.UndoErr
+	}
+	if n := len(f.CommittedMessages); n > 0 {
+		f.CommittedMessages = f.CommittedMessages[:n-1]
+	}
+	return nil
+}
+
+func (f *FakeGit) RepoState(ctx context.Context) (ports.RepoState, error) {
+	if f.RepoStateErr != nil {
+		return ports.RepoState{}, f.RepoStateErr
+	}
+	return f.RepoStateValue, nil
+}
+
+// StageAll counts calls and moves the working-tree diff into the index.
+func (f *FakeGit) StageAll(ctx context.Context) error {
+	f.StageAllCalls++
+	if f.StageAllErr != nil {
+		return f.StageAllErr
+	}
+	f.StagedDiffContent += f.WorkingTreeDiffContent
+	f.WorkingTreeDiffContent = ""
+	return nil
+}
+
 // FakeRedactor is a fake redactor that does nothing.
 type FakeRedactor struct{}
 
@@ -65,9 +233,14 @@ func (f *FakeRedactor) RedactLog(text string) string {
 	return text
 }
 
+func (f *FakeRedactor) Contains(text string) bool {
+	return false
+}
+
 // FakeCache is a simple in-memory fake cache.
 type FakeCache struct {
-	data map[string][]ports.CommitSuggestion
+	data    map[string][]ports.CommitSuggestion
+	Deleted []string // keys passed to Delete, in order
 }
 
 func NewFakeCache() *FakeCache {
@@ -88,6 +261,30 @@ func (f *FakeCache) Set(ctx context.Context, key string, suggestions []ports.Com
 	return nil
 }
 
+func (f *FakeCache) Delete(ctx context.Context, key string) error {
+	f.Deleted = append(f.Deleted, key)
+	delete(f.data, key)
+	return nil
+}
+
+// FakeClock is a ports.Clock that only moves when told to.
+type FakeClock struct {
+	T time.Time
+	// Step, when set, advances the clock after every Now call.
+	Step time.Duration
+}
+
+func (f *FakeClock) Now() time.Time {
+	now := f.T
+	f.T = f.T.Add(f.Step)
+	return now
+}
+
+// Advance moves the clock forward by d.
+func (f *FakeClock) Advance(d time.Duration) {
+	f.T = f.T.Add(d)
+}
+
 // DiffHash computes SHA256 hash of a diff string.
 func DiffHash(diff string) string {
 	h := sha256.New()
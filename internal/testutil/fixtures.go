@@ -1,6 +1,7 @@
 package testutil
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/chuckie/commit-coach/internal/ports"
@@ -22,6 +23,20 @@ index 1234567..abcdefg 100644
  }
 `
 
+// SampleDiffWithBinary stages an image alongside SampleDiffSmall.
+const SampleDiffWithBinary = `diff --git a/logo.png b/logo.png
+new file mode 100644
+index 0000000..89abcde
+Binary files /dev/null and b/logo.png differ
+` + SampleDiffSmall
+
+// SampleDiffSubmoduleLog is a staged diff with a submodule summarized by
+// git diff --submodule=log.
+const SampleDiffSubmoduleLog = `Submodule vendor/parser 1a2b3c4..5d6e7f8:
+  > fix: handle empty input
+  > feat: support nested lists
+` + SampleDiffSmall
+
 // SampleDiffLarge is a large sample diff for testing diff capping (generated at runtime).
 var SampleDiffLarge = func() string {
 	const header = `diff --git a/very_long_file.go b/very_long_file.go
@@ -32,9 +47,21 @@ index 1234567..abcdefg 100644
  package main
 
 `
-	return header + strings.Repeat("// This is a very long comment line that repeats\n", 200)
+	return header + strings.Repeat("+// This is a very long comment line that repeats\n", 200)
 }()
 
+// SampleDiffManyFiles returns a diff touching n files; file i adds i+1 lines,
+// so the last files are the largest.
+func SampleDiffManyFiles(n int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("pkg/file%03d.go", i)
+		fmt.Fprintf(&b, "diff --git a/%s b/%s\n--- a/%s\n+++ b/%s\n@@ -0,0 +1,%d @@\n", name, name, name, name, i+1)
+		b.WriteString(strings.Repeat("+// line\n", i+1))
+	}
+	return b.String()
+}
+
 // SampleLLMResponse returns a sample valid LLM response.
 func SampleLLMResponse() []ports.CommitSuggestion {
 	return []ports.CommitSuggestion{
@@ -0,0 +1,71 @@
+package ui
+
+import (
+	"errors"
+	"time"
+
+	"github.com/atotto/clipboard"
+)
+
+// clipboardTimeout bounds clipboard access. On some remote/X-forwarded
+// terminals the system clipboard can block for seconds.
+const clipboardTimeout = 750 * time.Millisecond
+
+var errClipboardUnavailable = errors.New("clipboard unavailable, type the key manually")
+
+// Clipboard abstracts system clipboard access so it can be faked in tests.
+type Clipboard interface {
+	ReadAll() (string, error)
+	WriteAll(text string) error
+}
+
+type systemClipboard struct{}
+
+func (systemClipboard) ReadAll() (string, error) { return clipboard.ReadAll() }
+
+func (systemClipboard) WriteAll(text string) error { return clipboard.WriteAll(text) }
+
+// timeoutClipboard runs clipboard calls in a goroutine and gives up after
+// timeout so a hung clipboard never freezes the UI.
+type timeoutClipboard struct {
+	inner   Clipboard
+	timeout time.Duration
+}
+
+func newTimeoutClipboard(inner Clipboard, timeout time.Duration) *timeoutClipboard {
+	return &timeoutClipboard{inner: inner, timeout: timeout}
+}
+
+func (c *timeoutClipboard) ReadAll() (string, error) {
+	type result struct {
+		text string
+		err  error
+	}
+	// Buffered so an abandoned read does not leak a blocked goroutine forever.
+	ch := make(chan result, 1)
+	go func() {
+		text, err := c.inner.ReadAll()
+		ch <- result{text: text, err: err}
+	}()
+
+	select {
+	case r := <-ch:
+		return r.text, r.err
+	case <-time.After(c.timeout):
+		return "", errClipboardUnavailable
+	}
+}
+
+func (c *timeoutClipboard) WriteAll(text string) error {
+	ch := make(chan error, 1)
+	go func() {
+		ch <- c.inner.WriteAll(text)
+	}()
+
+	select {
+	case err := <-ch:
+		return err
+	case <-time.After(c.timeout):
+		return errClipboardUnavailable
+	}
+}
@@ -0,0 +1,67 @@
+package ui
+
+import (
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/chuckie/commit-coach/internal/config"
+)
+
+// blockingClipboard never returns until released, like a hung X clipboard.
+type blockingClipboard struct {
+	release chan struct{}
+}
+
+func (c *blockingClipboard) ReadAll() (string, error) {
+	<-c.release
+	return "", nil
+}
+
+func (c *blockingClipboard) WriteAll(string) error {
+	<-c.release
+	return nil
+}
+
+func TestClipboardTimeout(t *testing.T) {
+	blocked := &blockingClipboard{release: make(chan struct{})}
+	defer close(blocked.release)
+	cb := newTimeoutClipboard(blocked, 10*time.Millisecond)
+
+	if _, err := cb.ReadAll(); err != errClipboardUnavailable {
+		t.Errorf("ReadAll() error = %v, want %v", err, errClipboardUnavailable)
+	}
+	if err := cb.WriteAll("x"); err != errClipboardUnavailable {
+		t.Errorf("WriteAll() error = %v, want %v", err, errClipboardUnavailable)
+	}
+}
+
+func TestSetupPasteWithHungClipboard(t *testing.T) {
+	blocked := &blockingClipboard{release: make(chan struct{})}
+	defer close(blocked.release)
+
+	m := NewSetup(&config.Config{Provider: "openai"})
+	m.clipboard = newTimeoutClipboard(blocked, 10*time.Millisecond)
+	m.step = setupStepAPIKey
+	m.apiKeyInput.Focus()
+
+	done := make(chan struct{})
+	go func() {
+		m.Update(tea.KeyMsg{Type: tea.KeyCtrlV})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("paste blocked the wizard")
+	}
+
+	if m.err != errClipboardUnavailable {
+		t.Errorf("err = %v, want %v", m.err, errClipboardUnavailable)
+	}
+	if m.step != setupStepAPIKey {
+		t.Errorf("step = %v, want API key step", m.step)
+	}
+}
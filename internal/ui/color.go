@@ -0,0 +1,59 @@
+package ui
+
+import (
+	"os"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/chuckie/commit-coach/internal/observability"
+)
+
+// NoColorEnv turns colors off when set to any non-empty value; see
+// https://no-color.org.
+const NoColorEnv = "NO_COLOR"
+
+// spinnerStyle colors the loading spinner unless colors are off.
+var spinnerStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
+
+// NoColorRequested reports whether NoColorEnv is set.
+func NoColorRequested() bool {
+	return os.Getenv(NoColorEnv) != ""
+}
+
+// SetNoColor renders every view as plain text, without colors or other
+// styling, for logs and terminals where colors are unreadable. New turns
+// it on when NoColorRequested.
+func (m *Model) SetNoColor(enabled bool) {
+	m.noColor = enabled
+	if enabled {
+		m.spinner.Style = lipgloss.NewStyle()
+	} else {
+		m.spinner.Style = spinnerStyle
+	}
+}
+
+// plainEditor strips the textarea's default colors.
+func plainEditor(ta *textarea.Model) {
+	ta.FocusedStyle = textarea.Style{}
+	ta.BlurredStyle = textarea.Style{}
+}
+
+// PlainEnv turns on plain mode when set to a truthy value; see SetPlain.
+const PlainEnv = "COMMIT_COACH_PLAIN"
+
+// PlainRequested reports whether PlainEnv is set.
+func PlainRequested() bool {
+	return observability.EnvEnabled(PlainEnv)
+}
+
+// SetPlain renders for screen readers: no spinner or colors, suggestions
+// labeled "Suggestion 1 of 3, selected" instead of marked with an arrow,
+// and key bindings spelled out as sentences. New turns it on when
+// PlainRequested.
+func (m *Model) SetPlain(enabled bool) {
+	m.plain = enabled
+	if enabled {
+		m.SetNoColor(true)
+	}
+}
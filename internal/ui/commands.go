@@ -2,22 +2,99 @@ package ui
 
 import (
 	"context"
+	"errors"
+	"time"
 
+	"github.com/charmbracelet/bubbles/textarea"
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/chuckie/commit-coach/internal/config"
+	"github.com/chuckie/commit-coach/internal/app"
 	"github.com/chuckie/commit-coach/internal/domain"
+	"github.com/chuckie/commit-coach/internal/ports"
 )
 
-// cmdLoadSuggestions loads suggestions asynchronously.
-func (m *Model) cmdLoadSuggestions() tea.Msg {
-	ctx := context.Background()
-	suggestions, err := m.app.Suggest.SuggestCommits(ctx, m.provider, m.model, m.temperature)
-	return msgSuggestionsLoaded{
-		suggestions: suggestions,
-		err:         err,
+// beginCancellableLoad enters StateLoading for a provider call that Esc can
+// cancel, returning the call's context.
+func (m *Model) beginCancellableLoad() context.Context {
+	ctx, cancel := context.WithCancel(m.context())
+	m.cancelLoad = cancel
+	m.loadStart = time.Now()
+	m.notice = ""
+	m.state = StateLoading
+	return ctx
+}
+
+// endCancellableLoad releases the context of a finished provider call.
+func (m *Model) endCancellableLoad() {
+	if m.cancelLoad != nil {
+		m.cancelLoad()
+		m.cancelLoad = nil
 	}
 }
 
+// canceledErr reports context.Canceled when ctx was cancelled, however the
+// provider wrapped it, and err otherwise.
+func canceledErr(ctx context.Context, err error) error {
+	if err != nil && errors.Is(ctx.Err(), context.Canceled) {
+		return context.Canceled
+	}
+	return err
+}
+
+// loadSuggestions starts loading suggestions asynchronously.
+func (m *Model) loadSuggestions() tea.Cmd {
+	return m.fetchSuggestions(m.app.Suggest.SuggestCommitsDetailed)
+}
+
+// regenerateSuggestions is loadSuggestions bypassing the cache, so the
+// regenerate key always gets fresh results.
+func (m *Model) regenerateSuggestions() tea.Cmd {
+	return m.fetchSuggestions(m.app.Suggest.RegenerateCommitsDetailed)
+}
+
+func (m *Model) fetchSuggestions(suggest func(ctx context.Context, provider, model string, temperature float32) (*app.SuggestResult, error)) tea.Cmd {
+	ctx := m.beginCancellableLoad()
+	return func() tea.Msg {
+		result, err := suggest(ctx, m.provider, m.model, m.temperature)
+		if err != nil {
+			return msgSuggestionsLoaded{err: canceledErr(ctx, err)}
+		}
+		return msgSuggestionsLoaded{
+			suggestions: result.Suggestions,
+			usage:       result.Usage,
+			warnings:    result.Warnings,
+			redacted:    result.Redacted,
+			unstaged:    result.Unstaged,
+			truncation:  result.TruncationNote(),
+			repoState:   result.RepoStateNote(),
+			cached:      result.Cached,
+		}
+	}
+}
+
+// regenerateBody starts rewriting the selected suggestion's body, keeping
+// its header.
+func (m *Model) regenerateBody() tea.Cmd {
+	ctx := m.beginCancellableLoad()
+	index := m.selectedIndex
+	chosen := m.suggestions[index]
+	return func() tea.Msg {
+		s, err := m.app.Suggest.RegenerateBody(ctx, m.provider, m.model, m.temperature, chosen)
+		return msgBodyRegenerated{index: index, suggestion: s, err: canceledErr(ctx, err)}
+	}
+}
+
+// cmdLoadSummary builds the pre-flight summary for the selected suggestion.
+func (m *Model) cmdLoadSummary() tea.Msg {
+	summary, err := m.app.Suggest.Summarize(m.context(), m.suggestions[m.selectedIndex])
+	return msgSummaryLoaded{summary: summary, err: err}
+}
+
+// cmdLoadDiff reads the diff the suggestions describe for the preview pane.
+func (m *Model) cmdLoadDiff() tea.Msg {
+	diff, err := m.app.Suggest.PreviewDiff(m.context())
+	return msgDiffLoaded{diff: diff, err: err}
+}
+
 // cmdCommit commits the selected message.
 func (m *Model) cmdCommit() tea.Msg {
 	if m.selectedIndex < 0 || m.selectedIndex >= len(m.suggestions) {
@@ -27,92 +104,156 @@ func (m *Model) cmdCommit() tea.Msg {
 		}
 	}
 
+	// Not the session context: quitting must not kill git mid-commit.
 	ctx := context.Background()
+	if !m.dryRun && !m.detachedOK && m.app.Commit.DetachedHead(ctx) {
+		return msgConfirmDetached{}
+	}
+	if m.unstaged && !m.dryRun {
+		return msgCommitComplete{err: errors.New("nothing is staged; stage the changes with git add before committing")}
+	}
 	msg := m.suggestions[m.selectedIndex].Format()
-	hash, err := m.app.Commit.Commit(ctx, msg, m.dryRun)
+	hash, err := m.app.Commit.Commit(ctx, msg, ports.CommitOptions{DryRun: m.dryRun, NoVerify: m.noVerify})
 	return msgCommitComplete{
 		hash: hash,
 		err:  err,
 	}
 }
 
-// handleListKeys handles keybindings in list state.
+// cmdStageAll runs git add -A after the user chose to from the
+// no-staged-changes error.
+func (m *Model) cmdStageAll() tea.Msg {
+	_, err := m.app.Commit.StageAllIfNothingStaged(m.context())
+	return msgStagedAll{err: err}
+}
+
+// cmdUndo undoes the commit this session just made.
+func (m *Model) cmdUndo() tea.Msg {
+	// Like cmdCommit, not the session context.
+	return msgUndoComplete{err: m.app.Commit.Undo(context.Background(), m.undoHash)}
+}
+
+// handleListKeys handles keybindings in list state, as mapped by m.keys.
 func (m *Model) handleListKeys(msg tea.KeyMsg) (*Model, tea.Cmd) {
-	switch msg.String() {
-	case "up", "k":
+	key := m.resolveKey(msg.String())
+	switch k := &m.keys; {
+	case key == "":
+		// Waiting for the rest of a key sequence.
+	case k.Up.Matches(key):
 		if m.selectedIndex > 0 {
 			m.selectedIndex--
 		}
-	case "down", "j":
+	case k.Down.Matches(key):
 		if m.selectedIndex < len(m.suggestions)-1 {
 			m.selectedIndex++
 		}
-	case "e":
-		m.isEditing = true
-		m.state = StateEdit
+	case k.First.Matches(key):
+		m.selectedIndex = 0
+	case k.Last.Matches(key):
+		m.selectedIndex = max(len(m.suggestions)-1, 0)
+	case k.Edit.Matches(key):
 		if m.selectedIndex < len(m.suggestions) {
-			m.editText = m.suggestions[m.selectedIndex].Format()
+			m.isEditing = true
+			m.state = StateEdit
+			m.editErr = ""
+			m.editor = newEditor(m.suggestions[m.selectedIndex].Format(), m.width, m.height)
+			if m.noColor {
+				plainEditor(&m.editor)
+			}
+			return m, m.editor.Focus()
 		}
-	case "r":
-		m.state = StateLoading
-		return m, m.cmdLoadSuggestions
-	case "s":
+	case k.Regenerate.Matches(key):
+		return m, m.regenerateSuggestions()
+	case k.RewriteBody.Matches(key):
+		if m.selectedIndex < len(m.suggestions) {
+			return m, m.regenerateBody()
+		}
+	case k.Setup.Matches(key):
 		m.state = StateSetup
-		m.setup = NewSetupEmbedded(&config.Config{Provider: m.provider, Model: m.model, OllamaURL: m.ollamaURL})
+		m.setup = m.newSetup()
 		return m, nil
-	case "n":
+	case k.DryRun.Matches(key):
 		m.dryRun = true
 		m.state = StateDryRun
-	case "enter":
-		m.dryRun = false
+	case k.Summary.Matches(key):
+		if m.selectedIndex < len(m.suggestions) {
+			m.state = StateLoading
+			return m, m.cmdLoadSummary
+		}
+	case k.Diff.Matches(key):
 		m.state = StateLoading
+		return m, m.cmdLoadDiff
+	case k.NoVerify.Matches(key):
+		m.noVerify = !m.noVerify
+	case k.Commit.Matches(key):
+		// Skipping hooks is always confirmed.
+		if (m.confirmCommit || m.noVerify) && m.selectedIndex < len(m.suggestions) {
+			m.state = StateConfirm
+			return m, nil
+		}
+		m.dryRun = m.dryRunMode
+		m.state = StateLoading
+		m.recordChosen()
 		return m, m.cmdCommit
 	}
 
 	return m, nil
 }
 
-// handleEditKeys handles keybindings in edit state.
+// newEditor returns a focused-ready multiline editor holding text, sized to
+// the window with room for the title and help lines.
+func newEditor(text string, width, height int) textarea.Model {
+	ta := textarea.New()
+	ta.ShowLineNumbers = false
+	ta.CharLimit = 0
+	ta.SetWidth(max(width-2, 20))
+	ta.SetHeight(max(height-8, 5))
+	ta.SetValue(text)
+	return ta
+}
+
+// handleEditKeys handles keybindings in edit state: Ctrl+S validates and
+// saves, Esc discards, everything else edits the buffer.
 func (m *Model) handleEditKeys(msg tea.KeyMsg) (*Model, tea.Cmd) {
 	switch msg.String() {
 	case "ctrl+s":
-		// Save and parse edited message
-		// For now, parse simple "type: subject" format
-		m.state = StateList
-		m.isEditing = false
-
-		// Try to parse the edited text as a new suggestion
+		parsed, err := m.parseEditedMessage(m.editor.Value())
+		if err != nil {
+			// Keep the buffer so the user can fix it.
+			m.editErr = err.Error()
+			return m, nil
+		}
 		if m.selectedIndex < len(m.suggestions) {
-			parsed := m.parseEditedMessage(m.editText)
-			if parsed != nil {
-				m.suggestions[m.selectedIndex] = *parsed
-			}
+			m.suggestions[m.selectedIndex] = parsed
 		}
+		m.state = StateList
+		m.isEditing = false
+		m.editErr = ""
+		return m, nil
 
 	case "esc":
 		m.state = StateList
 		m.isEditing = false
-		m.editText = ""
+		m.editErr = ""
+		return m, nil
 	}
 
-	return m, nil
+	var cmd tea.Cmd
+	m.editor, cmd = m.editor.Update(msg)
+	return m, cmd
 }
 
-// parseEditedMessage attempts to parse edited message back into suggestion.
-func (m *Model) parseEditedMessage(text string) *domain.Suggestion {
-	// Simple parsing: "type: subject" or multiline with body
-	// For MVP, just update the subject if it's simple format
-	lines := len(text) > 0
-	if !lines {
-		return nil
+// parseEditedMessage parses the edited buffer ("type: subject", then
+// blank-line separated body and footer) and validates it against the
+// configured subject limits.
+func (m *Model) parseEditedMessage(text string) (domain.Suggestion, error) {
+	parsed := domain.ParseMessage(text)
+	limits := domain.SubjectLimits{}
+	if m.app != nil {
+		limits = m.app.Suggest.SubjectLimits()
 	}
-
-	// Return a minimal suggestion for now
-	// TODO: improve parsing
-	return &domain.Suggestion{
-		Type:    "fix",
-		Subject: text,
-		Body:    "",
-		Footer:  "",
+	if err := parsed.ValidateWith(limits); err != nil {
+		return domain.Suggestion{}, err
 	}
+	return parsed, nil
 }
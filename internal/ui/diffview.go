@@ -0,0 +1,75 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	diffAddedStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+	diffRemovedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+	diffHunkStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("6"))
+	diffHeaderStyle  = lipgloss.NewStyle().Bold(true)
+)
+
+// diffPreviewChrome is the number of lines around the viewport: the title,
+// a blank line and the scroll footer.
+const diffPreviewChrome = 3
+
+// colorizeDiff styles a unified diff: added lines green, removed lines red,
+// hunk headers cyan and file headers bold.
+func colorizeDiff(diff string) string {
+	lines := strings.Split(strings.TrimRight(diff, "\n"), "\n")
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "diff --git "), strings.HasPrefix(line, "+++ "), strings.HasPrefix(line, "--- "):
+			lines[i] = diffHeaderStyle.Render(line)
+		case strings.HasPrefix(line, "@@"):
+			lines[i] = diffHunkStyle.Render(line)
+		case strings.HasPrefix(line, "+"):
+			lines[i] = diffAddedStyle.Render(line)
+		case strings.HasPrefix(line, "-"):
+			lines[i] = diffRemovedStyle.Render(line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// newDiffPreview returns a scrollable viewport over the diff, colorized
+// when color is set.
+func newDiffPreview(diff string, width, height int, color bool) viewport.Model {
+	vp := viewport.New(width, max(height-diffPreviewChrome, 1))
+	if color {
+		diff = colorizeDiff(diff)
+	}
+	vp.SetContent(diff)
+	return vp
+}
+
+// handleDiffPreviewKeys scrolls the preview; Esc or d returns to the list.
+func (m *Model) handleDiffPreviewKeys(msg tea.KeyMsg) (*Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "d":
+		m.state = StateList
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.diffView, cmd = m.diffView.Update(msg)
+	return m, cmd
+}
+
+// viewDiffPreview renders the diff preview with its scroll position.
+func (m *Model) viewDiffPreview() string {
+	title := "Diff to be committed (↑/↓ PgUp/PgDn to scroll, Esc or d to return)"
+	if m.unstaged {
+		title = "Unstaged working-tree diff (↑/↓ PgUp/PgDn to scroll, Esc or d to return)"
+	}
+	footer := "(end)"
+	if !m.diffView.AtBottom() {
+		footer = "(more below)"
+	}
+	return title + "\n\n" + m.diffView.View() + "\n" + footer
+}
@@ -0,0 +1,264 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Binding is one remappable list action: the keys that trigger it and the
+// text shown in the help footer. A key may be a sequence of plain
+// characters, e.g. "gg".
+type Binding struct {
+	Keys []string
+	Help string
+}
+
+// KeyMap holds the list view's key bindings. Ctrl+C always quits and is not
+// part of the map.
+type KeyMap struct {
+	Up          Binding
+	Down        Binding
+	First       Binding
+	Last        Binding
+	Edit        Binding
+	Regenerate  Binding
+	RewriteBody Binding
+	Setup       Binding
+	DryRun      Binding
+	Summary     Binding
+	Diff        Binding
+	NoVerify    Binding
+	Commit      Binding
+	Quit        Binding
+}
+
+// DefaultKeyMap returns the built-in bindings, with vim-style motions.
+func DefaultKeyMap() KeyMap {
+	return KeyMap{
+		Up:          Binding{Keys: []string{"up", "k"}, Help: "Previous suggestion"},
+		Down:        Binding{Keys: []string{"down", "j"}, Help: "Next suggestion"},
+		First:       Binding{Keys: []string{"home", "gg"}, Help: "First suggestion"},
+		Last:        Binding{Keys: []string{"end", "G"}, Help: "Last suggestion"},
+		Edit:        Binding{Keys: []string{"e"}, Help: "Edit"},
+		Regenerate:  Binding{Keys: []string{"r"}, Help: "Regenerate"},
+		RewriteBody: Binding{Keys: []string{"b"}, Help: "Rewrite body (keep subject)"},
+		Setup:       Binding{Keys: []string{"s"}, Help: "Setup (switch provider/model)"},
+		DryRun:      Binding{Keys: []string{"n"}, Help: "Dry-run"},
+		Summary:     Binding{Keys: []string{"v"}, Help: "Summary (pre-flight check)"},
+		Diff:        Binding{Keys: []string{"d"}, Help: "Diff preview"},
+		NoVerify:    Binding{Keys: []string{"H"}, Help: "Toggle git hooks (--no-verify)"},
+		Commit:      Binding{Keys: []string{"enter"}, Help: "Commit"},
+		Quit:        Binding{Keys: []string{"q"}, Help: "Quit"},
+	}
+}
+
+// actions lists the bindings by the names used in Config.Keybindings, in
+// help-footer order.
+func (k *KeyMap) actions() []struct {
+	name    string
+	binding *Binding
+} {
+	return []struct {
+		name    string
+		binding *Binding
+	}{
+		{"up", &k.Up},
+		{"down", &k.Down},
+		{"first", &k.First},
+		{"last", &k.Last},
+		{"edit", &k.Edit},
+		{"regenerate", &k.Regenerate},
+		{"rewrite-body", &k.RewriteBody},
+		{"setup", &k.Setup},
+		{"dry-run", &k.DryRun},
+		{"summary", &k.Summary},
+		{"diff", &k.Diff},
+		{"no-verify", &k.NoVerify},
+		{"commit", &k.Commit},
+		{"quit", &k.Quit},
+	}
+}
+
+// Apply replaces the keys of the named actions, e.g. {"commit": "c"} or
+// {"up": "up,ctrl+p"}. Unknown actions, empty key lists, ctrl+c and keys
+// bound to two actions are rejected.
+func (k *KeyMap) Apply(overrides map[string]string) error {
+	for name, spec := range overrides {
+		var target *Binding
+		var names []string
+		for _, a := range k.actions() {
+			names = append(names, a.name)
+			if a.name == name {
+				target = a.binding
+			}
+		}
+		if target == nil {
+			return fmt.Errorf("unknown key binding action %q (valid: %s)", name, strings.Join(names, ", "))
+		}
+		var keys []string
+		for _, key := range strings.Split(spec, ",") {
+			key = strings.TrimSpace(key)
+			if key == "" {
+				continue
+			}
+			if key == "ctrl+c" {
+				return fmt.Errorf("key binding %q: ctrl+c always quits and cannot be remapped", name)
+			}
+			keys = append(keys, key)
+		}
+		if len(keys) == 0 {
+			return fmt.Errorf("key binding %q has no keys", name)
+		}
+		target.Keys = keys
+	}
+
+	seen := map[string]string{}
+	for _, a := range k.actions() {
+		for _, key := range a.binding.Keys {
+			if other, ok := seen[key]; ok {
+				return fmt.Errorf("key %q is bound to both %q and %q", key, other, a.name)
+			}
+			seen[key] = a.name
+		}
+	}
+	return nil
+}
+
+// Matches reports whether key triggers b.
+func (b Binding) Matches(key string) bool {
+	for _, k := range b.Keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveKey turns a key press into the key or key sequence the bindings
+// should see. Presses that start a multi-key sequence ("g" of "gg") are held
+// and return "".
+func (m *Model) resolveKey(key string) string {
+	seq := m.keyPrefix + key
+	m.keyPrefix = ""
+	for _, a := range m.keys.actions() {
+		for _, k := range a.binding.Keys {
+			if len(k) > len(seq) && strings.HasPrefix(k, seq) && isSequenceKey(k) {
+				m.keyPrefix = seq
+				return ""
+			}
+		}
+	}
+	if seq != key && !m.keys.bound(seq) {
+		// An abandoned sequence: treat the press on its own.
+		return key
+	}
+	return seq
+}
+
+// bound reports whether any action uses key.
+func (k *KeyMap) bound(key string) bool {
+	for _, a := range k.actions() {
+		if a.binding.Matches(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSequenceKey reports whether k is a sequence of plain characters ("gg")
+// rather than a named key ("enter", "ctrl+p").
+func isSequenceKey(k string) bool {
+	return len([]rune(k)) > 1 && !strings.ContainsAny(k, "+") && !namedKeys[k]
+}
+
+// namedKeys are the multi-character key names Bubble Tea reports that a
+// binding might use.
+var namedKeys = map[string]bool{
+	"up": true, "down": true, "left": true, "right": true, "enter": true,
+	"esc": true, "tab": true, "home": true, "end": true, "pgup": true,
+	"pgdown": true, "space": true, "backspace": true, "delete": true,
+}
+
+// keyLabels are the help-footer spellings of named keys.
+var keyLabels = map[string]string{"up": "↑", "down": "↓", "enter": "Enter", "home": "Home", "end": "End", "esc": "Esc"}
+
+// spokenKeys are plain-mode spellings of named keys, which screen readers
+// handle better than symbols.
+var spokenKeys = map[string]string{"up": "the up arrow", "down": "the down arrow", "enter": "Enter", "home": "Home", "end": "End", "esc": "Escape"}
+
+// spoken is b's keys as words for plain mode, e.g. "the up arrow or k".
+func (b Binding) spoken() string {
+	words := make([]string, len(b.Keys))
+	for i, key := range b.Keys {
+		switch {
+		case spokenKeys[key] != "":
+			words[i] = spokenKeys[key]
+		case isSequenceKey(key):
+			words[i] = strings.Join(strings.Split(key, ""), " then ")
+		case len(key) == 1 && unicode.IsUpper(rune(key[0])):
+			words[i] = "Shift+" + key
+		default:
+			words[i] = key
+		}
+	}
+	return strings.Join(words, " or ")
+}
+
+// label is the help-footer spelling of b's keys, e.g. "↑/k".
+func (b Binding) label() string {
+	labels := make([]string, len(b.Keys))
+	for i, key := range b.Keys {
+		labels[i] = key
+		if l, ok := keyLabels[key]; ok {
+			labels[i] = l
+		}
+	}
+	return strings.Join(labels, "/")
+}
+
+// helpFooter renders the active bindings, one per line.
+func (k *KeyMap) helpFooter() string {
+	var b strings.Builder
+	b.WriteString("\nKeybindings:\n")
+	for _, a := range k.actions() {
+		fmt.Fprintf(&b, "  %-8s %s\n", a.binding.label(), a.binding.Help)
+	}
+	b.WriteString("  Ctrl+C   Exit\n")
+	return b.String()
+}
+
+// plainHelp spells out every binding as a sentence, for plain mode.
+func (k *KeyMap) plainHelp() string {
+	var b strings.Builder
+	b.WriteString("\nKeys:\n")
+	for _, a := range k.actions() {
+		fmt.Fprintf(&b, "%s: press %s.\n", a.binding.Help, a.binding.spoken())
+	}
+	b.WriteString("Exit: press Ctrl+C.\n")
+	return b.String()
+}
+
+// compactHelp renders the active bindings as "key action" pairs packed into
+// lines of at most width columns, for windows too short for helpFooter.
+func (k *KeyMap) compactHelp(width int) string {
+	var parts []string
+	for _, a := range k.actions() {
+		parts = append(parts, a.binding.label()+" "+a.name)
+	}
+	parts = append(parts, "Ctrl+C exit")
+
+	var b, line strings.Builder
+	for _, part := range parts {
+		if line.Len() > 0 && width > 0 && len([]rune(line.String()))+2+len([]rune(part)) > width {
+			b.WriteString(line.String() + "\n")
+			line.Reset()
+		}
+		if line.Len() > 0 {
+			line.WriteString("  ")
+		}
+		line.WriteString(part)
+	}
+	b.WriteString(line.String() + "\n")
+	return "\n" + b.String()
+}
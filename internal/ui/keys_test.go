@@ -0,0 +1,89 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/chuckie/commit-coach/internal/adapters/cache"
+	"github.com/chuckie/commit-coach/internal/app"
+	"github.com/chuckie/commit-coach/internal/domain"
+	"github.com/chuckie/commit-coach/internal/testutil"
+)
+
+func keyPress(s string) tea.KeyMsg {
+	if s == "enter" {
+		return tea.KeyMsg{Type: tea.KeyEnter}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+func TestRemappedCommitKey(t *testing.T) {
+	fakeGit := &testutil.FakeGit{StagedDiffContent: testutil.SampleDiffSmall, IsInRepoValue: true, Branch: "main"}
+	a := app.NewApp(&testutil.FakeLLM{}, fakeGit, cache.NewInMemory(), 8192, false)
+	m := New(a, "mock", "mock", 0.7, "", "", nil)
+	keys := DefaultKeyMap()
+	if err := keys.Apply(map[string]string{"commit": "c"}); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	m.SetKeyMap(keys)
+	m.Update(msgSuggestionsLoaded{suggestions: []domain.Suggestion{{Type: "feat", Subject: "add greeting"}}})
+
+	if _, cmd := m.Update(keyPress("enter")); cmd != nil || m.state != StateList {
+		t.Fatalf("Enter should no longer commit; state = %v", m.state)
+	}
+	if !strings.Contains(m.View(), "  c        Commit\n") {
+		t.Errorf("help footer should show the remapped key:\n%s", m.View())
+	}
+
+	_, cmd := m.Update(keyPress("c"))
+	if cmd == nil {
+		t.Fatal("c did not start a commit")
+	}
+	m.Update(cmd())
+	if m.state != StateSuccess || len(fakeGit.CommittedMessages) != 1 {
+		t.Errorf("state = %v, commits = %v; want one commit", m.state, fakeGit.CommittedMessages)
+	}
+}
+
+func TestVimMotions(t *testing.T) {
+	m := New(nil, "mock", "mock", 0.7, "", "", nil)
+	m.Update(msgSuggestionsLoaded{suggestions: []domain.Suggestion{
+		{Type: "feat", Subject: "one"}, {Type: "feat", Subject: "two"}, {Type: "feat", Subject: "three"},
+	}})
+
+	m.Update(keyPress("G"))
+	if m.selectedIndex != 2 {
+		t.Errorf("G selected %d, want 2", m.selectedIndex)
+	}
+	m.Update(keyPress("g"))
+	if m.selectedIndex != 2 {
+		t.Errorf("a lone g moved the selection to %d", m.selectedIndex)
+	}
+	m.Update(keyPress("g"))
+	if m.selectedIndex != 0 {
+		t.Errorf("gg selected %d, want 0", m.selectedIndex)
+	}
+
+	// An abandoned sequence falls back to the second key on its own.
+	m.Update(keyPress("g"))
+	m.Update(keyPress("j"))
+	if m.selectedIndex != 1 {
+		t.Errorf("g then j selected %d, want 1", m.selectedIndex)
+	}
+}
+
+func TestKeyMapApplyRejectsBadOverrides(t *testing.T) {
+	for name, overrides := range map[string]map[string]string{
+		"unknown action": {"launch": "l"},
+		"empty":          {"commit": " , "},
+		"ctrl+c":         {"commit": "ctrl+c"},
+		"duplicate":      {"commit": "e"},
+	} {
+		keys := DefaultKeyMap()
+		if err := keys.Apply(overrides); err == nil {
+			t.Errorf("%s: Apply(%v) error = nil", name, overrides)
+		}
+	}
+}
@@ -1,16 +1,24 @@
 package ui
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/reflow/wordwrap"
+	"github.com/muesli/reflow/wrap"
 
 	"github.com/chuckie/commit-coach/internal/app"
 	"github.com/chuckie/commit-coach/internal/config"
 	"github.com/chuckie/commit-coach/internal/domain"
+	"github.com/chuckie/commit-coach/internal/observability"
 	"github.com/chuckie/commit-coach/internal/ports"
 )
 
@@ -21,7 +29,8 @@ type Model struct {
 	setup         *SetupModel
 	suggestions   []domain.Suggestion
 	selectedIndex int
-	editText      string
+	editor        textarea.Model
+	editErr       string
 	isEditing     bool
 	dryRun        bool
 	provider      string
@@ -35,6 +44,49 @@ type Model struct {
 	height        int
 	err           error
 	lastHash      string
+	// undoHash is the commit this session just made, which u on the success
+	// screen undoes; empty after dry runs and once undone.
+	undoHash   string
+	usage      *ports.Usage
+	warnings   []string
+	redacted   bool
+	unstaged   bool
+	truncation string
+	repoState  string
+	cached     bool
+	summary    *app.CommitSummary
+	diffView   viewport.Model
+	listView   viewport.Model
+	transcript *Transcript
+	// detachedOK records that the user agreed to commit on a detached HEAD.
+	detachedOK bool
+	// defaultSelection is config.SelectionFirst or config.SelectionBest.
+	defaultSelection string
+	// confirmCommit asks y/n before Enter commits (Config.ConfirmSend).
+	confirmCommit bool
+	// dryRunMode makes every commit a dry run (Config.DryRun).
+	dryRunMode bool
+	// noVerify skips git hooks on commit; toggled from the list, and always
+	// confirmed before committing.
+	noVerify bool
+	// keys maps list keys to actions; keyPrefix holds the start of a
+	// multi-key sequence such as "gg".
+	keys      KeyMap
+	keyPrefix string
+	// ctx is the session context: every provider call derives from it and
+	// quitting cancels it. See context().
+	ctx    context.Context
+	cancel context.CancelFunc
+	// cancelLoad cancels the in-flight provider call started at loadStart;
+	// nil when there is none.
+	cancelLoad context.CancelFunc
+	loadStart  time.Time
+	// notice is a one-off message shown above the list, e.g. after a cancel.
+	notice string
+	// noColor renders views without styling; see SetNoColor.
+	noColor bool
+	// plain renders for screen readers; see SetPlain.
+	plain bool
 }
 
 // State represents the current UI state.
@@ -46,17 +98,28 @@ const (
 	StateList
 	StateEdit
 	StateDryRun
+	StateSummary
+	StateDiffPreview
+	StateConfirm
+	StateConfirmDetached
+	StateConfirmUndo
 	StateSuccess
 	StateError
 )
 
+// undoQuitDelay is how long the success screen waits for u before exiting.
+const undoQuitDelay = 5 * time.Second
+
 // New creates a new UI model.
 func New(app *app.App, provider, model string, temperature float32, baseURL, ollamaURL string, llmFactory func(provider, apiKey, baseURL, ollamaURL, model string) (ports.LLM, error)) *Model {
 	s := spinner.New()
 	s.Spinner = spinner.Dot
-	s.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
+	s.Style = spinnerStyle
+	ctx, cancel := context.WithCancel(context.Background())
 
-	return &Model{
+	m := &Model{
+		ctx:           ctx,
+		cancel:        cancel,
 		app:           app,
 		state:         StateLoading,
 		selectedIndex: 0,
@@ -67,15 +130,70 @@ func New(app *app.App, provider, model string, temperature float32, baseURL, oll
 		ollamaURL:     ollamaURL,
 		llmFactory:    llmFactory,
 		spinner:       s,
+		keys:          DefaultKeyMap(),
 		width:         80,
 		height:        24,
 		err:           nil,
 	}
+	if NoColorRequested() {
+		m.SetNoColor(true)
+	}
+	if PlainRequested() {
+		m.SetPlain(true)
+	}
+	return m
+}
+
+// context returns the session context, or Background for a Model built
+// without New.
+func (m *Model) context() context.Context {
+	if m.ctx == nil {
+		return context.Background()
+	}
+	return m.ctx
+}
+
+// quit cancels any in-flight work and ends the program.
+func (m *Model) quit() tea.Cmd {
+	if m.cancel != nil {
+		m.cancel()
+	}
+	return tea.Quit
+}
+
+// SetTranscript records key session transitions into t (nil disables).
+func (m *Model) SetTranscript(t *Transcript) {
+	m.transcript = t
+	t.record("provider: %s, model: %s", m.provider, m.model)
+}
+
+// SetDefaultSelection chooses which suggestion is highlighted when a list
+// loads: config.SelectionBest highlights the highest-scoring one, anything
+// else the first.
+func (m *Model) SetDefaultSelection(strategy string) {
+	m.defaultSelection = strategy
+}
+
+// SetConfirmCommit makes Enter in the list show the final message and ask
+// y/n before committing; off, Enter commits immediately.
+func (m *Model) SetConfirmCommit(confirm bool) {
+	m.confirmCommit = confirm
+}
+
+// SetKeyMap replaces the list view's key bindings (see KeyMap.Apply).
+func (m *Model) SetKeyMap(keys KeyMap) {
+	m.keys = keys
+}
+
+// SetDryRun makes Enter run the dry-run commit path instead of committing,
+// and marks the list view accordingly.
+func (m *Model) SetDryRun(enabled bool) {
+	m.dryRunMode = enabled
 }
 
 // Init initializes the model and starts the suggestion loading.
 func (m *Model) Init() tea.Cmd {
-	return tea.Batch(m.spinner.Tick, m.cmdLoadSuggestions)
+	return tea.Batch(m.spinner.Tick, m.loadSuggestions())
 }
 
 // Update handles messages and state transitions.
@@ -84,25 +202,30 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
+		m.diffView.Width = msg.Width
+		m.diffView.Height = max(msg.Height-diffPreviewChrome, 1)
 
 	case tea.KeyMsg:
 		switch msg.String() {
 		case "ctrl+c":
-			return m, tea.Quit
-		case "q":
-			if m.state != StateSetup {
-				return m, tea.Quit
-			}
+			return m, m.quit()
+		}
+		// Setup and the editor take typed text.
+		if m.state != StateSetup && m.state != StateEdit && m.keys.Quit.Matches(msg.String()) {
+			return m, m.quit()
 		}
 
 		// State-specific key handling
 		switch m.state {
 		case StateLoading:
-			// No keys during loading
+			// Esc cancels a provider call; other keys wait
+			if msg.String() == "esc" && m.cancelLoad != nil {
+				m.cancelLoad()
+			}
 
 		case StateSetup:
 			if m.setup == nil {
-				m.setup = NewSetupEmbedded(&config.Config{Provider: m.provider, Model: m.model, OllamaURL: m.ollamaURL})
+				m.setup = m.newSetup()
 			}
 			child, cmd := m.setup.Update(msg)
 			if sm, ok := child.(*SetupModel); ok {
@@ -124,47 +247,208 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			m = m2
 
+		case StateDiffPreview:
+			m2, cmd := m.handleDiffPreviewKeys(msg)
+			if cmd != nil {
+				return m2, cmd
+			}
+			m = m2
+
 		case StateDryRun:
 			// Any key returns to list
 			m.state = StateList
 
+		case StateSummary:
+			// Enter commits the summarized message; anything else returns to list
+			if msg.String() == "enter" {
+				// Skipping hooks is always confirmed, as from the list.
+				if (m.confirmCommit || m.noVerify) && m.selectedIndex < len(m.suggestions) {
+					m.state = StateConfirm
+					return m, nil
+				}
+				m.dryRun = m.dryRunMode
+				m.state = StateLoading
+				m.recordChosen()
+				return m, m.cmdCommit
+			}
+			m.state = StateList
+
+		case StateConfirm:
+			// y commits; anything else returns to list
+			if msg.String() == "y" {
+				m.dryRun = m.dryRunMode
+				m.state = StateLoading
+				m.recordChosen()
+				return m, m.cmdCommit
+			}
+			m.state = StateList
+
+		case StateConfirmDetached:
+			if msg.String() == "y" {
+				m.detachedOK = true
+				m.state = StateLoading
+				return m, m.cmdCommit
+			}
+			m.state = StateList
+
+		case StateConfirmUndo:
+			if msg.String() == "y" {
+				m.state = StateLoading
+				return m, m.cmdUndo
+			}
+			m.state = StateSuccess
+
 		case StateSuccess:
-			// Any key exits
-			return m, tea.Quit
+			// u undoes a commit made in this session; any other key exits
+			if msg.String() == "u" && m.undoHash != "" {
+				m.state = StateConfirmUndo
+				return m, nil
+			}
+			return m, m.quit()
 
 		case StateError:
-			// Any key returns to list
+			// With nothing staged, a stages everything and w retries from
+			// the working tree; any other key returns to list
+			if msg.String() == "a" && errors.Is(m.err, app.ErrNoStagedChanges) && !m.app.Suggest.IncludeUnstaged() {
+				m.err = nil
+				m.state = StateLoading
+				return m, m.cmdStageAll
+			}
+			if msg.String() == "w" && errors.Is(m.err, app.ErrNoStagedChanges) && !m.app.Suggest.IncludeUnstaged() {
+				m.app.Suggest.SetIncludeUnstaged(true)
+				m.err = nil
+				return m, m.loadSuggestions()
+			}
 			m.state = StateList
 			m.err = nil
 		}
 
 	case msgSuggestionsLoaded:
-		if msg.err != nil {
+		m.endCancellableLoad()
+		if errors.Is(msg.err, context.Canceled) {
+			m.state = StateList
+			m.notice = "Generation cancelled."
+		} else if msg.err != nil {
 			m.state = StateError
 			m.err = msg.err
+			m.transcript.record("\nsuggestions failed: %v", msg.err)
 		} else {
 			m.suggestions = msg.suggestions
+			m.usage = msg.usage
+			m.warnings = msg.warnings
+			m.redacted = msg.redacted
+			m.unstaged = msg.unstaged
+			m.truncation = msg.truncation
+			m.repoState = msg.repoState
+			m.cached = msg.cached
 			m.selectedIndex = 0
+			if m.defaultSelection == config.SelectionBest {
+				m.selectedIndex = domain.BestSuggestion(m.suggestions)
+			}
 			m.state = StateList
+			m.recordSuggestions()
 		}
 
-	case msgCommitComplete:
+	case msgBodyRegenerated:
+		m.endCancellableLoad()
+		if errors.Is(msg.err, context.Canceled) {
+			m.state = StateList
+			m.notice = "Body rewrite cancelled."
+		} else if msg.err != nil {
+			m.state = StateError
+			m.err = msg.err
+			m.transcript.record("\nbody regeneration failed: %v", msg.err)
+		} else {
+			if msg.index < len(m.suggestions) {
+				m.suggestions[msg.index] = msg.suggestion
+			}
+			m.state = StateList
+		}
+
+	case msgSummaryLoaded:
+		if msg.err != nil {
+			m.state = StateError
+			m.err = msg.err
+		} else {
+			m.summary = msg.summary
+			m.state = StateSummary
+		}
+
+	case msgDiffLoaded:
 		if msg.err != nil {
 			m.state = StateError
 			m.err = msg.err
+		} else {
+			m.diffView = newDiffPreview(msg.diff, m.width, m.height, !m.noColor)
+			m.state = StateDiffPreview
+		}
+
+	case msgConfirmDetached:
+		m.state = StateConfirmDetached
+
+	case msgCommitComplete:
+		var hookErr *ports.HookRejectedError
+		if errors.As(msg.err, &hookErr) {
+			// Back to the list with the (possibly edited) message intact, so
+			// the user can fix what the hook flagged and commit again.
+			m.state = StateList
+			m.notice = "Commit rejected by a git hook; your message is kept. Fix the issue and commit again.\n\n" +
+				tailLines(observability.RedactForLog(hookErr.Output), hookOutputLines)
+			m.transcript.record("\ncommit rejected by hook: %s", hookErr.Output)
+		} else if msg.err != nil {
+			m.state = StateError
+			m.err = msg.err
+			m.transcript.record("\ncommit failed: %v", msg.err)
 		} else {
 			m.state = StateSuccess
 			m.lastHash = msg.hash
+			m.transcript.record("\ncommitted as %s", msg.hash)
+			delay := 1500 * time.Millisecond
+			if !m.dryRun {
+				m.undoHash = msg.hash
+				delay = undoQuitDelay
+			}
 			// Give the user a moment to see the success message, then exit.
-			return m, tea.Tick(1500*time.Millisecond, func(time.Time) tea.Msg {
+			return m, tea.Tick(delay, func(time.Time) tea.Msg {
 				return msgAutoQuit{}
 			})
 		}
 
+	case msgStagedAll:
+		if msg.err != nil {
+			m.state = StateError
+			m.err = msg.err
+			return m, nil
+		}
+		m.transcript.record("\nstaged all changes (git add -A)")
+		return m, m.loadSuggestions()
+
+	case msgUndoComplete:
+		if msg.err != nil {
+			m.state = StateError
+			m.err = msg.err
+			m.transcript.record("\nundo failed: %v", msg.err)
+			return m, nil
+		}
+		m.transcript.record("\nundid commit %s", m.undoHash)
+		m.notice = "Undid commit " + m.undoHash + "; its changes are staged again. Pick another suggestion to commit."
+		m.undoHash = ""
+		m.lastHash = ""
+		m.state = StateList
+
 	case spinner.TickMsg:
 		var cmd tea.Cmd
 		m.spinner, cmd = m.spinner.Update(msg)
 		return m, cmd
+	case msgConnectionTested:
+		if m.setup != nil {
+			child, cmd := m.setup.Update(msg)
+			if sm, ok := child.(*SetupModel); ok {
+				m.setup = sm
+			}
+			return m, cmd
+		}
+		return m, nil
 	case msgSetupFinished:
 		m.setup = nil
 		if !msg.confirmed {
@@ -174,6 +458,13 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		m.provider = msg.provider
 		m.model = msg.model
+		if msg.ollamaURL != "" {
+			m.ollamaURL = msg.ollamaURL
+		}
+		if msg.baseURL != "" {
+			m.baseURL = msg.baseURL
+		}
+		m.transcript.record("\nswitched to provider: %s, model: %s", m.provider, m.model)
 
 		apiKey := msg.apiKey
 		if m.llmFactory == nil {
@@ -184,21 +475,7 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		// Best-effort persistence so setup changes are remembered across runs.
 		if path, err := config.DefaultConfigPath(); err == nil {
-			persisted, _ := config.Load() // may be partially invalid; best-effort
-			if persisted == nil {
-				persisted = &config.Config{}
-			}
-			persisted.Provider = m.provider
-			persisted.Model = m.model
-			switch m.provider {
-			case "openai", "groq", "anthropic":
-				persisted.APIKey = apiKey
-			case "ollama":
-				persisted.APIKey = "ollama"
-			case "mock":
-				persisted.APIKey = "mock"
-			}
-			_ = config.SaveToFile(path, persisted) // ignore persistence errors in UI flow
+			_ = persistSetup(path, msg) // ignore persistence errors in UI flow
 		}
 
 		llm, err := m.llmFactory(m.provider, apiKey, m.baseURL, m.ollamaURL, m.model)
@@ -208,18 +485,87 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 		m.app.Suggest.SetLLM(llm)
-		m.state = StateLoading
-		return m, m.cmdLoadSuggestions
+		return m, m.loadSuggestions()
 
 	case msgAutoQuit:
 		if m.state == StateSuccess {
-			return m, tea.Quit
+			return m, m.quit()
+		}
+
+	default:
+		// Cursor blinks and other editor messages.
+		if m.state == StateEdit {
+			var cmd tea.Cmd
+			m.editor, cmd = m.editor.Update(msg)
+			return m, cmd
 		}
 	}
 
 	return m, nil
 }
 
+// recordSuggestions adds the current suggestion list to the transcript.
+func (m *Model) recordSuggestions() {
+	if m.transcript == nil {
+		return
+	}
+	m.transcript.record("\nsuggestions:")
+	for i, s := range m.suggestions {
+		m.transcript.record("%d) %s", i+1, s.Format())
+	}
+}
+
+// recordChosen adds the suggestion about to be committed to the transcript.
+// It runs in Update, not in the commit command, so the transcript is only
+// touched from the Bubble Tea loop.
+func (m *Model) recordChosen() {
+	if m.selectedIndex < len(m.suggestions) {
+		m.transcript.record("\nchosen (#%d):\n%s", m.selectedIndex+1, m.suggestions[m.selectedIndex].Format())
+	}
+}
+
+// persistSetup writes the setup's provider, model, key and server URL to
+// the config file at path. Every other stored setting (temperature, diff
+// cap, ...) is kept as it is in the file; env overrides are not baked in. A
+// config file that can't be read is left alone.
+func persistSetup(path string, msg msgSetupFinished) error {
+	persisted, err := config.LoadStored(path)
+	if err != nil {
+		return err
+	}
+	persisted.Provider = msg.provider
+	persisted.Model = msg.model
+	switch msg.provider {
+	case "openai", "groq", "mistral", "anthropic":
+		persisted.APIKey = msg.apiKey
+	case "openai-compatible":
+		persisted.APIKey = msg.apiKey
+		persisted.BaseURL = msg.baseURL
+	case "ollama":
+		persisted.APIKey = "ollama"
+		persisted.OllamaURL = msg.ollamaURL
+	case "mock":
+		persisted.APIKey = "mock"
+	}
+	return config.SaveToFile(path, persisted)
+}
+
+// newSetup builds the embedded setup wizard seeded with the session's
+// provider/model and the persisted preferences (e.g. OmitAPIKey).
+func (m *Model) newSetup() *SetupModel {
+	cfg, _ := config.Load() // best-effort; may fail when setup required
+	if cfg == nil {
+		cfg = config.Defaults()
+	}
+	cfg.Provider = m.provider
+	cfg.Model = m.model
+	cfg.OllamaURL = m.ollamaURL
+	cfg.BaseURL = m.baseURL
+	setup := NewSetupEmbedded(cfg)
+	setup.SetLLMFactory(m.llmFactory)
+	return setup
+}
+
 // View renders the current state.
 func (m *Model) View() string {
 	switch m.state {
@@ -227,7 +573,7 @@ func (m *Model) View() string {
 		return m.viewLoading()
 	case StateSetup:
 		if m.setup == nil {
-			m.setup = NewSetupEmbedded(&config.Config{Provider: m.provider, Model: m.model, OllamaURL: m.ollamaURL})
+			m.setup = m.newSetup()
 		}
 		return m.setup.View()
 	case StateList:
@@ -236,6 +582,16 @@ func (m *Model) View() string {
 		return m.viewEdit()
 	case StateDryRun:
 		return m.viewDryRun()
+	case StateSummary:
+		return renderSummary(m.summary, m.noVerify)
+	case StateDiffPreview:
+		return m.viewDiffPreview()
+	case StateConfirm:
+		return m.viewConfirm()
+	case StateConfirmDetached:
+		return app.DetachedHeadWarning + "\n\nCommit anyway? (y to continue, any other key to go back)"
+	case StateConfirmUndo:
+		return "Undo commit " + m.undoHash + "? Its changes stay staged.\n\n(y to undo, any other key to keep it)"
 	case StateSuccess:
 		return m.viewSuccess()
 	case StateError:
@@ -247,41 +603,175 @@ func (m *Model) View() string {
 
 // viewLoading renders the loading state.
 func (m *Model) viewLoading() string {
-	return m.spinner.View() + " Generating suggestions..."
+	if m.plain {
+		output := "Generating suggestions."
+		if m.cancelLoad != nil {
+			output += fmt.Sprintf(" %d seconds elapsed. Press Escape to cancel.", int(time.Since(m.loadStart).Seconds()))
+		}
+		return output
+	}
+	output := m.spinner.View() + " Generating suggestions..."
+	if m.cancelLoad != nil {
+		output += fmt.Sprintf(" %ds\n\n(press Esc to cancel)", int(time.Since(m.loadStart).Seconds()))
+	}
+	return output
 }
 
 // viewList renders the suggestion list.
 func (m *Model) viewList() string {
 	if len(m.suggestions) == 0 {
+		if m.notice != "" {
+			return m.notice + "\n\nNo suggestions available. Press " + m.keys.Regenerate.label() + " to try again."
+		}
 		return "No suggestions available."
 	}
 
 	var output string
+	if m.notice != "" {
+		output += m.notice + "\n\n"
+	}
+	if m.dryRunMode {
+		output += "DRY RUN: Enter checks the commit without making it\n\n"
+	}
+	if m.noVerify {
+		output += "HOOKS OFF: the commit will skip git hooks (--no-verify); press " + m.keys.NoVerify.label() + " to turn them back on\n\n"
+	}
+	warn := "⚠ "
+	if m.plain {
+		warn = "Warning: "
+	}
+	if m.redacted {
+		output += warn + "secrets redacted before sending\n\n"
+	}
+	if m.unstaged {
+		output += warn + "generated from unstaged working-tree changes; stage them before committing\n\n"
+	}
+	if m.truncation != "" {
+		output += warn + m.truncation + "\n\n"
+	}
+	if m.repoState != "" {
+		output += warn + m.repoState + "\n\n"
+	}
 	output += "Suggestions:\n\n"
 
+	// Each suggestion is wrapped to the window; selStart/selEnd are the
+	// selected one's line range within list.
+	var list string
+	selStart, selEnd := 0, 0
 	for i, s := range m.suggestions {
 		prefix := "  "
 		if i == m.selectedIndex {
 			prefix = "> "
+			selStart = strings.Count(list, "\n")
+		}
+		if m.plain {
+			label := fmt.Sprintf("Suggestion %d of %d", i+1, len(m.suggestions))
+			if i == m.selectedIndex {
+				label += ", selected"
+			}
+			list += label + ":\n" + wrapText(s.Format(), m.width) + "\n\n"
+		} else {
+			list += indentLines(wrapText(s.Format(), m.width-2), prefix, "  ") + "\n\n"
+		}
+		if i == m.selectedIndex {
+			selEnd = strings.Count(list, "\n") - 1
 		}
-		output += prefix + s.Format() + "\n\n"
 	}
 
-	output += "\nKeybindings:\n"
-	output += "  ↑/↓    Navigate\n"
-	output += "  e      Edit\n"
-	output += "  r      Regenerate\n"
-	output += "  s      Setup (switch provider/model)\n"
-	output += "  n      Dry-run\n"
-	output += "  Enter  Commit\n"
-	output += "  Ctrl+C Exit\n"
+	var footer string
+	if m.cached {
+		footer += "(cached)\n"
+	}
+	if m.usage != nil && m.usage.TotalTokens > 0 {
+		footer += "~" + formatThousands(m.usage.TotalTokens) + " tokens\n"
+	}
+	for _, w := range m.warnings {
+		footer += wrapText("Warning: "+w, m.width) + "\n"
+	}
+	if m.plain {
+		// No scrolling viewport: its padding and partial views read badly
+		// aloud, and the terminal's own scrollback holds the rest.
+		return output + list + footer + m.keys.plainHelp()
+	}
+	help := m.keys.helpFooter()
+
+	// When everything doesn't fit, scroll the list and keep the banners and
+	// a compact help footer in place.
+	chrome := strings.Count(output, "\n") + strings.Count(footer+help, "\n") + 1
+	if m.height > 0 && chrome+strings.Count(list, "\n") > m.height {
+		help = m.keys.compactHelp(m.width)
+		chrome = strings.Count(output, "\n") + strings.Count(footer+help, "\n") + 1
+	}
+	if m.height > 0 && chrome+strings.Count(list, "\n") > m.height {
+		height := max(m.height-chrome, minListHeight)
+		m.listView.Width = m.width
+		m.listView.Height = height
+		m.listView.SetContent(strings.TrimSuffix(list, "\n"))
+		if selStart < m.listView.YOffset {
+			m.listView.SetYOffset(selStart)
+		} else if selEnd >= m.listView.YOffset+height {
+			// Bring the end into view, but never push the start out.
+			m.listView.SetYOffset(min(selEnd-height+1, selStart))
+		}
+		list = m.listView.View() + "\n"
+	}
 
-	return output
+	return output + list + footer + help
+}
+
+// minListHeight is the fewest suggestion lines shown on a tiny window.
+const minListHeight = 3
+
+// wrapText word-wraps text to width columns; width <= 0 leaves it as is.
+func wrapText(text string, width int) string {
+	if width <= 0 {
+		return text
+	}
+	return wrap.String(wordwrap.String(text, width), width)
+}
+
+// indentLines prefixes the first line of text with first and the other
+// non-empty lines with rest.
+func indentLines(text, first, rest string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if i == 0 {
+			lines[i] = first + line
+		} else if line != "" {
+			lines[i] = rest + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// hookOutputLines is how much of a rejecting hook's output the list shows.
+const hookOutputLines = 15
+
+// tailLines returns the last n lines of text, noting how many were dropped.
+func tailLines(text string, n int) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	if len(lines) <= n {
+		return strings.Join(lines, "\n")
+	}
+	return fmt.Sprintf("[%d earlier lines omitted]\n", len(lines)-n) + strings.Join(lines[len(lines)-n:], "\n")
 }
 
 // viewEdit renders the edit state.
 func (m *Model) viewEdit() string {
-	return "Edit message:\n\n" + m.editText + "\n\n(Ctrl+S to save, Esc to cancel)"
+	output := "Edit message:\n\n" + m.editor.View() + "\n\n"
+	if m.editErr != "" {
+		output += "✗ " + m.editErr + "\n\n"
+	}
+	return output + "(Ctrl+S to save, Esc to cancel)"
+}
+
+// viewConfirm shows the exact message about to be committed.
+func (m *Model) viewConfirm() string {
+	prompt := "Commit with this message?"
+	if m.noVerify {
+		prompt = "Commit with this message, skipping git hooks (--no-verify)?"
+	}
+	return prompt + "\n\n" + m.suggestions[m.selectedIndex].Format() + "\n\n(y to commit, any other key to go back)"
 }
 
 // viewDryRun renders the dry-run preview.
@@ -289,22 +779,121 @@ func (m *Model) viewDryRun() string {
 	return "Dry-run preview:\n\ngit commit -m \"" + m.suggestions[m.selectedIndex].Format() + "\"\n\n(Press any key to continue)"
 }
 
+// renderSummary renders the pre-flight summary: message, branch, diffstat
+// and warnings, and whether git hooks will be skipped.
+func renderSummary(s *app.CommitSummary, noVerify bool) string {
+	if s == nil {
+		return "No summary available.\n\n(Press any key to return)"
+	}
+
+	var b strings.Builder
+	b.WriteString("Summary:\n\n")
+	b.WriteString(s.Message + "\n\n")
+
+	branch := s.Branch
+	if branch == "" {
+		branch = "(unknown)"
+	}
+	b.WriteString("Branch: " + branch + "\n")
+	if noVerify {
+		b.WriteString("Hooks:  skipped (--no-verify)\n")
+	}
+	b.WriteString("\n")
+
+	added, deleted := 0, 0
+	for _, f := range s.Files {
+		if f.Binary {
+			fmt.Fprintf(&b, "  %s | binary\n", f.Path)
+			continue
+		}
+		fmt.Fprintf(&b, "  %s | +%d -%d\n", f.Path, f.Added, f.Deleted)
+		added += f.Added
+		deleted += f.Deleted
+	}
+	fmt.Fprintf(&b, "  %d file(s) changed, %d insertion(s)(+), %d deletion(s)(-)\n", len(s.Files), added, deleted)
+
+	if len(s.Warnings) > 0 {
+		b.WriteString("\nWarnings:\n")
+		for _, w := range s.Warnings {
+			b.WriteString("  ! " + w + "\n")
+		}
+	}
+
+	b.WriteString("\n(Enter to commit, any other key to return)")
+	return b.String()
+}
+
 // viewSuccess renders the success state.
 func (m *Model) viewSuccess() string {
+	if m.dryRun {
+		return "✓ Dry run succeeded; nothing was committed\nExiting...\n"
+	}
+	if m.undoHash != "" {
+		return "✓ Committed as " + m.lastHash + "\n\nPress u to undo it (the changes stay staged), any other key to exit.\n"
+	}
 	return "✓ Committed as " + m.lastHash + "\nExiting...\n"
 }
 
 // viewError renders the error state.
+// Provider errors lead with the provider's own message, which is usually the
+// actionable part (bad key, unknown model, rate limit).
 func (m *Model) viewError() string {
-	return "Error: " + m.err.Error() + "\n\n(Press any key to return)"
+	var pe *ports.ProviderError
+	if errors.As(m.err, &pe) {
+		return fmt.Sprintf("%s error (HTTP %d):\n\n  %s\n\n(Press any key to return)",
+			pe.Provider, pe.StatusCode, observability.RedactForLog(pe.Message))
+	}
+	if errors.Is(m.err, app.ErrNoStagedChanges) && !m.app.Suggest.IncludeUnstaged() {
+		return "No staged changes.\n\nPress w to generate from unstaged working-tree changes, a to stage everything (git add -A) and continue, or any other key to return."
+	}
+	return "Error: " + observability.RedactForLog(m.err.Error()) + "\n\n(Press any key to return)"
+}
+
+// formatThousands renders n with comma separators (1240 -> "1,240").
+func formatThousands(n int) string {
+	if n < 0 {
+		return "-" + formatThousands(-n)
+	}
+	s := strconv.Itoa(n)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
 }
 
 // Custom messages
 type msgSuggestionsLoaded struct {
 	suggestions []domain.Suggestion
-	err         error
+	usage       *ports.Usage
+	warnings    []string
+	redacted    bool
+	unstaged    bool
+	// truncation is SuggestResult.TruncationNote; empty when nothing was cut.
+	truncation string
+	// repoState is SuggestResult.RepoStateNote.
+	repoState string
+	cached    bool
+	err       error
+}
+
+type msgDiffLoaded struct {
+	diff string
+	err  error
 }
 
+type msgBodyRegenerated struct {
+	index      int
+	suggestion domain.Suggestion
+	err        error
+}
+
+type msgSummaryLoaded struct {
+	summary *app.CommitSummary
+	err     error
+}
+
+type msgConfirmDetached struct{}
+
 type msgCommitComplete struct {
 	hash string
 	err  error
@@ -314,7 +903,21 @@ type msgSetupFinished struct {
 	provider  string
 	model     string
 	apiKey    string
+	ollamaURL string
+	baseURL   string
 	confirmed bool
 }
 
+type msgConnectionTested struct {
+	err error
+}
+
+type msgStagedAll struct {
+	err error
+}
+
+type msgUndoComplete struct {
+	err error
+}
+
 type msgAutoQuit struct{}
@@ -1,16 +1,31 @@
 package ui
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/url"
 	"strings"
+	"time"
 
-	"github.com/atotto/clipboard"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 
 	"github.com/chuckie/commit-coach/internal/config"
+	"github.com/chuckie/commit-coach/internal/observability"
+	"github.com/chuckie/commit-coach/internal/ports"
 )
 
+// defaultCompatibleBaseURL prefills the base URL step; it is where
+// llama.cpp's server listens by default.
+const defaultCompatibleBaseURL = "http://localhost:8080/v1"
+
+// connectionTestTimeout bounds the opt-in connection test.
+const connectionTestTimeout = 30 * time.Second
+
+// connectionTestDiff is the tiny change sent by the connection test.
+const connectionTestDiff = "diff --git a/README.md b/README.md\n--- a/README.md\n+++ b/README.md\n@@ -1 +1 @@\n-hello\n+hello world\n"
+
 type setupStep int
 
 type setupMode int
@@ -19,6 +34,8 @@ const (
 	setupStepProvider setupStep = iota
 	setupStepModel
 	setupStepAPIKey
+	setupStepOllamaURL
+	setupStepBaseURL
 	setupStepConfirm
 	setupStepDone
 )
@@ -29,7 +46,8 @@ const (
 )
 
 // SetupModel is an interactive setup wizard.
-// It does not call any LLMs or write files.
+// It does not write files, and only calls an LLM when the user asks for a
+// connection test (see SetLLMFactory).
 type SetupModel struct {
 	mode setupMode
 	step setupStep
@@ -42,6 +60,20 @@ type SetupModel struct {
 	model         string
 	apiKeyInput   textinput.Model
 	ollamaURL     string
+	urlInput      textinput.Model
+	baseURLInput  textinput.Model
+	omitAPIKey    bool
+	clipboard     Clipboard
+	// keyWarningAcked records a first y on a confirm step that warned about
+	// the API key's format; the second y proceeds.
+	keyWarningAcked bool
+
+	baseURL    string
+	llmFactory func(provider, apiKey, baseURL, ollamaURL, model string) (ports.LLM, error)
+	// testing is set while a connection test runs; testResult is its
+	// outcome, cleared whenever the settings may have changed.
+	testing    bool
+	testResult string
 
 	completed bool
 
@@ -49,7 +81,7 @@ type SetupModel struct {
 }
 
 func NewSetup(cfg *config.Config) *SetupModel {
-	providers := []string{"openai", "anthropic", "groq", "ollama", "mock"}
+	providers := []string{"openai", "anthropic", "groq", "mistral", "openai-compatible", "ollama", "mock"}
 
 	keyIn := textinput.New()
 	keyIn.Prompt = "API key: "
@@ -59,7 +91,11 @@ func NewSetup(cfg *config.Config) *SetupModel {
 
 	provider := "openai"
 	ollamaURL := "http://localhost:11434"
+	omitAPIKey := false
+	baseURL := ""
 	if cfg != nil {
+		omitAPIKey = cfg.OmitAPIKey
+		baseURL = cfg.BaseURL
 		if cfg.Provider != "" {
 			provider = cfg.Provider
 		}
@@ -68,6 +104,19 @@ func NewSetup(cfg *config.Config) *SetupModel {
 		}
 	}
 
+	urlIn := textinput.New()
+	urlIn.Prompt = "Ollama URL: "
+	urlIn.CharLimit = 200
+	urlIn.SetValue(ollamaURL)
+
+	baseURLIn := textinput.New()
+	baseURLIn.Prompt = "Base URL: "
+	baseURLIn.CharLimit = 200
+	baseURLIn.SetValue(baseURL)
+	if baseURL == "" {
+		baseURLIn.SetValue(defaultCompatibleBaseURL)
+	}
+
 	// Align selection index with provider
 	providerIndex := 0
 	for i, p := range providers {
@@ -104,6 +153,11 @@ func NewSetup(cfg *config.Config) *SetupModel {
 		model:         model,
 		apiKeyInput:   keyIn,
 		ollamaURL:     ollamaURL,
+		urlInput:      urlIn,
+		baseURLInput:  baseURLIn,
+		omitAPIKey:    omitAPIKey,
+		baseURL:       baseURL,
+		clipboard:     newTimeoutClipboard(systemClipboard{}, clipboardTimeout),
 	}
 }
 
@@ -113,12 +167,27 @@ func NewSetupEmbedded(cfg *config.Config) *SetupModel {
 	return m
 }
 
+// SetLLMFactory enables the connection test on the confirm step: t builds
+// a provider with factory and sends it one tiny request. Without a factory
+// the wizard never touches the network.
+func (m *SetupModel) SetLLMFactory(factory func(provider, apiKey, baseURL, ollamaURL, model string) (ports.LLM, error)) {
+	m.llmFactory = factory
+}
+
 func (m *SetupModel) Init() tea.Cmd {
 	return nil
 }
 
 func (m *SetupModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
+	case msgConnectionTested:
+		m.testing = false
+		if msg.err != nil {
+			m.testResult = "Connection failed: " + msg.err.Error()
+		} else {
+			m.testResult = "Connection OK."
+		}
+		return m, nil
 	case tea.KeyMsg:
 		// Clear any previous validation error on input/navigation.
 		// Errors should not lock the user out of the wizard.
@@ -126,8 +195,8 @@ func (m *SetupModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.err = nil
 		}
 
-		switch msg.String() {
-		case "ctrl+c", "q":
+		// q is text on the key and URL steps; only ctrl+c quits there.
+		if key := msg.String(); key == "ctrl+c" || key == "q" && !m.onTextStep() {
 			if m.mode == setupModeEmbedded {
 				m.completed = false
 				m.step = setupStepDone
@@ -144,9 +213,33 @@ func (m *SetupModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case setupStepModel:
 			return m.updateModel(msg)
 		case setupStepAPIKey:
-			return m.updateTextStep(msg, &m.apiKeyInput, func() {
+			// Local OpenAI-compatible servers usually run without a key.
+			optional := m.providers[m.providerIndex] == "openai-compatible"
+			return m.updateTextStep(msg, &m.apiKeyInput, optional, func() error {
 				m.provider = m.providers[m.providerIndex]
 				m.step = setupStepConfirm
+				return nil
+			})
+		case setupStepOllamaURL:
+			return m.updateTextStep(msg, &m.urlInput, false, func() error {
+				base, err := validateOllamaURL(m.urlInput.Value())
+				if err != nil {
+					return err
+				}
+				m.ollamaURL = base
+				m.step = setupStepConfirm
+				return nil
+			})
+		case setupStepBaseURL:
+			return m.updateTextStep(msg, &m.baseURLInput, false, func() error {
+				base, err := validateBaseURL(m.baseURLInput.Value())
+				if err != nil {
+					return err
+				}
+				m.baseURLInput.SetValue(base)
+				m.step = setupStepAPIKey
+				m.focusStep()
+				return nil
 			})
 		case setupStepConfirm:
 			return m.updateConfirm(msg)
@@ -169,7 +262,15 @@ func (m *SetupModel) View() string {
 	case setupStepModel:
 		v = m.viewModel()
 	case setupStepAPIKey:
-		v = m.viewText("API key", "Enter your provider API key. Paste with Ctrl+V (or your terminal paste).", m.apiKeyInput.View())
+		hint := "Enter your provider API key. Paste with Ctrl+V (or your terminal paste)."
+		if m.providers[m.providerIndex] == "openai-compatible" {
+			hint = "Enter the server's API key, or leave it empty if it runs without auth."
+		}
+		v = m.viewText("API key", hint, m.apiKeyInput.View())
+	case setupStepOllamaURL:
+		v = m.viewText("Ollama URL", "Enter the base URL of your Ollama server, e.g. http://localhost:11434.", m.urlInput.View())
+	case setupStepBaseURL:
+		v = m.viewText("Base URL", "Enter the base URL of your OpenAI-compatible server (llama.cpp, LM Studio, ...), e.g. http://localhost:8080/v1.", m.baseURLInput.View())
 	case setupStepConfirm:
 		v = m.viewConfirm()
 	case setupStepDone:
@@ -195,19 +296,75 @@ func (m *SetupModel) updateProvider(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.providerIndex++
 		}
 	case "enter":
-		m.provider = m.providers[m.providerIndex]
-		m.models = config.ProviderModels[m.provider]
-		if len(m.models) == 0 {
-			m.models = []string{""}
+		// Coming back to the same provider keeps the chosen model.
+		if selected := m.providers[m.providerIndex]; selected != m.provider || m.model == "" {
+			m.provider = selected
+			m.models = config.ProviderModels[m.provider]
+			if len(m.models) == 0 {
+				m.models = []string{""}
+			}
+			m.modelIndex = 0
+			m.model = m.models[0]
 		}
-		m.modelIndex = 0
-		m.model = m.models[0]
 		m.step = setupStepModel
 	}
 
 	return m, nil
 }
 
+// back returns to the previous step (confirm → API key or Ollama URL →
+// model → provider; openai-compatible asks for the base URL before the API
+// key), keeping everything entered so far.
+func (m *SetupModel) back() {
+	switch m.step {
+	case setupStepConfirm:
+		m.step = nextStepAfterModel(m.provider)
+		if m.step == setupStepConfirm {
+			m.step = setupStepModel
+		}
+		m.focusStep()
+	case setupStepAPIKey:
+		m.apiKeyInput.Blur()
+		m.step = setupStepModel
+		if m.provider == "openai-compatible" {
+			m.step = setupStepBaseURL
+			m.focusStep()
+		}
+	case setupStepBaseURL:
+		m.baseURLInput.Blur()
+		m.step = setupStepModel
+	case setupStepOllamaURL:
+		m.urlInput.Blur()
+		m.step = setupStepModel
+	case setupStepModel:
+		m.step = setupStepProvider
+	}
+}
+
+// focusStep focuses the text input of the current step, if it has one.
+func (m *SetupModel) focusStep() {
+	switch m.step {
+	case setupStepAPIKey:
+		m.apiKeyInput.Focus()
+		m.apiKeyInput.CursorEnd()
+	case setupStepOllamaURL:
+		m.urlInput.Focus()
+		m.urlInput.CursorEnd()
+	case setupStepBaseURL:
+		m.baseURLInput.Focus()
+		m.baseURLInput.CursorEnd()
+	}
+}
+
+// onTextStep reports whether the current step takes typed text.
+func (m *SetupModel) onTextStep() bool {
+	switch m.step {
+	case setupStepAPIKey, setupStepOllamaURL, setupStepBaseURL:
+		return true
+	}
+	return false
+}
+
 func (m *SetupModel) updateModel(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
 	case "up", "k":
@@ -221,27 +378,29 @@ func (m *SetupModel) updateModel(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.model = m.models[m.modelIndex]
 		}
 	case "esc":
-		m.step = setupStepProvider
+		m.back()
 	case "enter":
 		m.provider = m.providers[m.providerIndex]
 		m.model = m.models[m.modelIndex]
 		m.step = nextStepAfterModel(m.provider)
-		if m.step == setupStepAPIKey {
-			m.apiKeyInput.Focus()
-			m.apiKeyInput.CursorEnd()
-		}
+		m.focusStep()
 	}
 	return m, nil
 }
 
-func (m *SetupModel) updateTextStep(msg tea.KeyMsg, input *textinput.Model, onEnter func()) (tea.Model, tea.Cmd) {
+// updateTextStep handles keys on a text step. An empty value is refused
+// unless optional is set.
+func (m *SetupModel) updateTextStep(msg tea.KeyMsg, input *textinput.Model, optional bool, onEnter func() error) (tea.Model, tea.Cmd) {
 	switch msg.String() {
 	case "esc":
-		m.step = setupStepProvider
-		input.Blur()
+		m.back()
 		return m, nil
 	case "ctrl+v", "ctrl+shift+v", "shift+insert":
-		clip, err := clipboard.ReadAll()
+		clip, err := m.clipboard.ReadAll()
+		if errors.Is(err, errClipboardUnavailable) {
+			m.err = err
+			return m, nil
+		}
 		if err != nil {
 			m.err = fmt.Errorf("clipboard paste failed: %w", err)
 			return m, nil
@@ -257,11 +416,14 @@ func (m *SetupModel) updateTextStep(msg tea.KeyMsg, input *textinput.Model, onEn
 		return m, nil
 	case "enter":
 		val := strings.TrimSpace(input.Value())
-		if val == "" {
+		if val == "" && !optional {
 			m.err = fmt.Errorf("value cannot be empty")
 			return m, nil
 		}
-		onEnter()
+		if err := onEnter(); err != nil {
+			m.err = err
+			return m, nil
+		}
 		input.Blur()
 		return m, nil
 	}
@@ -274,6 +436,10 @@ func (m *SetupModel) updateTextStep(msg tea.KeyMsg, input *textinput.Model, onEn
 func (m *SetupModel) updateConfirm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch strings.ToLower(msg.String()) {
 	case "y":
+		if m.keyWarning() != "" && !m.keyWarningAcked {
+			m.keyWarningAcked = true
+			return m, nil
+		}
 		cfg, err := m.buildRuntimeConfig()
 		if err != nil {
 			m.err = err
@@ -287,19 +453,70 @@ func (m *SetupModel) updateConfirm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 					provider:  cfg.Provider,
 					model:     cfg.Model,
 					apiKey:    cfg.APIKey,
+					ollamaURL: cfg.OllamaURL,
+					baseURL:   cfg.BaseURL,
 					confirmed: true,
 				}
 			}
 		}
 		return m, tea.Quit
-	case "n":
-		m.step = setupStepProvider
+	case "t":
+		if m.llmFactory == nil || m.testing {
+			return m, nil
+		}
+		cfg, err := m.buildRuntimeConfig()
+		if err != nil {
+			m.err = err
+			return m, nil
+		}
+		m.testing = true
+		m.testResult = ""
+		return m, m.testConnection(cfg)
+	case "n", "esc":
+		m.keyWarningAcked = false
+		m.testResult = ""
+		m.back()
 		return m, nil
 	}
 
 	return m, nil
 }
 
+// testConnection sends one minimal suggestion request with cfg's settings.
+// Any error is returned with the key masked and secrets redacted.
+func (m *SetupModel) testConnection(cfg *config.Config) tea.Cmd {
+	factory, baseURL := m.llmFactory, m.baseURL
+	if cfg.BaseURL != "" {
+		baseURL = cfg.BaseURL
+	}
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), connectionTestTimeout)
+		defer cancel()
+		llm, err := factory(cfg.Provider, cfg.APIKey, baseURL, cfg.OllamaURL, cfg.Model)
+		if err == nil {
+			_, err = llm.SuggestCommits(ctx, ports.SuggestInput{
+				StagedDiff: connectionTestDiff,
+				FileList:   []string{"README.md"},
+				Model:      cfg.Model,
+				Count:      1,
+			})
+		}
+		if err != nil {
+			return msgConnectionTested{err: errors.New(redactKey(err.Error(), cfg.APIKey))}
+		}
+		return msgConnectionTested{}
+	}
+}
+
+// redactKey masks key wherever it appears in s, then applies the usual log
+// redaction.
+func redactKey(s, key string) string {
+	if key != "" {
+		s = strings.ReplaceAll(s, key, maskSecret(key))
+	}
+	return observability.RedactForLog(s)
+}
+
 func (m *SetupModel) viewProvider() string {
 	var b strings.Builder
 	b.WriteString("commit-coach setup\n\n")
@@ -332,7 +549,7 @@ func (m *SetupModel) viewModel() string {
 
 func (m *SetupModel) viewText(title, hint, inputView string) string {
 	return fmt.Sprintf(
-		"commit-coach setup\n\n%s\n%s\n\n%s\n\nKeys: Enter next, Esc back, q quit\n",
+		"commit-coach setup\n\n%s\n%s\n\n%s\n\nKeys: Enter next, Esc back, Ctrl+C quit\n",
 		title,
 		hint,
 		inputView,
@@ -345,8 +562,17 @@ func (m *SetupModel) viewConfirm() string {
 	apiKey := strings.TrimSpace(m.apiKeyInput.Value())
 
 	apiKeyStatus := "(not required)"
-	if provider == "openai" || provider == "groq" || provider == "anthropic" {
+	if provider == "openai" || provider == "groq" || provider == "mistral" || provider == "anthropic" {
 		apiKeyStatus = maskSecret(apiKey)
+		if m.omitAPIKey {
+			apiKeyStatus += fmt.Sprintf(" (not stored; export %s)", config.APIKeyEnvVar(provider))
+		}
+	}
+	if provider == "openai-compatible" {
+		apiKeyStatus = "(none)"
+		if apiKey != "" {
+			apiKeyStatus = maskSecret(apiKey)
+		}
 	}
 
 	lines := []string{
@@ -356,8 +582,30 @@ func (m *SetupModel) viewConfirm() string {
 		fmt.Sprintf("Model:      %s", model),
 		fmt.Sprintf("API key:    %s", apiKeyStatus),
 	}
-	lines = append(lines,
-		"\nContinue? (y/n)")
+	if provider == "ollama" {
+		lines = append(lines, fmt.Sprintf("Ollama URL: %s", m.ollamaURL))
+	}
+	if provider == "openai-compatible" {
+		lines = append(lines, fmt.Sprintf("Base URL:   %s", m.baseURLInput.Value()))
+	}
+	if warning := m.keyWarning(); warning != "" {
+		lines = append(lines, "\nWarning: "+warning)
+		if m.keyWarningAcked {
+			lines = append(lines, "\nPress y again to use this key anyway, or n/Esc to fix it.")
+			return strings.Join(lines, "\n") + "\n"
+		}
+	}
+	switch {
+	case m.testing:
+		lines = append(lines, "\nTesting connection...")
+	case m.testResult != "":
+		lines = append(lines, "\n"+m.testResult)
+	}
+	prompt := "\nContinue? (y to start, n or Esc to go back)"
+	if m.llmFactory != nil {
+		prompt = "\nContinue? (y to start, t to test the connection, n or Esc to go back)"
+	}
+	lines = append(lines, prompt)
 
 	return strings.Join(lines, "\n") + "\n"
 }
@@ -382,12 +630,18 @@ func (m *SetupModel) buildRuntimeConfig() (*config.Config, error) {
 			return nil, fmt.Errorf("API key is required for anthropic")
 		}
 		return &config.Config{Provider: provider, Model: model, APIKey: key}, nil
-	case "groq":
+	case "groq", "mistral":
 		key := strings.TrimSpace(m.apiKeyInput.Value())
 		if key == "" {
-			return nil, fmt.Errorf("API key is required for groq")
+			return nil, fmt.Errorf("API key is required for %s", provider)
 		}
 		return &config.Config{Provider: provider, Model: model, APIKey: key}, nil
+	case "openai-compatible":
+		base, err := validateBaseURL(m.baseURLInput.Value())
+		if err != nil {
+			return nil, err
+		}
+		return &config.Config{Provider: provider, Model: model, APIKey: strings.TrimSpace(m.apiKeyInput.Value()), BaseURL: base}, nil
 	case "ollama":
 		return &config.Config{Provider: provider, Model: model, APIKey: "ollama", OllamaURL: m.ollamaURL}, nil
 	case "mock":
@@ -398,12 +652,69 @@ func (m *SetupModel) buildRuntimeConfig() (*config.Config, error) {
 }
 
 func nextStepAfterModel(provider string) setupStep {
-	if provider == "openai" || provider == "groq" || provider == "anthropic" {
+	switch provider {
+	case "openai", "groq", "mistral", "anthropic":
 		return setupStepAPIKey
+	case "openai-compatible":
+		return setupStepBaseURL
+	case "ollama":
+		return setupStepOllamaURL
 	}
 	return setupStepConfirm
 }
 
+// keyWarning is apiKeyFormatWarning for the key entered in this setup.
+func (m *SetupModel) keyWarning() string {
+	return apiKeyFormatWarning(m.providers[m.providerIndex], strings.TrimSpace(m.apiKeyInput.Value()))
+}
+
+// apiKeyKnownPrefixes are the prefixes each provider's keys currently start
+// with.
+var apiKeyKnownPrefixes = map[string]string{
+	"openai":    "sk-",
+	"anthropic": "sk-ant-",
+	"groq":      "gsk_",
+}
+
+// apiKeyFormatWarning describes why key doesn't look like a key for
+// provider, or returns "". It is advisory only: providers may change their
+// key formats, so the user can always go ahead.
+func apiKeyFormatWarning(provider, key string) string {
+	prefix, ok := apiKeyKnownPrefixes[provider]
+	if !ok || key == "" {
+		return ""
+	}
+	if strings.ContainsAny(key, " \t\"'") {
+		return "the API key contains spaces or quotes; was more than the key pasted?"
+	}
+	if provider == "openai" && strings.HasPrefix(key, apiKeyKnownPrefixes["anthropic"]) {
+		return "this looks like an Anthropic key, not an OpenAI one"
+	}
+	if !strings.HasPrefix(key, prefix) {
+		return fmt.Sprintf("%s keys usually start with %q", provider, prefix)
+	}
+	return ""
+}
+
+// validateOllamaURL trims raw and checks it is an absolute http(s) URL.
+func validateOllamaURL(raw string) (string, error) {
+	return validateServerURL("Ollama URL", "http://host:port", raw)
+}
+
+// validateBaseURL is validateOllamaURL for an OpenAI-compatible server.
+func validateBaseURL(raw string) (string, error) {
+	return validateServerURL("base URL", "http://host:port/v1", raw)
+}
+
+func validateServerURL(name, example, raw string) (string, error) {
+	raw = strings.TrimRight(strings.TrimSpace(raw), "/")
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("%s must look like %s, got %q", name, example, raw)
+	}
+	return raw, nil
+}
+
 func maskSecret(v string) string {
 	v = strings.TrimSpace(v)
 	if v == "" {
@@ -415,11 +726,20 @@ func maskSecret(v string) string {
 	return v[:3] + strings.Repeat("*", len(v)-6) + v[len(v)-3:]
 }
 
-// Result returns the selected provider/model/apikey.
+// Result returns the selected provider/model/apikey and Ollama URL.
 // ok is true only when the user confirmed the setup.
-func (m *SetupModel) Result() (provider, model, apiKey string, ok bool) {
+func (m *SetupModel) Result() (provider, model, apiKey, ollamaURL string, ok bool) {
 	provider = m.providers[m.providerIndex]
 	model = m.model
 	apiKey = strings.TrimSpace(m.apiKeyInput.Value())
-	return provider, model, apiKey, m.completed
+	return provider, model, apiKey, m.ollamaURL, m.completed
+}
+
+// BaseURL returns the server URL entered for the openai-compatible provider,
+// or the configured base URL for any other provider.
+func (m *SetupModel) BaseURL() string {
+	if m.providers[m.providerIndex] == "openai-compatible" {
+		return strings.TrimRight(strings.TrimSpace(m.baseURLInput.Value()), "/")
+	}
+	return m.baseURL
 }
@@ -0,0 +1,287 @@
+package ui
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/chuckie/commit-coach/internal/config"
+	"github.com/chuckie/commit-coach/internal/ports"
+	"github.com/chuckie/commit-coach/internal/testutil"
+)
+
+func setupKeys(m *SetupModel, keys ...string) {
+	for _, k := range keys {
+		var msg tea.KeyMsg
+		switch k {
+		case "enter":
+			msg = tea.KeyMsg{Type: tea.KeyEnter}
+		case "esc":
+			msg = tea.KeyMsg{Type: tea.KeyEsc}
+		case "down":
+			msg = tea.KeyMsg{Type: tea.KeyDown}
+		default:
+			msg = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+		}
+		m.Update(msg)
+	}
+}
+
+func TestSetupBackStepsKeepValues(t *testing.T) {
+	m := NewSetup(&config.Config{Provider: "openai"})
+	setupKeys(m, "enter", "down", "enter", "s", "k", "-", "o", "l", "d", "enter")
+	if m.step != setupStepConfirm {
+		t.Fatalf("step = %v, want confirm", m.step)
+	}
+	chosenModel := m.model
+
+	// n goes back one step: the key is still there.
+	setupKeys(m, "n")
+	if m.step != setupStepAPIKey || m.apiKeyInput.Value() != "sk-old" {
+		t.Fatalf("after n: step = %v, key = %q; want the API key step with the key kept", m.step, m.apiKeyInput.Value())
+	}
+
+	// Esc steps back to the model and the provider, keeping the model.
+	setupKeys(m, "esc")
+	if m.step != setupStepModel || m.model != chosenModel {
+		t.Fatalf("after esc: step = %v, model = %q; want the model step with %q", m.step, m.model, chosenModel)
+	}
+	setupKeys(m, "esc")
+	if m.step != setupStepProvider {
+		t.Fatalf("after second esc: step = %v, want provider", m.step)
+	}
+
+	// Forward again with the same provider: nothing was lost; fix the key.
+	setupKeys(m, "enter", "enter")
+	if m.model != chosenModel || m.step != setupStepAPIKey {
+		t.Fatalf("model = %q at step %v, want %q at the API key step", m.model, m.step, chosenModel)
+	}
+	setupKeys(m, "!", "enter", "y")
+	provider, model, key, _, ok := m.Result()
+	if !ok || provider != "openai" || model != chosenModel || key != "sk-old!" {
+		t.Errorf("Result() = %q, %q, %q, %t", provider, model, key, ok)
+	}
+}
+
+func TestSetupBackSkipsKeyStepWhenNotNeeded(t *testing.T) {
+	m := NewSetup(&config.Config{Provider: "mock"})
+	setupKeys(m, "enter", "enter")
+	if m.step != setupStepConfirm {
+		t.Fatalf("step = %v, want confirm", m.step)
+	}
+	setupKeys(m, "esc")
+	if m.step != setupStepModel {
+		t.Errorf("step = %v, want model (mock has no API key step)", m.step)
+	}
+}
+
+func TestSetupOllamaURLStep(t *testing.T) {
+	m := NewSetup(&config.Config{Provider: "ollama", OllamaURL: "http://localhost:11434"})
+	setupKeys(m, "enter", "enter")
+	if m.step != setupStepOllamaURL || m.urlInput.Value() != "http://localhost:11434" {
+		t.Fatalf("step = %v, input = %q; want the URL step pre-filled", m.step, m.urlInput.Value())
+	}
+
+	m.urlInput.SetValue("gpu-box:11434")
+	setupKeys(m, "enter")
+	if m.step != setupStepOllamaURL || m.err == nil {
+		t.Fatalf("a URL without a scheme should be rejected; step = %v, err = %v", m.step, m.err)
+	}
+
+	m.urlInput.SetValue("http://gpu-box:11434/")
+	setupKeys(m, "enter")
+	if m.step != setupStepConfirm {
+		t.Fatalf("step = %v, want confirm", m.step)
+	}
+	setupKeys(m, "esc")
+	if m.step != setupStepOllamaURL {
+		t.Fatalf("back from confirm: step = %v, want the URL step", m.step)
+	}
+	setupKeys(m, "enter", "y")
+	_, _, _, ollamaURL, ok := m.Result()
+	if !ok || ollamaURL != "http://gpu-box:11434" {
+		t.Errorf("Result() URL = %q, ok = %t; want http://gpu-box:11434", ollamaURL, ok)
+	}
+	cfg, err := m.buildRuntimeConfig()
+	if err != nil || cfg.OllamaURL != "http://gpu-box:11434" {
+		t.Errorf("buildRuntimeConfig() = %+v, %v", cfg, err)
+	}
+}
+
+func TestSetupCompatibleBaseURLStep(t *testing.T) {
+	m := NewSetup(&config.Config{Provider: "openai-compatible"})
+	setupKeys(m, "enter", "enter")
+	if m.step != setupStepBaseURL || m.baseURLInput.Value() != defaultCompatibleBaseURL {
+		t.Fatalf("step = %v, input = %q; want the base URL step pre-filled", m.step, m.baseURLInput.Value())
+	}
+
+	m.baseURLInput.SetValue("http://studio:1234/v1/")
+	setupKeys(m, "enter")
+	if m.step != setupStepAPIKey {
+		t.Fatalf("step = %v, want the API key step", m.step)
+	}
+	// The key is optional; Esc from it returns to the base URL.
+	setupKeys(m, "esc")
+	if m.step != setupStepBaseURL {
+		t.Fatalf("back from the key: step = %v, want the base URL step", m.step)
+	}
+	setupKeys(m, "enter", "enter", "y")
+	provider, _, key, _, ok := m.Result()
+	if !ok || provider != "openai-compatible" || key != "" || m.BaseURL() != "http://studio:1234/v1" {
+		t.Errorf("Result() = %q, key %q, ok %t, BaseURL() = %q", provider, key, ok, m.BaseURL())
+	}
+}
+
+func TestSetupQIsTextOnTextSteps(t *testing.T) {
+	m := NewSetup(&config.Config{Provider: "ollama"})
+	setupKeys(m, "enter", "enter")
+	m.urlInput.SetValue("http://")
+	m.urlInput.CursorEnd()
+	setupKeys(m, "q")
+	if m.step != setupStepOllamaURL || m.urlInput.Value() != "http://q" {
+		t.Fatalf("step = %v, input = %q; want q typed into the URL", m.step, m.urlInput.Value())
+	}
+
+	m = NewSetup(&config.Config{Provider: "openai-compatible"})
+	setupKeys(m, "enter", "enter")
+	m.baseURLInput.SetValue("http://")
+	m.baseURLInput.CursorEnd()
+	setupKeys(m, "q")
+	if m.step != setupStepBaseURL || m.baseURLInput.Value() != "http://q" {
+		t.Fatalf("step = %v, input = %q; want q typed into the base URL", m.step, m.baseURLInput.Value())
+	}
+
+	// On the list steps q still quits.
+	if _, cmd := NewSetup(&config.Config{}).Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")}); cmd == nil {
+		t.Error("q on the provider step should quit")
+	}
+}
+
+func TestValidateOllamaURL(t *testing.T) {
+	for raw, ok := range map[string]bool{
+		"http://localhost:11434":    true,
+		" https://ollama.example/ ": true,
+		"localhost:11434":           false,
+		"ftp://host":                false,
+		"http://":                   false,
+	} {
+		if _, err := validateOllamaURL(raw); (err == nil) != ok {
+			t.Errorf("validateOllamaURL(%q) error = %v, want ok=%t", raw, err, ok)
+		}
+	}
+}
+
+func TestAPIKeyFormatWarning(t *testing.T) {
+	for _, tc := range []struct {
+		provider, key string
+		warn          bool
+	}{
+		{"openai", "sk-proj-abc123", false},
+		{"openai", "sk-ant-api03-abc", true},
+		{"openai", "abc123", true},
+		{"anthropic", "sk-ant-api03-abc", false},
+		{"anthropic", "sk-abc", true},
+		{"groq", "gsk_abc123", false},
+		{"groq", "sk-abc", true},
+		{"groq", "gsk_abc 123", true},
+		{"ollama", "anything", false},
+		{"openai", "", false},
+	} {
+		if got := apiKeyFormatWarning(tc.provider, tc.key); (got != "") != tc.warn {
+			t.Errorf("apiKeyFormatWarning(%q, %q) = %q, want warning=%t", tc.provider, tc.key, got, tc.warn)
+		}
+	}
+}
+
+func TestSetupKeyWarningNeedsSecondConfirm(t *testing.T) {
+	m := NewSetup(&config.Config{Provider: "groq"})
+	m.omitAPIKey = false
+	setupKeys(m, "enter", "enter")
+	if m.step != setupStepAPIKey {
+		t.Fatalf("step = %v, want the API key step", m.step)
+	}
+	m.apiKeyInput.SetValue("sk-wrong-provider")
+	setupKeys(m, "enter")
+	if !strings.Contains(m.View(), "Warning:") {
+		t.Fatalf("confirm view should warn about the key:\n%s", m.View())
+	}
+
+	setupKeys(m, "y")
+	if _, _, _, _, ok := m.Result(); ok || !strings.Contains(m.View(), "y again") {
+		t.Fatalf("first y should only acknowledge the warning:\n%s", m.View())
+	}
+	setupKeys(m, "y")
+	if _, _, key, _, ok := m.Result(); !ok || key != "sk-wrong-provider" {
+		t.Errorf("Result() key = %q, ok = %t; want the key kept after the override", key, ok)
+	}
+}
+
+func TestSetupConnectionTest(t *testing.T) {
+	m := NewSetup(&config.Config{Provider: "groq"})
+	setupKeys(m, "enter", "enter")
+	m.apiKeyInput.SetValue("gsk_secretsecretsecret")
+	setupKeys(m, "enter")
+
+	// Without a factory the test is not offered and t does nothing.
+	setupKeys(m, "t")
+	if m.testing || strings.Contains(m.View(), "test the connection") {
+		t.Fatalf("connection test should be opt-in:\n%s", m.View())
+	}
+
+	fake := &testutil.FakeLLM{Err: errors.New("401: invalid key gsk_secretsecretsecret")}
+	var gotKey string
+	m.SetLLMFactory(func(provider, apiKey, baseURL, ollamaURL, model string) (ports.LLM, error) {
+		gotKey = apiKey
+		return fake, nil
+	})
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("t")})
+	if cmd == nil || !strings.Contains(m.View(), "Testing connection") {
+		t.Fatalf("t should start a test:\n%s", m.View())
+	}
+	m.Update(cmd())
+	if gotKey != "gsk_secretsecretsecret" || fake.CallCount != 1 {
+		t.Errorf("factory got key %q, %d calls; want the entered key and one call", gotKey, fake.CallCount)
+	}
+	view := m.View()
+	if !strings.Contains(view, "Connection failed: 401") || strings.Contains(view, "secretsecret") {
+		t.Errorf("the failure should be shown with the key redacted:\n%s", view)
+	}
+
+	fake.Err = nil
+	_, cmd = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("t")})
+	m.Update(cmd())
+	if !strings.Contains(m.View(), "Connection OK.") || m.step != setupStepConfirm {
+		t.Errorf("a successful test should stay on confirm:\n%s", m.View())
+	}
+}
+
+func TestPersistSetupKeepsOtherSettings(t *testing.T) {
+	t.Setenv("LLM_TEMPERATURE", "0.9")
+	path := filepath.Join(t.TempDir(), "config.json")
+	stored := config.Defaults()
+	stored.Provider, stored.Model, stored.APIKey = "openai", "gpt-4o-mini", "sk-old"
+	stored.Temperature = 0.2
+	stored.DiffCap = 4096
+	if err := config.SaveToFile(path, stored); err != nil {
+		t.Fatal(err)
+	}
+
+	err := persistSetup(path, msgSetupFinished{provider: "groq", model: "llama-3.1-8b-instant", apiKey: "gsk_new", confirmed: true})
+	if err != nil {
+		t.Fatalf("persistSetup() error = %v", err)
+	}
+	got, err := config.LoadStored(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Provider != "groq" || got.Model != "llama-3.1-8b-instant" || got.APIKey != "gsk_new" {
+		t.Errorf("setup fields = %q, %q, %q; want the new choice", got.Provider, got.Model, got.APIKey)
+	}
+	// The stored temperature survives and the env override is not baked in.
+	if got.Temperature != 0.2 || got.DiffCap != 4096 {
+		t.Errorf("Temperature = %v, DiffCap = %d; want 0.2 and 4096 kept", got.Temperature, got.DiffCap)
+	}
+}
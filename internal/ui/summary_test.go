@@ -0,0 +1,40 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/chuckie/commit-coach/internal/app"
+)
+
+func TestRenderSummary(t *testing.T) {
+	out := renderSummary(&app.CommitSummary{
+		Message: "feat(ui): add summary view",
+		Branch:  "feature/summary",
+		Files: []app.FileStat{
+			{Path: "internal/ui/model.go", Added: 40, Deleted: 2},
+			{Path: "docs/logo.png", Binary: true},
+		},
+		Warnings: []string{"lint: subject exceeds 72 characters (80)", "redacted 1 secret(s) from the diff sent to the LLM"},
+	}, false)
+
+	for _, want := range []string{
+		"feat(ui): add summary view",
+		"Branch: feature/summary",
+		"internal/ui/model.go | +40 -2",
+		"docs/logo.png | binary",
+		"2 file(s) changed, 40 insertion(s)(+), 2 deletion(s)(-)",
+		"lint: subject exceeds 72 characters (80)",
+		"redacted 1 secret(s)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("summary missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "Hooks:") {
+		t.Errorf("summary mentions hooks while they run:\n%s", out)
+	}
+	if out := renderSummary(&app.CommitSummary{Message: "fix: x"}, true); !strings.Contains(out, "Hooks:  skipped (--no-verify)") {
+		t.Errorf("summary should say hooks are skipped:\n%s", out)
+	}
+}
@@ -0,0 +1,37 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/chuckie/commit-coach/internal/observability"
+)
+
+// Transcript is a plain-text, redacted record of a TUI session's key
+// transitions, for sharing in bug reports.
+type Transcript struct {
+	lines []string
+}
+
+// NewTranscript starts a transcript stamped with the current time.
+func NewTranscript() *Transcript {
+	t := &Transcript{}
+	t.record("commit-coach session %s", time.Now().Format(time.RFC3339))
+	return t
+}
+
+func (t *Transcript) record(format string, args ...interface{}) {
+	if t == nil {
+		return
+	}
+	t.lines = append(t.lines, observability.RedactForLog(fmt.Sprintf(format, args...)))
+}
+
+// String renders the transcript.
+func (t *Transcript) String() string {
+	if t == nil {
+		return ""
+	}
+	return strings.Join(t.lines, "\n") + "\n"
+}
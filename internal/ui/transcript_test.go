@@ -0,0 +1,52 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/chuckie/commit-coach/internal/adapters/cache"
+	"github.com/chuckie/commit-coach/internal/app"
+	"github.com/chuckie/commit-coach/internal/domain"
+	"github.com/chuckie/commit-coach/internal/testutil"
+)
+
+func TestTranscriptRecordsSuggestionsAndCommit(t *testing.T) {
+	fakeGit := &testutil.FakeGit{StagedDiffContent: testutil.SampleDiffSmall, IsInRepoValue: true}
+	a := app.NewApp(&testutil.FakeLLM{}, fakeGit, cache.NewInMemory(), 8192, false)
+	m := New(a, "mock", "mock", 0.7, "", "", nil)
+	tr := NewTranscript()
+	m.SetTranscript(tr)
+
+	suggestions := []domain.Suggestion{
+		{Type: "feat", Subject: "add greeting"},
+		{Type: "fix", Subject: "use fmt for output", Footer: "Refs: token sk-abcdefghijklmnopqrstuvwxyz"},
+	}
+	m.Update(msgSuggestionsLoaded{suggestions: suggestions})
+	m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("Enter should start a commit")
+	}
+	m.Update(cmd())
+
+	out := tr.String()
+	for _, want := range []string{
+		"provider: mock, model: mock",
+		"1) feat: add greeting",
+		"2) fix: use fmt for output",
+		"chosen (#2):\nfix: use fmt for output",
+		"committed as abc123def456",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("transcript missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "sk-abcdefghijklmnopqrstuvwxyz") {
+		t.Errorf("transcript leaked a secret:\n%s", out)
+	}
+	if len(fakeGit.CommittedMessages) != 1 {
+		t.Errorf("CommittedMessages = %v, want one commit", fakeGit.CommittedMessages)
+	}
+}
@@ -0,0 +1,127 @@
+// Package wiring builds the application from a Config: the git adapter, the
+// provider and every suggestion setting. The command and pkg/coach share it
+// so they stay wired the same way.
+package wiring
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/chuckie/commit-coach/internal/adapters/cache"
+	"github.com/chuckie/commit-coach/internal/adapters/git"
+	"github.com/chuckie/commit-coach/internal/adapters/llm"
+	"github.com/chuckie/commit-coach/internal/adapters/llm/llmhttp"
+	"github.com/chuckie/commit-coach/internal/app"
+	"github.com/chuckie/commit-coach/internal/config"
+	"github.com/chuckie/commit-coach/internal/ports"
+	"github.com/chuckie/commit-coach/internal/security"
+)
+
+// Options are the per-process pieces a Config does not describe.
+type Options struct {
+	// Cache stores suggestions; nil means a fresh in-memory cache.
+	Cache ports.Cache
+	// FailureNotes enables hints on repeat failures; nil disables them.
+	FailureNotes ports.FailureNotes
+}
+
+// Git returns the git adapter with cfg's timeouts and commit, signing and
+// diff settings applied.
+func Git(cfg *config.Config) *git.Executor {
+	g := git.NewExecutor(cfg.GitTimeoutDuration())
+	g.SetCommitTimeout(cfg.GitCommitTimeoutDuration())
+	g.SetUseMessageFlag(cfg.CommitUseMessageFlag)
+	g.SetSigning(cfg.SignCommits, cfg.SigningKey)
+	g.SetSubmoduleContext(cfg.SubmoduleContext)
+	g.SetIncludeIntentToAdd(cfg.IncludeIntentToAdd)
+	return g
+}
+
+// NewCommitService returns a commit service on Git(cfg), for commands that
+// commit without suggesting.
+func NewCommitService(cfg *config.Config) *app.CommitService {
+	c := app.NewCommitService(Git(cfg))
+	configureCommit(c, cfg)
+	return c
+}
+
+// LLMFactory returns llm.NewFromConfig with cfg's provider-specific options
+// applied, so providers switched to from the TUI get them too. cfg may be
+// nil (e.g. before first-run setup), meaning no extra options.
+func LLMFactory(cfg *config.Config) llm.BuildFunc {
+	if cfg == nil {
+		cfg = &config.Config{}
+	}
+	// Each multi member's key comes from its own env var.
+	keyFor := func(p string) string { return os.Getenv(config.APIKeyEnvVar(p)) }
+	httpOpts := llmhttp.Options{CABundle: cfg.CABundle, InsecureSkipVerify: cfg.InsecureSkipVerify}
+	return llm.NewFactory(cfg.MultiProviders, keyFor, cfg.GroqAllowHighTemp, cfg.RequestTimeoutDuration(), httpOpts)
+}
+
+// NewApp builds the application for cfg in the repository of the current
+// directory, reading its .commit-coachignore. Errors in the provider
+// settings, redaction patterns, examples or prompt template are returned.
+func NewApp(cfg *config.Config, opts Options) (*app.App, error) {
+	provider, err := LLMFactory(cfg)(cfg.Provider, cfg.APIKey, cfg.BaseURL, cfg.OllamaURL, cfg.Model)
+	if err != nil {
+		return nil, fmt.Errorf("initialize LLM provider: %w", err)
+	}
+	gitAdapter := Git(cfg)
+	ignore, err := loadRepoIgnore(gitAdapter)
+	if err != nil {
+		return nil, err
+	}
+	redactor, err := security.NewRedactorWithPatterns(append(append([]string(nil), cfg.RedactPatterns...), ignore.Redact...))
+	if err != nil {
+		return nil, err
+	}
+	examples, err := cfg.Examples()
+	if err != nil {
+		return nil, err
+	}
+	promptTemplate, err := cfg.PromptTemplateText()
+	if err != nil {
+		return nil, err
+	}
+
+	suggestCache := opts.Cache
+	if suggestCache == nil {
+		suggestCache = cache.NewInMemory()
+	}
+	a := app.NewAppWithRedactor(provider, gitAdapter, suggestCache, cfg.DiffCap, cfg.UseCache, redactor)
+	if err := a.Suggest.SetExclude(ignore.Exclude); err != nil {
+		return nil, fmt.Errorf("%s: %w", config.IgnoreFileName, err)
+	}
+	a.Suggest.SetCount(cfg.SuggestCount)
+	a.Suggest.SetMaxFiles(cfg.MaxFiles)
+	a.Suggest.SetFailureNotes(opts.FailureNotes)
+	a.Suggest.SetRedact(cfg.Redact)
+	a.Suggest.SetSummarizeLargeHunks(cfg.SummarizeLargeHunks)
+	a.Suggest.SetSubjectLimits(cfg.SubjectLimits())
+	a.Suggest.SetSubjectStyle(cfg.SubjectStyle())
+	a.Suggest.SetSystemPrompt(cfg.SystemPromptFor(cfg.Provider))
+	a.Suggest.SetTimeout(cfg.RequestTimeoutDuration())
+	a.Suggest.SetRetryEmpty(cfg.RetryEmpty)
+	a.Suggest.SetExamples(examples)
+	a.Suggest.SetPromptTemplate(promptTemplate)
+	configureCommit(a.Commit, cfg)
+	return a, nil
+}
+
+func configureCommit(c *app.CommitService, cfg *config.Config) {
+	c.SetTimeout(cfg.GitTimeoutDuration())
+	c.SetCommitTimeout(cfg.GitCommitTimeoutDuration())
+	c.SetTicketPattern(cfg.TicketRegexp())
+}
+
+// loadRepoIgnore reads the repository's .commit-coachignore. When the root
+// can't be resolved there is nothing to load; the suggest pipeline reports
+// the missing repository itself.
+func loadRepoIgnore(gitAdapter *git.Executor) (*config.IgnoreFile, error) {
+	root, err := gitAdapter.RootDir(context.Background())
+	if err != nil {
+		return &config.IgnoreFile{}, nil
+	}
+	return config.LoadIgnoreFile(root)
+}
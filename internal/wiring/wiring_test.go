@@ -0,0 +1,38 @@
+package wiring
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/chuckie/commit-coach/internal/config"
+)
+
+func TestNewAppBuildsMockApp(t *testing.T) {
+	cfg := config.Defaults()
+	cfg.Provider = "mock"
+	a, err := NewApp(cfg, Options{})
+	if err != nil {
+		t.Fatalf("NewApp: %v", err)
+	}
+	defer a.Close()
+	if a.Suggest == nil || a.Commit == nil {
+		t.Fatal("NewApp left services unset")
+	}
+}
+
+func TestNewAppReportsExamplesError(t *testing.T) {
+	cfg := config.Defaults()
+	cfg.Provider = "mock"
+	cfg.FewShotExamplesFile = filepath.Join(t.TempDir(), "missing.txt")
+	if _, err := NewApp(cfg, Options{}); err == nil {
+		t.Fatal("NewApp with a missing examples file: want error")
+	}
+}
+
+func TestNewAppReportsProviderError(t *testing.T) {
+	cfg := config.Defaults()
+	cfg.Provider = "nope"
+	if _, err := NewApp(cfg, Options{}); err == nil {
+		t.Fatal("NewApp with an unknown provider: want error")
+	}
+}
@@ -1,35 +1,97 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"text/template"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 
 	"github.com/chuckie/commit-coach/internal/adapters/cache"
+	"github.com/chuckie/commit-coach/internal/adapters/clock"
 	"github.com/chuckie/commit-coach/internal/adapters/git"
 	"github.com/chuckie/commit-coach/internal/adapters/llm"
+	"github.com/chuckie/commit-coach/internal/adapters/notes"
 	"github.com/chuckie/commit-coach/internal/app"
 	"github.com/chuckie/commit-coach/internal/config"
+	"github.com/chuckie/commit-coach/internal/domain"
 	"github.com/chuckie/commit-coach/internal/observability"
+	"github.com/chuckie/commit-coach/internal/ports"
 	"github.com/chuckie/commit-coach/internal/ui"
+	"github.com/chuckie/commit-coach/internal/wiring"
 )
 
 func main() {
 	os.Exit(run(os.Args))
 }
 
+// loadConfig wraps config.Load. When no API key is configured and
+// COMMIT_COACH_FALLBACK_MOCK is set, it switches to the mock provider and
+// writes a notice to w instead of returning the setup-required error.
+func loadConfig(w io.Writer) (*config.Config, error) {
+	cfg, err := config.Load()
+	if err == nil || cfg == nil || !config.IsSetupRequired(err) || !config.FallbackToMockEnabled() {
+		return cfg, err
+	}
+	fmt.Fprintf(w, "Notice: no API key configured for %s; using the mock provider (%s is set).\n", cfg.Provider, config.FallbackMockEnv)
+	fmt.Fprintln(w, "Suggestions are canned examples. Run: commit-coach setup")
+	config.UseMock(cfg)
+	return cfg, nil
+}
+
+// tuiCacheTTL bounds how long the interactive session reuses suggestions for
+// an unchanged diff.
+const tuiCacheTTL = 30 * time.Minute
+
+// commandSlack is how much longer than the request timeout a one-shot
+// command may run, for git work around the suggestion request.
+const commandSlack = 30 * time.Second
+
 func run(args []string) int {
 	// Best-effort error logging to a local file.
 	if _, cleanup, err := observability.Init(); err == nil {
 		defer cleanup()
 	}
 
+	args, verbose := stripVerboseFlag(args)
+	args, logLevel := stripLogLevelFlag(args)
+	args, noColor := stripBoolFlag(args, "--no-color")
+	args, plain := stripBoolFlag(args, "--plain")
+	if logLevel != "" {
+		level, err := observability.ParseLevel(logLevel)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+		observability.SetLogLevel(level)
+	}
+	if verbose || observability.DebugEnvEnabled() {
+		if len(args) < 2 {
+			// The TUI owns the terminal; show debug output once it exits.
+			var buf bytes.Buffer
+			observability.SetDebugOutput(&buf)
+			defer func() {
+				observability.SetDebugOutput(nil)
+				os.Stderr.Write(buf.Bytes())
+			}()
+		} else {
+			observability.SetDebugOutput(os.Stderr)
+			defer observability.SetDebugOutput(nil)
+		}
+	}
+
 	if len(args) >= 2 {
 		switch args[1] {
 		case "-h", "--help", "help":
@@ -41,6 +103,12 @@ func run(args []string) int {
 			return runConfig(args[2:])
 		case "suggest":
 			return runSuggest(args[2:])
+		case "lint":
+			return runLint(args[2:])
+		case "hook":
+			return runHook(args[2:])
+		case "status":
+			return runStatus(args[2:])
 		default:
 			if strings.HasPrefix(args[1], "-") {
 				fmt.Fprintf(os.Stderr, "Unknown flag: %s\n\n", args[1])
@@ -53,13 +121,14 @@ func run(args []string) int {
 	}
 
 	// Load configuration
-	cfg, err := config.Load()
+	cfg, err := loadConfig(os.Stderr)
 	if err != nil {
 		// Fallback: even if the sentinel wrapper is lost, a missing key for
-		// openai/groq/anthropic should always trigger interactive setup.
-		needsSetup := config.IsSetupRequired(err) || (cfg != nil && (cfg.Provider == "openai" || cfg.Provider == "groq" || cfg.Provider == "anthropic") && cfg.APIKey == "")
+		// openai/groq/mistral/anthropic should always trigger interactive setup.
+		needsSetup := config.IsSetupRequired(err) || (cfg != nil && (cfg.Provider == "openai" || cfg.Provider == "groq" || cfg.Provider == "mistral" || cfg.Provider == "anthropic") && cfg.APIKey == "")
 		if needsSetup {
 			setup := ui.NewSetup(cfg)
+			setup.SetLLMFactory(wiring.LLMFactory(cfg))
 			p := tea.NewProgram(setup)
 			finalModel, runErr := p.Run()
 			if runErr != nil {
@@ -73,7 +142,7 @@ func run(args []string) int {
 				return 1
 			}
 
-			provider, model, apiKey, confirmed := sm.Result()
+			provider, model, apiKey, ollamaURL, confirmed := sm.Result()
 			if !confirmed {
 				fmt.Fprintf(os.Stderr, "Setup cancelled.\n")
 				return 1
@@ -83,10 +152,14 @@ func run(args []string) int {
 			cfg.Provider = provider
 			cfg.Model = model
 			switch provider {
-			case "openai", "groq", "anthropic":
+			case "openai", "groq", "mistral", "anthropic":
+				cfg.APIKey = apiKey
+			case "openai-compatible":
 				cfg.APIKey = apiKey
+				cfg.BaseURL = sm.BaseURL()
 			case "ollama":
 				cfg.APIKey = "ollama"
+				cfg.OllamaURL = ollamaURL
 			case "mock":
 				cfg.APIKey = "mock"
 			}
@@ -94,6 +167,7 @@ func run(args []string) int {
 			if path, err := config.DefaultConfigPath(); err == nil {
 				if err := config.SaveToFile(path, cfg); err == nil {
 					fmt.Fprintf(os.Stderr, "Saved config to %s\n", path)
+					printKeyNotStoredNote(os.Stderr, cfg)
 				}
 			}
 		} else {
@@ -102,31 +176,156 @@ func run(args []string) int {
 		}
 	}
 
-	// Create adapters
-	gitAdapter := git.NewExecutor()
-	cacheAdapter := cache.NewInMemory()
-
-	// Use factory to create LLM provider
-	llmAdapter, err := llm.NewFromConfig(cfg.Provider, cfg.APIKey, cfg.BaseURL, cfg.OllamaURL, cfg.Model)
+	application, err := wiring.NewApp(cfg, wiring.Options{
+		Cache:        cache.NewInMemoryWithTTL(tuiCacheTTL, clock.System{}),
+		FailureNotes: newFailureNotes(),
+	})
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to initialize LLM provider: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Configuration error: %v\n", err)
 		return 1
 	}
-
-	// Create application
-	application := app.NewApp(llmAdapter, gitAdapter, cacheAdapter, cfg.DiffCap, cfg.UseCache)
+	defer application.Close()
 
 	// Create TUI model
-	model := ui.New(application, cfg.Provider, cfg.Model, cfg.Temperature, cfg.BaseURL, cfg.OllamaURL, llm.NewFromConfig)
+	model := ui.New(application, cfg.Provider, cfg.Model, cfg.Temperature, cfg.BaseURL, cfg.OllamaURL, wiring.LLMFactory(cfg))
+	model.SetDefaultSelection(cfg.DefaultSelection)
+	model.SetConfirmCommit(cfg.ConfirmSend)
+	model.SetDryRun(cfg.DryRun)
+	if noColor {
+		model.SetNoColor(true)
+	}
+	if plain {
+		model.SetPlain(true)
+	}
+	keys := ui.DefaultKeyMap()
+	if err := keys.Apply(cfg.Keybindings); err != nil {
+		fmt.Fprintf(os.Stderr, "Configuration error: %v\n", err)
+		return 1
+	}
+	model.SetKeyMap(keys)
 
 	// Run TUI
+	transcriptPath := os.Getenv("COMMIT_COACH_TRANSCRIPT")
+	var transcript *ui.Transcript
+	if transcriptPath != "" {
+		transcript = ui.NewTranscript()
+		model.SetTranscript(transcript)
+	}
+
 	p := tea.NewProgram(model)
 	if _, err := p.Run(); err != nil {
 		log.Fatalf("Error running TUI: %v", err)
 	}
+
+	if transcript != nil {
+		writeTranscript(transcriptPath, transcript.String())
+	}
 	return 0
 }
 
+// stripVerboseFlag removes the global -v/--verbose flag from args (keeping
+// args[0]) and reports whether it was present.
+func stripVerboseFlag(args []string) ([]string, bool) {
+	return stripBoolFlag(args, "-v", "--verbose")
+}
+
+// stripBoolFlag removes every occurrence of the global flag names from the
+// flags of args (keeping args[0]; see globalFlagsEnd) and reports whether
+// any was present.
+func stripBoolFlag(args []string, names ...string) ([]string, bool) {
+	if len(args) == 0 {
+		return args, false
+	}
+	end := globalFlagsEnd(args)
+	out := []string{args[0]}
+	found := false
+	for i := 1; i < end; i++ {
+		a := args[i]
+		switch {
+		case slices.Contains(names, a):
+			found = true
+		case slices.Contains(valueFlags, a) && i+1 < end:
+			out = append(out, a, args[i+1])
+			i++
+		default:
+			out = append(out, a)
+		}
+	}
+	return append(out, args[end:]...), found
+}
+
+// stripLogLevelFlag removes the global --log-level flag ("--log-level L" or
+// "--log-level=L") from the flags of args and returns its value, or "" when
+// absent.
+func stripLogLevelFlag(args []string) ([]string, string) {
+	if len(args) == 0 {
+		return args, ""
+	}
+	end := globalFlagsEnd(args)
+	out := []string{args[0]}
+	level := ""
+	for i := 1; i < end; i++ {
+		a := args[i]
+		switch {
+		case a == "--log-level" && i+1 < end:
+			level = args[i+1]
+			i++
+		case strings.HasPrefix(a, "--log-level="):
+			level = strings.TrimPrefix(a, "--log-level=")
+		case slices.Contains(valueFlags, a) && i+1 < end:
+			out = append(out, a, args[i+1])
+			i++
+		default:
+			out = append(out, a)
+		}
+	}
+	return append(out, args[end:]...), level
+}
+
+// valueFlags are the flags, global or per command, that take the next
+// argument as their value, so "lint -m -v" keeps "-v" as the message.
+var valueFlags = []string{
+	"--log-level",
+	"--provider", "--model", "--api-key", "--baseurl", "--ollama-url", "--temperature",
+	"--output", "-o", "--template", "--prompt-examples", "--path", "--fixup", "--index",
+	"--max-files", "--count", "--message", "-m", "--file", "--ref", "--type",
+}
+
+// globalFlagsEnd returns the index in args where global flags stop being
+// recognized: at "--", or at the first positional argument after the
+// subcommand, so "config set system_prompt -v" keeps its value. Flag
+// values are skipped.
+func globalFlagsEnd(args []string) int {
+	command := false
+	for i := 1; i < len(args); i++ {
+		switch a := args[i]; {
+		case a == "--":
+			return i
+		case slices.Contains(valueFlags, a):
+			i++
+		case strings.HasPrefix(a, "-"):
+		case !command:
+			command = true
+		default:
+			return i
+		}
+	}
+	return len(args)
+}
+
+// writeTranscript writes the session transcript to path, or stdout for "-".
+func writeTranscript(path, text string) {
+	if path == "-" {
+		fmt.Fprint(os.Stdout, text)
+		return
+	}
+	if err := os.WriteFile(path, []byte(text), 0o600); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write transcript: %v\n", err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Transcript saved to %s\n", path)
+}
+
 func printHelp() {
 	fmt.Fprintln(os.Stdout, "commit-coach — AI-powered commit message suggestions")
 	fmt.Fprintln(os.Stdout, "")
@@ -134,26 +333,40 @@ func printHelp() {
 	fmt.Fprintln(os.Stdout, "  commit-coach            # Launch TUI")
 	fmt.Fprintln(os.Stdout, "  commit-coach setup      # Setup (persisted; interactive by default)")
 	fmt.Fprintln(os.Stdout, "  commit-coach config     # Show config path + active config")
-	fmt.Fprintln(os.Stdout, "  commit-coach suggest    # Print 3 suggestions (non-TUI)")
+	fmt.Fprintln(os.Stdout, "  commit-coach suggest    # Print suggestions (non-TUI, 3 by default)")
+	fmt.Fprintln(os.Stdout, "  commit-coach lint       # Check a commit message against the rules")
+	fmt.Fprintln(os.Stdout, "  commit-coach hook       # git prepare-commit-msg hook integration")
+	fmt.Fprintln(os.Stdout, "  commit-coach status     # Show provider, key, repo and cache readiness")
 	fmt.Fprintln(os.Stdout, "")
 	fmt.Fprintln(os.Stdout, "Commands:")
-	fmt.Fprintln(os.Stdout, "  setup [--provider P] [--model M] [--api-key K]")
-	fmt.Fprintln(os.Stdout, "  config [path|set|reset]")
-	fmt.Fprintln(os.Stdout, "  suggest [--json]")
+	fmt.Fprintln(os.Stdout, "  setup [--provider P] [--model M] [--api-key K] [--no-store-key]")
+	fmt.Fprintln(os.Stdout, "  config [path|set|unset|validate|reset]")
+	fmt.Fprintln(os.Stdout, "  suggest [--json | --output FORMAT [--template T]] [--count N] [--max-files N] [--retry-empty] [--prompt-examples FILE] [--dry-run] [--commit [--index N] [--yes] [--no-verify]]")
+	fmt.Fprintln(os.Stdout, "  lint [--message M | --file PATH | --ref REF]")
+	fmt.Fprintln(os.Stdout, "  hook [install | prepare-commit-msg [--type T] FILE [SOURCE]]")
 	fmt.Fprintln(os.Stdout, "")
 	fmt.Fprintln(os.Stdout, "Common flags:")
 	fmt.Fprintln(os.Stdout, "  -h, --help              Show help")
+	fmt.Fprintln(os.Stdout, "  -v, --verbose           Print the redacted prompt and response to stderr (or set COMMIT_COACH_DEBUG=1)")
+	fmt.Fprintln(os.Stdout, "  --log-level L           Error log threshold: error, warn (default), info or debug (or set COMMIT_COACH_LOG_LEVEL)")
+	fmt.Fprintln(os.Stdout, "  --no-color              Render the TUI without colors (or set NO_COLOR)")
+	fmt.Fprintln(os.Stdout, "  --plain                 Screen-reader friendly TUI: labeled suggestions, spelled-out keys (or set COMMIT_COACH_PLAIN=1)")
+	fmt.Fprintln(os.Stdout, "")
+	fmt.Fprintln(os.Stdout, "Common flags go before the subcommand's own arguments and before --; later ones are left to the subcommand.")
 }
 
 func runSetup(args []string) int {
 	// Minimal flags (no external deps):
-	// setup [--provider <p>] [--model <m>] [--api-key <k>]
+	// setup [--provider <p>] [--model <m>] [--api-key <k>] [--no-store-key]
 	var provider, model, apiKey string
+	noStoreKey := false
 	for i := 0; i < len(args); i++ {
 		switch args[i] {
 		case "-h", "--help":
-			fmt.Fprintln(os.Stdout, "Usage: commit-coach setup [--provider P] [--model M] [--api-key K]")
+			fmt.Fprintln(os.Stdout, "Usage: commit-coach setup [--provider P] [--model M] [--api-key K] [--no-store-key]")
 			return 0
+		case "--no-store-key":
+			noStoreKey = true
 		case "--provider":
 			i++
 			if i >= len(args) {
@@ -195,6 +408,9 @@ func runSetup(args []string) int {
 	if apiKey != "" {
 		cfg.APIKey = apiKey
 	}
+	if noStoreKey {
+		cfg.OmitAPIKey = true
+	}
 
 	if cfg.Provider != "" && cfg.Model != "" && (cfg.Provider == "mock" || cfg.Provider == "ollama" || cfg.APIKey != "") {
 		if cfg.Provider == "mock" {
@@ -213,10 +429,12 @@ func runSetup(args []string) int {
 			return 1
 		}
 		fmt.Fprintf(os.Stdout, "Saved config to %s\n", path)
+		printKeyNotStoredNote(os.Stdout, cfg)
 		return 0
 	}
 
 	setup := ui.NewSetup(cfg)
+	setup.SetLLMFactory(wiring.LLMFactory(cfg))
 	p := tea.NewProgram(setup)
 	finalModel, runErr := p.Run()
 	if runErr != nil {
@@ -230,7 +448,7 @@ func runSetup(args []string) int {
 		return 1
 	}
 
-	provider, model, apiKey, confirmed := sm.Result()
+	provider, model, apiKey, ollamaURL, confirmed := sm.Result()
 	if !confirmed {
 		fmt.Fprintf(os.Stderr, "Setup cancelled.\n")
 		return 1
@@ -239,10 +457,14 @@ func runSetup(args []string) int {
 	cfg.Provider = provider
 	cfg.Model = model
 	switch provider {
-	case "openai", "groq", "anthropic":
+	case "openai", "groq", "mistral", "anthropic":
+		cfg.APIKey = apiKey
+	case "openai-compatible":
 		cfg.APIKey = apiKey
+		cfg.BaseURL = sm.BaseURL()
 	case "ollama":
 		cfg.APIKey = "ollama"
+		cfg.OllamaURL = ollamaURL
 	case "mock":
 		cfg.APIKey = "mock"
 	}
@@ -257,9 +479,20 @@ func runSetup(args []string) int {
 		return 1
 	}
 	fmt.Fprintf(os.Stdout, "Saved config to %s\n", path)
+	printKeyNotStoredNote(os.Stdout, cfg)
 	return 0
 }
 
+// printKeyNotStoredNote tells the user which env var to export when the API
+// key was deliberately left out of the config file.
+func printKeyNotStoredNote(w io.Writer, cfg *config.Config) {
+	envVar := config.APIKeyEnvVar(cfg.Provider)
+	if !cfg.OmitAPIKey || envVar == "" {
+		return
+	}
+	fmt.Fprintf(w, "API key not stored in config. Export it before running commit-coach:\n  export %s=...\n", envVar)
+}
+
 func runConfig(args []string) int {
 	path, err := config.DefaultConfigPath()
 	if err != nil {
@@ -273,12 +506,46 @@ func runConfig(args []string) int {
 			fmt.Fprintln(os.Stdout, "Usage:")
 			fmt.Fprintln(os.Stdout, "  commit-coach config")
 			fmt.Fprintln(os.Stdout, "  commit-coach config path")
-			fmt.Fprintln(os.Stdout, "  commit-coach config set --provider P --model M [--api-key K]")
+			fmt.Fprintln(os.Stdout, "  commit-coach config set --provider P --model M [--api-key K] [--baseurl URL]")
+			fmt.Fprintln(os.Stdout, "  commit-coach config unset [--provider] [--model] [--api-key] [--baseurl] [--ollama-url] [--temperature]")
+			fmt.Fprintln(os.Stdout, "  commit-coach config validate [path]")
+			fmt.Fprintln(os.Stdout, "  commit-coach config models [--provider P]")
 			fmt.Fprintln(os.Stdout, "  commit-coach config reset")
 			return 0
 		case "path":
 			fmt.Fprintln(os.Stdout, path)
 			return 0
+		case "validate":
+			if len(args) > 2 {
+				fmt.Fprintf(os.Stderr, "Unknown config validate flag/arg: %s\n", args[2])
+				return 2
+			}
+			target := path
+			if len(args) == 2 {
+				target = args[1]
+				if _, err := os.Stat(target); err != nil {
+					fmt.Fprintf(os.Stdout, "FAIL  file: %v\n", err)
+					return 1
+				}
+			}
+			return validateConfig(target)
+		case "models":
+			provider := ""
+			for i := 1; i < len(args); i++ {
+				switch args[i] {
+				case "--provider":
+					i++
+					if i >= len(args) {
+						fmt.Fprintln(os.Stderr, "--provider requires a value")
+						return 2
+					}
+					provider = args[i]
+				default:
+					fmt.Fprintf(os.Stderr, "Unknown config models flag/arg: %s\n", args[i])
+					return 2
+				}
+			}
+			return runConfigModels(config.Resolve(path), provider, os.Stdout, os.Stderr)
 		case "reset":
 			if err := config.DeleteConfig(path); err != nil {
 				fmt.Fprintf(os.Stderr, "Failed to reset config: %v\n", err)
@@ -287,7 +554,7 @@ func runConfig(args []string) int {
 			fmt.Fprintf(os.Stdout, "Config reset. Removed %s\n", path)
 			return 0
 		case "set":
-			var provider, model, apiKey string
+			var provider, model, apiKey, baseURL string
 			for i := 1; i < len(args); i++ {
 				switch args[i] {
 				case "--provider":
@@ -311,6 +578,13 @@ func runConfig(args []string) int {
 						return 2
 					}
 					apiKey = args[i]
+				case "--baseurl":
+					i++
+					if i >= len(args) {
+						fmt.Fprintln(os.Stderr, "--baseurl requires a value")
+						return 2
+					}
+					baseURL = args[i]
 				default:
 					fmt.Fprintf(os.Stderr, "Unknown config set flag/arg: %s\n", args[i])
 					return 2
@@ -330,17 +604,25 @@ func runConfig(args []string) int {
 			if apiKey != "" {
 				cfg.APIKey = apiKey
 			}
+			if baseURL != "" {
+				cfg.BaseURL = baseURL
+			}
 
 			switch cfg.Provider {
 			case "mock":
 				cfg.APIKey = "mock"
 			case "ollama":
 				cfg.APIKey = "ollama"
-			case "openai", "groq", "anthropic":
+			case "openai", "groq", "mistral", "anthropic":
 				if strings.TrimSpace(cfg.APIKey) == "" {
 					fmt.Fprintf(os.Stderr, "API key is required for provider %s (pass --api-key or set env var)\n", cfg.Provider)
 					return 2
 				}
+			case "openai-compatible":
+				if strings.TrimSpace(cfg.BaseURL) == "" {
+					fmt.Fprintln(os.Stderr, "Base URL is required for provider openai-compatible (pass --baseurl or set OPENAI_BASE_URL)")
+					return 2
+				}
 			default:
 				fmt.Fprintf(os.Stderr, "Invalid provider: %s\n", cfg.Provider)
 				return 2
@@ -352,6 +634,51 @@ func runConfig(args []string) int {
 			}
 			fmt.Fprintf(os.Stdout, "Saved config to %s\n", path)
 			return 0
+		case "unset":
+			var cleared []string
+			for i := 1; i < len(args); i++ {
+				switch args[i] {
+				case "--provider", "--model", "--api-key", "--baseurl", "--ollama-url", "--temperature":
+					cleared = append(cleared, strings.TrimPrefix(args[i], "--"))
+				default:
+					fmt.Fprintf(os.Stderr, "Unknown config unset flag/arg: %s\n", args[i])
+					return 2
+				}
+			}
+			if len(cleared) == 0 {
+				fmt.Fprintln(os.Stderr, "config unset requires at least one field flag (e.g. --baseurl)")
+				return 2
+			}
+
+			cfg, _ := config.Load() // best-effort; may fail when setup required
+			if cfg == nil {
+				cfg = config.Defaults()
+			}
+			defaults := config.Defaults()
+			for _, field := range cleared {
+				switch field {
+				case "provider":
+					cfg.Provider = defaults.Provider
+				case "model":
+					cfg.Model = defaults.Model
+				case "api-key":
+					cfg.APIKey = defaults.APIKey
+				case "baseurl":
+					cfg.BaseURL = defaults.BaseURL
+				case "ollama-url":
+					cfg.OllamaURL = defaults.OllamaURL
+				case "temperature":
+					cfg.Temperature = defaults.Temperature
+				}
+			}
+
+			if err := config.SaveToFile(path, cfg); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to save config: %v\n", err)
+				return 1
+			}
+			fmt.Fprintf(os.Stdout, "Cleared: %s\n", strings.Join(cleared, ", "))
+			fmt.Fprintf(os.Stdout, "Saved config to %s\n", path)
+			return 0
 		default:
 			fmt.Fprintf(os.Stderr, "Unknown config subcommand: %s\n", args[0])
 			return 2
@@ -377,25 +704,275 @@ func runConfig(args []string) int {
 	fmt.Fprintf(os.Stdout, "Provider:    %s\n", cfg.Provider)
 	fmt.Fprintf(os.Stdout, "Model:       %s\n", cfg.Model)
 	fmt.Fprintf(os.Stdout, "API key:     %s\n", keyStatus)
+	if err := config.ValidateModel(cfg.Provider, cfg.Model); err != nil {
+		fmt.Fprintf(os.Stdout, "Warning:     %v\n", err)
+	}
+	return 0
+}
+
+// runConfigModels prints provider's models (cfg.Provider when empty), one
+// per line: the live list when the provider can fetch one, else the
+// built-in config.ProviderModels list.
+func runConfigModels(cfg *config.Config, provider string, stdout, stderr io.Writer) int {
+	if provider == "" {
+		provider = cfg.Provider
+	}
+	fallback, known := config.ProviderModels[provider]
+	if !known {
+		fmt.Fprintf(stderr, "Unknown provider: %s\n", provider)
+		return 2
+	}
+
+	apiKey := cfg.APIKey
+	if provider != cfg.Provider {
+		apiKey = os.Getenv(config.APIKeyEnvVar(provider))
+	}
+	var cache *llm.ModelCache
+	if path, err := config.DefaultModelCachePath(); err == nil {
+		cache = llm.NewModelCache(path, llm.ModelListTTL, clock.System{})
+	}
+
+	models, live := fallback, false
+	client, err := llm.NewFromConfig(provider, apiKey, cfg.BaseURL, cfg.OllamaURL, cfg.Model)
+	if err == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		models, live, err = llm.ListModels(ctx, provider, client, cache, fallback)
+		cancel()
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "Could not fetch the %s model list: %s\n", provider, observability.RedactForLog(err.Error()))
+	}
+	if !live {
+		fmt.Fprintf(stderr, "Showing the built-in %s model list.\n", provider)
+	}
+	for _, m := range models {
+		fmt.Fprintln(stdout, m)
+	}
+	return 0
+}
+
+// validateConfig prints a per-field PASS/FAIL summary for the values stored
+// in the config file at path, without env overrides, and returns 0 when it
+// is valid, 1 otherwise. A missing file has nothing to validate.
+func validateConfig(path string) int {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		fmt.Fprintf(os.Stdout, "No config file at %s; nothing to validate.\n", path)
+		return 0
+	}
+	fmt.Fprintf(os.Stdout, "Validating %s\n", path)
+
+	stored, err := config.LoadStored(path)
+	if err != nil {
+		fmt.Fprintf(os.Stdout, "FAIL  file: %v\n", err)
+		fmt.Fprintln(os.Stdout, "Config is invalid.")
+		return 1
+	}
+	fmt.Fprintln(os.Stdout, "PASS  file")
+
+	valid := true
+	for _, check := range config.Check(stored) {
+		if check.Field == "api-key" && check.Err != nil && stored.APIKey == "" {
+			// Keys are often kept out of the file and read from the env.
+			fmt.Fprintf(os.Stdout, "SKIP  api-key: not stored in the file (read from %s)\n", config.APIKeyEnvVar(stored.Provider))
+			continue
+		}
+		if check.Err != nil {
+			fmt.Fprintf(os.Stdout, "FAIL  %s: %v\n", check.Field, check.Err)
+			valid = false
+			continue
+		}
+		fmt.Fprintf(os.Stdout, "PASS  %s\n", check.Field)
+	}
+	// Model lists go stale, so an unknown model only warns.
+	if err := config.ValidateModel(stored.Provider, stored.Model); err != nil {
+		fmt.Fprintf(os.Stdout, "WARN  model: %v\n", err)
+	}
+
+	if !valid {
+		fmt.Fprintln(os.Stdout, "Config is invalid.")
+		return 1
+	}
+	fmt.Fprintln(os.Stdout, "Config is valid.")
 	return 0
 }
 
 func runSuggest(args []string) int {
-	jsonOut := false
+	output := ""
+	templateArg := ""
+	count := 0
+	doCommit := false
+	assumeYes := false
+	dryRun := false
+	retryEmpty := false
+	examplesFile := ""
+	index := 0
+	maxFiles := -1
+	includeUnstaged := false
+	addAll := false
+	fixupRef := ""
+	var paths []string
+	noRedact := false
+	noVerify := false
+	noCache := false
 	for i := 0; i < len(args); i++ {
 		switch args[i] {
 		case "-h", "--help":
-			fmt.Fprintln(os.Stdout, "Usage: commit-coach suggest [--json]")
+			fmt.Fprintln(os.Stdout, "Usage: commit-coach suggest [--json | --output FORMAT [--template T]] [--count N] [--max-files N] [--retry-empty] [--include-unstaged | --add-all] [--path P]... [--no-redact] [--no-cache] [--prompt-examples FILE] [--dry-run] [--commit [--index N] [--yes] [--no-verify]] [--fixup REF]")
+			fmt.Fprintln(os.Stdout, "")
+			fmt.Fprintln(os.Stdout, "--output picks the format: plain (default), json (same as --json), conventional (messages separated by --- lines)")
+			fmt.Fprintln(os.Stdout, "  or template, which renders --template T (a Go text/template string or file) per suggestion with .Type .Subject .Body .Footer.")
+			fmt.Fprintln(os.Stdout, "--dry-run alone shows what would be sent to the provider (size, files, redactions, cache key) without calling it.")
+			fmt.Fprintln(os.Stdout, "--commit commits suggestion 1 (or --index N) without the TUI; with --dry-run it prints the message instead.")
+			fmt.Fprintln(os.Stdout, "--yes skips the confirmation when committing on a detached HEAD or with --no-verify.")
+			fmt.Fprintln(os.Stdout, "--no-verify passes --no-verify to git commit, skipping the pre-commit and commit-msg hooks (asks first).")
+			fmt.Fprintln(os.Stdout, "--retry-empty regenerates once if the model returns empty output.")
+			fmt.Fprintln(os.Stdout, "--include-unstaged describes the working tree when nothing is staged.")
+			fmt.Fprintln(os.Stdout, "--add-all runs git add -A first when nothing is staged (never when something already is, nor with --dry-run).")
+			fmt.Fprintln(os.Stdout, "--no-cache asks the provider even when a cached answer exists.")
+			fmt.Fprintln(os.Stdout, "--no-redact sends the diff without secret redaction (for debugging false positives; secrets may be sent).")
+			fmt.Fprintln(os.Stdout, "--path P limits the suggestion to staged changes under P (repeatable); each path must have staged changes.")
+			fmt.Fprintln(os.Stdout, "  With --commit only those paths are committed (git commit -- P); other staged changes stay staged.")
+			fmt.Fprintln(os.Stdout, "--fixup REF commits the staged changes as \"fixup! <subject of REF>\" for git rebase --autosquash; no provider is called.")
+			fmt.Fprintln(os.Stdout, "--prompt-examples FILE shows the model example messages (separated by --- lines).")
 			return 0
 		case "--json":
-			jsonOut = true
+			output = outputJSON
+		case "--output", "-o":
+			i++
+			if i >= len(args) {
+				fmt.Fprintf(os.Stderr, "%s requires a value\n", args[i-1])
+				return 2
+			}
+			output = args[i]
+		case "--template":
+			i++
+			if i >= len(args) {
+				fmt.Fprintln(os.Stderr, "--template requires a value")
+				return 2
+			}
+			templateArg = args[i]
+		case "--commit":
+			doCommit = true
+		case "-y", "--yes":
+			assumeYes = true
+		case "--no-verify":
+			noVerify = true
+		case "--retry-empty":
+			retryEmpty = true
+		case "--no-redact":
+			noRedact = true
+		case "--no-cache":
+			noCache = true
+		case "--include-unstaged":
+			includeUnstaged = true
+		case "--add-all":
+			addAll = true
+		case "--prompt-examples":
+			i++
+			if i >= len(args) {
+				fmt.Fprintln(os.Stderr, "--prompt-examples requires a value")
+				return 2
+			}
+			examplesFile = args[i]
+		case "--dry-run":
+			dryRun = true
+		case "--path":
+			i++
+			if i >= len(args) {
+				fmt.Fprintln(os.Stderr, "--path requires a value")
+				return 2
+			}
+			paths = append(paths, args[i])
+		case "--fixup":
+			i++
+			if i >= len(args) {
+				fmt.Fprintln(os.Stderr, "--fixup requires a value")
+				return 2
+			}
+			fixupRef = args[i]
+		case "--index":
+			i++
+			if i >= len(args) {
+				fmt.Fprintln(os.Stderr, "--index requires a value")
+				return 2
+			}
+			n, err := strconv.Atoi(args[i])
+			if err != nil || n < 1 {
+				fmt.Fprintf(os.Stderr, "Invalid --index: %s (expected a positive number)\n", args[i])
+				return 2
+			}
+			index = n
+		case "--max-files":
+			i++
+			if i >= len(args) {
+				fmt.Fprintln(os.Stderr, "--max-files requires a value")
+				return 2
+			}
+			n, err := strconv.Atoi(args[i])
+			if err != nil || n < 0 {
+				fmt.Fprintf(os.Stderr, "Invalid --max-files: %s (expected 0 or more; 0 disables)\n", args[i])
+				return 2
+			}
+			maxFiles = n
+		case "--count":
+			i++
+			if i >= len(args) {
+				fmt.Fprintln(os.Stderr, "--count requires a value")
+				return 2
+			}
+			n, err := strconv.Atoi(args[i])
+			if err != nil || n < app.MinSuggestionCount || n > app.MaxSuggestionCount {
+				fmt.Fprintf(os.Stderr, "Invalid --count: %s (expected %d-%d)\n", args[i], app.MinSuggestionCount, app.MaxSuggestionCount)
+				return 2
+			}
+			count = n
 		default:
 			fmt.Fprintf(os.Stderr, "Unknown suggest flag/arg: %s\n", args[i])
 			return 2
 		}
 	}
+	if index > 0 && !doCommit {
+		fmt.Fprintln(os.Stderr, "--index requires --commit")
+		return 2
+	}
+	if output == "" && templateArg != "" {
+		output = outputTemplate
+	}
+	if output == "" {
+		output = outputPlain
+	}
+	tmpl, err := parseOutputFormat(output, templateArg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 2
+	}
+	jsonOut := output == outputJSON
+	if dryRun && !doCommit && output != outputPlain && !jsonOut {
+		fmt.Fprintf(os.Stderr, "--dry-run cannot be combined with --output %s\n", output)
+		return 2
+	}
+	if noVerify && !doCommit {
+		fmt.Fprintln(os.Stderr, "--no-verify requires --commit")
+		return 2
+	}
+	if doCommit && output != outputPlain {
+		fmt.Fprintln(os.Stderr, "--commit cannot be combined with --json or --output")
+		return 2
+	}
+	if fixupRef != "" {
+		if output != outputPlain || index > 0 {
+			fmt.Fprintln(os.Stderr, "--fixup cannot be combined with --json, --output or --index")
+			return 2
+		}
+		// Fixups need no provider, so they work before setup too: the config
+		// is only read for git settings such as signing.
+		path, _ := config.DefaultConfigPath()
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		return runFixup(ctx, wiring.NewCommitService(config.Resolve(path)), fixupRef, dryRun, os.Stdout, os.Stderr)
+	}
 
-	cfg, err := config.Load()
+	cfg, err := loadConfig(os.Stderr)
 	if err != nil {
 		if config.IsSetupRequired(err) {
 			fmt.Fprintln(os.Stderr, "Setup required. Run: commit-coach setup")
@@ -404,27 +981,145 @@ func runSuggest(args []string) int {
 		fmt.Fprintf(os.Stderr, "Configuration error: %v\n", err)
 		return 1
 	}
+	if count > 0 {
+		cfg.SuggestCount = count
+	}
+	if noCache {
+		cfg.UseCache = false
+	}
+	if retryEmpty {
+		cfg.RetryEmpty = true
+	}
+	if maxFiles >= 0 {
+		cfg.MaxFiles = maxFiles
+	}
+	if noRedact {
+		cfg.Redact = false
+	}
+	if examplesFile != "" {
+		cfg.FewShotExamplesFile = examplesFile
+	}
 
-	gitAdapter := git.NewExecutor()
-	cacheAdapter := cache.NewInMemory()
-	llmAdapter, err := llm.NewFromConfig(cfg.Provider, cfg.APIKey, cfg.BaseURL, cfg.OllamaURL, cfg.Model)
+	application, err := wiring.NewApp(cfg, wiring.Options{FailureNotes: newFailureNotes()})
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to initialize LLM provider: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Configuration error: %v\n", err)
 		return 1
 	}
-	application := app.NewApp(llmAdapter, gitAdapter, cacheAdapter, cfg.DiffCap, cfg.UseCache)
+	defer application.Close()
+	application.Suggest.SetIncludeUnstaged(includeUnstaged)
+	application.Suggest.SetPaths(paths)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.RequestTimeoutDuration()+commandSlack)
 	defer cancel()
 
-	suggestions, err := application.Suggest.SuggestCommits(ctx, cfg.Provider, cfg.Model, cfg.Temperature)
+	if addAll && !dryRun {
+		staged, err := application.Commit.StageAllIfNothingStaged(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return 1
+		}
+		if staged {
+			fmt.Fprintln(os.Stderr, "Nothing was staged; staged all changes (git add -A).")
+		}
+	}
+
+	if dryRun && !doCommit {
+		prepared, err := application.Suggest.PrepareDiff(ctx, cfg.Provider, cfg.Model, cfg.Temperature)
+		if err != nil {
+			if errors.Is(err, app.ErrNoStagedChanges) {
+				fmt.Fprintln(os.Stderr, noChangesMessage(includeUnstaged))
+				return 1
+			}
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return 1
+		}
+		if jsonOut {
+			b, err := json.MarshalIndent(prepared, "", "  ")
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to encode JSON: %v\n", err)
+				return 1
+			}
+			fmt.Fprintln(os.Stdout, string(b))
+			return 0
+		}
+		writePreparedDiff(os.Stdout, cfg.Provider, prepared)
+		return 0
+	}
+
+	// Ask before paying for a generation that would be committed off-branch.
+	if doCommit && !dryRun && !assumeYes && application.Commit.DetachedHead(ctx) {
+		if !confirm(os.Stdin, os.Stderr, app.DetachedHeadWarning+" Continue? [y/N] ") {
+			fmt.Fprintln(os.Stderr, "Commit cancelled (use --yes to skip this check).")
+			return 1
+		}
+	}
+
+	if noVerify && !dryRun && !assumeYes {
+		if !confirm(os.Stdin, os.Stderr, "Skip the pre-commit and commit-msg hooks for this commit? [y/N] ") {
+			fmt.Fprintln(os.Stderr, "Commit cancelled (use --yes to skip this check).")
+			return 1
+		}
+	}
+
+	result, err := application.Suggest.SuggestCommitsDetailed(ctx, cfg.Provider, cfg.Model, cfg.Temperature)
 	if err != nil {
+		if errors.Is(err, app.ErrNoStagedChanges) {
+			fmt.Fprintln(os.Stderr, noChangesMessage(includeUnstaged))
+			return 1
+		}
 		fmt.Fprintf(os.Stderr, "%v\n", err)
 		return 1
 	}
+	suggestions := result.Suggestions
+	if !jsonOut {
+		if result.Unstaged {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", app.UnstagedWarning)
+		}
+		if result.Redacted {
+			fmt.Fprintln(os.Stderr, "Warning: secrets were detected in the staged diff and redacted before sending")
+		}
+		if note := result.TruncationNote(); note != "" {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", note)
+		}
+		if note := result.RepoStateNote(); note != "" {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", note)
+		}
+		for _, w := range result.Warnings {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+		}
+	} else if !cfg.Redact {
+		// JSON consumers still get it on stderr; this one must not go unseen.
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", app.RedactionDisabledWarning)
+	}
+
+	if doCommit {
+		if index == 0 {
+			index = 1
+		}
+		if result.Unstaged && !dryRun {
+			fmt.Fprintln(os.Stderr, "Nothing is staged, so there is nothing to commit. Stage the changes with git add and run again.")
+			return 1
+		}
+		if index > len(suggestions) {
+			fmt.Fprintf(os.Stderr, "--index %d out of range (got %d suggestions)\n", index, len(suggestions))
+			return 1
+		}
+		// Not the suggestion deadline: the commit runs the hooks and is
+		// bounded by GitCommitTimeout instead.
+		hash, err := application.Commit.Commit(context.Background(), suggestions[index-1].Format(), ports.CommitOptions{DryRun: dryRun, NoVerify: noVerify, Paths: paths})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return 1
+		}
+		fmt.Fprintln(os.Stdout, hash)
+		return 0
+	}
 
 	if jsonOut {
-		b, err := json.MarshalIndent(suggestions, "", "  ")
+		b, err := json.MarshalIndent(struct {
+			*app.SuggestResult
+			Stats observability.RunStats `json:"stats"`
+		}{result, observability.Stats()}, "", "  ")
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Failed to encode JSON: %v\n", err)
 			return 1
@@ -433,15 +1128,528 @@ func runSuggest(args []string) int {
 		return 0
 	}
 
-	for i, s := range suggestions {
-		fmt.Fprintf(os.Stdout, "%d) %s: %s\n", i+1, s.Type, s.Subject)
-		if strings.TrimSpace(s.Body) != "" {
-			fmt.Fprintf(os.Stdout, "\n%s\n", strings.TrimSpace(s.Body))
+	if err := writeSuggestions(os.Stdout, output, tmpl, suggestions); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to render suggestions: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// suggest --output formats.
+const (
+	outputPlain        = "plain"
+	outputJSON         = "json"
+	outputConventional = "conventional"
+	outputTemplate     = "template"
+)
+
+// parseOutputFormat validates a suggest --output format and, for the
+// template format, parses templateArg: the path of a template file, or else
+// the template text itself.
+func parseOutputFormat(format, templateArg string) (*template.Template, error) {
+	switch format {
+	case outputPlain, outputJSON, outputConventional:
+		if templateArg != "" {
+			return nil, fmt.Errorf("--template requires --output template")
+		}
+		return nil, nil
+	case outputTemplate:
+	default:
+		return nil, fmt.Errorf("unknown --output format %q (want plain, json, conventional or template)", format)
+	}
+	if templateArg == "" {
+		return nil, fmt.Errorf("--output template requires --template")
+	}
+	text := templateArg
+	if info, err := os.Stat(templateArg); err == nil && !info.IsDir() {
+		b, err := os.ReadFile(templateArg)
+		if err != nil {
+			return nil, fmt.Errorf("read template: %w", err)
+		}
+		text = string(b)
+	}
+	tmpl, err := template.New("suggestion").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// writeSuggestions prints suggestions in a non-JSON suggest --output format.
+// Template output gets a newline after each suggestion unless the template
+// already ends with one.
+func writeSuggestions(w io.Writer, format string, tmpl *template.Template, suggestions []domain.Suggestion) error {
+	switch format {
+	case outputConventional:
+		for i, s := range suggestions {
+			if i > 0 {
+				fmt.Fprintln(w, "---")
+			}
+			fmt.Fprintln(w, s.Format())
 		}
-		if strings.TrimSpace(s.Footer) != "" {
-			fmt.Fprintf(os.Stdout, "\n%s\n", strings.TrimSpace(s.Footer))
+	case outputTemplate:
+		for _, s := range suggestions {
+			var b strings.Builder
+			if err := tmpl.Execute(&b, s); err != nil {
+				return err
+			}
+			out := b.String()
+			if !strings.HasSuffix(out, "\n") {
+				out += "\n"
+			}
+			fmt.Fprint(w, out)
+		}
+	default:
+		for i, s := range suggestions {
+			fmt.Fprintf(w, "%d) %s: %s\n", i+1, s.Type, s.Subject)
+			if strings.TrimSpace(s.Body) != "" {
+				fmt.Fprintf(w, "\n%s\n", strings.TrimSpace(s.Body))
+			}
+			if strings.TrimSpace(s.Footer) != "" {
+				fmt.Fprintf(w, "\n%s\n", strings.TrimSpace(s.Footer))
+			}
+			fmt.Fprintln(w, "")
 		}
-		fmt.Fprintln(os.Stdout, "")
 	}
+	return nil
+}
+
+// confirm writes prompt to w and reports whether the answer read from r is
+// yes. EOF or a read error counts as no.
+func confirm(r io.Reader, w io.Writer, prompt string) bool {
+	fmt.Fprint(w, prompt)
+	answer, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && answer == "" {
+		fmt.Fprintln(w)
+		return false
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
+
+// runFixup commits the staged changes as a fixup of ref, or prints the
+// message with dryRun.
+func runFixup(ctx context.Context, commit *app.CommitService, ref string, dryRun bool, stdout, stderr io.Writer) int {
+	msg, err := commit.FixupMessage(ctx, ref)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return 1
+	}
+	if dryRun {
+		fmt.Fprintln(stdout, msg)
+		return 0
+	}
+	hash, err := commit.Commit(ctx, msg, ports.CommitOptions{})
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return 1
+	}
+	fmt.Fprintln(stdout, hash)
 	return 0
 }
+
+// noChangesMessage explains an empty diff, pointing at --include-unstaged
+// when it wasn't used.
+func noChangesMessage(includeUnstaged bool) string {
+	if includeUnstaged {
+		return "No staged or unstaged changes."
+	}
+	return "No staged changes. Stage files with git add first (or pass --add-all or --include-unstaged)."
+}
+
+// writePreparedDiff prints the suggest --dry-run preview.
+func writePreparedDiff(w io.Writer, provider string, p *app.PreparedDiff) {
+	fmt.Fprintf(w, "Dry run: nothing was sent to %s.\n\n", provider)
+	fmt.Fprintf(w, "Cache key:  %s\n", p.CacheKey)
+	if p.Unstaged {
+		fmt.Fprintln(w, "Diff:       working tree (nothing staged)")
+	}
+	size := fmt.Sprintf("%d bytes", p.Bytes)
+	if p.Truncated {
+		size += fmt.Sprintf(" (truncated from %d staged bytes)", p.StagedBytes)
+	}
+	fmt.Fprintf(w, "Size:       %s\n", size)
+	if p.RedactionOff {
+		fmt.Fprintln(w, "Redactions: OFF (the raw diff would be sent)")
+	} else if p.Redactions > 0 {
+		fmt.Fprintf(w, "Redactions: %d secret(s) replaced with [REDACTED]\n", p.Redactions)
+	} else {
+		fmt.Fprintln(w, "Redactions: none")
+	}
+	if p.Summarized {
+		fmt.Fprintf(w, "Summarized: %d files changed; sending a stat summary plus the largest files\n", p.TotalFiles)
+	}
+	if p.BinaryOmitted > 0 {
+		fmt.Fprintf(w, "Binary:     %d file(s) omitted\n", p.BinaryOmitted)
+	}
+	if len(p.Excluded) > 0 {
+		fmt.Fprintf(w, "Excluded:   %d file(s) by %s\n", len(p.Excluded), config.IgnoreFileName)
+	}
+	fmt.Fprintf(w, "Files:      %d\n", len(p.Files))
+	for _, f := range p.Files {
+		fmt.Fprintf(w, "  %s\n", f)
+	}
+}
+
+// newFailureNotes returns the on-disk failure notes store, or nil when the
+// user config dir is unavailable (the notes are only hints).
+func newFailureNotes() ports.FailureNotes {
+	path, err := config.DefaultFailureNotesPath()
+	if err != nil {
+		return nil
+	}
+	return notes.NewFailures(path)
+}
+
+func runLint(args []string) int {
+	// lint [--message <m> | --file <path> | --ref <ref>]; defaults to --ref HEAD.
+	var message, file, ref string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-h", "--help":
+			fmt.Fprintln(os.Stdout, "Usage: commit-coach lint [--message M | --file PATH | --ref REF]")
+			fmt.Fprintln(os.Stdout, "")
+			fmt.Fprintln(os.Stdout, "Checks a commit message against the Conventional Commit rules.")
+			fmt.Fprintln(os.Stdout, "Defaults to the latest commit (--ref HEAD). Use --file .git/COMMIT_EDITMSG in hooks.")
+			return 0
+		case "--message", "-m":
+			i++
+			if i >= len(args) {
+				fmt.Fprintf(os.Stderr, "%s requires a value\n", args[i-1])
+				return 2
+			}
+			message = args[i]
+		case "--file":
+			i++
+			if i >= len(args) {
+				fmt.Fprintln(os.Stderr, "--file requires a value")
+				return 2
+			}
+			file = args[i]
+		case "--ref":
+			i++
+			if i >= len(args) {
+				fmt.Fprintln(os.Stderr, "--ref requires a value")
+				return 2
+			}
+			ref = args[i]
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown lint flag/arg: %s\n", args[i])
+			return 2
+		}
+	}
+
+	switch {
+	case message != "":
+	case file != "":
+		b, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to read message file: %v\n", err)
+			return 1
+		}
+		message = stripCommentLines(string(b))
+	default:
+		if ref == "" {
+			ref = "HEAD"
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		msg, err := git.NewExecutor(git.DefaultTimeout).CommitMessage(ctx, ref)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return 1
+		}
+		message = msg
+	}
+
+	// Lint needs no provider, so resolve the config without validating it;
+	// only the team's subject limits matter here.
+	path, _ := config.DefaultConfigPath()
+	limits := config.Resolve(path).SubjectLimits()
+	violations := domain.ParseMessage(message).LintWith(limits)
+	if len(violations) == 0 {
+		fmt.Fprintln(os.Stdout, "PASS")
+		return 0
+	}
+
+	fmt.Fprintln(os.Stdout, "FAIL")
+	for _, v := range violations {
+		fmt.Fprintf(os.Stdout, "  - %v\n", v)
+	}
+	return 1
+}
+
+// stripCommentLines drops git's "#" comment lines from a COMMIT_EDITMSG file.
+func stripCommentLines(text string) string {
+	var kept []string
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\n")
+}
+
+// hookScript is the prepare-commit-msg hook written by "hook install".
+const hookScript = `#!/bin/sh
+# Installed by commit-coach. Suggests a message when git opens an empty one.
+exec commit-coach hook prepare-commit-msg "$@"
+`
+
+func runHook(args []string) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" {
+		fmt.Fprintln(os.Stdout, "Usage: commit-coach hook install")
+		fmt.Fprintln(os.Stdout, "       commit-coach hook prepare-commit-msg [--type T] FILE [SOURCE]")
+		return 0
+	}
+
+	switch args[0] {
+	case "install":
+		if len(args) > 1 {
+			fmt.Fprintf(os.Stderr, "Unknown hook install flag/arg: %s\n", args[1])
+			return 2
+		}
+		return installHook()
+	case "prepare-commit-msg":
+		return runPrepareCommitMsg(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown hook subcommand: %s\n", args[0])
+		return 2
+	}
+}
+
+// installHook writes hookScript into the repository's hooks directory,
+// moving any existing, foreign prepare-commit-msg hook aside to .bak.
+func installHook() int {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	dir, err := git.NewExecutor(git.DefaultTimeout).HooksDir(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create hooks dir: %v\n", err)
+		return 1
+	}
+	path := filepath.Join(dir, "prepare-commit-msg")
+
+	if existing, err := os.ReadFile(path); err == nil {
+		if string(existing) == hookScript {
+			fmt.Fprintf(os.Stdout, "Hook already installed: %s\n", path)
+			return 0
+		}
+		backup := path + ".bak"
+		if _, err := os.Stat(backup); err == nil {
+			fmt.Fprintf(os.Stderr, "Existing hook and backup found; move %s away first\n", backup)
+			return 1
+		}
+		if err := os.Rename(path, backup); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to back up existing hook: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(os.Stdout, "Backed up existing hook to %s\n", backup)
+	}
+
+	if err := os.WriteFile(path, []byte(hookScript), 0o755); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write hook: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stdout, "Installed hook: %s\n", path)
+	return 0
+}
+
+// runPrepareCommitMsg fills an empty commit message file with one suggestion.
+// Failures are reported but never block the commit.
+func runPrepareCommitMsg(args []string) int {
+	var file, source, commitType string
+	var positional []string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--type":
+			i++
+			if i >= len(args) {
+				fmt.Fprintln(os.Stderr, "--type requires a value")
+				return 2
+			}
+			commitType = args[i]
+		default:
+			if strings.HasPrefix(args[i], "-") {
+				fmt.Fprintf(os.Stderr, "Unknown hook flag/arg: %s\n", args[i])
+				return 2
+			}
+			positional = append(positional, args[i])
+		}
+	}
+	if len(positional) == 0 || len(positional) > 3 {
+		fmt.Fprintln(os.Stderr, "Usage: commit-coach hook prepare-commit-msg [--type T] FILE [SOURCE [SHA]]")
+		return 2
+	}
+	file = positional[0]
+	if len(positional) > 1 {
+		source = positional[1]
+	}
+	if commitType != "" && !slices.Contains(domain.ValidCommitTypes, commitType) {
+		fmt.Fprintf(os.Stderr, "Invalid --type: %s (must be one of: %v)\n", commitType, domain.ValidCommitTypes)
+		return 2
+	}
+
+	// -m/-F, merges, squashes and amends (source "commit") already carry a message.
+	switch source {
+	case "message", "merge", "squash", "commit":
+		return 0
+	}
+
+	existing, err := os.ReadFile(file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "commit-coach: %v\n", err)
+		return 0
+	}
+	if strings.TrimSpace(stripCommentLines(string(existing))) != "" {
+		return 0
+	}
+
+	cfg, err := loadConfig(os.Stderr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "commit-coach: %v (leaving message unchanged)\n", err)
+		return 0
+	}
+	application, err := wiring.NewApp(cfg, wiring.Options{FailureNotes: newFailureNotes()})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "commit-coach: %v (leaving message unchanged)\n", err)
+		return 0
+	}
+	defer application.Close()
+	application.Suggest.SetCount(1)
+	application.Suggest.SetCommitType(commitType)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.RequestTimeoutDuration()+commandSlack)
+	defer cancel()
+
+	suggestions, err := application.Suggest.SuggestCommits(ctx, cfg.Provider, cfg.Model, cfg.Temperature)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "commit-coach: %v (leaving message unchanged)\n", err)
+		return 0
+	}
+	s := suggestions[0]
+	// The prompt asked for commitType; a model that ignored it still gets it.
+	if commitType != "" {
+		s.Type = commitType
+	}
+
+	// Keep git's comment block below the suggestion.
+	message := s.Format() + "\n"
+	if len(existing) > 0 {
+		message += "\n" + strings.TrimLeft(string(existing), "\n")
+	}
+	if err := os.WriteFile(file, []byte(message), 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "commit-coach: %v\n", err)
+	}
+	return 0
+}
+
+func runStatus(args []string) int {
+	for _, a := range args {
+		switch a {
+		case "-h", "--help":
+			fmt.Fprintln(os.Stdout, "Usage: commit-coach status")
+			fmt.Fprintln(os.Stdout, "")
+			fmt.Fprintln(os.Stdout, "Summarizes config, API key presence, staged changes, cache and the last log entry without network calls.")
+			return 0
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown status flag/arg: %s\n", a)
+			return 2
+		}
+	}
+
+	path, _ := config.DefaultConfigPath()
+	cfg := config.Resolve(path)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	writeStatus(ctx, os.Stdout, cfg, path, wiring.Git(cfg), cache.NewInMemory(), observability.Path())
+	return 0
+}
+
+// writeStatus prints the one-screen readiness summary used by "status".
+// The suggestion cache lives in each process, so c is normally this
+// process's empty cache.
+func writeStatus(ctx context.Context, w io.Writer, cfg *config.Config, cfgPath string, g ports.Git, c *cache.InMemory, logPath string) {
+	fmt.Fprintf(w, "Provider:  %s (%s)\n", cfg.Provider, cfg.Model)
+
+	switch envVar := config.APIKeyEnvVar(cfg.Provider); {
+	case envVar == "":
+		fmt.Fprintln(w, "API key:   not required")
+	case cfg.APIKey != "":
+		fmt.Fprintln(w, "API key:   set")
+	default:
+		fmt.Fprintf(w, "API key:   missing (set %s or run: commit-coach setup)\n", envVar)
+	}
+
+	switch _, err := os.Stat(cfgPath); {
+	case cfgPath == "":
+		fmt.Fprintln(w, "Config:    (no config dir)")
+	case err == nil:
+		fmt.Fprintf(w, "Config:    %s\n", cfgPath)
+	default:
+		fmt.Fprintf(w, "Config:    %s (not created)\n", cfgPath)
+	}
+
+	if inRepo, _ := g.IsInRepository(ctx); !inRepo {
+		fmt.Fprintln(w, "Repo:      not a git repository")
+	} else if diff, err := g.StagedDiff(ctx); err != nil {
+		fmt.Fprintf(w, "Repo:      error reading staged diff: %v\n", err)
+	} else if files := app.DiffStat(diff); len(files) == 0 {
+		fmt.Fprintln(w, "Repo:      no staged changes")
+	} else {
+		fmt.Fprintf(w, "Repo:      %d staged file(s)\n", len(files))
+	}
+
+	cacheState := "enabled"
+	if !cfg.UseCache {
+		cacheState = "disabled"
+	}
+	fmt.Fprintf(w, "Cache:     in-memory, %s, %d entries (per process, not kept between runs)\n", cacheState, c.Size())
+
+	if logPath == "" {
+		fmt.Fprintln(w, "Log:       (not initialized)")
+	} else if info, err := os.Stat(logPath); err == nil && info.Size() > 0 {
+		fmt.Fprintf(w, "Log:       %s (last written %s)\n", logPath, info.ModTime().Format("2006-01-02 15:04"))
+		if entry := lastLogEntry(logPath); entry != "" {
+			fmt.Fprintf(w, "Last log:  %s\n", observability.Snip(entry, statusLogEntryRunes))
+		}
+	} else {
+		fmt.Fprintf(w, "Log:       %s\n", logPath)
+	}
+}
+
+// statusLogEntryRunes caps the last log entry shown by "status".
+const statusLogEntryRunes = 160
+
+// lastLogEntry returns the last non-blank line of the log at path, reading
+// only its tail. It returns "" when the log can't be read.
+func lastLogEntry(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	const tail = 64 << 10
+	if info, err := f.Stat(); err == nil && info.Size() > tail {
+		if _, err := f.Seek(-tail, io.SeekEnd); err != nil {
+			return ""
+		}
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return ""
+	}
+	lines := strings.Split(strings.TrimRight(string(data), "\r\n"), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
@@ -0,0 +1,556 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chuckie/commit-coach/internal/adapters/cache"
+	"github.com/chuckie/commit-coach/internal/app"
+	"github.com/chuckie/commit-coach/internal/config"
+	"github.com/chuckie/commit-coach/internal/ports"
+	"github.com/chuckie/commit-coach/internal/testutil"
+)
+
+// captureStdout runs fn and returns what it wrote to os.Stdout.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("pipe: %v", err)
+	}
+	prev := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = prev }()
+
+	done := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(r)
+		done <- string(b)
+	}()
+
+	fn()
+	w.Close()
+	return <-done
+}
+
+func TestValidateConfigMissingFile(t *testing.T) {
+	var code int
+	out := captureStdout(t, func() {
+		code = validateConfig(filepath.Join(t.TempDir(), "config.json"))
+	})
+	if code != 0 || !strings.Contains(out, "nothing to validate") {
+		t.Errorf("validateConfig() = %d, output:\n%s\nwant 0 and nothing to validate", code, out)
+	}
+}
+
+func TestValidateConfigIgnoresEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"Provider": "groq", "Model": "llama-3.3-70b-versatile", "Temperature": 3}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// A valid env override must not hide the bad stored temperature.
+	t.Setenv("LLM_TEMPERATURE", "0.5")
+	t.Setenv("GROQ_API_KEY", "")
+
+	var code int
+	out := captureStdout(t, func() {
+		code = validateConfig(path)
+	})
+	if code != 1 {
+		t.Errorf("validateConfig() = %d, want 1 for the stored temperature", code)
+	}
+	for _, want := range []string{"FAIL  temperature", "SKIP  api-key: not stored in the file (read from GROQ_API_KEY)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRunLintCompliant(t *testing.T) {
+	var code int
+	out := captureStdout(t, func() {
+		code = runLint([]string{"--message", "feat: add commit linter\n\nReuses the domain rules.\n\nRefs: #42"})
+	})
+
+	if code != 0 {
+		t.Errorf("exit code = %d, want 0; output:\n%s", code, out)
+	}
+	if !strings.Contains(out, "PASS") {
+		t.Errorf("output = %q, want PASS", out)
+	}
+}
+
+func TestRunLintNonCompliant(t *testing.T) {
+	var code int
+	out := captureStdout(t, func() {
+		code = runLint([]string{"--message", "Added stuff"})
+	})
+
+	if code != 1 {
+		t.Errorf("exit code = %d, want 1", code)
+	}
+	if !strings.Contains(out, "FAIL") || !strings.Contains(out, "type is required") {
+		t.Errorf("output = %q, want FAIL with type violation", out)
+	}
+}
+
+func TestRunLintFileStripsComments(t *testing.T) {
+	path := t.TempDir() + "/COMMIT_EDITMSG"
+	content := "fix: handle empty diff\n# Please enter the commit message for your changes.\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var code int
+	out := captureStdout(t, func() {
+		code = runLint([]string{"--file", path})
+	})
+	if code != 0 {
+		t.Errorf("exit code = %d, want 0; output:\n%s", code, out)
+	}
+}
+
+func TestRunLintUnknownFlag(t *testing.T) {
+	if code := runLint([]string{"--bogus"}); code != 2 {
+		t.Errorf("exit code = %d, want 2", code)
+	}
+}
+
+// isolateConfig points config loading at an empty config dir with no API key.
+func isolateConfig(t *testing.T) {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("LLM_PROVIDER", "openai")
+	t.Setenv("OPENAI_API_KEY", "")
+}
+
+func TestLoadConfigFallsBackToMock(t *testing.T) {
+	isolateConfig(t)
+	t.Setenv("COMMIT_COACH_FALLBACK_MOCK", "1")
+
+	var notice strings.Builder
+	cfg, err := loadConfig(&notice)
+	if err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+	if cfg.Provider != "mock" {
+		t.Errorf("Provider = %q, want mock", cfg.Provider)
+	}
+	if !strings.Contains(notice.String(), "using the mock provider") {
+		t.Errorf("notice = %q, want mock fallback notice", notice.String())
+	}
+}
+
+func TestLoadConfigWithoutFallbackRequiresSetup(t *testing.T) {
+	isolateConfig(t)
+	t.Setenv("COMMIT_COACH_FALLBACK_MOCK", "")
+
+	var notice strings.Builder
+	if _, err := loadConfig(&notice); !config.IsSetupRequired(err) {
+		t.Errorf("loadConfig() error = %v, want setup required", err)
+	}
+	if notice.Len() != 0 {
+		t.Errorf("notice = %q, want none", notice.String())
+	}
+}
+
+// initMockRepo creates a temp git repo with one staged file and configures the
+// mock provider, so suggest runs end to end without network access.
+func initMockRepo(t *testing.T, stage bool) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(dir, ".gitconfig-global"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("LLM_PROVIDER", "mock")
+	t.Setenv("LLM_MODEL", "mock")
+
+	prev, _ := os.Getwd()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(prev) })
+
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"config", "user.name", "Test User"},
+		{"config", "user.email", "test@example.com"},
+	} {
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	if !stage {
+		return
+	}
+	if err := os.WriteFile("hello.txt", []byte("hello\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if out, err := exec.Command("git", "add", "hello.txt").CombinedOutput(); err != nil {
+		t.Fatalf("git add: %v\n%s", err, out)
+	}
+}
+
+func TestRunSuggestCommitDryRun(t *testing.T) {
+	initMockRepo(t, true)
+
+	var code int
+	out := captureStdout(t, func() {
+		code = runSuggest([]string{"--commit", "--index", "2", "--dry-run"})
+	})
+
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0; output:\n%s", code, out)
+	}
+	if !strings.Contains(out, "[DRY RUN] Would commit:") {
+		t.Errorf("output = %q, want dry-run message", out)
+	}
+	if err := exec.Command("git", "rev-parse", "--verify", "-q", "HEAD").Run(); err == nil {
+		t.Error("dry run created a commit")
+	}
+}
+
+func TestRunSuggestJSONIncludesStats(t *testing.T) {
+	initMockRepo(t, true)
+
+	var code int
+	out := captureStdout(t, func() {
+		code = runSuggest([]string{"--json"})
+	})
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0; output:\n%s", code, out)
+	}
+	var got struct {
+		Suggestions []json.RawMessage `json:"suggestions"`
+		Stats       *struct {
+			Calls    int64 `json:"calls"`
+			Failures int64 `json:"failures"`
+		} `json:"stats"`
+	}
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, out)
+	}
+	if len(got.Suggestions) == 0 || got.Stats == nil {
+		t.Errorf("output missing suggestions or stats:\n%s", out)
+	}
+	var fields map[string]json.RawMessage
+	_ = json.Unmarshal([]byte(out), &fields)
+	for _, key := range []string{"truncated", "redacted", "unstaged", "original_bytes", "sent_bytes", "duration_ms"} {
+		if _, ok := fields[key]; !ok {
+			t.Errorf("output missing result field %q:\n%s", key, out)
+		}
+	}
+}
+
+func TestRunSuggestOutputFormats(t *testing.T) {
+	initMockRepo(t, true)
+
+	var code int
+	out := captureStdout(t, func() {
+		code = runSuggest([]string{"--output", "conventional", "--count", "2"})
+	})
+	if code != 0 || strings.Count(out, "\n---\n") != 1 || strings.Contains(out, "1) ") {
+		t.Errorf("conventional output (exit %d):\n%s", code, out)
+	}
+
+	tmplFile := filepath.Join(t.TempDir(), "line.tmpl")
+	if err := os.WriteFile(tmplFile, []byte("{{.Type}}|{{.Subject}}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"--output", "template", "--template", tmplFile},
+		{"--template", "{{.Type}}|{{.Subject}}"},
+	} {
+		out = captureStdout(t, func() {
+			code = runSuggest(append(args, "--count", "2"))
+		})
+		lines := strings.Split(strings.TrimSpace(out), "\n")
+		if code != 0 || len(lines) != 2 || strings.Count(lines[0], "|") != 1 {
+			t.Errorf("template output for %q (exit %d):\n%s", args, code, out)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"--output", "yaml"},
+		{"--output", "template"},
+		{"--output", "json", "--template", "x"},
+		{"--output", "template", "--template", "{{.Nope"},
+	} {
+		captureStdout(t, func() {
+			code = runSuggest(args)
+		})
+		if code != 2 {
+			t.Errorf("runSuggest(%q) exit code = %d, want 2", args, code)
+		}
+	}
+}
+
+func TestRunSuggestDryRunPreview(t *testing.T) {
+	initMockRepo(t, true)
+
+	var code int
+	out := captureStdout(t, func() {
+		code = runSuggest([]string{"--dry-run"})
+	})
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0; output:\n%s", code, out)
+	}
+	for _, want := range []string{"Dry run: nothing was sent to mock.", "Cache key:  ", " bytes", "hello.txt"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("preview missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestStripVerboseFlag(t *testing.T) {
+	args, verbose := stripVerboseFlag([]string{"commit-coach", "suggest", "-v", "--json"})
+	if !verbose || strings.Join(args, " ") != "commit-coach suggest --json" {
+		t.Errorf("stripVerboseFlag() = %q, %v", args, verbose)
+	}
+	if _, verbose := stripVerboseFlag([]string{"commit-coach", "suggest"}); verbose {
+		t.Error("stripVerboseFlag() reported verbose without the flag")
+	}
+	if args, verbose := stripVerboseFlag([]string{"commit-coach", "-v", "status"}); !verbose || strings.Join(args, " ") != "commit-coach status" {
+		t.Errorf("stripVerboseFlag() before the subcommand = %q, %v", args, verbose)
+	}
+
+	// Values and arguments after "--" are not global flags.
+	for _, in := range [][]string{
+		{"commit-coach", "config", "set", "system_prompt", "-v"},
+		{"commit-coach", "lint", "-m", "-v"},
+		{"commit-coach", "suggest", "--", "-v"},
+	} {
+		if args, verbose := stripVerboseFlag(in); verbose || strings.Join(args, " ") != strings.Join(in, " ") {
+			t.Errorf("stripVerboseFlag(%q) = %q, %v; want the args unchanged", in, args, verbose)
+		}
+	}
+}
+
+func TestStripLogLevelFlag(t *testing.T) {
+	for _, in := range [][]string{
+		{"commit-coach", "--log-level", "debug", "suggest"},
+		{"commit-coach", "suggest", "--log-level=debug"},
+	} {
+		args, level := stripLogLevelFlag(in)
+		if level != "debug" || strings.Join(args, " ") != "commit-coach suggest" {
+			t.Errorf("stripLogLevelFlag(%q) = %q, %q", in, args, level)
+		}
+	}
+	if _, level := stripLogLevelFlag([]string{"commit-coach", "suggest"}); level != "" {
+		t.Errorf("stripLogLevelFlag() = %q without the flag", level)
+	}
+	in := []string{"commit-coach", "config", "set", "system_prompt", "--log-level=debug"}
+	if args, level := stripLogLevelFlag(in); level != "" || len(args) != len(in) {
+		t.Errorf("stripLogLevelFlag(%q) = %q, %q; want the value kept", in, args, level)
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	for input, want := range map[string]bool{"y\n": true, "YES\n": true, "n\n": false, "\n": false, "": false} {
+		var out strings.Builder
+		if got := confirm(strings.NewReader(input), &out, "Continue? "); got != want {
+			t.Errorf("confirm(%q) = %v, want %v", input, got, want)
+		}
+		if !strings.HasPrefix(out.String(), "Continue? ") {
+			t.Errorf("confirm(%q) did not print the prompt: %q", input, out.String())
+		}
+	}
+}
+
+func TestRunSuggestCommitNoStagedChanges(t *testing.T) {
+	initMockRepo(t, false)
+
+	var code int
+	captureStdout(t, func() {
+		code = runSuggest([]string{"--commit"})
+	})
+	if code != 1 {
+		t.Errorf("exit code = %d, want 1", code)
+	}
+}
+
+func TestRunSuggestIndexRequiresCommit(t *testing.T) {
+	if code := runSuggest([]string{"--index", "2"}); code != 2 {
+		t.Errorf("exit code = %d, want 2", code)
+	}
+}
+
+func TestHookInstallBacksUpExistingHook(t *testing.T) {
+	initMockRepo(t, false)
+	hook := filepath.Join(".git", "hooks", "prepare-commit-msg")
+	if err := os.MkdirAll(filepath.Dir(hook), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(hook, []byte("#!/bin/sh\necho mine\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	var code int
+	captureStdout(t, func() { code = runHook([]string{"install"}) })
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0", code)
+	}
+
+	got, _ := os.ReadFile(hook)
+	if string(got) != hookScript {
+		t.Errorf("hook = %q, want commit-coach script", got)
+	}
+	backup, _ := os.ReadFile(hook + ".bak")
+	if !strings.Contains(string(backup), "echo mine") {
+		t.Errorf("backup = %q, want original hook", backup)
+	}
+}
+
+func TestPrepareCommitMsgFillsEmptyMessage(t *testing.T) {
+	initMockRepo(t, true)
+	file := filepath.Join(t.TempDir(), "COMMIT_EDITMSG")
+	template := "\n# Please enter the commit message for your changes.\n"
+	if err := os.WriteFile(file, []byte(template), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if code := runHook([]string{"prepare-commit-msg", "--type", "docs", file}); code != 0 {
+		t.Fatalf("exit code = %d, want 0", code)
+	}
+
+	got, _ := os.ReadFile(file)
+	if !strings.HasPrefix(string(got), "docs") {
+		t.Errorf("message = %q, want a docs suggestion first", got)
+	}
+	if !strings.Contains(string(got), "# Please enter the commit message") {
+		t.Errorf("message = %q, want git's comments kept", got)
+	}
+}
+
+func TestPrepareCommitMsgSkipsAmendAndExistingMessages(t *testing.T) {
+	initMockRepo(t, true)
+	dir := t.TempDir()
+
+	empty := filepath.Join(dir, "amend")
+	os.WriteFile(empty, nil, 0o644)
+	runHook([]string{"prepare-commit-msg", empty, "commit", "HEAD"})
+	if got, _ := os.ReadFile(empty); len(got) != 0 {
+		t.Errorf("amend message = %q, want untouched", got)
+	}
+
+	written := filepath.Join(dir, "written")
+	os.WriteFile(written, []byte("fix: keep mine\n"), 0o644)
+	runHook([]string{"prepare-commit-msg", written})
+	if got, _ := os.ReadFile(written); string(got) != "fix: keep mine\n" {
+		t.Errorf("existing message = %q, want untouched", got)
+	}
+}
+
+func TestWriteStatus(t *testing.T) {
+	cfg := &config.Config{Provider: "groq", Model: "llama-3.3-70b-versatile", APIKey: "gsk_test", UseCache: true}
+	fakeGit := &testutil.FakeGit{StagedDiffContent: testutil.SampleDiffWithBinary, IsInRepoValue: true}
+	logPath := filepath.Join(t.TempDir(), "commit-coach-error.log")
+	entries := "2026/10/01 09:00:00 WARN groq: rate limited status=429\n2026/10/01 09:00:05 INFO groq: llm call elapsed_ms=812 model=\"llama-3.3-70b-versatile\"\n"
+	if err := os.WriteFile(logPath, []byte(entries), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	suggestions := cache.NewInMemory()
+	for _, key := range []string{"a", "b"} {
+		if err := suggestions.Set(context.Background(), key, []ports.CommitSuggestion{{Type: "feat", Subject: "x"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var out strings.Builder
+	writeStatus(context.Background(), &out, cfg, filepath.Join(t.TempDir(), "config.json"), fakeGit, suggestions, logPath)
+
+	for _, want := range []string{
+		"Provider:  groq (llama-3.3-70b-versatile)",
+		"API key:   set",
+		"(not created)",
+		"Repo:      2 staged file(s)",
+		"Cache:     in-memory, enabled, 2 entries (per process, not kept between runs)",
+		"Last log:  2026/10/01 09:00:05 INFO groq: llm call elapsed_ms=812",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("status missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestWriteStatusMissingKeyOutsideRepo(t *testing.T) {
+	cfg := &config.Config{Provider: "openai", Model: "gpt-4o-mini"}
+
+	var out strings.Builder
+	writeStatus(context.Background(), &out, cfg, "", &testutil.FakeGit{}, cache.NewInMemory(), "")
+
+	for _, want := range []string{"missing (set OPENAI_API_KEY", "not a git repository", "disabled, 0 entries"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("status missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestRunFixupCommitsFixupMessage(t *testing.T) {
+	fakeGit := &testutil.FakeGit{Subjects: map[string]string{"abc123": "feat: add parser"}}
+	commit := app.NewCommitService(fakeGit)
+
+	var stdout, stderr strings.Builder
+	if code := runFixup(context.Background(), commit, "abc123", true, &stdout, &stderr); code != 0 {
+		t.Fatalf("dry run exit = %d, stderr = %s", code, stderr.String())
+	}
+	if got := strings.TrimSpace(stdout.String()); got != "fixup! feat: add parser" {
+		t.Errorf("dry run printed %q", got)
+	}
+	if len(fakeGit.CommittedMessages) != 0 {
+		t.Fatal("dry run committed")
+	}
+
+	stdout.Reset()
+	if code := runFixup(context.Background(), commit, "abc123", false, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit = %d, stderr = %s", code, stderr.String())
+	}
+	if len(fakeGit.CommittedMessages) != 1 || fakeGit.CommittedMessages[0] != "fixup! feat: add parser" {
+		t.Errorf("CommittedMessages = %v", fakeGit.CommittedMessages)
+	}
+
+	if code := runFixup(context.Background(), commit, "missing", false, &stdout, &stderr); code != 1 {
+		t.Errorf("unknown ref exit = %d, want 1", code)
+	}
+}
+
+func TestRunConfigModelsFallsBackToBuiltInList(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	cfg := config.Defaults()
+	cfg.Provider = "mock"
+
+	var stdout, stderr strings.Builder
+	if code := runConfigModels(cfg, "", &stdout, &stderr); code != 0 {
+		t.Fatalf("runConfigModels() = %d, stderr %q", code, stderr.String())
+	}
+	if stdout.String() != "mock\n" || !strings.Contains(stderr.String(), "built-in mock model list") {
+		t.Errorf("stdout %q, stderr %q; want the built-in list and a note", stdout.String(), stderr.String())
+	}
+
+	// A bad key's error is shown redacted before the fallback.
+	t.Setenv("OPENAI_API_KEY", "")
+	stdout.Reset()
+	stderr.Reset()
+	cfg.BaseURL = "http://127.0.0.1:1"
+	cfg.Provider = "openai"
+	cfg.APIKey = "sk-abcdefghijklmnopqrstuvwxyz0123"
+	if code := runConfigModels(cfg, "openai", &stdout, &stderr); code != 0 {
+		t.Fatalf("runConfigModels(openai) = %d", code)
+	}
+	if !strings.Contains(stdout.String(), "gpt-4o-mini") || strings.Contains(stderr.String(), "abcdefghijklmnop") {
+		t.Errorf("stdout %q, stderr %q; want the built-in list and no key", stdout.String(), stderr.String())
+	}
+	if code := runConfigModels(cfg, "nope", &stdout, &stderr); code != 2 {
+		t.Errorf("unknown provider exit = %d, want 2", code)
+	}
+}
@@ -0,0 +1,113 @@
+// Package coach is the embeddable API of commit-coach: it suggests
+// Conventional Commit messages for the staged changes of the git repository
+// in the current directory and commits the chosen one, wired the same way as
+// the commit-coach command.
+package coach
+
+import (
+	"context"
+
+	"github.com/chuckie/commit-coach/internal/app"
+	"github.com/chuckie/commit-coach/internal/config"
+	"github.com/chuckie/commit-coach/internal/domain"
+	"github.com/chuckie/commit-coach/internal/ports"
+	"github.com/chuckie/commit-coach/internal/wiring"
+)
+
+// Config is the commit-coach configuration. Start from DefaultConfig or
+// LoadConfig and adjust fields as needed.
+type Config = config.Config
+
+// Suggestion is one suggested commit message; Format renders it.
+type Suggestion = domain.Suggestion
+
+// Result is a suggestion run plus metadata (token usage, truncation, ...).
+type Result = app.SuggestResult
+
+// CommitOptions changes how a commit is made (dry run, skipping hooks,
+// committing only some paths).
+type CommitOptions = ports.CommitOptions
+
+// ErrNoStagedChanges is returned by Suggest when nothing is staged.
+var ErrNoStagedChanges = app.ErrNoStagedChanges
+
+// DefaultConfig returns the built-in defaults (OpenAI, gpt-4o-mini).
+func DefaultConfig() Config {
+	return *config.Defaults()
+}
+
+// LoadConfig returns the configuration the command would use: defaults, the
+// user config file and environment overrides, validated.
+func LoadConfig() (Config, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return Config{}, err
+	}
+	return *cfg, nil
+}
+
+// SuggestOptions tunes a single Suggest call.
+type SuggestOptions struct {
+	// Paths limits the suggestion to staged changes under these pathspecs;
+	// empty means everything staged.
+	Paths []string
+	// IncludeUnstaged describes the working tree when nothing is staged.
+	IncludeUnstaged bool
+	// Fresh skips the cache and asks the provider again.
+	Fresh bool
+}
+
+// Coach suggests and makes commits in the repository of the current
+// directory. It is not safe for concurrent use.
+type Coach struct {
+	cfg Config
+	app *app.App
+}
+
+// New validates cfg and builds the provider, git and cache adapters. API keys
+// for Provider "multi" members come from their environment variables, as in
+// the command.
+func New(cfg Config) (*Coach, error) {
+	for _, check := range config.Check(&cfg) {
+		if check.Err != nil {
+			return nil, check.Err
+		}
+	}
+
+	a, err := wiring.NewApp(&cfg, wiring.Options{})
+	if err != nil {
+		return nil, err
+	}
+	return &Coach{cfg: cfg, app: a}, nil
+}
+
+// Suggest returns commit message suggestions for the staged changes.
+func (c *Coach) Suggest(ctx context.Context, opts SuggestOptions) ([]Suggestion, error) {
+	result, err := c.SuggestDetailed(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return result.Suggestions, nil
+}
+
+// SuggestDetailed is Suggest plus metadata about the run.
+func (c *Coach) SuggestDetailed(ctx context.Context, opts SuggestOptions) (*Result, error) {
+	c.app.Suggest.SetPaths(opts.Paths)
+	c.app.Suggest.SetIncludeUnstaged(opts.IncludeUnstaged)
+	if opts.Fresh {
+		return c.app.Suggest.RegenerateCommitsDetailed(ctx, c.cfg.Provider, c.cfg.Model, c.cfg.Temperature)
+	}
+	return c.app.Suggest.SuggestCommitsDetailed(ctx, c.cfg.Provider, c.cfg.Model, c.cfg.Temperature)
+}
+
+// Close releases the provider's idle connections and the cache. Call it
+// when done with c; calling it again does nothing.
+func (c *Coach) Close() error {
+	return c.app.Close()
+}
+
+// Commit commits the staged changes with message (e.g. a Suggestion's
+// Format()) and returns the new commit's short hash.
+func (c *Coach) Commit(ctx context.Context, message string, opts CommitOptions) (string, error) {
+	return c.app.Commit.Commit(ctx, message, opts)
+}
@@ -0,0 +1,82 @@
+package coach_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/chuckie/commit-coach/pkg/coach"
+)
+
+func Example() {
+	dir, cleanup := scratchRepo()
+	defer cleanup()
+	if err := os.WriteFile(filepath.Join(dir, "hello.txt"), []byte("hello\n"), 0o644); err != nil {
+		log.Fatal(err)
+	}
+	gitRun("add", "hello.txt")
+
+	cfg := coach.DefaultConfig()
+	cfg.Provider = "mock"
+	cfg.Model = "mock"
+	c, err := coach.New(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer c.Close()
+
+	ctx := context.Background()
+	suggestions, err := c.Suggest(ctx, coach.SuggestOptions{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(len(suggestions), "suggestions")
+
+	hash, err := c.Commit(ctx, suggestions[0].Format(), coach.CommitOptions{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("committed:", hash != "")
+
+	_, err = c.Suggest(ctx, coach.SuggestOptions{})
+	fmt.Println("nothing staged:", errors.Is(err, coach.ErrNoStagedChanges))
+	// Output:
+	// 3 suggestions
+	// committed: true
+	// nothing staged: true
+}
+
+// scratchRepo creates an empty repository with an isolated git config, makes
+// it the working directory and returns a func that undoes both.
+func scratchRepo() (string, func()) {
+	if _, err := exec.LookPath("git"); err != nil {
+		log.Fatal("git not available")
+	}
+	dir, err := os.MkdirTemp("", "coach-example-")
+	if err != nil {
+		log.Fatal(err)
+	}
+	prev, _ := os.Getwd()
+	if err := os.Chdir(dir); err != nil {
+		log.Fatal(err)
+	}
+	os.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(dir, ".gitconfig-global"))
+	os.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	gitRun("init", "-q")
+	gitRun("config", "user.name", "Example User")
+	gitRun("config", "user.email", "example@example.com")
+	return dir, func() {
+		_ = os.Chdir(prev)
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func gitRun(args ...string) {
+	if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+		log.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
@@ -2,11 +2,24 @@ package integration
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/chuckie/commit-coach/internal/adapters/cache"
+	"github.com/chuckie/commit-coach/internal/adapters/llm/prompts"
+	"github.com/chuckie/commit-coach/internal/adapters/notes"
 	"github.com/chuckie/commit-coach/internal/app"
+	"github.com/chuckie/commit-coach/internal/config"
+	"github.com/chuckie/commit-coach/internal/domain"
 	"github.com/chuckie/commit-coach/internal/ports"
+	"github.com/chuckie/commit-coach/internal/security"
 	"github.com/chuckie/commit-coach/internal/testutil"
 )
 
@@ -62,10 +75,13 @@ func TestSuggestWithCache(t *testing.T) {
 	ctx := context.Background()
 
 	// First call: should hit LLM
-	_, err := app.Suggest.SuggestCommits(ctx, "openai", "gpt-4o-mini", 0.7)
+	result, err := app.Suggest.SuggestCommitsDetailed(ctx, "openai", "gpt-4o-mini", 0.7)
 	if err != nil {
 		t.Fatalf("First SuggestCommits failed: %v", err)
 	}
+	if result.Cached {
+		t.Error("first result should not be marked cached")
+	}
 
 	firstCallCount := fakeLLM.CallCount
 	if firstCallCount != 1 {
@@ -73,10 +89,13 @@ func TestSuggestWithCache(t *testing.T) {
 	}
 
 	// Second call: should hit cache
-	_, err = app.Suggest.SuggestCommits(ctx, "openai", "gpt-4o-mini", 0.7)
+	result, err = app.Suggest.SuggestCommitsDetailed(ctx, "openai", "gpt-4o-mini", 0.7)
 	if err != nil {
 		t.Fatalf("Second SuggestCommits failed: %v", err)
 	}
+	if !result.Cached {
+		t.Error("second result should be marked cached")
+	}
 
 	if fakeLLM.CallCount != 1 {
 		t.Errorf("Expected 1 LLM call (cache hit), got %d", fakeLLM.CallCount)
@@ -85,124 +104,399 @@ func TestSuggestWithCache(t *testing.T) {
 	if cacheAdapter.Size() != 1 {
 		t.Errorf("Expected 1 cached entry, got %d", cacheAdapter.Size())
 	}
-}
 
-func TestSuggestNoStagedChanges(t *testing.T) {
-	fakeLLM := &testutil.FakeLLM{
-		Suggestions: testutil.SampleLLMResponse(),
+	// A different temperature is a different request: cache miss.
+	_, err = app.Suggest.SuggestCommits(ctx, "openai", "gpt-4o-mini", 0.2)
+	if err != nil {
+		t.Fatalf("SuggestCommits at another temperature failed: %v", err)
 	}
+	if fakeLLM.CallCount != 2 || cacheAdapter.Size() != 2 {
+		t.Errorf("temperature change: %d LLM calls, %d cache entries; want 2 and 2", fakeLLM.CallCount, cacheAdapter.Size())
+	}
+}
 
-	fakeGit := &testutil.FakeGit{
-		StagedDiffContent: "", // Empty diff
-		IsInRepoValue:     true,
+func TestRegenerateBypassesCache(t *testing.T) {
+	fakeLLM := &testutil.FakeLLM{Suggestions: testutil.SampleLLMResponse()}
+	fakeGit := &testutil.FakeGit{StagedDiffContent: testutil.SampleDiffSmall, IsInRepoValue: true}
+	fakeCache := testutil.NewFakeCache()
+	a := app.NewApp(fakeLLM, fakeGit, fakeCache, 8192, true)
+	ctx := context.Background()
+
+	if _, err := a.Suggest.SuggestCommitsDetailed(ctx, "openai", "gpt-4o-mini", 0.7); err != nil {
+		t.Fatalf("SuggestCommitsDetailed failed: %v", err)
+	}
+	prepared, err := a.Suggest.PrepareDiff(ctx, "openai", "gpt-4o-mini", 0.7)
+	if err != nil {
+		t.Fatalf("PrepareDiff failed: %v", err)
+	}
+	result, err := a.Suggest.RegenerateCommitsDetailed(ctx, "openai", "gpt-4o-mini", 0.7)
+	if err != nil {
+		t.Fatalf("RegenerateCommitsDetailed failed: %v", err)
+	}
+	if fakeLLM.CallCount != 2 || result.Cached {
+		t.Errorf("LLM calls = %d, cached = %v; want 2 and false (regenerate skips the cache)", fakeLLM.CallCount, result.Cached)
+	}
+	if len(fakeCache.Deleted) != 1 || fakeCache.Deleted[0] != prepared.CacheKey {
+		t.Errorf("deleted keys = %q, want the request's key %q", fakeCache.Deleted, prepared.CacheKey)
 	}
 
-	cacheAdapter := cache.NewInMemory()
-	app := app.NewApp(fakeLLM, fakeGit, cacheAdapter, 8192, true)
+	// The fresh result is cached again for the next plain call.
+	result, err = a.Suggest.SuggestCommitsDetailed(ctx, "openai", "gpt-4o-mini", 0.7)
+	if err != nil {
+		t.Fatalf("SuggestCommitsDetailed failed: %v", err)
+	}
+	if fakeLLM.CallCount != 2 || !result.Cached {
+		t.Errorf("LLM calls = %d, cached = %v; want 2 and true (served from cache)", fakeLLM.CallCount, result.Cached)
+	}
+}
 
+func TestSubjectStyleNormalization(t *testing.T) {
+	fakeLLM := &testutil.FakeLLM{Suggestions: []ports.CommitSuggestion{{Type: "feat", Subject: "Added config loader."}}}
+	fakeGit := &testutil.FakeGit{StagedDiffContent: testutil.SampleDiffSmall, IsInRepoValue: true}
+	a := app.NewApp(fakeLLM, fakeGit, cache.NewInMemory(), 8192, false)
+	a.Suggest.SetCount(1)
 	ctx := context.Background()
-	_, err := app.Suggest.SuggestCommits(ctx, "openai", "gpt-4o-mini", 0.7)
 
-	if err == nil {
-		t.Error("Expected error for empty staged diff")
+	got, err := a.Suggest.SuggestCommits(ctx, "openai", "gpt-4o-mini", 0.7)
+	if err != nil || got[0].Subject != "added config loader" {
+		t.Errorf("default style = %v, %v; want lowercased without the period", got, err)
+	}
+	a.Suggest.SetSubjectStyle(domain.SubjectStyle{Strict: true})
+	got, err = a.Suggest.SuggestCommits(ctx, "openai", "gpt-4o-mini", 0.7)
+	if err != nil || got[0].Subject != "add config loader" {
+		t.Errorf("strict style = %v, %v; want the imperative", got, err)
 	}
 }
 
-func TestSuggestNotInRepo(t *testing.T) {
-	fakeLLM := &testutil.FakeLLM{
-		Suggestions: testutil.SampleLLMResponse(),
+func TestSubjectMaxLenReachesPrompt(t *testing.T) {
+	fakeLLM := &testutil.FakeLLM{Suggestions: []ports.CommitSuggestion{{Type: "feat", Subject: strings.Repeat("a", 51)}}}
+	fakeGit := &testutil.FakeGit{StagedDiffContent: testutil.SampleDiffSmall, IsInRepoValue: true}
+	a := app.NewApp(fakeLLM, fakeGit, cache.NewInMemory(), 8192, false)
+	a.Suggest.SetCount(1)
+	a.Suggest.SetSubjectLimits(domain.SubjectLimits{Max: 50})
+
+	got, err := a.Suggest.SuggestCommits(context.Background(), "openai", "gpt-4o-mini", 0.7)
+	if err != nil {
+		t.Fatalf("SuggestCommits() error = %v", err)
 	}
+	if fakeLLM.LastInput.SubjectMaxLen != 50 {
+		t.Errorf("SubjectMaxLen sent = %d, want 50", fakeLLM.LastInput.SubjectMaxLen)
+	}
+	if len(got) != 1 || len(got[0].Subject) != 50 {
+		t.Errorf("suggestions = %v, want the subject truncated to 50", got)
+	}
+}
 
-	fakeGit := &testutil.FakeGit{
-		StagedDiffContent: testutil.SampleDiffSmall,
-		IsInRepoValue:     false, // Not in repo
+func TestSuggestSendsBranch(t *testing.T) {
+	fakeLLM := &testutil.FakeLLM{Suggestions: testutil.SampleLLMResponse()}
+	fakeGit := &testutil.FakeGit{StagedDiffContent: testutil.SampleDiffSmall, IsInRepoValue: true, Branch: "refactor-auth-middleware"}
+	a := app.NewApp(fakeLLM, fakeGit, cache.NewInMemory(), 8192, false)
+
+	if _, err := a.Suggest.SuggestCommits(context.Background(), "openai", "gpt-4o-mini", 0.7); err != nil {
+		t.Fatalf("SuggestCommits() error = %v", err)
+	}
+	if fakeLLM.LastInput.Branch != "refactor-auth-middleware" {
+		t.Errorf("Branch sent = %q, want refactor-auth-middleware", fakeLLM.LastInput.Branch)
 	}
 
-	cacheAdapter := cache.NewInMemory()
-	app := app.NewApp(fakeLLM, fakeGit, cacheAdapter, 8192, true)
+	fakeGit.BranchErr = errors.New("not a git repository")
+	if _, err := a.Suggest.SuggestCommits(context.Background(), "openai", "gpt-4o-mini", 0.7); err != nil || fakeLLM.LastInput.Branch != "" {
+		t.Errorf("branch lookup failure: Branch = %q, err = %v; want empty and no error", fakeLLM.LastInput.Branch, err)
+	}
+}
 
-	ctx := context.Background()
-	_, err := app.Suggest.SuggestCommits(ctx, "openai", "gpt-4o-mini", 0.7)
+func TestSuggestTimesOut(t *testing.T) {
+	fakeLLM := &testutil.FakeLLM{Suggestions: testutil.SampleLLMResponse(), Delay: time.Second}
+	fakeGit := &testutil.FakeGit{StagedDiffContent: testutil.SampleDiffSmall, IsInRepoValue: true}
+	a := app.NewApp(fakeLLM, fakeGit, cache.NewInMemory(), 8192, false)
+	a.Suggest.SetTimeout(20 * time.Millisecond)
 
-	if err == nil {
-		t.Error("Expected error when not in repository")
+	start := time.Now()
+	_, err := a.Suggest.SuggestCommits(context.Background(), "openai", "gpt-4o-mini", 0.7)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("SuggestCommits() error = %v, want a deadline error", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("SuggestCommits() took %v, want it cut off near the timeout", elapsed)
 	}
 }
 
-func TestCommitWorkflow(t *testing.T) {
-	fakeGit := &testutil.FakeGit{
-		IsInRepoValue: true,
+func TestTicketFromBranch(t *testing.T) {
+	pattern := regexp.MustCompile(config.DefaultTicketPattern)
+	tests := map[string]string{
+		"feature/JIRA-1234-thing": "JIRA-1234",
+		"JIRA-1234":               "JIRA-1234",
+		"bugfix/ABC-7_fix-login":  "ABC-7",
+		"user/jane/OPS-42/retry":  "OPS-42",
+		"feature/jira-1234-thing": "",
+		"refactor-auth":           "",
+		"main":                    "",
+		"HEAD":                    "",
 	}
+	for branch, want := range tests {
+		if got := app.TicketFromBranch(branch, pattern); got != want {
+			t.Errorf("TicketFromBranch(%q) = %q, want %q", branch, got, want)
+		}
+	}
+	if got := app.TicketFromBranch("feature/1234-thing", regexp.MustCompile(`\d+`)); got != "1234" {
+		t.Errorf("custom pattern = %q, want 1234", got)
+	}
+	if got := app.TicketFromBranch("feature/JIRA-1234", nil); got != "" {
+		t.Errorf("nil pattern = %q, want empty", got)
+	}
+}
 
-	commitService := app.NewCommitService(fakeGit)
+func TestCommitAddsTicketFooter(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		branch, message, want string
+	}{
+		{"feature/JIRA-1234-thing", "feat: add loader", "feat: add loader\n\nRefs: JIRA-1234"},
+		{"feature/JIRA-1234-thing", "feat: add loader\n\nLoads it.\n\nCloses: #9", "feat: add loader\n\nLoads it.\n\nCloses: #9\nRefs: JIRA-1234"},
+		{"feature/JIRA-1234-thing", "feat: add loader\n\nRefs: JIRA-1234", "feat: add loader\n\nRefs: JIRA-1234"},
+		{"main", "feat: add loader", "feat: add loader"},
+		{"feature/JIRA-1234-thing", "fixup! feat: add loader", "fixup! feat: add loader"},
+		{"feature/JIRA-1234-thing", "squash! feat: add loader\n\nMore.", "squash! feat: add loader\n\nMore."},
+	}
+	for _, tt := range tests {
+		fakeGit := &testutil.FakeGit{Branch: tt.branch}
+		svc := app.NewCommitService(fakeGit)
+		svc.SetTicketPattern(regexp.MustCompile(config.DefaultTicketPattern))
+		if _, err := svc.Commit(ctx, tt.message, ports.CommitOptions{}); err != nil {
+			t.Fatalf("Commit() error = %v", err)
+		}
+		if got := fakeGit.CommittedMessages[0]; got != tt.want {
+			t.Errorf("branch %q: committed %q, want %q", tt.branch, got, tt.want)
+		}
+	}
+
+	// Off by default.
+	fakeGit := &testutil.FakeGit{Branch: "feature/JIRA-1234-thing"}
+	if _, err := app.NewCommitService(fakeGit).Commit(ctx, "feat: add loader", ports.CommitOptions{}); err != nil || fakeGit.CommittedMessages[0] != "feat: add loader" {
+		t.Errorf("without a pattern committed %q, %v; want the message unchanged", fakeGit.CommittedMessages, err)
+	}
+}
 
+func TestEffectivelyEmptyDiffs(t *testing.T) {
+	const headersOnly = "diff --git a/a.txt b/a.txt\nindex 1234567..1234567 100644\n"
+	const modeOnly = "diff --git a/run.sh b/run.sh\nold mode 100644\nnew mode 100755\n"
 	ctx := context.Background()
-	message := "feat: add new feature"
 
-	hash, err := commitService.Commit(ctx, message, false)
-	if err != nil {
-		t.Fatalf("Commit failed: %v", err)
+	for _, diff := range []string{headersOnly, " \n\t\n"} {
+		fakeLLM := &testutil.FakeLLM{Suggestions: testutil.SampleLLMResponse()}
+		a := app.NewApp(fakeLLM, &testutil.FakeGit{StagedDiffContent: diff, IsInRepoValue: true}, cache.NewInMemory(), 8192, true)
+		if _, err := a.Suggest.SuggestCommits(ctx, "openai", "gpt-4o-mini", 0.7); !errors.Is(err, app.ErrNoMeaningfulChanges) {
+			t.Errorf("diff %q: error = %v, want ErrNoMeaningfulChanges", diff, err)
+		}
+		if fakeLLM.CallCount != 0 {
+			t.Errorf("diff %q: provider was called", diff)
+		}
 	}
 
-	if hash == "" {
-		t.Error("Expected non-empty commit hash")
+	for diff, wantNote := range map[string]bool{modeOnly: true, testutil.SampleDiffSmall: false} {
+		fakeLLM := &testutil.FakeLLM{Suggestions: testutil.SampleLLMResponse()}
+		a := app.NewApp(fakeLLM, &testutil.FakeGit{StagedDiffContent: diff, IsInRepoValue: true}, cache.NewInMemory(), 8192, true)
+		if _, err := a.Suggest.SuggestCommits(ctx, "openai", "gpt-4o-mini", 0.7); err != nil {
+			t.Errorf("diff %q: error = %v", diff, err)
+			continue
+		}
+		if gotNote := len(fakeLLM.LastInput.Notes) == 1 && strings.Contains(fakeLLM.LastInput.Notes[0], "file modes"); gotNote != wantNote {
+			t.Errorf("diff %q: notes = %q, want mode note %v", diff, fakeLLM.LastInput.Notes, wantNote)
+		}
 	}
+}
 
-	if len(fakeGit.CommittedMessages) != 1 {
-		t.Errorf("Expected 1 committed message, got %d", len(fakeGit.CommittedMessages))
+func TestRepoStateReachesPromptAndResult(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name      string
+		state     ports.RepoState
+		wantNote  string
+		wantNotes int
+	}{
+		{"clean", ports.RepoState{}, "", 0},
+		{"merge", ports.RepoState{Merging: true, MergeMessage: "Merge branch 'feature'"}, "merge is in progress", 1},
+		{"rebase", ports.RepoState{Rebasing: true}, "rebase is in progress", 0},
+	}
+	for _, tt := range tests {
+		fakeLLM := &testutil.FakeLLM{Suggestions: testutil.SampleLLMResponse()}
+		fakeGit := &testutil.FakeGit{StagedDiffContent: testutil.SampleDiffSmall, IsInRepoValue: true, RepoStateValue: tt.state}
+		a := app.NewApp(fakeLLM, fakeGit, cache.NewInMemory(), 8192, false)
+		result, err := a.Suggest.SuggestCommitsDetailed(ctx, "openai", "gpt-4o-mini", 0.7)
+		if err != nil {
+			t.Fatalf("%s: error = %v", tt.name, err)
+		}
+		if note := result.RepoStateNote(); (tt.wantNote == "") != (note == "") || !strings.Contains(note, tt.wantNote) {
+			t.Errorf("%s: RepoStateNote() = %q, want %q", tt.name, note, tt.wantNote)
+		}
+		if len(fakeLLM.LastInput.Notes) != tt.wantNotes {
+			t.Errorf("%s: notes = %q, want %d", tt.name, fakeLLM.LastInput.Notes, tt.wantNotes)
+		} else if tt.wantNotes == 1 && !strings.Contains(fakeLLM.LastInput.Notes[0], "Merge branch 'feature'") {
+			t.Errorf("%s: note %q should carry MERGE_MSG", tt.name, fakeLLM.LastInput.Notes[0])
+		}
 	}
 
-	if fakeGit.CommittedMessages[0] != message {
-		t.Errorf("Committed message mismatch: got %s, want %s", fakeGit.CommittedMessages[0], message)
+	// A failing state check only loses the context.
+	fakeGit := &testutil.FakeGit{StagedDiffContent: testutil.SampleDiffSmall, IsInRepoValue: true, RepoStateErr: errors.New("rev-parse failed")}
+	a := app.NewApp(&testutil.FakeLLM{Suggestions: testutil.SampleLLMResponse()}, fakeGit, cache.NewInMemory(), 8192, false)
+	if _, err := a.Suggest.SuggestCommits(ctx, "openai", "gpt-4o-mini", 0.7); err != nil {
+		t.Errorf("SuggestCommits() with a state error = %v, want success", err)
 	}
 }
 
-func TestCommitDryRun(t *testing.T) {
-	fakeGit := &testutil.FakeGit{
-		IsInRepoValue: true,
+func TestCachedSuggestionsExpireWithClock(t *testing.T) {
+	fakeLLM := &testutil.FakeLLM{Suggestions: testutil.SampleLLMResponse()}
+	fakeGit := &testutil.FakeGit{StagedDiffContent: testutil.SampleDiffSmall, IsInRepoValue: true}
+	clock := &testutil.FakeClock{T: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)}
+	a := app.NewApp(fakeLLM, fakeGit, cache.NewInMemoryWithTTL(time.Hour, clock), 8192, true)
+	ctx := context.Background()
+
+	suggest := func() *app.SuggestResult {
+		t.Helper()
+		result, err := a.Suggest.SuggestCommitsDetailed(ctx, "openai", "gpt-4o-mini", 0.7)
+		if err != nil {
+			t.Fatalf("SuggestCommitsDetailed failed: %v", err)
+		}
+		return result
 	}
 
-	commitService := app.NewCommitService(fakeGit)
+	suggest()
+	clock.Advance(59 * time.Minute)
+	suggest()
+	if fakeLLM.CallCount != 1 {
+		t.Fatalf("LLM calls = %d, want 1 (entry still fresh)", fakeLLM.CallCount)
+	}
+	clock.Advance(time.Minute)
+	suggest()
+	if fakeLLM.CallCount != 2 {
+		t.Errorf("LLM calls = %d, want 2 (entry expired)", fakeLLM.CallCount)
+	}
+}
 
+func TestSuggestResultReportsProviderDuration(t *testing.T) {
+	fakeGit := &testutil.FakeGit{StagedDiffContent: testutil.SampleDiffSmall, IsInRepoValue: true}
+	a := app.NewApp(&testutil.FakeLLM{Suggestions: testutil.SampleLLMResponse()}, fakeGit, cache.NewInMemory(), 8192, true)
+	// Each Now call moves 250ms, so the provider call spans exactly one step.
+	a.Suggest.SetClock(&testutil.FakeClock{Step: 250 * time.Millisecond})
 	ctx := context.Background()
-	message := "feat: add new feature"
 
-	_, err := commitService.Commit(ctx, message, true)
+	result, err := a.Suggest.SuggestCommitsDetailed(ctx, "openai", "gpt-4o-mini", 0.7)
 	if err != nil {
-		t.Fatalf("Dry-run commit failed: %v", err)
+		t.Fatalf("SuggestCommitsDetailed failed: %v", err)
+	}
+	if result.DurationMS != 250 {
+		t.Errorf("DurationMS = %d, want 250", result.DurationMS)
+	}
+	cached, err := a.Suggest.SuggestCommitsDetailed(ctx, "openai", "gpt-4o-mini", 0.7)
+	if err != nil {
+		t.Fatalf("SuggestCommitsDetailed failed: %v", err)
+	}
+	if cached.DurationMS != 0 {
+		t.Errorf("cached DurationMS = %d, want 0", cached.DurationMS)
 	}
+}
 
-	if len(fakeGit.CommittedMessages) != 0 {
-		t.Error("Expected no commits in dry-run mode")
+func TestCacheKeyIsStableAndCoversTemperature(t *testing.T) {
+	fakeGit := &testutil.FakeGit{StagedDiffContent: testutil.SampleDiffSmall, IsInRepoValue: true}
+	ctx := context.Background()
+	key := func(temperature float32) string {
+		// A fresh app each time, as in separate runs.
+		a := app.NewApp(&testutil.FakeLLM{}, fakeGit, cache.NewInMemory(), 8192, true)
+		prepared, err := a.Suggest.PrepareDiff(ctx, "openai", "gpt-4o-mini", temperature)
+		if err != nil {
+			t.Fatalf("PrepareDiff failed: %v", err)
+		}
+		return prepared.CacheKey
+	}
+	if key(0.7) != key(0.7) {
+		t.Error("cache key differs between runs with the same settings")
+	}
+	if key(0.7) == key(0.2) {
+		t.Error("cache key ignores temperature")
 	}
 }
 
-func TestCommitEmptyMessage(t *testing.T) {
-	fakeGit := &testutil.FakeGit{
-		IsInRepoValue: true,
+func TestSystemPromptChangeMissesCache(t *testing.T) {
+	fakeLLM := &testutil.FakeLLM{Suggestions: testutil.SampleLLMResponse()}
+	fakeGit := &testutil.FakeGit{StagedDiffContent: testutil.SampleDiffSmall, IsInRepoValue: true}
+	a := app.NewApp(fakeLLM, fakeGit, cache.NewInMemory(), 8192, true)
+	ctx := context.Background()
+
+	for _, prompt := range []string{"", "You write terse messages.", "You write terse messages.", "Tu écris en français."} {
+		a.Suggest.SetSystemPrompt(prompt)
+		if _, err := a.Suggest.SuggestCommits(ctx, "openai", "gpt-4o-mini", 0.7); err != nil {
+			t.Fatalf("SuggestCommits(%q) failed: %v", prompt, err)
+		}
+	}
+	if fakeLLM.CallCount != 3 {
+		t.Errorf("provider called %d times, want 3 (only the repeated prompt hits the cache)", fakeLLM.CallCount)
 	}
+}
 
-	commitService := app.NewCommitService(fakeGit)
+func TestPromptTemplateAndBranchChangesMissCache(t *testing.T) {
+	fakeLLM := &testutil.FakeLLM{Suggestions: testutil.SampleLLMResponse()}
+	fakeGit := &testutil.FakeGit{StagedDiffContent: testutil.SampleDiffSmall, IsInRepoValue: true, Branch: "main"}
+	a := app.NewApp(fakeLLM, fakeGit, cache.NewInMemory(), 8192, true)
+	ctx := context.Background()
+	suggest := func() {
+		t.Helper()
+		if _, err := a.Suggest.SuggestCommits(ctx, "openai", "gpt-4o-mini", 0.7); err != nil {
+			t.Fatalf("SuggestCommits failed: %v", err)
+		}
+	}
+
+	suggest()
+	a.Suggest.SetPromptTemplate("Describe {{.Diff}} as JSON.")
+	suggest()
+	fakeGit.Branch = "feature/login"
+	suggest()
+	suggest()
+	if fakeLLM.CallCount != 3 {
+		t.Errorf("provider called %d times, want 3 (template and branch changes miss the cache)", fakeLLM.CallCount)
+	}
+	if fakeLLM.LastInput.Branch != "feature/login" {
+		t.Errorf("Branch = %q, want the current branch", fakeLLM.LastInput.Branch)
+	}
+}
 
+func TestRecentSubjectsReachCustomTemplate(t *testing.T) {
+	fakeLLM := &testutil.FakeLLM{Suggestions: testutil.SampleLLMResponse()}
+	fakeGit := &testutil.FakeGit{
+		StagedDiffContent:   testutil.SampleDiffSmall,
+		IsInRepoValue:       true,
+		RecentSubjectsValue: []string{"feat: add login", "fix: handle nil"},
+	}
+	a := app.NewApp(fakeLLM, fakeGit, cache.NewInMemory(), 8192, true)
 	ctx := context.Background()
-	_, err := commitService.Commit(ctx, "", false)
+	suggest := func() {
+		t.Helper()
+		if _, err := a.Suggest.SuggestCommits(ctx, "openai", "gpt-4o-mini", 0.7); err != nil {
+			t.Fatalf("SuggestCommits failed: %v", err)
+		}
+	}
 
-	if err == nil {
-		t.Error("Expected error for empty commit message")
+	// The default prompt does not use them, so git log is not read.
+	suggest()
+	if fakeLLM.LastInput.RecentSubjects != nil {
+		t.Errorf("RecentSubjects = %q without a custom template, want nil", fakeLLM.LastInput.RecentSubjects)
+	}
+
+	a.Suggest.SetPromptTemplate("After {{join .RecentSubjects \", \"}}: {{.Diff}} as JSON.")
+	suggest()
+	if got := strings.Join(fakeLLM.LastInput.RecentSubjects, "|"); got != "feat: add login|fix: handle nil" {
+		t.Errorf("RecentSubjects = %q, want the git log subjects", got)
+	}
+	fakeGit.RecentSubjectsValue = []string{"docs: readme"}
+	suggest()
+	if fakeLLM.CallCount != 3 {
+		t.Errorf("provider called %d times, want 3 (new commits miss the cache)", fakeLLM.CallCount)
 	}
 }
 
-func TestSuggestionValidationInOrchestrator(t *testing.T) {
-	// Test that invalid LLM responses are rejected
+func TestSuggestReportsUsage(t *testing.T) {
 	fakeLLM := &testutil.FakeLLM{
-		Suggestions: []ports.CommitSuggestion{
-			{
-				Type:    "invalid_type",
-				Subject: "bad suggestion",
-				Body:    "",
-				Footer:  "",
-			},
-		},
+		Suggestions: testutil.SampleLLMResponse(),
+		Usage:       &ports.Usage{PromptTokens: 1000, CompletionTokens: 240, TotalTokens: 1240},
 	}
 
 	fakeGit := &testutil.FakeGit{
@@ -210,36 +504,1187 @@ func TestSuggestionValidationInOrchestrator(t *testing.T) {
 		IsInRepoValue:     true,
 	}
 
-	cacheAdapter := cache.NewInMemory()
-	app := app.NewApp(fakeLLM, fakeGit, cacheAdapter, 8192, true)
+	app := app.NewApp(fakeLLM, fakeGit, cache.NewInMemory(), 8192, true)
+	ctx := context.Background()
+
+	result, err := app.Suggest.SuggestCommitsDetailed(ctx, "openai", "gpt-4o-mini", 0.7)
+	if err != nil {
+		t.Fatalf("SuggestCommitsDetailed failed: %v", err)
+	}
+	if result.Usage == nil || result.Usage.TotalTokens != 1240 {
+		t.Errorf("Usage = %+v, want total 1240", result.Usage)
+	}
+
+	// Cached results cost nothing, so no usage is reported.
+	result, err = app.Suggest.SuggestCommitsDetailed(ctx, "openai", "gpt-4o-mini", 0.7)
+	if err != nil {
+		t.Fatalf("cached SuggestCommitsDetailed failed: %v", err)
+	}
+	if result.Usage != nil {
+		t.Errorf("Usage = %+v, want nil for cache hit", result.Usage)
+	}
+}
 
+func TestSuggestCount(t *testing.T) {
+	fakeGit := &testutil.FakeGit{
+		StagedDiffContent: testutil.SampleDiffSmall,
+		IsInRepoValue:     true,
+	}
 	ctx := context.Background()
-	_, err := app.Suggest.SuggestCommits(ctx, "openai", "gpt-4o-mini", 0.7)
 
-	if err == nil {
-		t.Error("Expected error for invalid suggestion type")
+	// Fewer than the LLM returned: keep the first N.
+	fakeLLM := &testutil.FakeLLM{Suggestions: testutil.SampleLLMResponse()}
+	a := app.NewApp(fakeLLM, fakeGit, cache.NewInMemory(), 8192, false)
+	a.Suggest.SetCount(2)
+	suggestions, err := a.Suggest.SuggestCommits(ctx, "openai", "gpt-4o-mini", 0.7)
+	if err != nil {
+		t.Fatalf("SuggestCommits failed: %v", err)
+	}
+	if len(suggestions) != 2 {
+		t.Errorf("Expected 2 suggestions, got %d", len(suggestions))
+	}
+	if fakeLLM.LastInput.Count != 2 {
+		t.Errorf("LLM asked for %d suggestions, want 2", fakeLLM.LastInput.Count)
+	}
+
+	// More than the LLM returned is an error.
+	a.Suggest.SetCount(5)
+	if _, err := a.Suggest.SuggestCommits(ctx, "openai", "gpt-4o-mini", 0.7); err == nil {
+		t.Error("Expected error when the LLM returns fewer suggestions than requested")
+	}
+
+	// Out-of-range counts are clamped.
+	a.Suggest.SetCount(50)
+	a.Suggest.SuggestCommits(ctx, "openai", "gpt-4o-mini", 0.7)
+	if fakeLLM.LastInput.Count != app.MaxSuggestionCount {
+		t.Errorf("LLM asked for %d suggestions, want %d", fakeLLM.LastInput.Count, app.MaxSuggestionCount)
 	}
 }
 
-func TestDiffCapcing(t *testing.T) {
-	// Test that large diffs are capped
-	largeGit := &testutil.FakeGit{
-		StagedDiffContent: testutil.SampleDiffLarge,
+func TestSuggestOmitsBinaryFiles(t *testing.T) {
+	fakeLLM := &testutil.FakeLLM{Suggestions: testutil.SampleLLMResponse()}
+	fakeGit := &testutil.FakeGit{
+		StagedDiffContent: testutil.SampleDiffWithBinary,
 		IsInRepoValue:     true,
 	}
 
-	fakeLLM := &testutil.FakeLLM{
-		Suggestions: testutil.SampleLLMResponse(),
+	app := app.NewApp(fakeLLM, fakeGit, cache.NewInMemory(), 8192, false)
+	if _, err := app.Suggest.SuggestCommits(context.Background(), "openai", "gpt-4o-mini", 0.7); err != nil {
+		t.Fatalf("SuggestCommits failed: %v", err)
 	}
 
-	cacheAdapter := cache.NewInMemory()
-	app := app.NewApp(fakeLLM, largeGit, cacheAdapter, 100, true) // Small cap
+	sent := fakeLLM.LastInput.StagedDiff
+	if strings.Contains(sent, "logo.png") {
+		t.Errorf("binary file section was sent to the LLM:\n%s", sent)
+	}
+	if !strings.Contains(sent, "diff --git a/main.go b/main.go") {
+		t.Errorf("source file section missing from diff:\n%s", sent)
+	}
+	if !strings.Contains(sent, "(1 binary file omitted)") {
+		t.Errorf("omission note missing from diff:\n%s", sent)
+	}
+}
+
+func TestSuggestPassesSubmoduleLog(t *testing.T) {
+	fakeLLM := &testutil.FakeLLM{Suggestions: testutil.SampleLLMResponse()}
+	fakeGit := &testutil.FakeGit{
+		StagedDiffContent: testutil.SampleDiffSubmoduleLog,
+		IsInRepoValue:     true,
+	}
+
+	app := app.NewApp(fakeLLM, fakeGit, cache.NewInMemory(), 8192, false)
+	if _, err := app.Suggest.SuggestCommits(context.Background(), "openai", "gpt-4o-mini", 0.7); err != nil {
+		t.Fatalf("SuggestCommits failed: %v", err)
+	}
+
+	sent := fakeLLM.LastInput.StagedDiff
+	for _, want := range []string{"Submodule vendor/parser 1a2b3c4..5d6e7f8:", "> feat: support nested lists", "diff --git a/main.go b/main.go"} {
+		if !strings.Contains(sent, want) {
+			t.Errorf("diff sent to LLM missing %q:\n%s", want, sent)
+		}
+	}
+}
 
+func TestSuggestRetriesEmptyOutput(t *testing.T) {
+	fakeGit := &testutil.FakeGit{
+		StagedDiffContent: testutil.SampleDiffSmall,
+		IsInRepoValue:     true,
+	}
+	emptyOnce := func() *testutil.FakeLLM {
+		return &testutil.FakeLLM{
+			Suggestions: testutil.SampleLLMResponse(),
+			Errs:        []error{fmt.Errorf("groq returned %w", ports.ErrEmptyOutput)},
+		}
+	}
 	ctx := context.Background()
-	_, err := app.Suggest.SuggestCommits(ctx, "openai", "gpt-4o-mini", 0.7)
 
-	// Should still work, but diff will be capped
+	// Opt-in: one regeneration with a nudged temperature succeeds.
+	fakeLLM := emptyOnce()
+	a := app.NewApp(fakeLLM, fakeGit, cache.NewInMemory(), 8192, false)
+	a.Suggest.SetRetryEmpty(true)
+	suggestions, err := a.Suggest.SuggestCommits(ctx, "groq", "llama", 0.5)
 	if err != nil {
-		t.Fatalf("Expected success with capped diff, got error: %v", err)
+		t.Fatalf("SuggestCommits failed: %v", err)
+	}
+	if len(suggestions) != 3 {
+		t.Errorf("Expected 3 suggestions, got %d", len(suggestions))
+	}
+	if fakeLLM.CallCount != 2 {
+		t.Errorf("Expected 2 LLM calls (one retry), got %d", fakeLLM.CallCount)
+	}
+	if fakeLLM.LastInput.Temperature <= 0.5 {
+		t.Errorf("retry temperature = %v, want above 0.5", fakeLLM.LastInput.Temperature)
+	}
+
+	// Default: the empty output is reported without retrying.
+	fakeLLM = emptyOnce()
+	a = app.NewApp(fakeLLM, fakeGit, cache.NewInMemory(), 8192, false)
+	if _, err := a.Suggest.SuggestCommits(ctx, "groq", "llama", 0.5); !errors.Is(err, ports.ErrEmptyOutput) {
+		t.Errorf("error = %v, want ErrEmptyOutput", err)
+	}
+	if fakeLLM.CallCount != 1 {
+		t.Errorf("Expected 1 LLM call without --retry-empty, got %d", fakeLLM.CallCount)
+	}
+}
+
+func TestRepeatedFailureSurfacesHint(t *testing.T) {
+	fakeGit := &testutil.FakeGit{
+		StagedDiffContent: testutil.SampleDiffSmall,
+		IsInRepoValue:     true,
+	}
+	statePath := filepath.Join(t.TempDir(), "failures.json")
+	ctx := context.Background()
+
+	// Each run gets a fresh app and store, like separate invocations.
+	run := func(llm *testutil.FakeLLM) error {
+		a := app.NewApp(llm, fakeGit, cache.NewInMemory(), 8192, false)
+		a.Suggest.SetFailureNotes(notes.NewFailures(statePath))
+		_, err := a.Suggest.SuggestCommits(ctx, "ollama", "llama3", 0.5)
+		return err
+	}
+	parseErr := fmt.Errorf("%w: unexpected end of JSON input", ports.ErrInvalidJSON)
+
+	err := run(&testutil.FakeLLM{Err: parseErr})
+	if err == nil || strings.Contains(err.Error(), "hint:") {
+		t.Fatalf("first failure = %v, want an error without a hint", err)
+	}
+
+	err = run(&testutil.FakeLLM{Err: parseErr})
+	if !errors.Is(err, ports.ErrInvalidJSON) {
+		t.Fatalf("second failure = %v, want ErrInvalidJSON", err)
+	}
+	if !strings.HasPrefix(err.Error(), "hint: this model has returned non-JSON before") {
+		t.Errorf("second failure = %q, want the stored hint first", err.Error())
+	}
+
+	// A success clears the note, so the next failure starts fresh.
+	if err := run(&testutil.FakeLLM{Suggestions: testutil.SampleLLMResponse()}); err != nil {
+		t.Fatalf("SuggestCommits failed: %v", err)
+	}
+	err = run(&testutil.FakeLLM{Err: parseErr})
+	if err == nil || strings.Contains(err.Error(), "hint:") {
+		t.Errorf("failure after success = %v, want no hint", err)
+	}
+}
+
+func TestSuggestFewShotExamples(t *testing.T) {
+	fakeGit := &testutil.FakeGit{
+		StagedDiffContent: testutil.SampleDiffSmall,
+		IsInRepoValue:     true,
+	}
+	examples := []string{
+		"feat(parser): support nested lists\n\nRefs: PAR-12",
+		"fix(cli): exit 2 on unknown flags",
+	}
+	ctx := context.Background()
+
+	fakeLLM := &testutil.FakeLLM{Suggestions: testutil.SampleLLMResponse()}
+	a := app.NewApp(fakeLLM, fakeGit, cache.NewInMemory(), 8192, false)
+	a.Suggest.SetExamples(examples)
+	if _, err := a.Suggest.SuggestCommits(ctx, "openai", "gpt-4o-mini", 0.7); err != nil {
+		t.Fatalf("SuggestCommits failed: %v", err)
+	}
+	if len(fakeLLM.LastInput.Examples) != 2 {
+		t.Errorf("Examples = %q, want both examples", fakeLLM.LastInput.Examples)
+	}
+
+	// A tight diff cap leaves no room for examples.
+	fakeLLM = &testutil.FakeLLM{Suggestions: testutil.SampleLLMResponse()}
+	a = app.NewApp(fakeLLM, fakeGit, cache.NewInMemory(), 120, false)
+	a.Suggest.SetExamples(examples)
+	if _, err := a.Suggest.SuggestCommits(ctx, "openai", "gpt-4o-mini", 0.7); err != nil {
+		t.Fatalf("SuggestCommits failed: %v", err)
+	}
+	if len(fakeLLM.LastInput.Examples) != 0 {
+		t.Errorf("Examples = %q, want none under a tight budget", fakeLLM.LastInput.Examples)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	fakeGit := &testutil.FakeGit{
+		StagedDiffContent: testutil.SampleDiffWithBinary,
+		IsInRepoValue:     true,
+		Branch:            "feature/summary",
+	}
+	app := app.NewApp(&testutil.FakeLLM{}, fakeGit, cache.NewInMemory(), 8192, false)
+
+	summary, err := app.Suggest.Summarize(context.Background(), domain.Suggestion{Type: "feature", Subject: "add greeting"})
+	if err != nil {
+		t.Fatalf("Summarize failed: %v", err)
+	}
+	if summary.Branch != "feature/summary" {
+		t.Errorf("Branch = %q, want feature/summary", summary.Branch)
+	}
+	if len(summary.Files) != 2 {
+		t.Fatalf("Files = %+v, want 2 entries", summary.Files)
+	}
+	if !summary.Files[0].Binary || summary.Files[0].Path != "logo.png" {
+		t.Errorf("Files[0] = %+v, want binary logo.png", summary.Files[0])
+	}
+	if f := summary.Files[1]; f.Path != "main.go" || f.Added != 3 || f.Deleted != 1 {
+		t.Errorf("Files[1] = %+v, want main.go +3 -1", f)
+	}
+	if len(summary.Warnings) == 0 {
+		t.Error("expected a lint warning for the invalid type")
+	}
+}
+
+func TestSummarizeMatchesSuggestedDiff(t *testing.T) {
+	fakeGit := &testutil.FakeGit{
+		StagedDiffContent: testutil.SampleDiffWithBinary,
+		PathDiffs:         map[string]string{"main.go": testutil.SampleDiffSmall},
+		IsInRepoValue:     true,
+	}
+	a := app.NewApp(&testutil.FakeLLM{}, fakeGit, cache.NewInMemory(), 8192, false)
+	ctx := context.Background()
+	suggestion := domain.Suggestion{Type: "feat", Subject: "add greeting"}
+	paths := func(summary *app.CommitSummary) string {
+		var names []string
+		for _, f := range summary.Files {
+			names = append(names, f.Path)
+		}
+		return strings.Join(names, ",")
+	}
+
+	a.Suggest.SetPaths([]string{"main.go"})
+	summary, err := a.Suggest.Summarize(ctx, suggestion)
+	if err != nil {
+		t.Fatalf("Summarize failed: %v", err)
+	}
+	if got := paths(summary); got != "main.go" {
+		t.Errorf("Files with paths set = %q, want main.go", got)
+	}
+
+	a.Suggest.SetPaths(nil)
+	if err := a.Suggest.SetExclude([]string{"*.png"}); err != nil {
+		t.Fatal(err)
+	}
+	if summary, err = a.Suggest.Summarize(ctx, suggestion); err != nil {
+		t.Fatalf("Summarize failed: %v", err)
+	}
+	if got := paths(summary); got != "main.go" {
+		t.Errorf("Files with *.png excluded = %q, want main.go", got)
+	}
+
+	fakeGit.StagedDiffContent = ""
+	fakeGit.WorkingTreeDiffContent = testutil.SampleDiffSmall
+	a.Suggest.SetIncludeUnstaged(true)
+	if summary, err = a.Suggest.Summarize(ctx, suggestion); err != nil {
+		t.Fatalf("Summarize failed: %v", err)
+	}
+	if got := paths(summary); got != "main.go" {
+		t.Errorf("Files from the working tree = %q, want main.go", got)
+	}
+}
+
+func TestSuggestNoStagedChanges(t *testing.T) {
+	fakeLLM := &testutil.FakeLLM{
+		Suggestions: testutil.SampleLLMResponse(),
+	}
+
+	fakeGit := &testutil.FakeGit{
+		StagedDiffContent: "", // Empty diff
+		IsInRepoValue:     true,
+	}
+
+	cacheAdapter := cache.NewInMemory()
+	app := app.NewApp(fakeLLM, fakeGit, cacheAdapter, 8192, true)
+
+	ctx := context.Background()
+	_, err := app.Suggest.SuggestCommits(ctx, "openai", "gpt-4o-mini", 0.7)
+
+	if err == nil {
+		t.Error("Expected error for empty staged diff")
+	}
+}
+
+func TestSuggestNotInRepo(t *testing.T) {
+	fakeLLM := &testutil.FakeLLM{
+		Suggestions: testutil.SampleLLMResponse(),
+	}
+
+	fakeGit := &testutil.FakeGit{
+		StagedDiffContent: testutil.SampleDiffSmall,
+		IsInRepoValue:     false, // Not in repo
+	}
+
+	cacheAdapter := cache.NewInMemory()
+	app := app.NewApp(fakeLLM, fakeGit, cacheAdapter, 8192, true)
+
+	ctx := context.Background()
+	_, err := app.Suggest.SuggestCommits(ctx, "openai", "gpt-4o-mini", 0.7)
+
+	if err == nil {
+		t.Error("Expected error when not in repository")
+	}
+}
+
+func TestCommitWorkflow(t *testing.T) {
+	fakeGit := &testutil.FakeGit{
+		IsInRepoValue: true,
+	}
+
+	commitService := app.NewCommitService(fakeGit)
+
+	ctx := context.Background()
+	message := "feat: add new feature"
+
+	hash, err := commitService.Commit(ctx, message, ports.CommitOptions{})
+	if err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	if hash == "" {
+		t.Error("Expected non-empty commit hash")
+	}
+
+	if len(fakeGit.CommittedMessages) != 1 {
+		t.Errorf("Expected 1 committed message, got %d", len(fakeGit.CommittedMessages))
+	}
+
+	if fakeGit.CommittedMessages[0] != message {
+		t.Errorf("Committed message mismatch: got %s, want %s", fakeGit.CommittedMessages[0], message)
+	}
+}
+
+func TestCommitReportsHeadHash(t *testing.T) {
+	fakeGit := &testutil.FakeGit{IsInRepoValue: true, HeadHashValue: "1a2b3c4"}
+	commitService := app.NewCommitService(fakeGit)
+
+	hash, err := commitService.Commit(context.Background(), "feat: add new feature", ports.CommitOptions{})
+	if err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	if hash != "1a2b3c4" || fakeGit.HeadHashCalls != 1 {
+		t.Errorf("hash = %q after %d rev-parse calls, want 1a2b3c4 from one call", hash, fakeGit.HeadHashCalls)
+	}
+
+	// When rev-parse fails, the hash parsed from git commit is used.
+	fakeGit.HeadHashErr = errors.New("rev-parse failed")
+	hash, err = commitService.Commit(context.Background(), "feat: another", ports.CommitOptions{})
+	if err != nil || hash != "abc123def456" {
+		t.Errorf("Commit() = %q, %v; want the parsed hash as fallback", hash, err)
+	}
+
+	// A dry run doesn't ask for HEAD.
+	fakeGit.HeadHashCalls = 0
+	if _, err := commitService.Commit(context.Background(), "feat: dry", ports.CommitOptions{DryRun: true}); err != nil || fakeGit.HeadHashCalls != 0 {
+		t.Errorf("dry run: err = %v, rev-parse calls = %d; want none", err, fakeGit.HeadHashCalls)
+	}
+}
+
+func TestCommitOnlyPaths(t *testing.T) {
+	fakeGit := &testutil.FakeGit{IsInRepoValue: true, StagedFiles: []string{"main.go", "README.md", "go.mod"}}
+	commitService := app.NewCommitService(fakeGit)
+
+	paths := []string{"main.go", "go.mod"}
+	if _, err := commitService.Commit(context.Background(), "feat: add flag", ports.CommitOptions{Paths: paths}); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	if got := fakeGit.LastCommitOptions.Paths; !slices.Equal(got, paths) {
+		t.Errorf("paths sent to git = %v, want %v", got, paths)
+	}
+	if !slices.Equal(fakeGit.StagedFiles, []string{"README.md"}) {
+		t.Errorf("still staged = %v, want the unlisted README.md", fakeGit.StagedFiles)
+	}
+}
+
+func TestStageAllBeforeSuggesting(t *testing.T) {
+	ctx := context.Background()
+	fakeLLM := &testutil.FakeLLM{Suggestions: testutil.SampleLLMResponse()}
+	fakeGit := &testutil.FakeGit{WorkingTreeDiffContent: testutil.SampleDiffSmall, IsInRepoValue: true}
+	a := app.NewApp(fakeLLM, fakeGit, cache.NewInMemory(), 8192, false)
+
+	staged, err := a.Commit.StageAllIfNothingStaged(ctx)
+	if err != nil || !staged || fakeGit.StageAllCalls != 1 {
+		t.Fatalf("StageAllIfNothingStaged() = %v, %v after %d calls; want one git add -A", staged, err, fakeGit.StageAllCalls)
+	}
+	// The suggestion reads the index after staging, not the working tree.
+	result, err := a.Suggest.SuggestCommitsDetailed(ctx, "openai", "gpt-4o-mini", 0.7)
+	if err != nil || result.Unstaged || fakeLLM.LastInput.StagedDiff == "" {
+		t.Fatalf("SuggestCommitsDetailed() = %+v, %v; want suggestions from the newly staged diff", result, err)
+	}
+
+	// Something staged already: selective staging is left alone.
+	fakeGit.WorkingTreeDiffContent = "diff --git a/other.go b/other.go\n"
+	if staged, err := a.Commit.StageAllIfNothingStaged(ctx); err != nil || staged || fakeGit.StageAllCalls != 1 {
+		t.Errorf("StageAllIfNothingStaged() with a staged diff = %v, %v after %d calls; want no git add", staged, err, fakeGit.StageAllCalls)
+	}
+}
+
+func TestUndoResetsLastCommit(t *testing.T) {
+	fakeGit := &testutil.FakeGit{IsInRepoValue: true, HeadHashValue: "1a2b3c4"}
+	commitService := app.NewCommitService(fakeGit)
+
+	hash, err := commitService.Commit(context.Background(), "feat: add new feature", ports.CommitOptions{})
+	if err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	if err := commitService.Undo(context.Background(), hash); err != nil {
+		t.Fatalf("Undo() error = %v", err)
+	}
+	if fakeGit.UndoCalls != 1 || len(fakeGit.CommittedMessages) != 0 {
+		t.Errorf("reset calls = %d, commits = %v; want one reset and no commit left", fakeGit.UndoCalls, fakeGit.CommittedMessages)
+	}
+
+	// Someone else committed since: HEAD no longer matches, so no reset.
+	fakeGit.HeadHashValue = "9f8e7d6"
+	if err := commitService.Undo(context.Background(), hash); err == nil || fakeGit.UndoCalls != 1 {
+		t.Errorf("Undo() with HEAD moved = %v after %d resets; want an error and no new reset", err, fakeGit.UndoCalls)
+	}
+}
+
+func TestCommitDryRun(t *testing.T) {
+	fakeGit := &testutil.FakeGit{
+		IsInRepoValue: true,
+	}
+
+	commitService := app.NewCommitService(fakeGit)
+
+	ctx := context.Background()
+	message := "feat: add new feature"
+
+	_, err := commitService.Commit(ctx, message, ports.CommitOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Dry-run commit failed: %v", err)
+	}
+
+	if len(fakeGit.CommittedMessages) != 0 {
+		t.Error("Expected no commits in dry-run mode")
+	}
+}
+
+func TestCommitEmptyMessage(t *testing.T) {
+	fakeGit := &testutil.FakeGit{
+		IsInRepoValue: true,
+	}
+
+	commitService := app.NewCommitService(fakeGit)
+
+	ctx := context.Background()
+	_, err := commitService.Commit(ctx, "", ports.CommitOptions{})
+
+	if err == nil {
+		t.Error("Expected error for empty commit message")
+	}
+}
+
+func TestSuggestionValidationInOrchestrator(t *testing.T) {
+	// Test that invalid LLM responses are rejected
+	fakeLLM := &testutil.FakeLLM{
+		Suggestions: []ports.CommitSuggestion{
+			{
+				Type:    "invalid_type",
+				Subject: "bad suggestion",
+				Body:    "",
+				Footer:  "",
+			},
+		},
+	}
+
+	fakeGit := &testutil.FakeGit{
+		StagedDiffContent: testutil.SampleDiffSmall,
+		IsInRepoValue:     true,
+	}
+
+	cacheAdapter := cache.NewInMemory()
+	app := app.NewApp(fakeLLM, fakeGit, cacheAdapter, 8192, true)
+
+	ctx := context.Background()
+	_, err := app.Suggest.SuggestCommits(ctx, "openai", "gpt-4o-mini", 0.7)
+
+	if err == nil {
+		t.Error("Expected error for invalid suggestion type")
+	}
+}
+
+func TestDiffCapcing(t *testing.T) {
+	// Test that large diffs are capped
+	largeGit := &testutil.FakeGit{
+		StagedDiffContent: testutil.SampleDiffLarge,
+		IsInRepoValue:     true,
+	}
+
+	fakeLLM := &testutil.FakeLLM{
+		Suggestions: testutil.SampleLLMResponse(),
+	}
+
+	cacheAdapter := cache.NewInMemory()
+	app := app.NewApp(fakeLLM, largeGit, cacheAdapter, 100, true) // Small cap
+
+	ctx := context.Background()
+	_, err := app.Suggest.SuggestCommits(ctx, "openai", "gpt-4o-mini", 0.7)
+
+	// Should still work, but diff will be capped
+	if err != nil {
+		t.Fatalf("Expected success with capped diff, got error: %v", err)
+	}
+}
+
+func TestDiffCapKeepsEveryFileHeader(t *testing.T) {
+	diff := testutil.SampleDiffManyFiles(20)
+	fakeGit := &testutil.FakeGit{StagedDiffContent: diff, IsInRepoValue: true}
+	fakeLLM := &testutil.FakeLLM{Suggestions: testutil.SampleLLMResponse()}
+	a := app.NewApp(fakeLLM, fakeGit, cache.NewInMemory(), 200, true) // far below the header total
+
+	if _, err := a.Suggest.SuggestCommitsDetailed(context.Background(), "mock", "m", 0.5); err != nil {
+		t.Fatalf("SuggestCommitsDetailed failed: %v", err)
+	}
+	sent := fakeLLM.LastInput.StagedDiff
+	for i := 0; i < 20; i++ {
+		header := fmt.Sprintf("diff --git a/pkg/file%03d.go b/pkg/file%03d.go\n", i, i)
+		if !strings.Contains(sent, header) {
+			t.Errorf("capped diff lost %q", header)
+		}
+	}
+	if !strings.HasSuffix(sent, "[20 more files truncated]\n") {
+		t.Errorf("capped diff should end with a truncation note:\n%s", sent)
+	}
+	prepared, err := a.Suggest.PrepareDiff(context.Background(), "mock", "m", 0.7)
+	if err != nil {
+		t.Fatalf("PrepareDiff failed: %v", err)
+	}
+	if !prepared.Truncated {
+		t.Error("Truncated = false, want true")
+	}
+	if len(fakeLLM.LastInput.FileList) != 20 {
+		t.Errorf("FileList has %d entries, want 20", len(fakeLLM.LastInput.FileList))
+	}
+
+	// With room to spare, small files are kept whole and the budget goes to
+	// the large ones.
+	fakeLLM = &testutil.FakeLLM{Suggestions: testutil.SampleLLMResponse()}
+	a = app.NewApp(fakeLLM, fakeGit, cache.NewInMemory(), len(diff)*3/4, true)
+	if _, err := a.Suggest.SuggestCommitsDetailed(context.Background(), "mock", "m", 0.5); err != nil {
+		t.Fatalf("SuggestCommitsDetailed failed: %v", err)
+	}
+	sent = fakeLLM.LastInput.StagedDiff
+	if !strings.Contains(sent, "+++ b/pkg/file000.go\n@@ -0,0 +1,1 @@\n+// line\n") {
+		t.Error("the smallest file should survive intact")
+	}
+	if len(sent) > len(diff)*3/4 {
+		t.Errorf("capped diff is %d bytes, want at most %d", len(sent), len(diff)*3/4)
+	}
+}
+
+func TestSuggestReportsTruncation(t *testing.T) {
+	fakeGit := &testutil.FakeGit{StagedDiffContent: testutil.SampleDiffLarge, IsInRepoValue: true}
+	fakeLLM := &testutil.FakeLLM{Suggestions: testutil.SampleLLMResponse()}
+	a := app.NewApp(fakeLLM, fakeGit, cache.NewInMemory(), 4096, true)
+
+	result, err := a.Suggest.SuggestCommitsDetailed(context.Background(), "mock", "m", 0.5)
+	if err != nil {
+		t.Fatalf("SuggestCommitsDetailed failed: %v", err)
+	}
+	if !result.Truncated || result.OriginalBytes != len(testutil.SampleDiffLarge) || result.SentBytes != len(fakeLLM.LastInput.StagedDiff) {
+		t.Errorf("Truncated/OriginalBytes/SentBytes = %t/%d/%d, want true/%d/%d",
+			result.Truncated, result.OriginalBytes, result.SentBytes, len(testutil.SampleDiffLarge), len(fakeLLM.LastInput.StagedDiff))
+	}
+	if want := "diff truncated: sent 4 KB of 10 KB"; result.TruncationNote() != want {
+		t.Errorf("TruncationNote() = %q, want %q", result.TruncationNote(), want)
+	}
+
+	// Cache hits carry the same metadata.
+	cached, err := a.Suggest.SuggestCommitsDetailed(context.Background(), "mock", "m", 0.5)
+	if err != nil {
+		t.Fatalf("SuggestCommitsDetailed (cached) failed: %v", err)
+	}
+	if !cached.Truncated || cached.SentBytes != result.SentBytes {
+		t.Errorf("cached result lost truncation metadata: %+v", cached)
+	}
+
+	small := app.NewApp(fakeLLM, &testutil.FakeGit{StagedDiffContent: testutil.SampleDiffSmall, IsInRepoValue: true}, cache.NewInMemory(), 8192, false)
+	result, err = small.Suggest.SuggestCommitsDetailed(context.Background(), "mock", "m", 0.5)
+	if err != nil {
+		t.Fatalf("SuggestCommitsDetailed failed: %v", err)
+	}
+	if result.Truncated || result.TruncationNote() != "" {
+		t.Errorf("small diff reported as truncated: %q", result.TruncationNote())
+	}
+}
+
+// clampingLLM is a FakeLLM that caps temperature like the Groq client.
+type clampingLLM struct {
+	*testutil.FakeLLM
+	max float32
+}
+
+func (c clampingLLM) EffectiveTemperature(requested float32) float32 {
+	if requested > c.max {
+		return c.max
+	}
+	return requested
+}
+
+func TestSuggestWarnsWhenTemperatureClamped(t *testing.T) {
+	fakeGit := &testutil.FakeGit{
+		StagedDiffContent: testutil.SampleDiffSmall,
+		IsInRepoValue:     true,
+	}
+	llm := clampingLLM{FakeLLM: &testutil.FakeLLM{Suggestions: testutil.SampleLLMResponse()}, max: 0.2}
+	a := app.NewApp(llm, fakeGit, cache.NewInMemory(), 8192, false)
+	ctx := context.Background()
+
+	result, err := a.Suggest.SuggestCommitsDetailed(ctx, "groq", "llama", 0.8)
+	if err != nil {
+		t.Fatalf("SuggestCommitsDetailed failed: %v", err)
+	}
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "temperature 0.80 to 0.20") {
+		t.Errorf("Warnings = %q, want a clamp warning", result.Warnings)
+	}
+
+	result, err = a.Suggest.SuggestCommitsDetailed(ctx, "groq", "llama", 0.1)
+	if err != nil {
+		t.Fatalf("SuggestCommitsDetailed failed: %v", err)
+	}
+	if len(result.Warnings) != 0 {
+		t.Errorf("Warnings = %q, want none below the clamp", result.Warnings)
+	}
+}
+
+func TestPrepareDiffDoesNotCallLLM(t *testing.T) {
+	fakeGit := &testutil.FakeGit{
+		StagedDiffContent: testutil.SampleDiffWithBinary,
+		IsInRepoValue:     true,
+	}
+	fakeLLM := &testutil.FakeLLM{Suggestions: testutil.SampleLLMResponse()}
+	memCache := cache.NewInMemory()
+	a := app.NewApp(fakeLLM, fakeGit, memCache, 8192, true)
+	ctx := context.Background()
+
+	prepared, err := a.Suggest.PrepareDiff(ctx, "openai", "gpt-4o-mini", 0.7)
+	if err != nil {
+		t.Fatalf("PrepareDiff failed: %v", err)
+	}
+	if fakeLLM.CallCount != 0 {
+		t.Errorf("PrepareDiff made %d LLM call(s), want 0", fakeLLM.CallCount)
+	}
+	if prepared.CacheKey == "" || prepared.Bytes != len(prepared.Diff) || prepared.Bytes == 0 {
+		t.Errorf("prepared = %+v, want a cache key and byte size", prepared)
+	}
+	if prepared.BinaryOmitted != 1 {
+		t.Errorf("BinaryOmitted = %d, want 1", prepared.BinaryOmitted)
+	}
+
+	// The previewed key is the one a real run caches under.
+	if _, err := a.Suggest.SuggestCommits(ctx, "openai", "gpt-4o-mini", 0.7); err != nil {
+		t.Fatalf("SuggestCommits failed: %v", err)
+	}
+	if _, err := memCache.Get(ctx, prepared.CacheKey); err != nil {
+		t.Errorf("cache has no entry for previewed key: %v", err)
+	}
+}
+
+func TestCacheKeyIsScopedToRepoRoot(t *testing.T) {
+	ctx := context.Background()
+	keyFor := func(root string) string {
+		fakeGit := &testutil.FakeGit{
+			StagedDiffContent: testutil.SampleDiffSmall,
+			IsInRepoValue:     true,
+			RootDirValue:      root,
+		}
+		a := app.NewApp(&testutil.FakeLLM{}, fakeGit, cache.NewInMemory(), 8192, true)
+		prepared, err := a.Suggest.PrepareDiff(ctx, "openai", "gpt-4o-mini", 0.7)
+		if err != nil {
+			t.Fatalf("PrepareDiff failed: %v", err)
+		}
+		return prepared.CacheKey
+	}
+
+	if keyFor("/src/service-a") == keyFor("/src/service-b") {
+		t.Error("identical diffs in different repositories share a cache key")
+	}
+	if keyFor("/src/service-a") != keyFor("/src/service-a") {
+		t.Error("cache key is not stable for the same repository")
+	}
+}
+
+func TestCustomRedactPatternsApplyToPrompt(t *testing.T) {
+	fakeGit := &testutil.FakeGit{
+		StagedDiffContent: "diff --git a/app.env b/app.env\n--- a/app.env\n+++ b/app.env\n@@ -0,0 +1 @@\n+ACME_TOKEN=acme_sk_0123456789abcdef\n",
+		IsInRepoValue:     true,
+	}
+	redactor, err := security.NewRedactorWithPatterns([]string{`acme_sk_[a-z0-9]{16}`})
+	if err != nil {
+		t.Fatalf("NewRedactorWithPatterns failed: %v", err)
+	}
+	fakeLLM := &testutil.FakeLLM{Suggestions: testutil.SampleLLMResponse()}
+	a := app.NewAppWithRedactor(fakeLLM, fakeGit, cache.NewInMemory(), 8192, false, redactor)
+
+	if _, err := a.Suggest.SuggestCommits(context.Background(), "openai", "gpt-4o-mini", 0.7); err != nil {
+		t.Fatalf("SuggestCommits failed: %v", err)
+	}
+	if strings.Contains(fakeLLM.LastInput.StagedDiff, "acme_sk_") {
+		t.Errorf("custom secret reached the provider:\n%s", fakeLLM.LastInput.StagedDiff)
+	}
+}
+
+func TestRegenerateBodyKeepsSubject(t *testing.T) {
+	fakeGit := &testutil.FakeGit{
+		StagedDiffContent: testutil.SampleDiffSmall,
+		IsInRepoValue:     true,
+	}
+	// The fake ignores the lock and also proposes a new subject; only its
+	// body and footer may be used.
+	fakeLLM := &testutil.FakeLLM{Suggestions: []ports.CommitSuggestion{{
+		Type:    "fix",
+		Subject: "something else entirely",
+		Body:    "Explain the greeting and why it is printed at startup.",
+		Footer:  "Refs: #42",
+	}}}
+	a := app.NewApp(fakeLLM, fakeGit, cache.NewInMemory(), 8192, false)
+	a.Suggest.SetPromptTemplate("Describe {{.Diff}} as JSON.")
+	a.Suggest.SetSubjectLimits(domain.SubjectLimits{Max: 50})
+	chosen := domain.Suggestion{Type: "feat", Subject: "add greeting", Body: "Old body."}
+
+	got, err := a.Suggest.RegenerateBody(context.Background(), "openai", "gpt-4o-mini", 0.7, chosen)
+	if err != nil {
+		t.Fatalf("RegenerateBody failed: %v", err)
+	}
+	want := domain.Suggestion{
+		Type:    "feat",
+		Subject: "add greeting",
+		Body:    "Explain the greeting and why it is printed at startup.",
+		Footer:  "Refs: #42",
+	}
+	if got != want {
+		t.Errorf("RegenerateBody() = %+v, want %+v", got, want)
+	}
+	in := fakeLLM.LastInput
+	if in.LockedType != "feat" || in.LockedSubject != "add greeting" || in.WantCount() != 1 {
+		t.Errorf("provider input = type %q subject %q count %d, want the locked header and 1", in.LockedType, in.LockedSubject, in.WantCount())
+	}
+	if in.PromptTemplate != "Describe {{.Diff}} as JSON." || in.SubjectMaxLen != 50 {
+		t.Errorf("provider input = template %q, subject limit %d; want the service's", in.PromptTemplate, in.SubjectMaxLen)
+	}
+}
+
+func TestDetachedHead(t *testing.T) {
+	ctx := context.Background()
+	for _, tt := range []struct {
+		git  *testutil.FakeGit
+		want bool
+	}{
+		{&testutil.FakeGit{Branch: "HEAD"}, true},
+		{&testutil.FakeGit{Branch: "main"}, false},
+		{&testutil.FakeGit{BranchErr: errors.New("unborn HEAD")}, false},
+	} {
+		a := app.NewApp(&testutil.FakeLLM{}, tt.git, cache.NewInMemory(), 8192, false)
+		if got := a.Commit.DetachedHead(ctx); got != tt.want {
+			t.Errorf("DetachedHead() with branch %q err %v = %v, want %v", tt.git.Branch, tt.git.BranchErr, got, tt.want)
+		}
+	}
+}
+
+func TestSuggestFlagsRedactedSecrets(t *testing.T) {
+	ctx := context.Background()
+	withSecret := "diff --git a/config.go b/config.go\n--- a/config.go\n+++ b/config.go\n@@ -1 +1,2 @@\n package config\n+const key = \"sk-abcdefghijklmnopqrstuvwxyz123456\"\n"
+
+	for diff, want := range map[string]bool{withSecret: true, testutil.SampleDiffSmall: false} {
+		fakeGit := &testutil.FakeGit{StagedDiffContent: diff, IsInRepoValue: true}
+		fakeLLM := &testutil.FakeLLM{Suggestions: testutil.SampleLLMResponse()}
+		a := app.NewApp(fakeLLM, fakeGit, cache.NewInMemory(), 8192, false)
+
+		result, err := a.Suggest.SuggestCommitsDetailed(ctx, "openai", "gpt-4o-mini", 0.7)
+		if err != nil {
+			t.Fatalf("SuggestCommitsDetailed failed: %v", err)
+		}
+		if result.Redacted != want {
+			t.Errorf("Redacted = %v, want %v for diff:\n%s", result.Redacted, want, diff)
+		}
+		if strings.Contains(fakeLLM.LastInput.StagedDiff, "sk-abcdef") {
+			t.Error("secret reached the provider")
+		}
+	}
+}
+
+func TestSuggestSummarizesDiffsWithManyFiles(t *testing.T) {
+	ctx := context.Background()
+	run := func(diff string) (*app.SuggestResult, *testutil.FakeLLM) {
+		t.Helper()
+		fakeGit := &testutil.FakeGit{StagedDiffContent: diff, IsInRepoValue: true}
+		fakeLLM := &testutil.FakeLLM{Suggestions: testutil.SampleLLMResponse()}
+		a := app.NewApp(fakeLLM, fakeGit, cache.NewInMemory(), 1<<20, false)
+		a.Suggest.SetMaxFiles(100)
+		result, err := a.Suggest.SuggestCommitsDetailed(ctx, "openai", "gpt-4o-mini", 0.7)
+		if err != nil {
+			t.Fatalf("SuggestCommitsDetailed failed: %v", err)
+		}
+		return result, fakeLLM
+	}
+
+	result, fakeLLM := run(testutil.SampleDiffManyFiles(300))
+	sent := fakeLLM.LastInput.StagedDiff
+	if !strings.HasPrefix(sent, "(300 files changed; showing a stat summary and the 10 largest files)\n") {
+		t.Errorf("300-file diff was not summarized:\n%.300s", sent)
+	}
+	if !strings.Contains(sent, " pkg/file000.go | +1 -0\n") {
+		t.Error("stat summary should list every file")
+	}
+	if !strings.Contains(sent, "diff --git a/pkg/file299.go") || strings.Contains(sent, "diff --git a/pkg/file000.go") {
+		t.Error("summary should keep full sections only for the largest files")
+	}
+	if len(fakeLLM.LastInput.FileList) != 300 {
+		t.Errorf("FileList has %d entries, want 300", len(fakeLLM.LastInput.FileList))
+	}
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "300 files changed") {
+		t.Errorf("Warnings = %q, want a summarization note", result.Warnings)
+	}
+
+	result, fakeLLM = run(testutil.SampleDiffManyFiles(2))
+	if fakeLLM.LastInput.StagedDiff != testutil.SampleDiffManyFiles(2) || len(result.Warnings) != 0 {
+		t.Errorf("2-file diff should be sent unchanged; warnings %q", result.Warnings)
+	}
+}
+
+func TestSuggestFallsBackToWorkingTree(t *testing.T) {
+	fakeGit := &testutil.FakeGit{
+		WorkingTreeDiffContent: testutil.SampleDiffSmall,
+		IsInRepoValue:          true,
+	}
+	fakeLLM := &testutil.FakeLLM{Suggestions: testutil.SampleLLMResponse()}
+	a := app.NewApp(fakeLLM, fakeGit, cache.NewInMemory(), 8192, false)
+	ctx := context.Background()
+
+	// Off by default: an empty index is still an error.
+	if _, err := a.Suggest.SuggestCommitsDetailed(ctx, "mock", "m", 0.5); !errors.Is(err, app.ErrNoStagedChanges) {
+		t.Fatalf("error = %v, want ErrNoStagedChanges", err)
+	}
+
+	a.Suggest.SetIncludeUnstaged(true)
+	result, err := a.Suggest.SuggestCommitsDetailed(ctx, "mock", "m", 0.5)
+	if err != nil {
+		t.Fatalf("SuggestCommitsDetailed failed: %v", err)
+	}
+	if !result.Unstaged {
+		t.Error("Unstaged = false, want true when the working tree was used")
+	}
+	if fakeLLM.LastInput.StagedDiff != testutil.SampleDiffSmall {
+		t.Errorf("LLM got %q, want the working-tree diff", fakeLLM.LastInput.StagedDiff)
+	}
+
+	// Staged changes always win over the working tree.
+	fakeGit.StagedDiffContent = testutil.SampleDiffManyFiles(2)
+	result, err = a.Suggest.SuggestCommitsDetailed(ctx, "mock", "m", 0.5)
+	if err != nil {
+		t.Fatalf("SuggestCommitsDetailed failed: %v", err)
+	}
+	if result.Unstaged {
+		t.Error("Unstaged = true, want false when changes are staged")
+	}
+}
+
+func TestFixupMessage(t *testing.T) {
+	fakeGit := &testutil.FakeGit{
+		IsInRepoValue: true,
+		Subjects: map[string]string{
+			"abc123": "feat(parser): support nested lists",
+			"HEAD~1": "fixup! squash! fix: handle empty input",
+			"HEAD~2": "",
+		},
+	}
+	commit := app.NewCommitService(fakeGit)
+	ctx := context.Background()
+
+	msg, err := commit.FixupMessage(ctx, "abc123")
+	if err != nil {
+		t.Fatalf("FixupMessage failed: %v", err)
+	}
+	if msg != "fixup! feat(parser): support nested lists" {
+		t.Errorf("FixupMessage = %q", msg)
+	}
+
+	// Fixing up a fixup still targets the original commit.
+	if msg, _ := commit.FixupMessage(ctx, "HEAD~1"); msg != "fixup! fix: handle empty input" {
+		t.Errorf("FixupMessage of a fixup = %q, want the original subject", msg)
+	}
+
+	if _, err := commit.FixupMessage(ctx, "HEAD~2"); err == nil {
+		t.Error("expected an error for an empty target subject")
+	}
+	if _, err := commit.FixupMessage(ctx, "nope"); err == nil {
+		t.Error("expected an error for an unknown ref")
+	}
+}
+
+func TestCommitTypeReachesPrompt(t *testing.T) {
+	fakeGit := &testutil.FakeGit{StagedDiffContent: testutil.SampleDiffSmall, IsInRepoValue: true}
+	fakeLLM := &testutil.FakeLLM{Suggestions: testutil.SampleLLMResponse()}
+	a := app.NewApp(fakeLLM, fakeGit, cache.NewInMemory(), 8192, true)
+	ctx := context.Background()
+
+	if _, err := a.Suggest.SuggestCommits(ctx, "mock", "m", 0.5); err != nil {
+		t.Fatalf("SuggestCommits failed: %v", err)
+	}
+	a.Suggest.SetCommitType("fix")
+	if _, err := a.Suggest.SuggestCommits(ctx, "mock", "m", 0.5); err != nil {
+		t.Fatalf("SuggestCommits failed: %v", err)
+	}
+	// A cached answer for any type must not be reused.
+	if fakeLLM.CallCount != 2 {
+		t.Errorf("CallCount = %d, want the typed request sent", fakeLLM.CallCount)
+	}
+	if prompt := prompts.Commit(fakeLLM.LastInput); !strings.Contains(prompt, `"type":"fix"`) {
+		t.Errorf("prompt does not ask for type fix:\n%s", prompt)
+	}
+}
+
+func TestSuggestScopedToPaths(t *testing.T) {
+	fakeGit := &testutil.FakeGit{
+		StagedDiffContent: testutil.SampleDiffSmall,
+		PathDiffs:         map[string]string{"main.go": testutil.SampleDiffSmall},
+		IsInRepoValue:     true,
+	}
+	fakeLLM := &testutil.FakeLLM{Suggestions: testutil.SampleLLMResponse()}
+	a := app.NewApp(fakeLLM, fakeGit, cache.NewInMemory(), 8192, true)
+	ctx := context.Background()
+
+	full, err := a.Suggest.PrepareDiff(ctx, "mock", "m", 0.7)
+	if err != nil {
+		t.Fatalf("PrepareDiff failed: %v", err)
+	}
+
+	a.Suggest.SetPaths([]string{"main.go"})
+	if _, err := a.Suggest.SuggestCommits(ctx, "mock", "m", 0.5); err != nil {
+		t.Fatalf("SuggestCommits failed: %v", err)
+	}
+	if strings.Join(fakeGit.LastPaths, ",") != "main.go" {
+		t.Errorf("LastPaths = %v, want [main.go]", fakeGit.LastPaths)
+	}
+	scoped, err := a.Suggest.PrepareDiff(ctx, "mock", "m", 0.7)
+	if err != nil {
+		t.Fatalf("PrepareDiff failed: %v", err)
+	}
+	// Same diff bytes, but scoped and full runs must not share a cache entry.
+	if scoped.CacheKey == full.CacheKey {
+		t.Error("scoped and full runs share a cache key")
+	}
+
+	a.Suggest.SetPaths([]string{"main.go", "docs/typo.md"})
+	fakeGit.PathDiffCalls = 0
+	_, err = a.Suggest.SuggestCommits(ctx, "mock", "m", 0.5)
+	if err == nil || !strings.Contains(err.Error(), "no staged changes in docs/typo.md") {
+		t.Errorf("error = %v, want one naming the path with nothing staged", err)
+	}
+	if fakeGit.PathDiffCalls != 1 {
+		t.Errorf("StagedDiffForPaths called %d times, want one combined diff", fakeGit.PathDiffCalls)
+	}
+}
+
+func TestSuggestScopedToDirectory(t *testing.T) {
+	docs := "diff --git a/docs/guide.md b/docs/guide.md\n--- a/docs/guide.md\n+++ b/docs/guide.md\n@@ -1 +1 @@\n-old\n+new\n"
+	fakeGit := &testutil.FakeGit{
+		PathDiffs:     map[string]string{"./docs/": docs, "main.go": testutil.SampleDiffSmall},
+		IsInRepoValue: true,
+	}
+	fakeLLM := &testutil.FakeLLM{Suggestions: testutil.SampleLLMResponse()}
+	a := app.NewApp(fakeLLM, fakeGit, cache.NewInMemory(), 8192, true)
+
+	a.Suggest.SetPaths([]string{"./docs/", "main.go"})
+	if _, err := a.Suggest.SuggestCommits(context.Background(), "mock", "m", 0.5); err != nil {
+		t.Errorf("SuggestCommits() error = %v, want the directory pathspec to match docs/guide.md", err)
+	}
+}
+
+func TestRedactionCanBeDisabled(t *testing.T) {
+	const secret = "sk-abcdefghijklmnopqrstuvwxyz012345"
+	diff := "diff --git a/app.env b/app.env\n--- a/app.env\n+++ b/app.env\n@@ -0,0 +1 @@\n+OPENAI_API_KEY=" + secret + "\n"
+	fakeGit := &testutil.FakeGit{StagedDiffContent: diff, IsInRepoValue: true}
+	ctx := context.Background()
+
+	// Default: the provider never sees the secret and there is no warning.
+	fakeLLM := &testutil.FakeLLM{Suggestions: testutil.SampleLLMResponse()}
+	a := app.NewApp(fakeLLM, fakeGit, cache.NewInMemory(), 8192, false)
+	result, err := a.Suggest.SuggestCommitsDetailed(ctx, "mock", "m", 0.5)
+	if err != nil {
+		t.Fatalf("SuggestCommitsDetailed failed: %v", err)
+	}
+	if strings.Contains(fakeLLM.LastInput.StagedDiff, secret) || !result.Redacted {
+		t.Errorf("default run sent %q (Redacted=%v), want it redacted", fakeLLM.LastInput.StagedDiff, result.Redacted)
+	}
+	for _, w := range result.Warnings {
+		if w == app.RedactionDisabledWarning {
+			t.Error("default run warned that redaction is off")
+		}
+	}
+
+	// Opted out: the raw diff is sent and the first result says so once.
+	fakeLLM = &testutil.FakeLLM{Suggestions: testutil.SampleLLMResponse()}
+	a = app.NewApp(fakeLLM, fakeGit, cache.NewInMemory(), 8192, false)
+	a.Suggest.SetRedact(false)
+	result, err = a.Suggest.SuggestCommitsDetailed(ctx, "mock", "m", 0.5)
+	if err != nil {
+		t.Fatalf("SuggestCommitsDetailed failed: %v", err)
+	}
+	if !strings.Contains(fakeLLM.LastInput.StagedDiff, secret) {
+		t.Errorf("provider got %q, want the raw diff", fakeLLM.LastInput.StagedDiff)
+	}
+	if result.Redacted {
+		t.Error("Redacted = true with redaction off")
+	}
+	if len(result.Warnings) == 0 || result.Warnings[0] != app.RedactionDisabledWarning {
+		t.Errorf("Warnings = %v, want the redaction-off warning", result.Warnings)
+	}
+	again, err := a.Suggest.SuggestCommitsDetailed(ctx, "mock", "m", 0.5)
+	if err != nil {
+		t.Fatalf("SuggestCommitsDetailed failed: %v", err)
+	}
+	if len(again.Warnings) != 0 {
+		t.Errorf("second run Warnings = %v, want the warning only once", again.Warnings)
+	}
+}
+
+func TestSummarizeLargeHunks(t *testing.T) {
+	fakeGit := &testutil.FakeGit{
+		StagedDiffContent: testutil.SampleDiffWithBinary + testutil.SampleDiffLarge,
+		IsInRepoValue:     true,
+	}
+	ctx := context.Background()
+
+	// Off: the binary is dropped and the long hunk is sent whole.
+	fakeLLM := &testutil.FakeLLM{Suggestions: testutil.SampleLLMResponse()}
+	a := app.NewApp(fakeLLM, fakeGit, cache.NewInMemory(), 65536, false)
+	if _, err := a.Suggest.SuggestCommits(ctx, "mock", "m", 0.5); err != nil {
+		t.Fatalf("SuggestCommits failed: %v", err)
+	}
+	full := fakeLLM.LastInput.StagedDiff
+	if strings.Contains(full, "[binary: logo.png]") || strings.Contains(full, "lines omitted") {
+		t.Errorf("placeholders used without opting in:\n%s", full)
+	}
+
+	fakeLLM = &testutil.FakeLLM{Suggestions: testutil.SampleLLMResponse()}
+	a = app.NewApp(fakeLLM, fakeGit, cache.NewInMemory(), 65536, false)
+	a.Suggest.SetSummarizeLargeHunks(true)
+	if _, err := a.Suggest.SuggestCommits(ctx, "mock", "m", 0.5); err != nil {
+		t.Fatalf("SuggestCommits failed: %v", err)
+	}
+	sent := fakeLLM.LastInput.StagedDiff
+	for _, want := range []string{
+		"diff --git a/logo.png b/logo.png\n[binary: logo.png]\n",
+		"@@ -1,5 +1,1000 @@\n",
+		"more lines omitted",
+		"+    fmt.Println(\"Hello, World!\")\n", // small hunks are untouched
+	} {
+		if !strings.Contains(sent, want) {
+			t.Errorf("summarized diff missing %q:\n%s", want, sent)
+		}
+	}
+	if len(sent) >= len(full)/2 {
+		t.Errorf("summarized diff is %d bytes, want well under the %d-byte original", len(sent), len(full))
+	}
+	if got := fakeLLM.LastInput.FileList; len(got) != 3 {
+		t.Errorf("FileList = %v, want all three files including the binary", got)
+	}
+}
+
+func TestIgnoreFileExcludesAndRedacts(t *testing.T) {
+	ignore, err := config.ParseIgnoreFile("pkg/file001.go\ndocs/\n\nredact:\nbuild-[0-9]+\\.corp\\.example\n")
+	if err != nil {
+		t.Fatalf("ParseIgnoreFile failed: %v", err)
+	}
+	diff := testutil.SampleDiffManyFiles(3) +
+		"diff --git a/docs/guide.md b/docs/guide.md\n--- a/docs/guide.md\n+++ b/docs/guide.md\n@@ -0,0 +1 @@\n+guide\n" +
+		"diff --git a/deploy.sh b/deploy.sh\n--- a/deploy.sh\n+++ b/deploy.sh\n@@ -0,0 +1 @@\n+ssh build-42.corp.example\n"
+	fakeGit := &testutil.FakeGit{StagedDiffContent: diff, IsInRepoValue: true}
+	fakeLLM := &testutil.FakeLLM{Suggestions: testutil.SampleLLMResponse()}
+	redactor, err := security.NewRedactorWithPatterns(ignore.Redact)
+	if err != nil {
+		t.Fatalf("NewRedactorWithPatterns failed: %v", err)
+	}
+	a := app.NewAppWithRedactor(fakeLLM, fakeGit, cache.NewInMemory(), 8192, false, redactor)
+	if err := a.Suggest.SetExclude(ignore.Exclude); err != nil {
+		t.Fatalf("SetExclude failed: %v", err)
+	}
+
+	prepared, err := a.Suggest.PrepareDiff(context.Background(), "mock", "m", 0.7)
+	if err != nil {
+		t.Fatalf("PrepareDiff failed: %v", err)
+	}
+	if want := []string{"pkg/file001.go", "docs/guide.md"}; fmt.Sprint(prepared.Excluded) != fmt.Sprint(want) {
+		t.Errorf("Excluded = %q, want %q", prepared.Excluded, want)
+	}
+	if strings.Contains(prepared.Diff, "file001") || strings.Contains(prepared.Diff, "guide") {
+		t.Errorf("excluded files reached the diff:\n%s", prepared.Diff)
+	}
+	if !strings.Contains(prepared.Diff, "pkg/file002.go") || strings.Contains(prepared.Diff, "build-42") {
+		t.Errorf("diff should keep other files and redact the host:\n%s", prepared.Diff)
+	}
+
+	if err := a.Suggest.SetExclude([]string{"*.go", "*.sh", "**/*.md"}); err != nil {
+		t.Fatalf("SetExclude failed: %v", err)
+	}
+	if _, err := a.Suggest.SuggestCommitsDetailed(context.Background(), "mock", "m", 0.5); !errors.Is(err, app.ErrAllExcluded) {
+		t.Errorf("error = %v, want ErrAllExcluded", err)
+	}
+	if err := a.Suggest.SetExclude([]string{"*.go", "!pkg/file000.go"}); err != nil {
+		t.Fatalf("SetExclude failed: %v", err)
+	}
+	prepared, err = a.Suggest.PrepareDiff(context.Background(), "mock", "m", 0.7)
+	if err != nil {
+		t.Fatalf("PrepareDiff failed: %v", err)
+	}
+	if !strings.Contains(prepared.Diff, "pkg/file000.go") || strings.Contains(prepared.Diff, "pkg/file002.go") {
+		t.Errorf("a negated pattern should re-include its file:\n%s", prepared.Diff)
+	}
+}
+
+func TestCloseReleasesProviderAndCacheOnce(t *testing.T) {
+	fakeLLM := &testutil.FakeLLM{Suggestions: testutil.SampleLLMResponse(), CloseErr: errors.New("connection reset")}
+	fakeGit := &testutil.FakeGit{StagedDiffContent: testutil.SampleDiffSmall, IsInRepoValue: true}
+	cacheAdapter := cache.NewInMemory()
+	a := app.NewApp(fakeLLM, fakeGit, cacheAdapter, 8192, true)
+	if _, err := a.Suggest.SuggestCommits(context.Background(), "openai", "gpt-4o-mini", 0.7); err != nil {
+		t.Fatalf("SuggestCommits() error = %v", err)
+	}
+	if cacheAdapter.Size() != 1 {
+		t.Fatalf("cache size = %d, want the suggestions cached", cacheAdapter.Size())
+	}
+
+	if err := a.Close(); err == nil || !strings.Contains(err.Error(), "connection reset") {
+		t.Errorf("Close() error = %v, want the provider's", err)
+	}
+	if err := a.Close(); err != nil {
+		t.Errorf("second Close() error = %v, want nil", err)
+	}
+	if fakeLLM.CloseCalls != 1 || cacheAdapter.Size() != 0 {
+		t.Errorf("provider closed %d times, cache size %d; want 1 and 0", fakeLLM.CloseCalls, cacheAdapter.Size())
+	}
+}
+
+func TestConcurrentCloseClosesOnce(t *testing.T) {
+	fakeLLM := &testutil.FakeLLM{Suggestions: testutil.SampleLLMResponse()}
+	a := app.NewApp(fakeLLM, &testutil.FakeGit{}, cache.NewInMemory(), 8192, true)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = a.Close()
+		}()
+	}
+	wg.Wait()
+	if fakeLLM.CloseCalls != 1 {
+		t.Errorf("provider closed %d times, want 1", fakeLLM.CloseCalls)
 	}
 }